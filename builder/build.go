@@ -194,6 +194,17 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 		return BuildResult{}, fmt.Errorf("unknown libc: %s", config.Target.Libc)
 	}
 
+	if config.GC() == "incremental" {
+		// The incremental GC relies on a single thread of execution, and on
+		// being able to scan the stack of a goroutine when it is paused.
+		if strings.HasPrefix(config.Triple(), "wasm32-") {
+			return BuildResult{}, fmt.Errorf("-gc=incremental is not supported on %s", config.Triple())
+		}
+		if config.Scheduler() != "none" && config.Scheduler() != "tasks" {
+			return BuildResult{}, fmt.Errorf("-gc=incremental only supports -scheduler=none and -scheduler=tasks")
+		}
+	}
+
 	optLevel, speedLevel, sizeLevel := config.OptLevel()
 	compilerConfig := &compiler.Config{
 		Triple:          config.Triple(),
@@ -216,6 +227,9 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 		Debug:              !config.Options.SkipDWARF, // emit DWARF except when -internal-nodwarf is passed
 		Nobounds:           config.Options.Nobounds,
 		PanicStrategy:      config.PanicStrategy(),
+		SymTab:             config.SymTab(),
+		WriteBarrier:       config.GC() == "incremental",
+		GCPauseBudget:      config.GCPauseBudget(),
 	}
 
 	// Load the target machine, which is the LLVM object that contains all
@@ -248,6 +262,13 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 		return result, err
 	}
 
+	// Embed the module dependencies and build settings, for
+	// runtime/debug.ReadBuildInfo.
+	globalValues["runtime"]["modinfo"], err = makeModInfo(config, lprogram)
+	if err != nil {
+		return result, err
+	}
+
 	// Store which filesystem paths map to which package name.
 	result.PackagePathMap = make(map[string]string, len(lprogram.Packages))
 	for _, pkg := range lprogram.Sorted() {
@@ -556,6 +577,13 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 			globalsMod := makeGlobalsModule(ctx, globalValues, machine)
 			llvm.LinkModules(mod, globalsMod)
 
+			// The module information is only used by
+			// runtime/debug.ReadBuildInfo. Let it be internalized below, so
+			// that it is removed from programs that don't call it.
+			if modinfo := mod.NamedGlobal("runtime.modinfo"); !modinfo.IsNil() {
+				modinfo.SetVisibility(llvm.HiddenVisibility)
+			}
+
 			// Create runtime.initAll function that calls the runtime
 			// initializer of each package.
 			llvmInitFn := mod.NamedFunction("runtime.initAll")
@@ -700,6 +728,17 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 		}
 	}
 
+	if config.SymTab() == "full" {
+		switch compileopts.CanonicalArchName(config.Triple()) {
+		case "x86_64", "i386", "aarch64", "arm", "riscv32", "riscv64", "wasm32":
+		default:
+			return result, fmt.Errorf("-symtab=full is not supported on %s", config.Triple())
+		}
+		if config.GOOS() == "darwin" || config.GOOS() == "windows" {
+			return result, fmt.Errorf("-symtab=full is not supported on %s", config.GOOS())
+		}
+	}
+
 	// Add compiler-rt dependency if needed. Usually this is a simple load from
 	// a cache.
 	if config.Target.RTLib == "compiler-rt" {
@@ -779,15 +818,18 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 	}
 
 	// Strip debug information with -no-debug.
+	// These flags are added separately, because the symbol table (if any) is
+	// created from the debug information.
+	var stripFlags []string
 	if hasDebug && !config.Debug() {
 		if config.Target.Linker == "wasm-ld" {
 			// Don't just strip debug information, also compress relocations
 			// while we're at it. Relocations can only be compressed when debug
 			// information is stripped.
-			ldflags = append(ldflags, "--strip-debug", "--compress-relocations")
+			stripFlags = append(stripFlags, "--strip-debug", "--compress-relocations")
 		} else if config.Target.Linker == "ld.lld" {
 			// ld.lld is also used on Linux.
-			ldflags = append(ldflags, "--strip-debug")
+			stripFlags = append(stripFlags, "--strip-debug")
 		} else {
 			// Other linkers may have different flags.
 			return result, errors.New("cannot remove debug information: unknown linker: " + config.Target.Linker)
@@ -836,10 +878,20 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 				ldflags = append(ldflags,
 					"-mllvm", "--rotation-max-header-size=0")
 			}
-			if config.Options.PrintCommands != nil {
-				config.Options.PrintCommands(config.Target.Linker, ldflags...)
+			linkExecutable := func(extraFlags ...string) error {
+				flags := append(append([]string(nil), ldflags...), extraFlags...)
+				if config.Options.PrintCommands != nil {
+					config.Options.PrintCommands(config.Target.Linker, flags...)
+				}
+				return link(config.Target.Linker, flags...)
+			}
+			if config.SymTab() == "full" && config.Target.Linker == "ld.lld" {
+				// Create a symbol table for runtime.Callers and friends.
+				// This requires linking twice.
+				err = linkWithSymbolTable(linkExecutable, stripFlags, config.Target.LinkerScript != "", result.Executable, tmpdir, compilerConfig)
+			} else {
+				err = linkExecutable(stripFlags...)
 			}
-			err = link(config.Target.Linker, ldflags...)
 			if err != nil {
 				return err
 			}
@@ -1057,6 +1109,13 @@ func Build(pkgName, outpath, tmpdir string, config *compileopts.Config) (BuildRe
 		if err != nil {
 			return result, err
 		}
+	case "tgo":
+		// Signed firmware container (see firmware.go).
+		result.Binary = filepath.Join(tmpdir, "main"+outext)
+		err = makeFirmwareContainer(config, result.Executable, result.Binary)
+		if err != nil {
+			return result, err
+		}
 	default:
 		return result, fmt.Errorf("unknown output binary format: %s", outputBinaryFormat)
 	}
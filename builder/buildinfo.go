@@ -0,0 +1,159 @@
+package builder
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tinygo-org/tinygo/compileopts"
+	"github.com/tinygo-org/tinygo/loader"
+)
+
+// Markers around the build information in runtime.modinfo. These are the same
+// as in the gc toolchain, and are stripped by runtime/debug.ReadBuildInfo.
+const (
+	modInfoStart = "0w\xaf\x0c\x92t\b\x02A\xe1\xc1\a\xe6\xd6\x18\xe6"
+	modInfoEnd   = "\xf92C1\x86\x18 r\x00\x82B\x10A\x16\xd8\xf2"
+)
+
+// makeModInfo returns the build information of the program (module
+// dependencies and build settings), in the same format as the gc toolchain
+// stores it in the runtime.modinfo string.
+func makeModInfo(config *compileopts.Config, lprogram *loader.Program) (string, error) {
+	mainPkg := lprogram.MainPkg()
+	info := debug.BuildInfo{
+		Path: mainPkg.ImportPath,
+	}
+	if mainPkg.Module.Path != "" {
+		info.Main = debug.Module{
+			Path:    mainPkg.Module.Path,
+			Version: "(devel)",
+		}
+	}
+
+	// Add all modules that provide a package in the program.
+	deps := map[string]*debug.Module{}
+	for _, pkg := range lprogram.Sorted() {
+		mod := &pkg.Module
+		if mod.Path == "" || mod.Main || deps[mod.Path] != nil {
+			continue
+		}
+		dep := &debug.Module{
+			Path:    mod.Path,
+			Version: mod.Version,
+			Sum:     mod.Sum,
+		}
+		if mod.Replace != nil {
+			dep.Replace = &debug.Module{
+				Path:    mod.Replace.Path,
+				Version: mod.Replace.Version,
+				Sum:     mod.Replace.Sum,
+			}
+		}
+		deps[mod.Path] = dep
+		info.Deps = append(info.Deps, dep)
+	}
+	sort.Slice(info.Deps, func(i, j int) bool {
+		return info.Deps[i].Path < info.Deps[j].Path
+	})
+
+	// Add the build settings. These are mostly the same as with the gc
+	// toolchain, plus the TinyGo specific flags that affect the binary.
+	addSetting := func(key, value string) {
+		info.Settings = append(info.Settings, debug.BuildSetting{Key: key, Value: value})
+	}
+	if config.BuildMode() != "default" {
+		addSetting("-buildmode", config.BuildMode())
+	}
+	addSetting("-compiler", "tinygo")
+	if len(config.Options.Tags) != 0 {
+		addSetting("-tags", strings.Join(config.Options.Tags, ","))
+	}
+	if config.Options.Target != "" {
+		addSetting("-target", config.Options.Target)
+	}
+	addSetting("-gc", config.GC())
+	addSetting("-scheduler", config.Scheduler())
+	addSetting("GOARCH", config.GOARCH())
+	addSetting("GOOS", config.GOOS())
+	if config.GOARCH() == "arm" {
+		addSetting("GOARM", config.GOARM())
+	}
+	if mainPkg.Module.Main && mainPkg.Module.Dir != "" && config.Options.BuildVCS != "false" {
+		settings, err := readVCSSettings(mainPkg.Module.Dir)
+		if err != nil && config.Options.BuildVCS == "true" {
+			return "", fmt.Errorf("error obtaining VCS status: %w\n\tUse -buildvcs=false to disable VCS stamping.", err)
+		}
+		info.Settings = append(info.Settings, settings...)
+	}
+
+	return modInfoStart + info.String() + modInfoEnd, nil
+}
+
+// readVCSSettings returns the version control settings (vcs.revision and
+// similar) of the git repository that contains the given directory. Like with
+// the gc toolchain, nothing is returned if the directory is not part of a git
+// repository. An error is returned if it is, but the settings can't be read
+// (for example, because git is not installed). The error is only reported
+// with -buildvcs=true.
+func readVCSSettings(dir string) ([]debug.BuildSetting, error) {
+	if !inGitRepository(dir) {
+		return nil, nil
+	}
+	git := func(args ...string) (string, error) {
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		var stdout bytes.Buffer
+		cmd.Stdout = &stdout
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("git %s: %w", args[len(args)-1], err)
+		}
+		return stdout.String(), nil
+	}
+
+	// The last commit, as "<hash>:<commit time>". This fails in a repository
+	// without commits.
+	out, err := git("-c", "log.showsignature=false", "log", "-1", "--format=%H:%ct")
+	if err != nil {
+		return nil, err
+	}
+	hash, timestamp, ok := strings.Cut(strings.TrimSpace(out), ":")
+	if !ok {
+		return nil, fmt.Errorf("unexpected git log output: %q", out)
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	status, err := git("status", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	return []debug.BuildSetting{
+		{Key: "vcs", Value: "git"},
+		{Key: "vcs.revision", Value: hash},
+		{Key: "vcs.time", Value: time.Unix(seconds, 0).UTC().Format(time.RFC3339Nano)},
+		{Key: "vcs.modified", Value: strconv.FormatBool(status != "")},
+	}, nil
+}
+
+// inGitRepository returns whether the given directory or one of its parents
+// contains a .git directory (or a .git file, for worktrees and submodules).
+func inGitRepository(dir string) bool {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+}
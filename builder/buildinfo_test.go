@@ -0,0 +1,65 @@
+package builder
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// Test that version control settings are read from git repositories, and that
+// directories outside a repository are not an error.
+func TestReadVCSSettings(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	settings, err := readVCSSettings(dir)
+	if err != nil || settings != nil {
+		t.Fatalf("outside a repository: got %v (%v), expected no settings", settings, err)
+	}
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found:", err)
+	}
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	if _, err := readVCSSettings(dir); err == nil {
+		t.Error("expected an error in a repository without commits")
+	}
+
+	path := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(path, []byte("package main\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	git("add", "main.go")
+	git("commit", "-q", "-m", "initial commit")
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	for _, modified := range []string{"false", "true"} {
+		settings, err := readVCSSettings(sub)
+		if err != nil {
+			t.Fatal("could not read settings:", err)
+		}
+		values := map[string]string{}
+		for _, setting := range settings {
+			values[setting.Key] = setting.Value
+		}
+		if values["vcs"] != "git" || len(values["vcs.revision"]) != 40 || values["vcs.time"] == "" {
+			t.Errorf("unexpected settings: %v", settings)
+		}
+		if values["vcs.modified"] != modified {
+			t.Errorf("expected vcs.modified=%s, got %v", modified, settings)
+		}
+		if err := os.WriteFile(path, []byte("package main // changed\n"), 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
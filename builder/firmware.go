@@ -0,0 +1,316 @@
+package builder
+
+// This file creates and verifies signed firmware containers (.tgo files), for
+// over-the-air updates and for flashing firmware that was built elsewhere, for
+// example by CI.
+//
+// The format of a firmware container is:
+//
+//	magic     [8]byte  "TinyGoFW"
+//	length    uint32   length of the manifest (little endian)
+//	manifest  []byte   JSON, see firmwareManifest
+//	signature [64]byte Ed25519 signature of all bytes before it
+//	payload   []byte   the firmware image, like a .bin file
+//
+// Only the header and the manifest are signed. The manifest contains the
+// SHA-256 hash of the payload, so that a bootloader can check the signature
+// before it receives the payload, and then hash the payload while writing it
+// to flash.
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"debug/elf"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/marcinbor85/gohex"
+	"github.com/tinygo-org/tinygo/compileopts"
+)
+
+const (
+	firmwareMagic         = "TinyGoFW"
+	firmwareFormatVersion = 1
+)
+
+// firmwareManifest is the manifest of a firmware container.
+type firmwareManifest struct {
+	Format  int    `json:"format"`            // firmwareFormatVersion
+	Target  string `json:"target"`            // -target flag, or GOOS/GOARCH
+	Version string `json:"version,omitempty"` // main.version, set with -ldflags="-X main.version=..."
+	BuildID string `json:"build_id"`          // build ID of the firmware, in hex, see firmwareBuildID
+	Address uint64 `json:"address"`           // load address of the payload
+	Size    int    `json:"size"`              // size of the payload
+	SHA256  string `json:"sha256"`            // hash of the payload, in hex
+	KeyID   string `json:"key_id"`            // first 8 bytes of the SHA-256 hash of the public key, in hex
+}
+
+// firmwareTarget returns the name of the target, as stored in firmware
+// containers.
+func firmwareTarget(config *compileopts.Config) string {
+	if config.Options.Target != "" {
+		return config.Options.Target
+	}
+	return config.GOOS() + "/" + config.GOARCH()
+}
+
+// keyID returns the key ID of a public key, as stored in firmware containers.
+func keyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// makeFirmwareContainer creates a signed firmware container from an ELF file,
+// using the private key of the -signing-key flag.
+func makeFirmwareContainer(config *compileopts.Config, infile, outfile string) error {
+	if config.Options.SigningKey == "" {
+		return errors.New("a .tgo firmware container requires a private key, set with -signing-key")
+	}
+	key, err := readPrivateKey(config.Options.SigningKey)
+	if err != nil {
+		return err
+	}
+	address, payload, err := extractROM(infile)
+	if err != nil {
+		return err
+	}
+	buildID, err := firmwareBuildID(infile)
+	if err != nil {
+		return fmt.Errorf("could not read build ID: %w", err)
+	}
+	manifest := firmwareManifest{
+		Target:  firmwareTarget(config),
+		Version: config.Options.GlobalValues["main"]["version"],
+		BuildID: hex.EncodeToString(buildID),
+		Address: address,
+	}
+	container, err := signFirmware(manifest, payload, key)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outfile, container, 0o666)
+}
+
+// firmwareBuildID returns the build ID of the linked ELF file: the GNU build ID
+// note if the linker added one (with --build-id), or otherwise the SHA-256 hash
+// of the file. Either way it identifies the firmware, not the compiler.
+func firmwareBuildID(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := elf.NewFile(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	for _, section := range f.Sections {
+		if section.Type != elf.SHT_NOTE {
+			continue
+		}
+		notes, err := section.Data()
+		if err != nil {
+			return nil, err
+		}
+		if id := gnuBuildID(notes, f.ByteOrder); id != nil {
+			return id, nil
+		}
+	}
+	sum := sha256.Sum256(data)
+	return sum[:], nil
+}
+
+// gnuBuildID returns the descriptor of the NT_GNU_BUILD_ID note in the given
+// note section, or nil if there is none.
+func gnuBuildID(notes []byte, order binary.ByteOrder) []byte {
+	const ntGNUBuildID = 3
+	align4 := func(n uint32) int { return int((uint64(n) + 3) &^ 3) }
+	for len(notes) >= 12 {
+		nameSize := order.Uint32(notes[0:])
+		descSize := order.Uint32(notes[4:])
+		noteType := order.Uint32(notes[8:])
+		notes = notes[12:]
+		if align4(nameSize) > len(notes) {
+			return nil
+		}
+		name := notes[:nameSize]
+		notes = notes[align4(nameSize):]
+		if align4(descSize) > len(notes) {
+			return nil
+		}
+		desc := notes[:descSize]
+		notes = notes[align4(descSize):]
+		if noteType == ntGNUBuildID && string(name) == "GNU\x00" {
+			return desc
+		}
+	}
+	return nil
+}
+
+// signFirmware returns a firmware container with the given manifest and
+// payload. The fields of the manifest that describe the payload and the key
+// are filled in.
+func signFirmware(manifest firmwareManifest, payload []byte, key ed25519.PrivateKey) ([]byte, error) {
+	sum := sha256.Sum256(payload)
+	manifest.Format = firmwareFormatVersion
+	manifest.Size = len(payload)
+	manifest.SHA256 = hex.EncodeToString(sum[:])
+	manifest.KeyID = keyID(key.Public().(ed25519.PublicKey))
+	manifestData, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString(firmwareMagic)
+	binary.Write(buf, binary.LittleEndian, uint32(len(manifestData)))
+	buf.Write(manifestData)
+	buf.Write(ed25519.Sign(key, buf.Bytes()))
+	buf.Write(payload)
+	return buf.Bytes(), nil
+}
+
+// parseFirmware checks the signature and the payload of a firmware container,
+// and returns its manifest and payload.
+func parseFirmware(container []byte, key ed25519.PublicKey) (*firmwareManifest, []byte, error) {
+	headerSize := len(firmwareMagic) + 4
+	if len(container) < headerSize || string(container[:len(firmwareMagic)]) != firmwareMagic {
+		return nil, nil, errors.New("not a firmware container")
+	}
+	manifestSize := binary.LittleEndian.Uint32(container[len(firmwareMagic):])
+	if uint64(len(container)-headerSize) < uint64(manifestSize)+ed25519.SignatureSize {
+		return nil, nil, errors.New("firmware container is truncated")
+	}
+	signed := container[:headerSize+int(manifestSize)]
+	signature := container[len(signed) : len(signed)+ed25519.SignatureSize]
+	payload := container[len(signed)+ed25519.SignatureSize:]
+
+	manifest := &firmwareManifest{}
+	if err := json.Unmarshal(signed[headerSize:], manifest); err != nil {
+		return nil, nil, fmt.Errorf("could not read firmware manifest: %w", err)
+	}
+	if !ed25519.Verify(key, signed, signature) {
+		if manifest.KeyID != keyID(key) {
+			return nil, nil, fmt.Errorf("firmware container is signed with a different key (key ID %s, expected %s)", manifest.KeyID, keyID(key))
+		}
+		return nil, nil, errors.New("firmware container has an invalid signature")
+	}
+	if manifest.Format != firmwareFormatVersion {
+		return nil, nil, fmt.Errorf("unsupported firmware container format: %d", manifest.Format)
+	}
+	sum := sha256.Sum256(payload)
+	if manifest.Size != len(payload) || manifest.SHA256 != hex.EncodeToString(sum[:]) {
+		return nil, nil, errors.New("firmware payload does not match the manifest")
+	}
+	return manifest, payload, nil
+}
+
+// ExtractFirmwareContainer verifies a firmware container with the public key
+// of the -verify-key flag, and converts the firmware in it to a file in tmpdir
+// in the given format (.bin, .hex or .uf2), for flashing. It returns the path
+// of that file.
+func ExtractFirmwareContainer(path, fileExt, tmpdir string, config *compileopts.Config) (string, error) {
+	if config.Options.VerifyKey == "" {
+		return "", errors.New("flashing a .tgo firmware container requires a public key, set with -verify-key")
+	}
+	key, err := readPublicKey(config.Options.VerifyKey)
+	if err != nil {
+		return "", err
+	}
+	container, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	manifest, payload, err := parseFirmware(container, key)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	if target := firmwareTarget(config); manifest.Target != target {
+		return "", fmt.Errorf("%s: firmware is for target %s, not %s", path, manifest.Target, target)
+	}
+
+	outfile := filepath.Join(tmpdir, "main"+fileExt)
+	switch config.BinaryFormat(fileExt) {
+	case "bin":
+		return outfile, os.WriteFile(outfile, payload, 0o666)
+	case "hex":
+		mem := gohex.NewMemory()
+		if err := mem.AddBinary(uint32(manifest.Address), payload); err != nil {
+			return "", objcopyError{"failed to create .hex file", err}
+		}
+		f, err := os.Create(outfile)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		return outfile, mem.DumpIntelHex(f, 16)
+	case "uf2":
+		output, _, err := convertBinToUF2(payload, uint32(manifest.Address), config.Target.UF2FamilyID)
+		if err != nil {
+			return "", err
+		}
+		return outfile, os.WriteFile(outfile, output, 0o666)
+	default:
+		return "", fmt.Errorf("cannot flash a firmware container as a %s file", fileExt)
+	}
+}
+
+// readPrivateKey reads an Ed25519 private key from a PEM file, as created by
+// "openssl genpkey -algorithm ed25519".
+func readPrivateKey(path string) (ed25519.PrivateKey, error) {
+	der, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if key, ok := key.(ed25519.PrivateKey); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%s: not an Ed25519 private key", path)
+}
+
+// readPublicKey reads an Ed25519 public key from a PEM file, as created by
+// "openssl pkey -pubout".
+func readPublicKey(path string) (ed25519.PublicKey, error) {
+	der, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if key, ok := key.(ed25519.PublicKey); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%s: not an Ed25519 public key", path)
+}
+
+// readPEM returns the contents of the first PEM block of the given type in a
+// file.
+func readPEM(path, blockType string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("%s: no %s found", path, blockType)
+		}
+		if block.Type == blockType {
+			return block.Bytes, nil
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package builder
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"debug/elf"
+	"encoding/binary"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tinygo-org/tinygo/compileopts"
+)
+
+// Test that firmware containers are verified, and that changes to any part of
+// them are detected.
+func TestFirmwareContainer(t *testing.T) {
+	t.Parallel()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := bytes.Repeat([]byte("firmware"), 100)
+	container, err := signFirmware(firmwareManifest{
+		Target:  "pico",
+		Version: "1.2.3",
+		Address: 0x10000000,
+	}, payload, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, data, err := parseFirmware(container, publicKey)
+	if err != nil {
+		t.Fatal("could not parse firmware container:", err)
+	}
+	if manifest.Target != "pico" || manifest.Version != "1.2.3" || manifest.Address != 0x10000000 || manifest.Size != len(payload) {
+		t.Errorf("unexpected manifest: %+v", manifest)
+	}
+	if !bytes.Equal(data, payload) {
+		t.Error("payload was not preserved")
+	}
+
+	modify := func(offset int) []byte {
+		modified := bytes.Clone(container)
+		modified[offset] ^= 1
+		return modified
+	}
+	manifestOffset := len(firmwareMagic) + 4
+	for _, tc := range []struct {
+		name      string
+		container []byte
+		key       ed25519.PublicKey
+		err       string
+	}{
+		{"magic", modify(0), publicKey, "not a firmware container"},
+		{"manifest", modify(manifestOffset + 2), publicKey, ""},
+		{"signature", modify(len(container) - len(payload) - 1), publicKey, "invalid signature"},
+		{"payload", modify(len(container) - 1), publicKey, "does not match"},
+		{"truncated", container[:manifestOffset+10], publicKey, "truncated"},
+		{"key", container, otherKey, "different key"},
+	} {
+		_, _, err := parseFirmware(tc.container, tc.key)
+		if err == nil {
+			t.Errorf("%s: changed firmware container was accepted", tc.name)
+		} else if !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
+// Test reading keys in the PEM format that OpenSSL uses, and extracting the
+// firmware from a container for flashing.
+func TestFirmwareKeys(t *testing.T) {
+	t.Parallel()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	writePEM := func(name, blockType string, der []byte, err error) string {
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, name)
+		err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o666)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	privatePath := writePEM("key.pem", "PRIVATE KEY", privateDER, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	publicPath := writePEM("key.pub.pem", "PUBLIC KEY", publicDER, err)
+
+	readPrivate, err := readPrivateKey(privatePath)
+	if err != nil {
+		t.Fatal("could not read private key:", err)
+	}
+	if !readPrivate.Equal(privateKey) {
+		t.Error("private key was not read correctly")
+	}
+	readPublic, err := readPublicKey(publicPath)
+	if err != nil {
+		t.Fatal("could not read public key:", err)
+	}
+	if !readPublic.Equal(publicKey) {
+		t.Error("public key was not read correctly")
+	}
+	if _, err := readPublicKey(privatePath); err == nil {
+		t.Error("private key was accepted as public key")
+	}
+
+	payload := []byte("firmware")
+	container, err := signFirmware(firmwareManifest{Target: "pico"}, payload, readPrivate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	containerPath := filepath.Join(dir, "fw.tgo")
+	if err := os.WriteFile(containerPath, container, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	config := &compileopts.Config{
+		Options: &compileopts.Options{Target: "pico", VerifyKey: publicPath},
+		Target:  &compileopts.TargetSpec{},
+	}
+	binary, err := ExtractFirmwareContainer(containerPath, ".bin", dir, config)
+	if err != nil {
+		t.Fatal("could not extract firmware:", err)
+	}
+	if data, err := os.ReadFile(binary); err != nil || !bytes.Equal(data, payload) {
+		t.Errorf("unexpected firmware: %q (%v)", data, err)
+	}
+	config.Options.Target = "wioterminal"
+	if _, err := ExtractFirmwareContainer(containerPath, ".bin", dir, config); err == nil {
+		t.Error("firmware was extracted for a different target")
+	}
+}
+
+// Test that the build ID is read from the GNU build ID note, and that other
+// notes and malformed notes are skipped.
+func TestFirmwareBuildID(t *testing.T) {
+	t.Parallel()
+
+	note := func(name string, noteType uint32, desc []byte) []byte {
+		buf := binary.LittleEndian.AppendUint32(nil, uint32(len(name)))
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(desc)))
+		buf = binary.LittleEndian.AppendUint32(buf, noteType)
+		buf = append(buf, name...)
+		for len(buf)%4 != 0 {
+			buf = append(buf, 0)
+		}
+		buf = append(buf, desc...)
+		for len(buf)%4 != 0 {
+			buf = append(buf, 0)
+		}
+		return buf
+	}
+	id := []byte{0xde, 0xad, 0xbe, 0xef, 0x01}
+	order := binary.LittleEndian
+	for _, tc := range []struct {
+		name  string
+		notes []byte
+		want  []byte
+	}{
+		{"build ID", note("GNU\x00", 3, id), id},
+		{"after other note", append(note("Go\x00\x00", 4, []byte("go build id")), note("GNU\x00", 3, id)...), id},
+		{"other type", note("GNU\x00", 1, id), nil},
+		{"other name", note("XYZ\x00", 3, id), nil},
+		{"truncated", note("GNU\x00", 3, id)[:18], nil},
+		{"empty", nil, nil},
+	} {
+		if got := gnuBuildID(tc.notes, order); !bytes.Equal(got, tc.want) {
+			t.Errorf("%s: got build ID %x, expected %x", tc.name, got, tc.want)
+		}
+	}
+
+	// The build ID of a real ELF file (note or hash) must be stable.
+	path, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := elf.Open(path); err != nil {
+		t.Skip("test binary is not an ELF file")
+	}
+	first, err := firmwareBuildID(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := firmwareBuildID(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) == 0 || !bytes.Equal(first, second) {
+		t.Errorf("unstable build ID: %x != %x", first, second)
+	}
+	if _, err := firmwareBuildID(filepath.Join(t.TempDir(), "missing.elf")); err == nil {
+		t.Error("build ID of a missing file was returned")
+	}
+}
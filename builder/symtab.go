@@ -0,0 +1,540 @@
+package builder
+
+// This file creates the symbol table that is used by runtime.Callers,
+// runtime.CallersFrames, runtime.FuncForPC and friends on targets that walk
+// the stack using frame pointers.
+//
+// The symbol table maps code addresses to a function name, file and line,
+// including inlined functions. It is created from the DWARF debug information
+// of a first link of the program. The program is then linked a second time
+// with the symbol table included. The symbol table is placed in a read-only
+// section that is laid out after all code, so that the second link doesn't
+// change any code addresses (this is verified).
+
+import (
+	"bytes"
+	"debug/dwarf"
+	"debug/elf"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/tinygo-org/tinygo/compiler"
+	"tinygo.org/x/go-llvm"
+)
+
+// symtabFunction is a single function (or a single address range of a
+// function) in the symbol table.
+type symtabFunction struct {
+	entry, end uint64
+	name       string
+	lines      []symtabLine
+	inlines    []symtabInline
+}
+
+// symtabLine is a row in the line table: all code starting at pc (until the
+// next row) belongs to the given file and line.
+type symtabLine struct {
+	pc   uint64
+	file string
+	line int
+}
+
+// symtabInline is an address range where a function is inlined into another.
+// Inlines are stored in preorder, so the innermost inline for a given address
+// is the last one that contains the address.
+type symtabInline struct {
+	start, end uint64
+	parent     int // index of the parent inline, or -1 if inlined in the function itself
+	name       string
+	callFile   string
+	callLine   int
+}
+
+// linkWithSymbolTable links the program with a symbol table. It is linked
+// twice: first with an empty symbol table (and with debug information even
+// when -no-debug is used), and a second time with the symbol table created
+// from the debug information of the first link.
+//
+// Targets with a linker script place the .tinygo_symtab section after the code
+// in the linker script. Other targets use the default layout of the linker,
+// which puts read-only data before the code, so the section is inserted after
+// .text using a small linker script.
+func linkWithSymbolTable(linkExecutable func(extraFlags ...string) error, stripFlags []string, hasLinkerScript bool, executable, tmpdir string, compilerConfig *compiler.Config) error {
+	var layoutFlags []string
+	if !hasLinkerScript {
+		script := filepath.Join(tmpdir, "symtab.ld")
+		err := os.WriteFile(script, []byte("SECTIONS {\n  .tinygo_symtab : { KEEP(*(.tinygo_symtab)) }\n} INSERT AFTER .text;\n"), 0o666)
+		if err != nil {
+			return err
+		}
+		layoutFlags = []string{"-T", script}
+	}
+	emptyTable, err := createSymbolTableObjectFile(nil, binary.LittleEndian, "symtab-empty", tmpdir, compilerConfig)
+	if err != nil {
+		return err
+	}
+	err = linkExecutable(append(append([]string(nil), layoutFlags...), emptyTable)...)
+	if err != nil {
+		return err
+	}
+	functions, byteOrder, err := readSymbolTable(executable)
+	if err != nil {
+		return fmt.Errorf("could not create symbol table: %w", err)
+	}
+	codeLayout, err := readCodeLayout(executable)
+	if err != nil {
+		return err
+	}
+	table, err := createSymbolTableObjectFile(functions, byteOrder, "symtab", tmpdir, compilerConfig)
+	if err != nil {
+		return err
+	}
+	err = linkExecutable(append(append(append([]string(nil), layoutFlags...), stripFlags...), table)...)
+	if err != nil {
+		return err
+	}
+	newCodeLayout, err := readCodeLayout(executable)
+	if err != nil {
+		return err
+	}
+	if newCodeLayout != codeLayout {
+		return errors.New("could not create symbol table: code layout changed after adding the symbol table")
+	}
+	return nil
+}
+
+// readCodeLayout returns a string describing the address and size of each
+// executable section, to verify that the code didn't move while relinking.
+func readCodeLayout(executable string) (string, error) {
+	f, err := elf.Open(executable)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	var layout string
+	for _, section := range f.Sections {
+		if section.Flags&elf.SHF_EXECINSTR != 0 {
+			layout += fmt.Sprintf("%s:%#x:%#x\n", section.Name, section.Addr, section.Size)
+		}
+	}
+	return layout, nil
+}
+
+// readSymbolTable reads all functions with their line tables and inlined
+// functions from the DWARF debug information in the given ELF file. The
+// returned functions are sorted by address.
+func readSymbolTable(executable string) ([]*symtabFunction, binary.ByteOrder, error) {
+	f, err := elf.Open(executable)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	data, err := f.DWARF()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var functions []*symtabFunction
+	names := map[dwarf.Offset]string{}
+	r := data.Reader()
+	for {
+		e, err := r.Next()
+		if err != nil {
+			return nil, nil, err
+		}
+		if e == nil {
+			break
+		}
+		if e.Tag != dwarf.TagCompileUnit {
+			r.SkipChildren()
+			continue
+		}
+
+		// Read the line table of this compile unit.
+		lr, err := data.LineReader(e)
+		if err != nil {
+			return nil, nil, err
+		}
+		var files []*dwarf.LineFile
+		var rows []symtabLine
+		if lr != nil {
+			files = lr.Files()
+			rows, err = readLineRows(lr)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		// Read all functions in this compile unit.
+		if !e.Children {
+			continue
+		}
+		for {
+			e, err := r.Next()
+			if err != nil {
+				return nil, nil, err
+			}
+			if e == nil || e.Tag == 0 {
+				break
+			}
+			if e.Tag != dwarf.TagSubprogram {
+				if e.Children {
+					r.SkipChildren()
+				}
+				continue
+			}
+			ranges, err := data.Ranges(e)
+			if err != nil {
+				return nil, nil, err
+			}
+			name, err := symtabEntryName(data, e, names)
+			if err != nil {
+				return nil, nil, err
+			}
+			var inlines []symtabInline
+			if e.Children {
+				inlines, err = readInlines(data, r, files, names, -1, inlines)
+				if err != nil {
+					return nil, nil, err
+				}
+			}
+			for _, rng := range ranges {
+				if rng[0] == 0 || rng[0] >= rng[1] {
+					// Removed by the linker (tombstone value), or empty.
+					continue
+				}
+				functions = append(functions, &symtabFunction{
+					entry:   rng[0],
+					end:     rng[1],
+					name:    compiler.RuntimeFunctionName(name),
+					lines:   rowsInRange(rows, rng[0], rng[1]),
+					inlines: inlinesInRange(inlines, rng[0], rng[1]),
+				})
+			}
+		}
+	}
+
+	sort.Slice(functions, func(i, j int) bool {
+		return functions[i].entry < functions[j].entry
+	})
+	return functions, f.ByteOrder, nil
+}
+
+// readLineRows reads all rows from the line table, sorted by address. The end
+// of a sequence is stored as a row with line number -1.
+func readLineRows(lr *dwarf.LineReader) ([]symtabLine, error) {
+	var rows []symtabLine
+	var entry dwarf.LineEntry
+	for {
+		err := lr.Next(&entry)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		row := symtabLine{pc: entry.Address, line: -1}
+		if !entry.EndSequence {
+			row.line = entry.Line
+			if entry.File != nil {
+				row.file = entry.File.Name
+			}
+		}
+		rows = append(rows, row)
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].pc < rows[j].pc
+	})
+	return rows, nil
+}
+
+// rowsInRange returns the line table rows for the code between start and end,
+// merging rows that refer to the same file and line.
+func rowsInRange(rows []symtabLine, start, end uint64) []symtabLine {
+	index := sort.Search(len(rows), func(i int) bool {
+		return rows[i].pc > start
+	})
+	if index > 0 {
+		// The row that covers the start of the range.
+		index--
+	}
+	var result []symtabLine
+	for _, row := range rows[index:] {
+		if row.pc >= end {
+			break
+		}
+		if row.line <= 0 {
+			// End of sequence, or code without line information. Treat it
+			// as belonging to the previous row.
+			continue
+		}
+		if row.pc < start {
+			row.pc = start
+		}
+		if len(result) != 0 {
+			last := &result[len(result)-1]
+			if last.file == row.file && last.line == row.line {
+				continue
+			}
+			if last.pc == row.pc {
+				*last = row
+				continue
+			}
+		}
+		result = append(result, row)
+	}
+	return result
+}
+
+// readInlines reads all (possibly nested) inlined functions that are children
+// of the current DWARF entry, in preorder. The reader must be positioned at
+// the first child.
+func readInlines(data *dwarf.Data, r *dwarf.Reader, files []*dwarf.LineFile, names map[dwarf.Offset]string, parent int, inlines []symtabInline) ([]symtabInline, error) {
+	for {
+		e, err := r.Next()
+		if err != nil {
+			return nil, err
+		}
+		if e == nil || e.Tag == 0 {
+			return inlines, nil
+		}
+		if e.Tag != dwarf.TagInlinedSubroutine {
+			// Look into lexical blocks etc, as they may contain inlined
+			// functions too.
+			if e.Children {
+				inlines, err = readInlines(data, r, files, names, parent, inlines)
+				if err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		ranges, err := data.Ranges(e)
+		if err != nil {
+			return nil, err
+		}
+		name, err := symtabEntryName(data, e, names)
+		if err != nil {
+			return nil, err
+		}
+		inline := symtabInline{
+			parent: parent,
+			name:   compiler.RuntimeFunctionName(name),
+		}
+		if index, ok := e.Val(dwarf.AttrCallFile).(int64); ok && index >= 0 && int(index) < len(files) && files[index] != nil {
+			inline.callFile = files[index].Name
+		}
+		if line, ok := e.Val(dwarf.AttrCallLine).(int64); ok {
+			inline.callLine = int(line)
+		}
+		// Each address range gets its own entry. Children are attributed to
+		// the first range, which is good enough in practice since nested
+		// inlines are looked up by address anyway.
+		self := len(inlines)
+		for _, rng := range ranges {
+			if rng[0] == 0 || rng[0] >= rng[1] {
+				continue
+			}
+			inline.start, inline.end = rng[0], rng[1]
+			inlines = append(inlines, inline)
+		}
+		if len(inlines) == self {
+			// No code left for this inlined function.
+			if e.Children {
+				r.SkipChildren()
+			}
+			continue
+		}
+		if e.Children {
+			inlines, err = readInlines(data, r, files, names, self, inlines)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// inlinesInRange returns the inlines between start and end, with parent
+// indices updated accordingly.
+func inlinesInRange(inlines []symtabInline, start, end uint64) []symtabInline {
+	var result []symtabInline
+	remap := make(map[int]int)
+	for i, inline := range inlines {
+		if inline.start < start || inline.end > end {
+			continue
+		}
+		if inline.parent >= 0 {
+			parent, ok := remap[inline.parent]
+			if !ok {
+				// The parent is in a different range.
+				parent = -1
+			}
+			inline.parent = parent
+		}
+		remap[i] = len(result)
+		result = append(result, inline)
+	}
+	return result
+}
+
+// symtabEntryName returns the name of the given subprogram or inlined
+// subroutine, following abstract origins where needed.
+func symtabEntryName(data *dwarf.Data, e *dwarf.Entry, names map[dwarf.Offset]string) (string, error) {
+	if name, ok := e.Val(dwarf.AttrName).(string); ok {
+		return name, nil
+	}
+	var origin dwarf.Offset
+	if offset, ok := e.Val(dwarf.AttrAbstractOrigin).(dwarf.Offset); ok {
+		origin = offset
+	} else if offset, ok := e.Val(dwarf.AttrSpecification).(dwarf.Offset); ok {
+		origin = offset
+	} else {
+		return "", nil
+	}
+	if name, ok := names[origin]; ok {
+		return name, nil
+	}
+	r := data.Reader()
+	r.Seek(origin)
+	originEntry, err := r.Next()
+	if err != nil {
+		return "", err
+	}
+	if originEntry == nil {
+		return "", nil
+	}
+	names[origin] = "" // avoid infinite recursion
+	name, err := symtabEntryName(data, originEntry, names)
+	names[origin] = name
+	return name, err
+}
+
+// createSymbolTableObjectFile encodes the symbol table and writes it to an
+// object file, returning the path to this object file.
+//
+// The format must match the one used in src/runtime/symtab_table.go. All code
+// addresses are stored as 32-bit offsets from the lowest function address, and
+// all strings are stored as 32-bit offsets into a string table.
+func createSymbolTableObjectFile(functions []*symtabFunction, byteOrder binary.ByteOrder, name, tmpdir string, compilerConfig *compiler.Config) (string, error) {
+	var base uint64
+	if len(functions) != 0 {
+		base = functions[0].entry
+	}
+	strtab := newStringTable()
+	var funcBuf, lineBuf, inlineBuf bytes.Buffer
+	nlines := 0
+	put := func(buf *bytes.Buffer, values ...uint32) {
+		for _, value := range values {
+			binary.Write(buf, byteOrder, value)
+		}
+	}
+	for _, fn := range functions {
+		put(&funcBuf,
+			uint32(fn.entry-base),
+			uint32(fn.end-base),
+			strtab.add(fn.name),
+			uint32(nlines),
+			uint32(inlineBuf.Len()/24),
+			uint32(len(fn.inlines)))
+		for _, row := range fn.lines {
+			put(&lineBuf, uint32(row.pc-base), strtab.add(row.file), uint32(row.line))
+		}
+		nlines += len(fn.lines)
+		for _, inline := range fn.inlines {
+			put(&inlineBuf,
+				uint32(inline.start-base),
+				uint32(inline.end-base),
+				uint32(int32(inline.parent)),
+				strtab.add(inline.name),
+				strtab.add(inline.callFile),
+				uint32(inline.callLine))
+		}
+	}
+
+	// Create a new LLVM module with the symbol table.
+	ctx := llvm.NewContext()
+	defer ctx.Dispose()
+	mod := ctx.NewModule(name)
+	defer mod.Dispose()
+	machine, err := compiler.NewTargetMachine(compilerConfig)
+	if err != nil {
+		return "", err
+	}
+	defer machine.Dispose()
+	targetData := machine.CreateTargetData()
+	defer targetData.Dispose()
+	mod.SetTarget(compilerConfig.Triple)
+	mod.SetDataLayout(targetData.String())
+	uintptrType := ctx.IntType(targetData.PointerSize() * 8)
+
+	// The table is never modified, so it is stored in a read-only section.
+	addBlob := func(name string, data []byte) llvm.Value {
+		value := ctx.ConstString(string(data), false)
+		global := llvm.AddGlobal(mod, value.Type(), name)
+		global.SetInitializer(value)
+		global.SetGlobalConstant(true)
+		global.SetLinkage(llvm.InternalLinkage)
+		global.SetAlignment(4)
+		global.SetSection(".tinygo_symtab")
+		return global
+	}
+	header := ctx.ConstStruct([]llvm.Value{
+		llvm.ConstInt(uintptrType, base, false),
+		addBlob("tinygo_symtab.funcs", funcBuf.Bytes()),
+		llvm.ConstInt(uintptrType, uint64(len(functions)), false),
+		addBlob("tinygo_symtab.lines", lineBuf.Bytes()),
+		llvm.ConstInt(uintptrType, uint64(nlines), false),
+		addBlob("tinygo_symtab.inlines", inlineBuf.Bytes()),
+		addBlob("tinygo_symtab.strings", strtab.buf.Bytes()),
+	}, false)
+	global := llvm.AddGlobal(mod, header.Type(), "tinygo_symtab")
+	global.SetInitializer(header)
+	global.SetGlobalConstant(true)
+	global.SetSection(".tinygo_symtab")
+
+	// Write this LLVM module out as an object file.
+	outfile, err := os.CreateTemp(tmpdir, name+"-*.o")
+	if err != nil {
+		return "", err
+	}
+	defer outfile.Close()
+	buf, err := machine.EmitToMemoryBuffer(mod, llvm.ObjectFile)
+	if err != nil {
+		return "", err
+	}
+	defer buf.Dispose()
+	_, err = outfile.Write(buf.Bytes())
+	if err != nil {
+		return "", err
+	}
+	return outfile.Name(), outfile.Close()
+}
+
+// stringTable is a deduplicated list of NUL-terminated strings.
+type stringTable struct {
+	buf     bytes.Buffer
+	offsets map[string]uint32
+}
+
+func newStringTable() *stringTable {
+	t := &stringTable{offsets: map[string]uint32{}}
+	t.add("") // offset 0 is the empty string
+	return t
+}
+
+// add adds the string to the table (if needed) and returns its offset.
+func (t *stringTable) add(s string) uint32 {
+	if offset, ok := t.offsets[s]; ok {
+		return offset
+	}
+	offset := uint32(t.buf.Len())
+	t.buf.WriteString(s)
+	t.buf.WriteByte(0)
+	t.offsets[s] = offset
+	return offset
+}
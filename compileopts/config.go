@@ -10,6 +10,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/shlex"
 	"github.com/tinygo-org/tinygo/goenv"
@@ -110,7 +111,8 @@ func (c *Config) BuildTags() []string {
 		"osusergo",                                   // to get os/user to work
 		"math_big_pure_go",                           // to get math/big to work
 		"gc." + c.GC(), "scheduler." + c.Scheduler(), // used inside the runtime package
-		"serial." + c.Serial()}...) // used inside the machine package
+		"serial." + c.Serial(),     // used inside the machine package
+		"symtab." + c.SymTab()}...) // used inside the runtime package
 	switch c.Scheduler() {
 	case "threads", "cores":
 	default:
@@ -124,7 +126,8 @@ func (c *Config) BuildTags() []string {
 }
 
 // GC returns the garbage collection strategy in use on this platform. Valid
-// values are "none", "leaking", "conservative" and "precise".
+// values are "none", "leaking", "conservative", "custom", "precise",
+// "incremental", "sizeclass" and "boehm".
 func (c *Config) GC() string {
 	if c.Options.GC != "" {
 		return c.Options.GC
@@ -135,11 +138,20 @@ func (c *Config) GC() string {
 	return "conservative"
 }
 
+// GCPauseBudget returns the maximum time in nanoseconds that a single step of
+// the incremental garbage collector may take. It defaults to 1ms.
+func (c *Config) GCPauseBudget() uint64 {
+	if c.Options.GCPause > 0 {
+		return uint64(c.Options.GCPause)
+	}
+	return uint64(time.Millisecond)
+}
+
 // NeedsStackObjects returns true if the compiler should insert stack objects
 // that can be traced by the garbage collector.
 func (c *Config) NeedsStackObjects() bool {
 	switch c.GC() {
-	case "conservative", "custom", "precise", "boehm":
+	case "conservative", "custom", "precise", "incremental", "sizeclass", "boehm":
 		for _, tag := range c.BuildTags() {
 			if tag == "tinygo.wasm" {
 				return true
@@ -177,6 +189,17 @@ func (c *Config) Serial() string {
 	return "none"
 }
 
+// SymTab returns the kind of symbol table that is included in the binary for
+// runtime.Callers, runtime.CallersFrames and related functions. Valid values
+// are "none" (no symbol table, the default) and "full" (function names, files
+// and line numbers including inlined functions).
+func (c *Config) SymTab() string {
+	if c.Options.SymTab != "" {
+		return c.Options.SymTab
+	}
+	return "none"
+}
+
 // OptLevels returns the optimization level (0-2), size level (0-2), and inliner
 // threshold as used in the LLVM optimization pipeline.
 func (c *Config) OptLevel() (level string, speedLevel, sizeLevel int) {
@@ -522,6 +545,9 @@ func (c *Config) BinaryFormat(ext string) string {
 			return c.Target.BinaryFormat
 		}
 		return "zip"
+	case ".tgo":
+		// Signed firmware container, for over-the-air updates.
+		return "tgo"
 	default:
 		// Use the ELF format for unrecognized file formats.
 		return "elf"
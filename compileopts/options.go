@@ -9,12 +9,14 @@ import (
 
 var (
 	validBuildModeOptions     = []string{"default", "c-shared", "wasi-legacy"}
-	validGCOptions            = []string{"none", "leaking", "conservative", "custom", "precise", "boehm"}
+	validGCOptions            = []string{"none", "leaking", "conservative", "custom", "precise", "incremental", "sizeclass", "boehm"}
 	validSchedulerOptions     = []string{"none", "tasks", "asyncify", "threads", "cores"}
 	validSerialOptions        = []string{"none", "uart", "usb", "rtt"}
 	validPrintSizeOptions     = []string{"none", "short", "full", "html"}
 	validPanicStrategyOptions = []string{"print", "trap"}
+	validSymTabOptions        = []string{"none", "full"}
 	validOptOptions           = []string{"none", "0", "1", "2", "s", "z"}
+	validBuildVCSOptions      = []string{"auto", "true", "false"}
 )
 
 // Options contains extra options to give to the compiler. These options are
@@ -28,9 +30,12 @@ type Options struct {
 	Directory       string // working dir, leave it unset to use the current working dir
 	Target          string
 	BuildMode       string // -buildmode flag
+	BuildVCS        string // -buildvcs flag: auto (or empty), true, false
 	Opt             string
 	GC              string
+	GCPause         time.Duration // -gc-pause flag: maximum pause of the incremental GC
 	PanicStrategy   string
+	SymTab          string
 	Scheduler       string
 	StackSize       uint64 // goroutine stack size (if none could be automatically determined)
 	Serial          string
@@ -59,7 +64,9 @@ type Options struct {
 	WITPackage      string // pass through to wasm-tools component embed invocation
 	WITWorld        string // pass through to wasm-tools component embed -w option
 	ExtLDFlags      []string
-	GoCompatibility bool // enable to check for Go version compatibility
+	GoCompatibility bool   // enable to check for Go version compatibility
+	SigningKey      string // -signing-key flag: private key to sign .tgo firmware containers
+	VerifyKey       string // -verify-key flag: public key to verify .tgo firmware containers
 }
 
 // Verify performs a validation on the given options, raising an error if options are not valid.
@@ -81,6 +88,10 @@ func (o *Options) Verify() error {
 		}
 	}
 
+	if o.GCPause < 0 {
+		return fmt.Errorf("invalid -gc-pause=%s: the pause must not be negative", o.GCPause)
+	}
+
 	if o.Scheduler != "" {
 		valid := isInArray(validSchedulerOptions, o.Scheduler)
 		if !valid {
@@ -117,12 +128,27 @@ func (o *Options) Verify() error {
 		}
 	}
 
+	if o.SymTab != "" {
+		valid := isInArray(validSymTabOptions, o.SymTab)
+		if !valid {
+			return fmt.Errorf(`invalid symtab option '%s': valid values are %s`,
+				o.SymTab,
+				strings.Join(validSymTabOptions, ", "))
+		}
+	}
+
 	if o.Opt != "" {
 		if !isInArray(validOptOptions, o.Opt) {
 			return fmt.Errorf("invalid -opt=%s: valid values are %s", o.Opt, strings.Join(validOptOptions, ", "))
 		}
 	}
 
+	if o.BuildVCS != "" {
+		if !isInArray(validBuildVCSOptions, o.BuildVCS) {
+			return fmt.Errorf("invalid -buildvcs=%s: valid values are %s", o.BuildVCS, strings.Join(validBuildVCSOptions, ", "))
+		}
+	}
+
 	return nil
 }
 
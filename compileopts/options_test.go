@@ -3,16 +3,20 @@ package compileopts_test
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/tinygo-org/tinygo/compileopts"
 )
 
 func TestVerifyOptions(t *testing.T) {
 
-	expectedGCError := errors.New(`invalid gc option 'incorrect': valid values are none, leaking, conservative, custom, precise, boehm`)
+	expectedGCError := errors.New(`invalid gc option 'incorrect': valid values are none, leaking, conservative, custom, precise, incremental, sizeclass, boehm`)
 	expectedSchedulerError := errors.New(`invalid scheduler option 'incorrect': valid values are none, tasks, asyncify, threads, cores`)
 	expectedPrintSizeError := errors.New(`invalid size option 'incorrect': valid values are none, short, full, html`)
 	expectedPanicStrategyError := errors.New(`invalid panic option 'incorrect': valid values are print, trap`)
+	expectedSymTabError := errors.New(`invalid symtab option 'incorrect': valid values are none, full`)
+	expectedGCPauseError := errors.New(`invalid -gc-pause=-1ms: the pause must not be negative`)
+	expectedBuildVCSError := errors.New(`invalid -buildvcs=incorrect: valid values are auto, true, false`)
 
 	testCases := []struct {
 		name          string
@@ -54,6 +58,33 @@ func TestVerifyOptions(t *testing.T) {
 				GC: "custom",
 			},
 		},
+		{
+			name: "GCOptionIncremental",
+			opts: compileopts.Options{
+				GC: "incremental",
+			},
+		},
+		{
+			name: "GCOptionSizeClass",
+			opts: compileopts.Options{
+				GC: "sizeclass",
+			},
+		},
+		{
+			name: "GCPause",
+			opts: compileopts.Options{
+				GC:      "incremental",
+				GCPause: 500 * time.Microsecond,
+			},
+		},
+		{
+			name: "InvalidGCPause",
+			opts: compileopts.Options{
+				GC:      "incremental",
+				GCPause: -time.Millisecond,
+			},
+			expectedError: expectedGCPauseError,
+		},
 		{
 			name: "InvalidSchedulerOption",
 			opts: compileopts.Options{
@@ -117,6 +148,38 @@ func TestVerifyOptions(t *testing.T) {
 				PanicStrategy: "trap",
 			},
 		},
+		{
+			name: "InvalidSymTabOption",
+			opts: compileopts.Options{
+				SymTab: "incorrect",
+			},
+			expectedError: expectedSymTabError,
+		},
+		{
+			name: "SymTabOptionNone",
+			opts: compileopts.Options{
+				SymTab: "none",
+			},
+		},
+		{
+			name: "SymTabOptionFull",
+			opts: compileopts.Options{
+				SymTab: "full",
+			},
+		},
+		{
+			name: "InvalidBuildVCSOption",
+			opts: compileopts.Options{
+				BuildVCS: "incorrect",
+			},
+			expectedError: expectedBuildVCSError,
+		},
+		{
+			name: "BuildVCSOptionFalse",
+			opts: compileopts.Options{
+				BuildVCS: "false",
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -29,6 +29,7 @@ func (b *builder) createAtomicOp(name string) llvm.Value {
 		ptr := b.getValue(b.fn.Params[0], getPos(b.fn))
 		val := b.getValue(b.fn.Params[1], getPos(b.fn))
 		oldVal := b.CreateAtomicRMW(llvm.AtomicRMWBinOpXchg, ptr, val, llvm.AtomicOrderingSequentiallyConsistent, true)
+		b.createWriteBarrier(nil, ptr, val)
 		return oldVal
 	case "CompareAndSwapInt32", "CompareAndSwapInt64", "CompareAndSwapUint32", "CompareAndSwapUint64", "CompareAndSwapUintptr", "CompareAndSwapPointer":
 		ptr := b.getValue(b.fn.Params[0], getPos(b.fn))
@@ -36,6 +37,9 @@ func (b *builder) createAtomicOp(name string) llvm.Value {
 		newVal := b.getValue(b.fn.Params[2], getPos(b.fn))
 		tuple := b.CreateAtomicCmpXchg(ptr, old, newVal, llvm.AtomicOrderingSequentiallyConsistent, llvm.AtomicOrderingSequentiallyConsistent, true)
 		swapped := b.CreateExtractValue(tuple, 1, "")
+		// The new value may not have been stored, but marking it anyway is
+		// harmless.
+		b.createWriteBarrier(nil, ptr, newVal)
 		return swapped
 	case "LoadInt32", "LoadInt64", "LoadUint32", "LoadUint64", "LoadUintptr", "LoadPointer":
 		ptr := b.getValue(b.fn.Params[0], getPos(b.fn))
@@ -49,6 +53,7 @@ func (b *builder) createAtomicOp(name string) llvm.Value {
 		store := b.CreateStore(val, ptr)
 		store.SetOrdering(llvm.AtomicOrderingSequentiallyConsistent)
 		store.SetAlignment(b.targetData.PrefTypeAlignment(val.Type())) // required
+		b.createWriteBarrier(nil, ptr, val)
 		return llvm.Value{}
 	default:
 		b.addError(b.fn.Pos(), "unknown atomic operation: "+b.fn.Name())
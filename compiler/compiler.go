@@ -60,6 +60,9 @@ type Config struct {
 	Debug              bool // Whether to emit debug information in the LLVM module.
 	Nobounds           bool // Whether to skip bounds checks
 	PanicStrategy      string
+	SymTab             string // Symbol table for runtime.Callers (none, full).
+	WriteBarrier       bool   // Emit write barriers for the incremental GC.
+	GCPauseBudget      uint64 // Maximum pause of the incremental GC in nanoseconds.
 }
 
 // compilerContext contains function-independent data that should still be
@@ -89,6 +92,7 @@ type compilerContext struct {
 	functionInfos    map[*ssa.Function]functionInfo
 	astComments      map[string]*ast.CommentGroup
 	embedGlobals     map[string][]*loader.EmbedFile
+	callSites        map[string]llvm.Value // call site descriptors (WebAssembly only)
 	pkg              *types.Package
 	packageDir       string // directory for this package
 	runtimePkg       *types.Package
@@ -106,6 +110,7 @@ func newCompilerContext(moduleName string, machine llvm.TargetMachine, config *C
 		targetData:    machine.CreateTargetData(),
 		functionInfos: map[*ssa.Function]functionInfo{},
 		astComments:   map[string]*ast.CommentGroup{},
+		callSites:     map[string]llvm.Value{},
 	}
 
 	c.ctx = llvm.NewContext()
@@ -162,6 +167,9 @@ type builder struct {
 	deferPtr          llvm.Value
 	deferFrame        llvm.Value
 	stackChainAlloca  llvm.Value
+	callFrame         llvm.Value // call chain frame (WebAssembly only)
+	callFrameParent   llvm.Value
+	callFrameSite     llvm.Value
 	landingpad        llvm.BasicBlock
 	difunc            llvm.Metadata
 	dilocals          map[*types.Var]llvm.Metadata
@@ -1338,6 +1346,11 @@ func (b *builder) createFunctionStart(intrinsic bool) {
 		// because runtime.trackPointer is replaced by an alloca store.
 		b.stackChainAlloca = b.CreateAlloca(b.ctx.Int8Type(), "stackalloc")
 	}
+
+	if !intrinsic && b.needsCallChain() {
+		// Push a frame on the call chain, for runtime.Callers.
+		b.createCallChainPush()
+	}
 }
 
 // createFunction builds the LLVM IR implementation for this function. The
@@ -1500,6 +1513,18 @@ func (b *builder) createInstruction(instr ssa.Instruction) {
 		b.setDebugLocation(getPos(instr))
 	}
 
+	if !b.callFrame.IsNil() {
+		// Update the call site in the call chain frame.
+		switch instr.(type) {
+		case *ssa.Call, *ssa.Defer, *ssa.Panic:
+			b.setCallSite(getPos(instr))
+		case *ssa.RunDefers:
+			if syntax := b.fn.Syntax(); syntax != nil {
+				b.setCallSite(syntax.End())
+			}
+		}
+	}
+
 	switch instr := instr.(type) {
 	case ssa.Value:
 		if value, err := b.createExpr(instr); err != nil {
@@ -1547,6 +1572,9 @@ func (b *builder) createInstruction(instr ssa.Instruction) {
 		if b.hasDeferFrame() {
 			b.createRuntimeCall("destroyDeferFrame", []llvm.Value{b.deferFrame}, "")
 		}
+		if !b.callFrame.IsNil() {
+			b.createCallChainPop()
+		}
 		if len(instr.Results) == 0 {
 			b.CreateRetVoid()
 		} else if len(instr.Results) == 1 {
@@ -1580,6 +1608,7 @@ func (b *builder) createInstruction(instr ssa.Instruction) {
 			return
 		}
 		b.CreateStore(llvmVal, llvmAddr)
+		b.createWriteBarrier(instr.Addr, llvmAddr, llvmVal)
 	default:
 		b.addError(instr.Pos(), "unknown instruction: "+instr.String())
 	}
@@ -1919,6 +1948,8 @@ func (b *builder) createFunctionCall(instr *ssa.CallCommon) (llvm.Value, error)
 				"trap":  tinygo.PanicStrategyTrap,
 			}[b.Config.PanicStrategy]
 			return llvm.ConstInt(b.ctx.Int8Type(), panicStrategy, false), nil
+		case name == "runtime.gcPauseBudget":
+			return llvm.ConstInt(b.ctx.Int64Type(), b.GCPauseBudget, false), nil
 		case name == "runtime/interrupt.New":
 			return b.createInterruptGlobal(instr)
 		case name == "runtime.exportedFuncPtr":
@@ -1,10 +1,11 @@
 package compiler
 
 // This file provides IR transformations necessary for precise and portable
-// garbage collectors.
+// garbage collectors, and the write barriers of the incremental GC.
 
 import (
 	"go/token"
+	"go/types"
 
 	"golang.org/x/tools/go/ssa"
 	"tinygo.org/x/go-llvm"
@@ -107,3 +108,47 @@ func typeHasPointers(t llvm.Type) bool {
 		return false
 	}
 }
+
+// createWriteBarrier emits a write barrier for the incremental GC, after value
+// was stored at addr (the SSA value of the address, if known). The write
+// barrier marks the objects that value points to while the GC is marking, so
+// that they can't be hidden from the GC by storing them in an object that was
+// already scanned.
+func (b *builder) createWriteBarrier(addr ssa.Value, llvmAddr, value llvm.Value) {
+	if !b.WriteBarrier || !typeHasPointers(value.Type()) || !b.needsWriteBarrier(addr) {
+		return
+	}
+	if value.Type().TypeKind() == llvm.PointerTypeKind {
+		b.createRuntimeCall("gcWriteBarrier", []llvm.Value{value}, "")
+		return
+	}
+	// Aggregates like strings, slices, interfaces and structs are scanned in
+	// memory, so that only a single call is needed.
+	size := llvm.ConstInt(b.uintptrType, b.targetData.TypeAllocSize(value.Type()), false)
+	b.createRuntimeCall("gcWriteBarrierRange", []llvm.Value{llvmAddr, size}, "")
+}
+
+// needsWriteBarrier returns whether a store to the given address needs a write
+// barrier. Globals and local variables on the stack don't need one, as the GC
+// scans them again at the end of the mark phase.
+func (b *builder) needsWriteBarrier(addr ssa.Value) bool {
+	for {
+		switch expr := addr.(type) {
+		case *ssa.Global:
+			return false
+		case *ssa.Alloc:
+			typ := b.getLLVMType(expr.Type().Underlying().(*types.Pointer).Elem())
+			return expr.Heap || b.targetData.TypeAllocSize(typ) > b.MaxStackAlloc
+		case *ssa.FieldAddr:
+			addr = expr.X
+		case *ssa.IndexAddr:
+			if _, ok := expr.X.Type().Underlying().(*types.Pointer); !ok {
+				// Slice elements may be anywhere.
+				return true
+			}
+			addr = expr.X
+		default:
+			return true
+		}
+	}
+}
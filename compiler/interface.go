@@ -61,6 +61,10 @@ const (
 	structFieldFlagIsEmbedded
 )
 
+// Flag stored in the numOut field of a function type. Must be kept up to date
+// with src/internal/reflectlite/type.go.
+const funcTypeFlagVariadic = 1 << 15
+
 type reflectChanDir int
 
 const (
@@ -244,14 +248,20 @@ func (c *compilerContext) getTypeCode(typ types.Type) llvm.Value {
 			)
 		case *types.Interface:
 			typeFieldTypes = append(typeFieldTypes,
+				types.NewVar(token.NoPos, nil, "numMethods", types.Typ[types.Uint16]),
 				types.NewVar(token.NoPos, nil, "ptrTo", types.Typ[types.UnsafePointer]),
+				types.NewVar(token.NoPos, nil, "methods", types.NewArray(types.Typ[types.UnsafePointer], int64(typ.NumMethods()))),
 			)
-			// TODO: methods
 		case *types.Signature:
 			typeFieldTypes = append(typeFieldTypes,
+				types.NewVar(token.NoPos, nil, "numMethods", types.Typ[types.Uint16]),
 				types.NewVar(token.NoPos, nil, "ptrTo", types.Typ[types.UnsafePointer]),
+				types.NewVar(token.NoPos, nil, "numIn", types.Typ[types.Uint16]),
+				types.NewVar(token.NoPos, nil, "numOut", types.Typ[types.Uint16]),
+				types.NewVar(token.NoPos, nil, "call", types.Typ[types.UnsafePointer]),
+				types.NewVar(token.NoPos, nil, "makeFunc", types.Typ[types.UnsafePointer]),
+				types.NewVar(token.NoPos, nil, "params", types.NewArray(types.Typ[types.UnsafePointer], int64(typ.Params().Len()+typ.Results().Len()))),
 			)
-			// TODO: signature params and return values
 		}
 		if hasMethodSet {
 			// This method set is appended at the start of the struct. It is
@@ -408,11 +418,39 @@ func (c *compilerContext) getTypeCode(typ types.Type) llvm.Value {
 			}
 			typeFields = append(typeFields, llvm.ConstArray(structFieldType, fields))
 		case *types.Interface:
-			typeFields = []llvm.Value{c.getTypeCode(types.NewPointer(typ))}
-			// TODO: methods
+			// The method signatures of the interface, in the same order as in
+			// the method set of a type that implements it. They are used by
+			// the reflect package to look up methods on interface values.
+			var methods []llvm.Value
+			for i := 0; i < typ.NumMethods(); i++ {
+				methods = append(methods, c.getMethodSignature(typ.Method(i)))
+			}
+			typeFields = []llvm.Value{
+				llvm.ConstInt(c.ctx.Int16Type(), uint64(typ.NumMethods()), false), // numMethods
+				c.getTypeCode(types.NewPointer(typ)),                              // ptrTo
+				llvm.ConstArray(c.dataPtrType, methods),                           // methods
+			}
 		case *types.Signature:
-			typeFields = []llvm.Value{c.getTypeCode(types.NewPointer(typ))}
-			// TODO: params, return values, etc
+			var params []llvm.Value
+			for i := 0; i < typ.Params().Len(); i++ {
+				params = append(params, c.getTypeCode(typ.Params().At(i).Type()))
+			}
+			for i := 0; i < typ.Results().Len(); i++ {
+				params = append(params, c.getTypeCode(typ.Results().At(i).Type()))
+			}
+			numOut := uint64(typ.Results().Len())
+			if typ.Variadic() {
+				numOut |= funcTypeFlagVariadic
+			}
+			typeFields = []llvm.Value{
+				llvm.ConstInt(c.ctx.Int16Type(), 0, false),                          // numMethods
+				c.getTypeCode(types.NewPointer(typ)),                                // ptrTo
+				llvm.ConstInt(c.ctx.Int16Type(), uint64(typ.Params().Len()), false), // numIn
+				llvm.ConstInt(c.ctx.Int16Type(), numOut, false),                     // numOut
+				c.getFuncCallWrapper(typ, typeCodeName, isLocal),                    // call
+				c.getMakeFuncTrampoline(typ, typeCodeName, isLocal),                 // makeFunc
+				llvm.ConstArray(c.dataPtrType, params),                              // params
+			}
 		}
 		// Prepend metadata byte.
 		typeFields = append([]llvm.Value{
@@ -579,7 +617,11 @@ func getTypeCodeName(t types.Type) (string, bool) {
 			}
 			results[i] = s
 		}
-		return "func:" + "{" + strings.Join(params, ",") + "}{" + strings.Join(results, ",") + "}", isLocal
+		variadic := ""
+		if t.Variadic() {
+			variadic = "..."
+		}
+		return "func:" + "{" + strings.Join(params, ",") + variadic + "}{" + strings.Join(results, ",") + "}", isLocal
 	case *types.Slice:
 		s, isLocal := getTypeCodeName(t.Elem())
 		return "slice:" + s, isLocal
@@ -615,7 +657,7 @@ func (c *compilerContext) getTypeMethodSet(typ types.Type) llvm.Value {
 		ms := c.program.MethodSets.MethodSet(typ)
 
 		// Create method set.
-		var signatures, wrappers []llvm.Value
+		var signatures, wrappers, methodTypes, functions []llvm.Value
 		for i := 0; i < ms.Len(); i++ {
 			method := ms.At(i)
 			signatureGlobal := c.getMethodSignature(method.Obj().(*types.Func))
@@ -628,13 +670,29 @@ func (c *compilerContext) getTypeMethodSet(typ types.Type) llvm.Value {
 			}
 			wrapper := c.getInterfaceInvokeWrapper(fn, llvmFnType, llvmFn)
 			wrappers = append(wrappers, wrapper)
+
+			// The method as a function with the receiver as the first
+			// parameter, as used by reflect.Type.Method and
+			// reflect.Value.Method.
+			sig := fn.Signature
+			params := []*types.Var{sig.Recv()}
+			for j := 0; j < sig.Params().Len(); j++ {
+				params = append(params, sig.Params().At(j))
+			}
+			methodType := types.NewSignatureType(nil, nil, nil, types.NewTuple(params...), sig.Results(), sig.Variadic())
+			methodTypes = append(methodTypes, c.getTypeCode(methodType))
+			functions = append(functions, llvmFn)
 		}
 
-		// Construct global value.
+		// Construct global value. The last two fields are only used by the
+		// reflect package, the interface lowering pass only looks at the
+		// signatures and wrappers.
 		globalValue := c.ctx.ConstStruct([]llvm.Value{
 			llvm.ConstInt(c.uintptrType, uint64(ms.Len()), false),
 			llvm.ConstArray(c.dataPtrType, signatures),
 			c.ctx.ConstStruct(wrappers, false),
+			llvm.ConstArray(c.dataPtrType, methodTypes),
+			llvm.ConstArray(c.dataPtrType, functions),
 		}, false)
 		global = llvm.AddGlobal(c.mod, globalValue.Type(), globalName)
 		global.SetInitializer(globalValue)
@@ -658,20 +716,31 @@ func (c *compilerContext) getMethodSignatureName(method *types.Func) string {
 	return globalName
 }
 
-// getMethodSignature returns a global variable which is a reference to an
-// external *i8 indicating the indicating the signature of this method. It is
-// used during the interface lowering pass.
+// getMethodSignature returns a global variable indicating the signature of this
+// method. The name of the global is used during the interface lowering pass to
+// match methods with interfaces. Its contents are used by the reflect package:
+// the method type (without receiver), the package path (empty for exported
+// methods) and the method name. These must match the methodSignature struct in
+// src/internal/reflectlite/type.go.
 func (c *compilerContext) getMethodSignature(method *types.Func) llvm.Value {
 	globalName := c.getMethodSignatureName(method)
 	signatureGlobal := c.mod.NamedGlobal(globalName)
 	if signatureGlobal.IsNil() {
-		// TODO: put something useful in these globals, such as the method
-		// signature. Useful to one day implement reflect.Value.Method(n).
-		signatureGlobal = llvm.AddGlobal(c.mod, c.ctx.Int8Type(), globalName)
-		signatureGlobal.SetInitializer(llvm.ConstInt(c.ctx.Int8Type(), 0, false))
+		sig := method.Type().(*types.Signature)
+		var pkgpath string
+		if !method.Exported() {
+			pkgpath = method.Pkg().Path()
+		}
+		signatureInitializer := c.ctx.ConstStruct([]llvm.Value{
+			c.getTypeCode(types.NewSignatureType(nil, nil, nil, sig.Params(), sig.Results(), sig.Variadic())),
+			c.pkgPathPtr(pkgpath),
+			c.ctx.ConstString(method.Name()+"\x00", false),
+		}, false)
+		signatureGlobal = llvm.AddGlobal(c.mod, signatureInitializer.Type(), globalName)
+		signatureGlobal.SetInitializer(signatureInitializer)
 		signatureGlobal.SetLinkage(llvm.LinkOnceODRLinkage)
 		signatureGlobal.SetGlobalConstant(true)
-		signatureGlobal.SetAlignment(1)
+		signatureGlobal.SetAlignment(int(c.targetData.ABITypeAlignment(c.dataPtrType)))
 	}
 	return signatureGlobal
 }
@@ -892,6 +961,207 @@ func (c *compilerContext) getInterfaceInvokeWrapper(fn *ssa.Function, llvmFnType
 	return wrapper
 }
 
+// getFuncCallWrapper returns a function that calls a function value of the
+// given signature, for use by reflect.Value.Call. It has the signature
+// func(fn, params, results unsafe.Pointer) where fn points to the function
+// value, params to an array of pointers to each parameter, and results to an
+// array of pointers where each result will be stored.
+func (c *compilerContext) getFuncCallWrapper(sig *types.Signature, typeCodeName string, isLocal bool) llvm.Value {
+	wrapperName := typeCodeName + ".$call"
+	if !isLocal {
+		// Local types are unique to each function, so always create a new
+		// wrapper for them.
+		wrapper := c.mod.NamedFunction(wrapperName)
+		if !wrapper.IsNil() {
+			return wrapper
+		}
+	}
+
+	// Create the wrapper function.
+	wrapFnType := llvm.FunctionType(c.ctx.VoidType(), []llvm.Type{c.dataPtrType, c.dataPtrType, c.dataPtrType, c.dataPtrType}, false)
+	wrapper := llvm.AddFunction(c.mod, wrapperName, wrapFnType)
+	c.addStandardAttributes(wrapper)
+	if isLocal {
+		wrapper.SetLinkage(llvm.InternalLinkage)
+	} else {
+		wrapper.SetLinkage(llvm.LinkOnceODRLinkage)
+	}
+	wrapper.SetUnnamedAddr(true)
+
+	// Create a new builder just to create this wrapper.
+	b := builder{
+		compilerContext: c,
+		Builder:         c.ctx.NewBuilder(),
+	}
+	defer b.Builder.Dispose()
+
+	// Add debug info if needed.
+	if c.Debug {
+		b.createWrapperDebugInfo(wrapper, "(Go function call)", "<Go function call>")
+	}
+
+	block := b.ctx.AddBasicBlock(wrapper, "entry")
+	b.SetInsertPointAtEnd(block)
+
+	// Load the function value and all parameters.
+	fnValue := b.CreateLoad(c.getFuncType(sig), wrapper.Param(0), "fn")
+	funcPtr, context := b.decodeFuncValue(fnValue)
+	var params []llvm.Value
+	for i := 0; i < sig.Params().Len(); i++ {
+		gep := b.CreateInBoundsGEP(c.dataPtrType, wrapper.Param(1), []llvm.Value{
+			llvm.ConstInt(c.ctx.Int32Type(), uint64(i), false),
+		}, "")
+		paramPtr := b.CreateLoad(c.dataPtrType, gep, "")
+		params = append(params, b.CreateLoad(c.getLLVMType(sig.Params().At(i).Type()), paramPtr, ""))
+	}
+	params = append(params, context)
+
+	// Call the function, and store the results.
+	llvmFnType := c.getLLVMFunctionType(types.NewSignatureType(nil, nil, nil, sig.Params(), sig.Results(), sig.Variadic()))
+	result := b.createCall(llvmFnType, funcPtr, params, "")
+	for i := 0; i < sig.Results().Len(); i++ {
+		value := result
+		if sig.Results().Len() > 1 {
+			value = b.CreateExtractValue(result, i, "")
+		}
+		gep := b.CreateInBoundsGEP(c.dataPtrType, wrapper.Param(2), []llvm.Value{
+			llvm.ConstInt(c.ctx.Int32Type(), uint64(i), false),
+		}, "")
+		resultPtr := b.CreateLoad(c.dataPtrType, gep, "")
+		b.CreateStore(value, resultPtr)
+	}
+	b.CreateRetVoid()
+
+	return wrapper
+}
+
+// getMakeFuncTrampoline returns a function of the given signature that calls
+// a function created by reflect.MakeFunc. The context parameter of the
+// trampoline is the closure created by MakeFunc. It stores all parameters in
+// memory and calls internal/reflectlite.callMakeFunc with pointers to them, and
+// then loads the results that were stored by callMakeFunc.
+//
+// The trampoline is lowered by LLVM like any other function of this signature,
+// so it follows the calling convention of the target without any
+// architecture-specific code.
+func (c *compilerContext) getMakeFuncTrampoline(sig *types.Signature, typeCodeName string, isLocal bool) llvm.Value {
+	trampolineName := typeCodeName + ".$makefunc"
+	if !isLocal {
+		trampoline := c.mod.NamedFunction(trampolineName)
+		if !trampoline.IsNil() {
+			return trampoline
+		}
+	}
+
+	// Create the trampoline function.
+	fnType := c.getLLVMFunctionType(sig)
+	trampoline := llvm.AddFunction(c.mod, trampolineName, fnType)
+	c.addStandardAttributes(trampoline)
+	if isLocal {
+		trampoline.SetLinkage(llvm.InternalLinkage)
+	} else {
+		trampoline.SetLinkage(llvm.LinkOnceODRLinkage)
+	}
+	trampoline.SetUnnamedAddr(true)
+
+	// Create a new builder just to create this trampoline.
+	b := builder{
+		compilerContext: c,
+		Builder:         c.ctx.NewBuilder(),
+	}
+	defer b.Builder.Dispose()
+
+	// Add debug info if needed.
+	if c.Debug {
+		b.createWrapperDebugInfo(trampoline, "(Go MakeFunc trampoline)", "<Go MakeFunc>")
+	}
+
+	block := b.ctx.AddBasicBlock(trampoline, "entry")
+	b.SetInsertPointAtEnd(block)
+
+	// Store all parameters in memory, and create an array of pointers to them.
+	numParams := sig.Params().Len()
+	paramsType := llvm.ArrayType(c.dataPtrType, numParams)
+	params := b.CreateAlloca(paramsType, "params")
+	llvmParams := trampoline.Params()
+	for i := 0; i < numParams; i++ {
+		paramType := c.getLLVMType(sig.Params().At(i).Type())
+		numFields := len(c.expandFormalParamType(paramType, "", nil))
+		param := b.collapseFormalParam(paramType, llvmParams[:numFields])
+		llvmParams = llvmParams[numFields:]
+		paramAlloca := b.CreateAlloca(paramType, "")
+		b.CreateStore(param, paramAlloca)
+		gep := b.CreateInBoundsGEP(paramsType, params, []llvm.Value{
+			llvm.ConstInt(c.ctx.Int32Type(), 0, false),
+			llvm.ConstInt(c.ctx.Int32Type(), uint64(i), false),
+		}, "")
+		b.CreateStore(paramAlloca, gep)
+	}
+	context := llvmParams[0]
+
+	// Allocate space for the results, and create an array of pointers to them.
+	numResults := sig.Results().Len()
+	resultsType := llvm.ArrayType(c.dataPtrType, numResults)
+	results := b.CreateAlloca(resultsType, "results")
+	var resultAllocas []llvm.Value
+	for i := 0; i < numResults; i++ {
+		resultType := c.getLLVMType(sig.Results().At(i).Type())
+		resultAlloca := b.CreateAlloca(resultType, "")
+		b.CreateStore(llvm.ConstNull(resultType), resultAlloca)
+		resultAllocas = append(resultAllocas, resultAlloca)
+		gep := b.CreateInBoundsGEP(resultsType, results, []llvm.Value{
+			llvm.ConstInt(c.ctx.Int32Type(), 0, false),
+			llvm.ConstInt(c.ctx.Int32Type(), uint64(i), false),
+		}, "")
+		b.CreateStore(resultAlloca, gep)
+	}
+
+	// Call the function created by MakeFunc.
+	callMakeFunc := c.program.ImportedPackage("internal/reflectlite").Members["callMakeFunc"].(*ssa.Function)
+	callMakeFuncType, callMakeFuncFn := b.getFunction(callMakeFunc)
+	b.createCall(callMakeFuncType, callMakeFuncFn, []llvm.Value{context, params, results, llvm.Undef(c.dataPtrType)}, "")
+
+	// Load and return the results.
+	switch numResults {
+	case 0:
+		b.CreateRetVoid()
+	case 1:
+		b.CreateRet(b.CreateLoad(fnType.ReturnType(), resultAllocas[0], ""))
+	default:
+		result := llvm.Undef(fnType.ReturnType())
+		for i, resultAlloca := range resultAllocas {
+			resultType := c.getLLVMType(sig.Results().At(i).Type())
+			result = b.CreateInsertValue(result, b.CreateLoad(resultType, resultAlloca, ""), i, "")
+		}
+		b.CreateRet(result)
+	}
+
+	return trampoline
+}
+
+// createWrapperDebugInfo adds debug information to a wrapper function that is
+// not part of the Go source code, such as the wrappers used by the reflect
+// package.
+func (b *builder) createWrapperDebugInfo(fn llvm.Value, name, filename string) {
+	difile := b.getDIFile(filename)
+	diFuncType := b.dibuilder.CreateSubroutineType(llvm.DISubroutineType{
+		File: difile,
+	})
+	difunc := b.dibuilder.CreateFunction(difile, llvm.DIFunction{
+		Name:         name,
+		File:         difile,
+		Line:         0,
+		Type:         diFuncType,
+		LocalToUnit:  true,
+		IsDefinition: true,
+		ScopeLine:    0,
+		Flags:        llvm.FlagPrototyped,
+		Optimized:    true,
+	})
+	fn.SetSubprogram(difunc)
+	b.SetCurrentDebugLocation(0, 0, difunc, llvm.Metadata{})
+}
+
 // methodSignature creates a readable version of a method signature (including
 // the function name, excluding the receiver name). This string is used
 // internally to match interfaces and to call the correct method on an
@@ -0,0 +1,195 @@
+package compiler
+
+// This file implements support for runtime.Callers and related functions.
+//
+// On most architectures, the runtime walks the frame pointer chain and looks
+// up the return addresses in a symbol table that is generated by the builder
+// after linking. Therefore the compiler only needs to make sure frame pointers
+// are not omitted.
+//
+// WebAssembly doesn't allow a program to inspect its own call stack, so there
+// the compiler maintains an explicit chain of call frames instead: every
+// function that calls other functions pushes a frame on entry and pops it on
+// return. Before each call, the frame is updated to point to a constant call
+// site descriptor (function name, file and line). The runtime walks this
+// chain and uses the call site descriptors as "program counters".
+
+import (
+	"go/token"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+	"tinygo.org/x/go-llvm"
+)
+
+// callSiteMagic is stored in every call site descriptor, so that the runtime
+// can distinguish call sites from other pointers. It must match the constant
+// of the same name in src/runtime/symtab_chain.go.
+const callSiteMagic = 0x74676373
+
+// usesFramePointers returns whether all functions should keep a frame pointer
+// so that the runtime can walk the stack.
+func (c *compilerContext) usesFramePointers() bool {
+	return c.SymTab == "full" && c.archFamily() != "wasm32"
+}
+
+// needsCallChain returns whether the current function needs to maintain a
+// frame in the call chain. This is only the case on WebAssembly, and only for
+// functions that actually call other functions.
+func (b *builder) needsCallChain() bool {
+	if b.SymTab != "full" || b.archFamily() != "wasm32" {
+		return false
+	}
+	if b.fn.Synthetic != "" && b.fn.Synthetic != "package initializer" {
+		// Wrappers are not visible in stack traces.
+		return false
+	}
+	if b.fn.Pkg != nil {
+		switch b.fn.Pkg.Pkg.Path() {
+		case "runtime", "runtime/interrupt", "runtime/volatile", "internal/task":
+			// These packages implement the call chain (and goroutine
+			// switching) so must not modify it.
+			return false
+		}
+	}
+	for _, block := range b.fn.Blocks {
+		for _, instr := range block.Instrs {
+			switch instr.(type) {
+			case *ssa.Call, *ssa.Defer, *ssa.Panic:
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// createCallChainPush creates a new call frame in the entry block and makes it
+// the top of the call chain.
+func (b *builder) createCallChainPush() {
+	chain := b.getCallChainGlobal()
+	frameType := b.ctx.StructType([]llvm.Type{b.dataPtrType, b.dataPtrType}, false)
+	b.callFrame = b.CreateAlloca(frameType, "callframe")
+	b.callFrameParent = b.CreateLoad(b.dataPtrType, chain, "callframe.parent")
+	zero := llvm.ConstInt(b.ctx.Int32Type(), 0, false)
+	one := llvm.ConstInt(b.ctx.Int32Type(), 1, false)
+	parentPtr := b.CreateInBoundsGEP(frameType, b.callFrame, []llvm.Value{zero, zero}, "")
+	b.CreateStore(b.callFrameParent, parentPtr)
+	b.callFrameSite = b.CreateInBoundsGEP(frameType, b.callFrame, []llvm.Value{zero, one}, "")
+	b.CreateStore(llvm.ConstNull(b.dataPtrType), b.callFrameSite)
+	b.CreateStore(b.callFrame, chain)
+}
+
+// createCallChainPop removes the call frame of this function from the call
+// chain. It must be called right before returning.
+func (b *builder) createCallChainPop() {
+	b.CreateStore(b.callFrameParent, b.getCallChainGlobal())
+}
+
+// setCallSite updates the call frame of the current function to point to the
+// given source location, so that the runtime knows where the next call
+// happened.
+func (b *builder) setCallSite(pos token.Pos) {
+	position := b.program.Fset.Position(pos)
+	name := RuntimeFunctionName(b.fn.RelString(nil))
+	key := name + "\x00" + position.Filename + "\x00" + strconv.Itoa(position.Line)
+	site, ok := b.callSites[key]
+	if !ok {
+		stringType := b.getLLVMRuntimeType("_string")
+		value := b.ctx.ConstStruct([]llvm.Value{
+			b.createStringGlobal(name, stringType),
+			b.createStringGlobal(position.Filename, stringType),
+			llvm.ConstInt(b.ctx.Int32Type(), uint64(position.Line), false),
+			llvm.ConstInt(b.ctx.Int32Type(), callSiteMagic, false),
+		}, false)
+		site = llvm.AddGlobal(b.mod, value.Type(), "runtime.callSite")
+		site.SetInitializer(value)
+		site.SetLinkage(llvm.InternalLinkage)
+		site.SetGlobalConstant(true)
+		site.SetUnnamedAddr(true)
+		b.callSites[key] = site
+	}
+	b.CreateStore(site, b.callFrameSite)
+}
+
+// getCallChainGlobal returns the runtime.callChain global, which points to the
+// call frame of the most recently called function on this goroutine.
+func (c *compilerContext) getCallChainGlobal() llvm.Value {
+	chain := c.mod.NamedGlobal("runtime.callChain")
+	if chain.IsNil() {
+		chain = llvm.AddGlobal(c.mod, c.dataPtrType, "runtime.callChain")
+	}
+	return chain
+}
+
+// createStringGlobal returns a constant Go string with the given contents.
+func (c *compilerContext) createStringGlobal(s string, stringType llvm.Type) llvm.Value {
+	strPtr := llvm.ConstNull(c.dataPtrType)
+	if s != "" {
+		globalType := llvm.ArrayType(c.ctx.Int8Type(), len(s))
+		global := llvm.AddGlobal(c.mod, globalType, "runtime.callSite$string")
+		global.SetInitializer(c.ctx.ConstString(s, false))
+		global.SetLinkage(llvm.InternalLinkage)
+		global.SetGlobalConstant(true)
+		global.SetUnnamedAddr(true)
+		global.SetAlignment(1)
+		strPtr = global
+	}
+	strLen := llvm.ConstInt(c.uintptrType, uint64(len(s)), false)
+	return llvm.ConstNamedStruct(stringType, []llvm.Value{strPtr, strLen})
+}
+
+// RuntimeFunctionName converts a function name as returned by
+// (*ssa.Function).RelString(nil), which is also the name stored in DWARF debug
+// information, to the name that the gc toolchain would report from
+// runtime.FuncForPC and runtime.CallersFrames. For example, "(*main.T).Foo"
+// is converted to "main.(*T).Foo" and "main.main$1" to "main.main.func1".
+func RuntimeFunctionName(name string) string {
+	// Method: "(*pkg.T).M" or "(pkg.T).M".
+	if strings.HasPrefix(name, "(") {
+		if end := strings.Index(name, ")"); end > 0 {
+			recv := name[1:end]
+			ptr := ""
+			if strings.HasPrefix(recv, "*") {
+				ptr = "*"
+				recv = recv[1:]
+			}
+			typeName := recv
+			if bracket := strings.IndexByte(typeName, '['); bracket >= 0 {
+				typeName = typeName[:bracket]
+			}
+			if dot := strings.LastIndexByte(typeName, '.'); dot >= 0 {
+				pkgPath, typ := recv[:dot], recv[dot+1:]
+				if ptr != "" {
+					typ = "(" + ptr + typ + ")"
+				}
+				name = pkgPath + "." + typ + name[end+1:]
+			}
+		}
+	}
+
+	// Package initializer pseudo-functions (for debug information) and
+	// numbered init functions: "main.init#file.go" and "main.init#1".
+	if hash := strings.IndexByte(name, '#'); hash >= 0 {
+		if n, err := strconv.Atoi(name[hash+1:]); err == nil && n > 0 {
+			name = name[:hash] + "." + strconv.Itoa(n-1)
+		} else {
+			name = name[:hash]
+		}
+	}
+
+	// Closures: "main.main$1$2" becomes "main.main.func1.2", and bound method
+	// wrappers "main.T.M$bound" become "main.T.M-fm".
+	name = strings.ReplaceAll(name, "$bound", "-fm")
+	if dollar := strings.IndexByte(name, '$'); dollar >= 0 {
+		suffix := strings.Split(name[dollar+1:], "$")
+		for _, part := range suffix {
+			if _, err := strconv.Atoi(part); err != nil {
+				// Not a closure (for example a $thunk wrapper).
+				return name
+			}
+		}
+		name = name[:dollar] + ".func" + strings.Join(suffix, ".")
+	}
+	return name
+}
@@ -11,10 +11,16 @@ target triple = "wasm32-unknown-wasi"
 @"reflect/types.type:pointer:named:error" = linkonce_odr constant { i8, i16, ptr } { i8 -43, i16 0, ptr @"reflect/types.type:named:error" }, align 4
 @"reflect/types.type:named:error" = linkonce_odr constant { i8, i16, ptr, ptr, ptr, [7 x i8] } { i8 116, i16 1, ptr @"reflect/types.type:pointer:named:error", ptr @"reflect/types.type:interface:{Error:func:{}{basic:string}}", ptr @"reflect/types.type.pkgpath.empty", [7 x i8] c".error\00" }, align 4
 @"reflect/types.type.pkgpath.empty" = linkonce_odr unnamed_addr constant [1 x i8] zeroinitializer, align 1
-@"reflect/types.type:interface:{Error:func:{}{basic:string}}" = linkonce_odr constant { i8, ptr } { i8 84, ptr @"reflect/types.type:pointer:interface:{Error:func:{}{basic:string}}" }, align 4
+@"reflect/types.type:interface:{Error:func:{}{basic:string}}" = linkonce_odr constant { i8, i16, ptr, [1 x ptr] } { i8 84, i16 1, ptr @"reflect/types.type:pointer:interface:{Error:func:{}{basic:string}}", [1 x ptr] [ptr @"reflect/methods.Error() string"] }, align 4
+@"reflect/types.type:func:{}{basic:string}" = linkonce_odr constant { i8, i16, ptr, i16, i16, ptr, ptr, [1 x ptr] } { i8 24, i16 0, ptr @"reflect/types.type:pointer:func:{}{basic:string}", i16 0, i16 1, ptr @"func:{}{basic:string}.$call", ptr @"func:{}{basic:string}.$makefunc", [1 x ptr] [ptr @"reflect/types.type:basic:string"] }, align 4
+@"reflect/types.type:basic:string" = linkonce_odr constant { i8, ptr } { i8 81, ptr @"reflect/types.type:pointer:basic:string" }, align 4
+@"reflect/types.type:pointer:basic:string" = linkonce_odr constant { i8, i16, ptr } { i8 -43, i16 0, ptr @"reflect/types.type:basic:string" }, align 4
+@"reflect/types.type:pointer:func:{}{basic:string}" = linkonce_odr constant { i8, i16, ptr } { i8 -43, i16 0, ptr @"reflect/types.type:func:{}{basic:string}" }, align 4
+@"reflect/methods.Error() string" = linkonce_odr constant { ptr, ptr, [6 x i8] } { ptr @"reflect/types.type:func:{}{basic:string}", ptr @"reflect/types.type.pkgpath.empty", [6 x i8] c"Error\00" }, align 4
 @"reflect/types.type:pointer:interface:{Error:func:{}{basic:string}}" = linkonce_odr constant { i8, i16, ptr } { i8 -43, i16 0, ptr @"reflect/types.type:interface:{Error:func:{}{basic:string}}" }, align 4
 @"reflect/types.type:pointer:interface:{String:func:{}{basic:string}}" = linkonce_odr constant { i8, i16, ptr } { i8 -43, i16 0, ptr @"reflect/types.type:interface:{String:func:{}{basic:string}}" }, align 4
-@"reflect/types.type:interface:{String:func:{}{basic:string}}" = linkonce_odr constant { i8, ptr } { i8 84, ptr @"reflect/types.type:pointer:interface:{String:func:{}{basic:string}}" }, align 4
+@"reflect/types.type:interface:{String:func:{}{basic:string}}" = linkonce_odr constant { i8, i16, ptr, [1 x ptr] } { i8 84, i16 1, ptr @"reflect/types.type:pointer:interface:{String:func:{}{basic:string}}", [1 x ptr] [ptr @"reflect/methods.String() string"] }, align 4
+@"reflect/methods.String() string" = linkonce_odr constant { ptr, ptr, [7 x i8] } { ptr @"reflect/types.type:func:{}{basic:string}", ptr @"reflect/types.type.pkgpath.empty", [7 x i8] c"String\00" }, align 4
 @"reflect/types.typeid:basic:int" = external constant i8
 
 ; Function Attrs: allockind("alloc,zeroed") allocsize(0)
@@ -55,6 +61,43 @@ entry:
   ret %runtime._interface { ptr @"reflect/types.type:pointer:named:error", ptr null }
 }
 
+; Function Attrs: nounwind
+define linkonce_odr void @"func:{}{basic:string}.$call"(ptr %0, ptr %1, ptr %2, ptr %3) unnamed_addr #2 {
+entry:
+  %fn.unpack = load ptr, ptr %0, align 4
+  %fn.elt1 = getelementptr inbounds nuw i8, ptr %0, i32 4
+  %fn.unpack2 = load ptr, ptr %fn.elt1, align 4
+  %4 = call %runtime._string %fn.unpack2(ptr %fn.unpack) #7
+  %5 = load ptr, ptr %2, align 4
+  %.elt = extractvalue %runtime._string %4, 0
+  store ptr %.elt, ptr %5, align 4
+  %.repack4 = getelementptr inbounds nuw i8, ptr %5, i32 4
+  %.elt5 = extractvalue %runtime._string %4, 1
+  store i32 %.elt5, ptr %.repack4, align 4
+  ret void
+}
+
+; Function Attrs: nounwind
+define linkonce_odr %runtime._string @"func:{}{basic:string}.$makefunc"(ptr %0) unnamed_addr #2 {
+entry:
+  %params = alloca [0 x ptr], align 4
+  %results = alloca [1 x ptr], align 4
+  %1 = alloca %runtime._string, align 8
+  store ptr null, ptr %1, align 8
+  %.repack1 = getelementptr inbounds nuw i8, ptr %1, i32 4
+  store i32 0, ptr %.repack1, align 4
+  store ptr %1, ptr %results, align 4
+  call void @"internal/reflectlite.callMakeFunc"(ptr %0, ptr nonnull %params, ptr nonnull %results, ptr undef) #7
+  %.unpack = load ptr, ptr %1, align 8
+  %2 = insertvalue %runtime._string poison, ptr %.unpack, 0
+  %.elt2 = getelementptr inbounds nuw i8, ptr %1, i32 4
+  %.unpack3 = load i32, ptr %.elt2, align 4
+  %3 = insertvalue %runtime._string %2, i32 %.unpack3, 1
+  ret %runtime._string %3
+}
+
+declare void @"internal/reflectlite.callMakeFunc"(ptr dereferenceable_or_null(12), ptr, ptr, ptr) #1
+
 ; Function Attrs: nounwind
 define hidden %runtime._interface @main.anonymousInterfaceType(ptr %context) unnamed_addr #2 {
 entry:
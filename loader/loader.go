@@ -53,10 +53,17 @@ type PackageJSON struct {
 	Root       string
 	Module     struct {
 		Path      string
+		Version   string
+		Sum       string
 		Main      bool
 		Dir       string
 		GoMod     string
 		GoVersion string
+		Replace   *struct {
+			Path    string
+			Version string
+			Sum     string
+		}
 	}
 
 	// Source files
@@ -391,6 +391,23 @@ func Flash(pkgName, port, outpath string, options *compileopts.Options) error {
 		return errors.New("unknown flash method: " + flashMethod)
 	}
 
+	if filepath.Ext(pkgName) == ".tgo" {
+		// A firmware container only holds the raw firmware, without the ELF
+		// file it was created from. Reject what needs the ELF file before
+		// verifying the container.
+		switch format := config.BinaryFormat(fileExt); format {
+		case "bin", "hex", "uf2":
+		default:
+			if flashMethod == "" {
+				flashMethod = "command"
+			}
+			return fmt.Errorf("cannot flash a .tgo firmware container with flash method %s: it needs the %s format, but only bin, hex and uf2 are supported", flashMethod, format)
+		}
+		if options.Monitor && config.Options.Serial == "rtt" {
+			return errors.New("cannot use -monitor with -serial=rtt when flashing a .tgo firmware container: the RTT control block is found in the ELF file, which the container doesn't include")
+		}
+	}
+
 	// Create a temporary directory for intermediary files.
 	tmpdir, err := os.MkdirTemp("", "tinygo")
 	if err != nil {
@@ -405,8 +422,15 @@ func Flash(pkgName, port, outpath string, options *compileopts.Options) error {
 			return err
 		}
 	}
-	// Build the binary.
-	result, err := builder.Build(pkgName, fileExt, tmpdir, config)
+	var result builder.BuildResult
+	if filepath.Ext(pkgName) == ".tgo" {
+		// Flash a firmware container that was built before, after checking
+		// its signature.
+		result.Binary, err = builder.ExtractFirmwareContainer(pkgName, fileExt, tmpdir, config)
+	} else {
+		// Build the binary.
+		result, err = builder.Build(pkgName, fileExt, tmpdir, config)
+	}
 	if err != nil {
 		return err
 	}
@@ -523,6 +547,8 @@ func Flash(pkgName, port, outpath string, options *compileopts.Options) error {
 		return fmt.Errorf("unknown flash method: %s", flashMethod)
 	}
 	if options.Monitor {
+		// The executable is only needed for RTT, which was rejected above
+		// for firmware containers.
 		return Monitor(result.Executable, "", config)
 	}
 	return nil
@@ -1301,6 +1327,13 @@ extension:
 	.wasm:
 			Compile and link a WebAssembly file.
 
+	.tgo:
+			Create a firmware container for over-the-air updates: the
+			firmware like a .bin file, with a manifest (target, version from
+			-ldflags="-X main.version=...", hash) signed with the Ed25519
+			private key of the -signing-key flag. It can be flashed with
+			"tinygo flash -verify-key={public key} fw.tgo".
+
 (all other) Compile and link the program into a regular executable. For
 microcontrollers, it is common to use the .elf file extension to indicate a
 linked ELF file is generated. For Linux, it is common to build binaries with no
@@ -1323,6 +1356,13 @@ extension at all.`
 			format must match the target's expected format (e.g., .hex,
 			.uf2). Both flashing and saving will be performed.
 
+	-verify-key={filename}:
+			Flash a .tgo firmware container (see "tinygo help build")
+			instead of building a package. The container is only flashed
+			if it is signed with the private key of this Ed25519 public
+			key, and built for the same -target. Only flash methods that
+			use a .bin, .hex or .uf2 file are supported.
+
 	-monitor: 
 			Start the serial monitor (see below) immediately after
 			flashing. However, some microcontrollers need a split second
@@ -1601,16 +1641,19 @@ func main() {
 	command := os.Args[1]
 
 	opt := flag.String("opt", "z", "optimization level: 0, 1, 2, s, z")
-	gc := flag.String("gc", "", "garbage collector to use (none, leaking, conservative, custom, precise, boehm)")
+	gc := flag.String("gc", "", "garbage collector to use (none, leaking, conservative, custom, precise, incremental, sizeclass, boehm)")
+	gcPause := flag.Duration("gc-pause", 0, "maximum pause of the incremental garbage collector (default 1ms)")
 	panicStrategy := flag.String("panic", "print", "panic strategy (print, trap)")
 	scheduler := flag.String("scheduler", "", "which scheduler to use (none, tasks, cores, threads, asyncify)")
 	serial := flag.String("serial", "", "which serial output to use (none, uart, usb, rtt)")
+	symtab := flag.String("symtab", "", "symbol table for runtime.Callers and tracebacks (none, full)")
 	work := flag.Bool("work", false, "print the name of the temporary build directory and do not delete this directory on exit")
 	interpTimeout := flag.Duration("interp-timeout", 180*time.Second, "interp optimization pass timeout")
 	var tags buildutil.TagsFlag
 	flag.Var(&tags, "tags", "a space-separated list of extra build tags")
 	target := flag.String("target", "", "chip/board name or JSON target specification file")
 	buildMode := flag.String("buildmode", "", "build mode to use (default, c-shared, wasi-legacy)")
+	buildVCS := flag.String("buildvcs", "auto", "stamp binaries with version control information (auto, true, false)")
 	var stackSize uint64
 	flag.Func("stack-size", "goroutine stack size (if unknown at compile time)", func(s string) error {
 		size, err := bytesize.Parse(s)
@@ -1658,6 +1701,14 @@ func main() {
 		flag.StringVar(&outpath, "o", "", "output filename")
 	}
 
+	var signingKey, verifyKey string
+	if command == "help" || command == "build" {
+		flag.StringVar(&signingKey, "signing-key", "", "Ed25519 private key (PEM) to sign .tgo firmware containers")
+	}
+	if command == "help" || command == "flash" {
+		flag.StringVar(&verifyKey, "verify-key", "", "Ed25519 public key (PEM) to verify a .tgo firmware container before flashing it")
+	}
+
 	var witPackage, witWorld string
 	if command == "help" || command == "build" || command == "test" || command == "run" {
 		flag.StringVar(&witPackage, "wit-package", "", "wit package for wasm component embedding")
@@ -1723,6 +1774,12 @@ func main() {
 		*gocompatibility = b
 	}
 
+	if *buildVCS == "auto" && (command == "run" || command == "test") {
+		// Like the gc toolchain, don't stamp version control information by
+		// default in binaries that are built to be run right away.
+		*buildVCS = "false"
+	}
+
 	options := &compileopts.Options{
 		GOOS:            goenv.Get("GOOS"),
 		GOARCH:          goenv.Get("GOARCH"),
@@ -1730,12 +1787,15 @@ func main() {
 		GOMIPS:          goenv.Get("GOMIPS"),
 		Target:          *target,
 		BuildMode:       *buildMode,
+		BuildVCS:        *buildVCS,
 		StackSize:       stackSize,
 		Opt:             *opt,
 		GC:              *gc,
+		GCPause:         *gcPause,
 		PanicStrategy:   *panicStrategy,
 		Scheduler:       *scheduler,
 		Serial:          *serial,
+		SymTab:          *symtab,
 		Work:            *work,
 		InterpTimeout:   *interpTimeout,
 		PrintIR:         *printIR,
@@ -1760,6 +1820,8 @@ func main() {
 		WITPackage:      witPackage,
 		WITWorld:        witWorld,
 		GoCompatibility: *gocompatibility,
+		SigningKey:      signingKey,
+		VerifyKey:       verifyKey,
 	}
 	if *printCommands {
 		options.PrintCommands = printCommand
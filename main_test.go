@@ -15,6 +15,7 @@ import (
 	"reflect"
 	"regexp"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"testing"
@@ -64,6 +65,7 @@ func TestBuild(t *testing.T) {
 		"alias.go",
 		"atomic.go",
 		"binop.go",
+		"buildinfo.go",
 		"calls.go",
 		"cgo/",
 		"channel.go",
@@ -78,6 +80,7 @@ func TestBuild(t *testing.T) {
 		"json.go",
 		"map.go",
 		"math.go",
+		"metrics.go",
 		"oldgo/",
 		"print.go",
 		"reflect.go",
@@ -247,6 +250,11 @@ func runPlatTests(options compileopts.Options, tests []string, t *testing.T) {
 	isWASI := strings.HasPrefix(options.Target, "wasi")
 	isWebAssembly := isWASI || strings.HasPrefix(options.Target, "wasm") || (options.Target == "" && strings.HasPrefix(options.GOARCH, "wasm"))
 	isBaremetal := options.Target == "simavr" || options.Target == "cortex-m-qemu" || options.Target == "riscv-qemu"
+	config := &compileopts.Config{Options: &options, Target: spec}
+	// The symbol table is opt-in, test it on the targets that support it.
+	supportsSymTab := isWebAssembly && options.Target != "wasm-unknown" ||
+		options.Target == "cortex-m-qemu" || options.Target == "riscv-qemu" ||
+		(options.Target == "" && options.GOOS == "linux" && options.GOARCH != "mips" && options.GOARCH != "mipsle")
 
 	for _, name := range tests {
 		if options.GOOS == "linux" && (options.GOARCH == "arm" || options.GOARCH == "386") {
@@ -330,6 +338,10 @@ func runPlatTests(options compileopts.Options, tests []string, t *testing.T) {
 			t.Parallel()
 			runTest("env.go", options, t, []string{"first", "second"}, []string{"ENV1=VALUE1", "ENV2=VALUE2"})
 		})
+		t.Run("gcsettings.go", func(t *testing.T) {
+			t.Parallel()
+			runTest("gcsettings.go", options, t, nil, []string{"GOGC=50", "GOMEMLIMIT=1MiB"})
+		})
 	}
 	if isWebAssembly {
 		t.Run("alias.go-scheduler-none", func(t *testing.T) {
@@ -345,6 +357,93 @@ func runPlatTests(options compileopts.Options, tests []string, t *testing.T) {
 			runTest("filesystem.go", options, t, nil, nil)
 		})
 	}
+	if supportsSymTab {
+		t.Run("callers.go", func(t *testing.T) {
+			t.Parallel()
+			options := compileopts.Options(options)
+			options.SymTab = "full"
+			runTest("callers.go", options, t, nil, nil)
+		})
+	}
+	if options.Target == "" {
+		// The simulated board of the machine package is only available on the
+		// host.
+		t.Run("machine.go", func(t *testing.T) {
+			t.Parallel()
+			options := compileopts.Options(options)
+			options.Tags = append([]string{"pico"}, options.Tags...)
+			runTest("machine.go", options, t, nil, nil)
+		})
+		t.Run("fatfs.go", func(t *testing.T) {
+			t.Parallel()
+			runTest("fatfs.go", options, t, nil, nil)
+		})
+		t.Run("flashfs.go", func(t *testing.T) {
+			t.Parallel()
+			runTest("flashfs.go", options, t, nil, nil)
+		})
+	}
+	if supportsSymTab && ((options.Target == "" && options.GOOS == "linux") || isWASI) {
+		// Heap profiles need one of the block based GCs.
+		t.Run("pprof.go", func(t *testing.T) {
+			t.Parallel()
+			options := compileopts.Options(options)
+			options.GC = "precise"
+			options.SymTab = "full"
+			runTest("pprof.go", options, t, nil, nil)
+		})
+	}
+	if supportsSymTab && options.Target == "" && options.GOOS == "linux" {
+		// CPU profiles are only supported on Linux.
+		t.Run("cpuprof.go", func(t *testing.T) {
+			t.Parallel()
+			options := compileopts.Options(options)
+			options.SymTab = "full"
+			runTest("cpuprof.go", options, t, nil, nil)
+		})
+	}
+	if isWebAssembly || (options.Target == "" && options.GOOS == "linux") {
+		// Tracing needs one of the cooperative schedulers.
+		t.Run("trace.go", func(t *testing.T) {
+			t.Parallel()
+			options := compileopts.Options(options)
+			if scheduler := config.Scheduler(); scheduler != "tasks" && scheduler != "asyncify" {
+				options.Scheduler = "tasks"
+				options.GC = "precise"
+			}
+			runTest("trace.go", options, t, nil, nil)
+		})
+	}
+	if options.Target == "" || options.Target == "wasm" || isWASI {
+		// Finalizers and cleanups need one of the block based GCs.
+		t.Run("finalizer.go", func(t *testing.T) {
+			t.Parallel()
+			options := compileopts.Options(options)
+			if gc := config.GC(); gc != "conservative" && gc != "precise" {
+				options.GC = "precise"
+			}
+			runTest("finalizer.go", options, t, nil, nil)
+		})
+	}
+	if options.Target == "" || options.Target == "cortex-m-qemu" || options.Target == "riscv-qemu" {
+		// The incremental GC only supports the single-threaded schedulers, and
+		// is not supported on WebAssembly.
+		t.Run("gc_incremental.go", func(t *testing.T) {
+			t.Parallel()
+			options := compileopts.Options(options)
+			options.GC = "incremental"
+			options.Scheduler = "tasks"
+			runTest("gc_incremental.go", options, t, nil, nil)
+		})
+	}
+	if options.Target == "" || options.Target == "wasm" || isWASI || options.Target == "cortex-m-qemu" {
+		t.Run("gc_sizeclass.go", func(t *testing.T) {
+			t.Parallel()
+			options := compileopts.Options(options)
+			options.GC = "sizeclass"
+			runTest("gc_sizeclass.go", options, t, nil, nil)
+		})
+	}
 	if options.Target == "" || options.Target == "wasm" || isWASI {
 		t.Run("rand.go", func(t *testing.T) {
 			t.Parallel()
@@ -550,6 +649,9 @@ func TestWebAssembly(t *testing.T) {
 						}
 					}
 				}
+				// The order of imports depends on the linker, so compare
+				// them sorted (the expected list is sorted already).
+				sort.Strings(imports)
 				if !stringSlicesEqual(imports, tc.imports) {
 					t.Errorf("import list not as expected!\nexpected: %v\nactual:   %v", tc.imports, imports)
 				}
@@ -1087,6 +1189,34 @@ func TestGetListOfPackages(t *testing.T) {
 	}
 }
 
+// Test that flashing a .tgo firmware container fails early when the flash
+// method needs a file that can't be created from the container.
+func TestFlashFirmwareContainer(t *testing.T) {
+	tests := []struct {
+		target     string
+		programmer string
+		serial     string
+		err        string
+	}{
+		{target: "esp32-coreboard-v2", err: "cannot flash a .tgo firmware container with flash method command: it needs the esp32 format"},
+		{target: "pca10059", err: "cannot flash a .tgo firmware container with flash method command: it needs the nrf-dfu format"},
+		{target: "bluepill", programmer: "bmp", err: "cannot flash a .tgo firmware container with flash method bmp: it needs the elf format"},
+		{target: "bluepill", serial: "rtt", err: "cannot use -monitor with -serial=rtt when flashing a .tgo firmware container"},
+	}
+	for _, tc := range tests {
+		options := optionsFromTarget(tc.target, sema)
+		options.Programmer = tc.programmer
+		options.Serial = tc.serial
+		options.Monitor = true
+		// The container doesn't exist and there is no -verify-key: the error
+		// must be reported before either is used.
+		err := Flash("testdata/nonexistent.tgo", "", "", &options)
+		if err == nil || !strings.HasPrefix(err.Error(), tc.err) {
+			t.Errorf("%s: expected error %q, got %v", tc.target, tc.err, err)
+		}
+	}
+}
+
 // This TestMain is necessary because TinyGo may also be invoked to run certain
 // LLVM tools in a separate process. Not capturing these invocations would lead
 // to recursive tests.
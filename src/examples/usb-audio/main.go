@@ -0,0 +1,53 @@
+package main
+
+import (
+	"machine"
+	"machine/usb/adc/audio"
+	"math"
+	"time"
+)
+
+// A USB microphone that records a 440Hz sine wave. Try it by recording
+// from the "TinyGo" audio input on the host.
+
+const sampleRate = 16000
+
+func main() {
+	led := machine.LED
+	led.Configure(machine.PinConfig{Mode: machine.PinOutput})
+
+	mic := audio.Port()
+	err := mic.Configure(audio.Config{
+		SampleRates:   []uint32{sampleRate},
+		InputChannels: 1,
+	})
+	if err != nil {
+		println("could not configure audio:", err.Error())
+		return
+	}
+
+	// One period of a 440Hz tone, as 16-bit little endian samples.
+	period := make([]byte, 0, 2*sampleRate/440)
+	for i := 0; i < cap(period)/2; i++ {
+		sample := int16(math.Sin(2*math.Pi*float64(i)/float64(cap(period)/2)) * 8000)
+		period = append(period, byte(sample), byte(sample>>8))
+	}
+
+	buf := period
+	for {
+		led.Set(mic.Streaming(audio.Microphone))
+		if !mic.Streaming(audio.Microphone) {
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+		n, err := mic.Write(buf)
+		buf = buf[n:]
+		if len(buf) == 0 {
+			buf = period
+		}
+		if err != nil {
+			// The buffer is full, wait for the host to read some samples.
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
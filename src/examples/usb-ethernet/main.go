@@ -0,0 +1,117 @@
+package main
+
+import (
+	"machine"
+	"machine/usb/ethernet"
+	"time"
+)
+
+// A USB network interface that answers ARP requests and pings for 10.0.0.2.
+// Try it by giving the new network interface on the host the address
+// 10.0.0.1/24 and running "ping 10.0.0.2".
+
+var ip = [4]byte{10, 0, 0, 2}
+
+var (
+	mac [6]byte
+	out [ethernet.MTU + 14]byte
+)
+
+func main() {
+	led := machine.LED
+	led.Configure(machine.PinConfig{Mode: machine.PinOutput})
+
+	port := ethernet.Port()
+	err := port.Configure(ethernet.Config{})
+	if err != nil {
+		println("could not configure ethernet:", err.Error())
+		return
+	}
+	mac, _ = port.HardwareAddr6()
+	port.RecvEthHandle(func(frame []byte) error {
+		return handleFrame(port, frame)
+	})
+
+	for {
+		led.Set(port.LinkUp())
+		received, err := port.PollOne()
+		if err != nil {
+			println("receive error:", err.Error())
+		}
+		if !received {
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+func handleFrame(dev ethernet.Device, frame []byte) error {
+	switch {
+	case frame[12] == 0x08 && frame[13] == 0x06 && len(frame) >= 42:
+		return handleARP(dev, frame)
+	case frame[12] == 0x08 && frame[13] == 0x00 && len(frame) >= 34:
+		return handleIPv4(dev, frame)
+	}
+	return nil
+}
+
+// handleARP answers requests for our IP address.
+func handleARP(dev ethernet.Device, frame []byte) error {
+	arp := frame[14:42]
+	if arp[7] != 1 || [4]byte(arp[24:28]) != ip {
+		return nil
+	}
+	reply := out[:42]
+	copy(reply[0:6], frame[6:12])
+	copy(reply[6:12], mac[:])
+	copy(reply[12:22], frame[12:22]) // EtherType and ARP header
+	reply[21] = 2                    // reply
+	copy(reply[22:28], mac[:])
+	copy(reply[28:32], ip[:])
+	copy(reply[32:42], arp[8:18]) // sender hardware and protocol address
+	return dev.SendEth(reply)
+}
+
+// handleIPv4 answers ICMP echo requests to our IP address.
+func handleIPv4(dev ethernet.Device, frame []byte) error {
+	hdr := frame[14:]
+	hdrLen := int(hdr[0]&0xf) * 4
+	total := int(hdr[2])<<8 | int(hdr[3])
+	if hdr[9] != 1 || [4]byte(hdr[16:20]) != ip || total > len(hdr) || hdrLen+8 > total {
+		return nil
+	}
+	icmp := hdr[hdrLen:total]
+	if icmp[0] != 8 {
+		return nil
+	}
+
+	reply := out[:14+total]
+	copy(reply[0:6], frame[6:12])
+	copy(reply[6:12], mac[:])
+	copy(reply[12:], frame[12:14+total])
+	rhdr := reply[14:]
+	copy(rhdr[12:16], ip[:])
+	copy(rhdr[16:20], hdr[12:16])
+	rhdr[10], rhdr[11] = 0, 0
+	putChecksum(rhdr[10:], rhdr[:hdrLen])
+	ricmp := rhdr[hdrLen:total]
+	ricmp[0] = 0 // echo reply
+	ricmp[2], ricmp[3] = 0, 0
+	putChecksum(ricmp[2:], ricmp)
+	return dev.SendEth(reply)
+}
+
+// putChecksum stores the internet checksum of data in b.
+func putChecksum(b, data []byte) {
+	var sum uint32
+	for i := 0; i+1 < len(data); i += 2 {
+		sum += uint32(data[i])<<8 | uint32(data[i+1])
+	}
+	if len(data)%2 == 1 {
+		sum += uint32(data[len(data)-1]) << 8
+	}
+	for sum > 0xffff {
+		sum = sum&0xffff + sum>>16
+	}
+	b[0] = byte(^sum >> 8)
+	b[1] = byte(^sum)
+}
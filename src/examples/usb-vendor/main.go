@@ -0,0 +1,37 @@
+package main
+
+import (
+	"machine/usb/vendor"
+	"time"
+)
+
+// A vendor specific USB interface that echoes all data it receives. It can be
+// used without drivers on Windows, and from a browser with WebUSB:
+//
+//	const [device] = await navigator.usb.requestDevice({filters: []});
+//	await device.open();
+//	await device.claimInterface(2);
+//	await device.transferOut(5, new TextEncoder().encode("hello"));
+//	const result = await device.transferIn(4, 64);
+
+func main() {
+	port := vendor.Port()
+	err := port.Configure(vendor.Config{
+		Name:        "TinyGo echo",
+		LandingPage: "https://tinygo.org",
+	})
+	if err != nil {
+		println("could not configure vendor interface:", err.Error())
+		return
+	}
+
+	buf := make([]byte, 64)
+	for {
+		n, _ := port.Read(buf)
+		if n == 0 {
+			time.Sleep(time.Millisecond)
+			continue
+		}
+		port.Write(buf[:n])
+	}
+}
@@ -0,0 +1,104 @@
+package reflectlite
+
+import "unsafe"
+
+// makeFuncImpl is the closure context of a function created by MakeFunc.
+type makeFuncImpl struct {
+	ftyp *RawType
+	fn   func([]Value) []Value
+}
+
+// MakeFunc returns a new function of the given Type that wraps the function
+// fn. When called, that new function converts its arguments to a slice of
+// Values, runs fn, and returns the results converted back to the result types
+// of the function.
+//
+// Each function type has a trampoline, created by the compiler, that has the
+// same signature (and thus the same calling convention) as the function type.
+// The trampoline stores all arguments in memory and calls callMakeFunc, with
+// the makeFuncImpl as the context parameter.
+func MakeFunc(typ Type, fn func(args []Value) (results []Value)) Value {
+	if typ.Kind() != Func {
+		panic("reflect: call of MakeFunc with non-Func type")
+	}
+	t := typ.(*RawType)
+	ft := (*funcType)(unsafe.Pointer(t.underlying()))
+	if ft.makeFunc == nil {
+		// This should not happen: the compiler keeps the trampolines when
+		// MakeFunc is used.
+		panic("reflect: MakeFunc trampoline for " + t.String() + " was not included in the program")
+	}
+	return Value{
+		typecode: t,
+		value: unsafe.Pointer(&funcHeader{
+			Context: unsafe.Pointer(&makeFuncImpl{ftyp: t, fn: fn}),
+			Code:    ft.makeFunc,
+		}),
+		flags: valueFlagExported,
+	}
+}
+
+// callMakeFunc is called by the trampoline of a function created by MakeFunc.
+// The params and results arrays contain a pointer to each parameter and result
+// of the function. The parameters are stored on the stack of the trampoline,
+// so they're copied before they are passed to the function.
+func callMakeFunc(impl *makeFuncImpl, params, results unsafe.Pointer) {
+	ft := (*funcType)(unsafe.Pointer(impl.ftyp.underlying()))
+	numIn := int(ft.numIn)
+	numOut := int(ft.numOut &^ funcTypeFlagVariadic)
+
+	in := make([]Value, numIn)
+	for i := range in {
+		tin := ft.param(i)
+		param := *(*unsafe.Pointer)(unsafe.Add(params, uintptr(i)*unsafe.Sizeof(params)))
+		var value unsafe.Pointer
+		if size := tin.Size(); size <= unsafe.Sizeof(uintptr(0)) {
+			value = unsafe.Pointer(loadValue(param, size))
+		} else {
+			value = alloc(size, tin.gcLayout())
+			memcpy(value, param, size)
+		}
+		in[i] = Value{
+			typecode: tin,
+			value:    value,
+			flags:    valueFlagExported,
+		}
+	}
+
+	out := impl.fn(in)
+
+	if len(out) != numOut {
+		panic("reflect: wrong return count from function created by MakeFunc")
+	}
+	for i, v := range out {
+		tout := ft.param(numIn + i)
+		if v.typecode == nil {
+			panic("reflect: function created by MakeFunc returned zero Value")
+		}
+		if v.isRO() {
+			panic("reflect: function created by MakeFunc returned value obtained from unexported field")
+		}
+		if !v.typecode.AssignableTo(tout) {
+			panic("reflect: function created by MakeFunc returned wrong type: have " + v.typecode.String() + " for " + tout.String())
+		}
+		result := Value{
+			typecode: tout,
+			value:    *(*unsafe.Pointer)(unsafe.Add(results, uintptr(i)*unsafe.Sizeof(results))),
+			flags:    valueFlagExported | valueFlagIndirect,
+		}
+		result.Set(v)
+	}
+}
+
+// makeMethodValue converts a method value created by Value.Method into a
+// regular function value, so that it can be stored in an interface or
+// assigned to a variable of function type.
+func makeMethodValue(v Value) Value {
+	isVariadic := v.typecode.IsVariadic()
+	fn := MakeFunc(v.typecode, func(in []Value) []Value {
+		// The last argument of a variadic function is already a slice.
+		return v.call("call", in, isVariadic)
+	})
+	fn.flags |= v.flags.ro()
+	return fn
+}
@@ -177,10 +177,36 @@ type ptrType struct {
 	elem      *RawType
 }
 
+// Type for interface types. The methods array isn't necessarily 1 element
+// long, instead it contains numMethod method signatures.
 type interfaceType struct {
 	RawType
-	ptrTo *RawType
-	// TODO: methods
+	numMethod uint16
+	ptrTo     *RawType
+	methods   [1]*methodSignature
+}
+
+// Type for function types. The params array isn't necessarily 1 element long,
+// instead it contains numIn parameter types followed by the result types.
+type funcType struct {
+	RawType
+	numMethod uint16
+	ptrTo     *RawType
+	numIn     uint16
+	numOut    uint16         // number of results, the top bit is set for variadic functions
+	call      unsafe.Pointer // wrapper to call a function of this type, nil if unused
+	makeFunc  unsafe.Pointer // trampoline for functions created by MakeFunc, nil if unused
+	params    [1]*RawType
+}
+
+// Flag that is set in the numOut field of variadic function types.
+const funcTypeFlagVariadic = 1 << 15
+
+// methodSignature is the signature of a single method in a method set.
+type methodSignature struct {
+	typ     *RawType // method type without receiver
+	pkgpath *byte    // package path for unexported methods; null terminated
+	name    [1]byte  // method name; null terminated
 }
 
 type arrayType struct {
@@ -341,8 +367,58 @@ func (t *RawType) String() string {
 		s += " }"
 		return s
 	case Interface:
-		// TODO(dgryski): Needs actual method set info
-		return "interface {}"
+		n := t.NumMethod()
+		if n == 0 {
+			return "interface {}"
+		}
+		s := "interface {"
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				s += ";"
+			}
+			sig := t.interfaceMethod(i)
+			s += " "
+			if pkgpath := readStringZ(unsafe.Pointer(sig.pkgpath)); pkgpath != "" {
+				// Unexported methods are qualified with the package name.
+				for j := len(pkgpath) - 1; j >= 0; j-- {
+					if pkgpath[j] == '/' {
+						pkgpath = pkgpath[j+1:]
+						break
+					}
+				}
+				s += pkgpath + "."
+			}
+			s += readStringZ(unsafe.Pointer(&sig.name[0])) + sig.typ.String()[len("func"):]
+		}
+		return s + " }"
+	case Func:
+		ft := (*funcType)(unsafe.Pointer(t))
+		s := "func("
+		for i := 0; i < int(ft.numIn); i++ {
+			if i > 0 {
+				s += ", "
+			}
+			if i == int(ft.numIn)-1 && ft.numOut&funcTypeFlagVariadic != 0 {
+				s += "..." + ft.param(i).elem().String()
+			} else {
+				s += ft.param(i).String()
+			}
+		}
+		s += ")"
+		numOut := int(ft.numOut &^ funcTypeFlagVariadic)
+		if numOut == 1 {
+			s += " " + ft.param(int(ft.numIn)).String()
+		} else if numOut > 1 {
+			s += " ("
+			for i := 0; i < numOut; i++ {
+				if i > 0 {
+					s += ", "
+				}
+				s += ft.param(int(ft.numIn) + i).String()
+			}
+			s += ")"
+		}
+		return s
 	default:
 		return t.Kind().String()
 	}
@@ -372,6 +448,11 @@ var (
 	errTypeChanDir      = &TypeError{"ChanDir"}
 	errTypeFieldByName  = &TypeError{"FieldByName"}
 	errTypeFieldByIndex = &TypeError{"FieldByIndex"}
+	errTypeIn           = &TypeError{"In"}
+	errTypeNumIn        = &TypeError{"NumIn"}
+	errTypeOut          = &TypeError{"Out"}
+	errTypeNumOut       = &TypeError{"NumOut"}
+	errTypeIsVariadic   = &TypeError{"IsVariadic"}
 )
 
 // Elem returns the element type for channel, slice and array types, the
@@ -745,12 +826,68 @@ func (t *RawType) AssignableTo(u Type) bool {
 		return true
 	}
 
+	if t.Kind() == Chan && u.Kind() == Chan && t.ChanDir() == BothDir && t.elem() == u.(*RawType).elem() && (!t.isNamed() || !u.(*RawType).isNamed()) {
+		// A bidirectional channel can be assigned to a directional channel
+		// with the same element type.
+		return true
+	}
+
 	if u.Kind() == Interface {
-		panic("reflect: unimplemented: AssignableTo with interface")
+		if t.Kind() == Interface {
+			return t.hasInterfaceMethods(u.(*RawType))
+		}
+		return t.implements(u.(*RawType))
 	}
 	return false
 }
 
+// implements returns whether the method set of the non-interface type t has
+// all methods of the interface type u.
+//
+// The method set can only be found for types with exported methods, so types
+// that only have unexported methods never implement an interface.
+func (t *RawType) implements(u *RawType) bool {
+	methodSet := t.methodSet()
+	if methodSet == nil {
+		return false
+	}
+	n := methodSetLen(methodSet)
+	for i := 0; i < u.NumMethod(); i++ {
+		sig := u.interfaceMethod(i)
+		found := false
+		for j := 0; j < n; j++ {
+			if (*methodSignature)(methodSetEntry(methodSet, methodSetSignatures, j)) == sig {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// hasInterfaceMethods returns whether the interface type t has all methods of
+// the interface type u.
+func (t *RawType) hasInterfaceMethods(u *RawType) bool {
+	n := t.NumMethod()
+	for i := 0; i < u.NumMethod(); i++ {
+		sig := u.interfaceMethod(i)
+		found := false
+		for j := 0; j < n; j++ {
+			if t.interfaceMethod(j) == sig {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
 func (t *RawType) Implements(u Type) bool {
 	if u.Kind() != Interface {
 		panic("reflect: non-interface type passed to Type.Implements")
@@ -773,7 +910,7 @@ func (t *RawType) ChanDir() ChanDir {
 		panic(errTypeChanDir)
 	}
 
-	dir := int((*elemType)(unsafe.Pointer(t)).numMethod)
+	dir := int((*elemType)(unsafe.Pointer(t.underlying())).numMethod)
 
 	// nummethod is overloaded for channel to store channel direction
 	return ChanDir(dir)
@@ -787,18 +924,267 @@ func (t *RawType) NumMethod() int {
 
 	switch t.Kind() {
 	case Pointer:
+		if t.ptrtag() != 0 {
+			// Pointers to pointers don't have methods.
+			return 0
+		}
 		return int((*ptrType)(unsafe.Pointer(t)).numMethod)
 	case Struct:
 		return int((*structType)(unsafe.Pointer(t)).numMethod)
 	case Interface:
-		//FIXME: Use len(methods)
-		return (*interfaceType)(unsafe.Pointer(t)).ptrTo.NumMethod()
+		return int((*interfaceType)(unsafe.Pointer(t)).numMethod)
 	}
 
 	// Other types have no methods attached.  Note we don't panic here.
 	return 0
 }
 
+// funcType returns the function type struct of t. It panics with the given
+// error if t is not a function type.
+func (t *RawType) funcType(err *TypeError) *funcType {
+	if t.Kind() != Func {
+		panic(err)
+	}
+	return (*funcType)(unsafe.Pointer(t.underlying()))
+}
+
+// param returns the i'th parameter type, where the results follow the input
+// parameters.
+func (t *funcType) param(i int) *RawType {
+	return *(**RawType)(unsafe.Add(unsafe.Pointer(&t.params[0]), uintptr(i)*unsafe.Sizeof(t.params[0])))
+}
+
+// NumIn returns the number of input parameters of a function type. It panics
+// for other type kinds.
+func (t *RawType) NumIn() int {
+	return int(t.funcType(errTypeNumIn).numIn)
+}
+
+// In returns the type of the i'th input parameter of a function type. It
+// panics for other type kinds.
+func (t *RawType) In(i int) Type {
+	return t.in(i)
+}
+
+func (t *RawType) in(i int) *RawType {
+	ft := t.funcType(errTypeIn)
+	if uint(i) >= uint(ft.numIn) {
+		panic("reflect: function parameter index out of range")
+	}
+	return ft.param(i)
+}
+
+// NumOut returns the number of results of a function type. It panics for
+// other type kinds.
+func (t *RawType) NumOut() int {
+	return int(t.funcType(errTypeNumOut).numOut &^ funcTypeFlagVariadic)
+}
+
+// Out returns the type of the i'th result of a function type. It panics for
+// other type kinds.
+func (t *RawType) Out(i int) Type {
+	return t.out(i)
+}
+
+func (t *RawType) out(i int) *RawType {
+	ft := t.funcType(errTypeOut)
+	if uint(i) >= uint(ft.numOut&^funcTypeFlagVariadic) {
+		panic("reflect: function result index out of range")
+	}
+	return ft.param(int(ft.numIn) + i)
+}
+
+// IsVariadic returns whether the last input parameter of a function type is a
+// "..." parameter. It panics for other type kinds.
+func (t *RawType) IsVariadic() bool {
+	return t.funcType(errTypeIsVariadic).numOut&funcTypeFlagVariadic != 0
+}
+
+// Method represents a single method.
+// This must be kept in sync with [reflect.Method].
+type Method struct {
+	// Name is the method name.
+	Name string
+
+	// PkgPath is the package path that qualifies a lower case (unexported)
+	// method name. It is empty for upper case (exported) method names.
+	PkgPath string
+
+	Type  Type  // method type
+	Func  Value // func with receiver as first argument
+	Index int   // index for Type.Method
+}
+
+// IsExported reports whether the method is exported.
+func (m Method) IsExported() bool {
+	return m.PkgPath == ""
+}
+
+// methodSet returns a pointer to the method set of this type, or nil if it
+// has no (exported) methods. The method set is stored in the pointer-sized
+// field just before the type struct, and looks like this:
+//
+//	length      uintptr
+//	signatures  [length]*methodSignature
+//	wrappers    [length]unsafe.Pointer // used for interface method calls
+//	types       [length]*RawType       // method type with the receiver as first parameter
+//	functions   [length]unsafe.Pointer // the method itself
+//
+// The compiler only keeps the method sets when the reflect package needs them.
+// When only AssignableTo and Implements need them, just the signatures are
+// kept.
+func (t *RawType) methodSet() unsafe.Pointer {
+	if t.ptrtag() != 0 || t.Kind() == Interface || t.NumMethod() == 0 {
+		return nil
+	}
+	return *(*unsafe.Pointer)(unsafe.Add(unsafe.Pointer(t), -int(unsafe.Sizeof(uintptr(0)))))
+}
+
+// methodSetLen returns the number of methods in the method set, including
+// unexported methods.
+func methodSetLen(methodSet unsafe.Pointer) int {
+	return int(*(*uintptr)(methodSet))
+}
+
+// methodSetEntry returns the i'th entry in the given array of the method set,
+// where array 0 is the list of signatures.
+func methodSetEntry(methodSet unsafe.Pointer, array, i int) unsafe.Pointer {
+	n := methodSetLen(methodSet)
+	offset := uintptr(1+array*n+i) * unsafe.Sizeof(uintptr(0))
+	return *(*unsafe.Pointer)(unsafe.Add(methodSet, offset))
+}
+
+// Indices of the arrays in a method set, for use in methodSetEntry.
+const (
+	methodSetSignatures = 0
+	methodSetTypes      = 2
+	methodSetFunctions  = 3
+)
+
+// exportedMethod returns the index in the method set of the i'th exported
+// method. It panics if there is no such method.
+func exportedMethod(methodSet unsafe.Pointer, i int) int {
+	if i >= 0 {
+		n := methodSetLen(methodSet)
+		for index := 0; index < n; index++ {
+			sig := (*methodSignature)(methodSetEntry(methodSet, methodSetSignatures, index))
+			if *sig.pkgpath != 0 {
+				// Unexported method.
+				continue
+			}
+			if i == 0 {
+				return index
+			}
+			i--
+		}
+	}
+	panic("reflect: Method index out of range")
+}
+
+// methodByName returns the index in the method set of the exported method with
+// the given name.
+func methodByName(methodSet unsafe.Pointer, name string) (int, bool) {
+	if methodSet == nil {
+		return 0, false
+	}
+	n := methodSetLen(methodSet)
+	for index := 0; index < n; index++ {
+		sig := (*methodSignature)(methodSetEntry(methodSet, methodSetSignatures, index))
+		if *sig.pkgpath == 0 && readStringZ(unsafe.Pointer(&sig.name[0])) == name {
+			return index, true
+		}
+	}
+	return 0, false
+}
+
+// interfaceMethod returns the signature of the i'th method of the interface
+// type t. Unlike for other types, this includes unexported methods.
+func (t *RawType) interfaceMethod(i int) *methodSignature {
+	it := (*interfaceType)(unsafe.Pointer(t.underlying()))
+	if uint(i) >= uint(it.numMethod) {
+		panic("reflect: Method index out of range")
+	}
+	return *(**methodSignature)(unsafe.Add(unsafe.Pointer(&it.methods[0]), uintptr(i)*unsafe.Sizeof(it.methods[0])))
+}
+
+// interfaceMethodByName returns the index of the method with the given name in
+// the interface type t.
+func (t *RawType) interfaceMethodByName(name string) (int, bool) {
+	for i := 0; i < t.NumMethod(); i++ {
+		sig := t.interfaceMethod(i)
+		if readStringZ(unsafe.Pointer(&sig.name[0])) == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Method returns the i'th exported method in the method set of the type. For
+// interface types, it returns the i'th method of the interface, which has no
+// Func.
+func (t *RawType) Method(i int) Method {
+	if t.Kind() == Interface {
+		sig := t.interfaceMethod(i)
+		return Method{
+			Name:    readStringZ(unsafe.Pointer(&sig.name[0])),
+			PkgPath: readStringZ(unsafe.Pointer(sig.pkgpath)),
+			Type:    sig.typ,
+			Index:   i,
+		}
+	}
+	methodSet := t.methodSet()
+	if methodSet == nil {
+		panic("reflect: Method index out of range")
+	}
+	return t.method(methodSet, exportedMethod(methodSet, i), i)
+}
+
+// MethodByName returns the exported method with the given name in the method
+// set of the type.
+func (t *RawType) MethodByName(name string) (Method, bool) {
+	if t.Kind() == Interface {
+		i, ok := t.interfaceMethodByName(name)
+		if !ok {
+			return Method{}, false
+		}
+		return t.Method(i), true
+	}
+	methodSet := t.methodSet()
+	index, ok := methodByName(methodSet, name)
+	if !ok {
+		return Method{}, false
+	}
+
+	// Find the index as used by Method.
+	i := 0
+	for j := 0; j < index; j++ {
+		sig := (*methodSignature)(methodSetEntry(methodSet, methodSetSignatures, j))
+		if *sig.pkgpath == 0 {
+			i++
+		}
+	}
+	return t.method(methodSet, index, i), true
+}
+
+// method returns the method at the given index in the method set.
+func (t *RawType) method(methodSet unsafe.Pointer, index, i int) Method {
+	sig := (*methodSignature)(methodSetEntry(methodSet, methodSetSignatures, index))
+	typ := (*RawType)(methodSetEntry(methodSet, methodSetTypes, index))
+	fn := &funcHeader{
+		Code: methodSetEntry(methodSet, methodSetFunctions, index),
+	}
+	return Method{
+		Name: readStringZ(unsafe.Pointer(&sig.name[0])),
+		Type: typ,
+		Func: Value{
+			typecode: typ,
+			value:    unsafe.Pointer(fn),
+			flags:    valueFlagExported,
+		},
+		Index: i,
+	}
+}
+
 // Read and return a null terminated string starting from data.
 func readStringZ(data unsafe.Pointer) string {
 	start := data
@@ -1099,6 +1485,40 @@ func StructOf([]StructField) Type {
 	panic("unimplemented: reflect.StructOf()")
 }
 
+// chanTypes is the list of all channel types in the program, used by ChanOf.
+// It is defined by the compiler, but only if ChanOf is used.
+//
+//go:extern internal/reflectlite.chanTypes
+var chanTypes struct {
+	count uintptr
+	types [1]*RawType // actually count elements long
+}
+
+// ChanOf returns the channel type with the given direction and element type.
+// Types cannot be created at runtime, so ChanOf panics if the channel type is
+// not used anywhere else in the program.
+func ChanOf(dir ChanDir, t Type) Type {
+	elem := t.(*RawType)
+	var s string
+	switch dir {
+	case SendDir:
+		s = "chan<- " + elem.String()
+	case RecvDir:
+		s = "<-chan " + elem.String()
+	case BothDir:
+		s = "chan " + elem.String()
+	default:
+		panic("reflect.ChanOf: invalid dir")
+	}
+	for i := uintptr(0); i < chanTypes.count; i++ {
+		typ := *(**RawType)(unsafe.Add(unsafe.Pointer(&chanTypes.types[0]), i*unsafe.Sizeof(chanTypes.types[0])))
+		if typ.ChanDir() == dir && typ.elem() == elem {
+			return typ
+		}
+	}
+	panic("unimplemented: reflect.ChanOf: type " + s + " is not used in the program")
+}
+
 func MapOf(key, value Type) Type {
 	panic("unimplemented: reflect.MapOf()")
 }
@@ -16,6 +16,7 @@ const (
 	valueFlagExported
 	valueFlagEmbedRO
 	valueFlagStickyRO
+	valueFlagMethod // method value created by Value.Method, see methodValue
 
 	valueFlagRO = valueFlagEmbedRO | valueFlagStickyRO
 )
@@ -89,6 +90,9 @@ func (v Value) Interface() interface{} {
 // valueInterfaceUnsafe is used by the runtime to hash map keys. It should not
 // be subject to the isExported check.
 func valueInterfaceUnsafe(v Value) interface{} {
+	if v.flags&valueFlagMethod != 0 {
+		v = makeMethodValue(v)
+	}
 	if v.typecode.Kind() == Interface {
 		// The value itself is an interface. This can happen when getting the
 		// value of a struct field of interface type, like this:
@@ -171,6 +175,9 @@ func (v Value) IsNil() bool {
 	case Chan, Map, Ptr, UnsafePointer:
 		return v.pointer() == nil
 	case Func:
+		if v.flags&valueFlagMethod != 0 {
+			return false
+		}
 		if v.value == nil {
 			return true
 		}
@@ -206,6 +213,10 @@ func (v Value) UnsafePointer() unsafe.Pointer {
 		slice := (*sliceHeader)(v.value)
 		return slice.data
 	case Func:
+		if v.flags&valueFlagMethod != 0 {
+			mv := (*methodValue)(v.value)
+			return methodSetEntry(mv.receiver.typecode.methodSet(), methodSetFunctions, mv.index)
+		}
 		fn := (*funcHeader)(v.value)
 		if fn.Context != nil {
 			return fn.Context
@@ -1178,6 +1189,9 @@ func (v Value) Set(x Value) {
 	if !x.typecode.AssignableTo(v.typecode) {
 		panic("reflect.Value.Set: value of type " + x.typecode.String() + " cannot be assigned to type " + v.typecode.String())
 	}
+	if x.flags&valueFlagMethod != 0 {
+		x = makeMethodValue(x)
+	}
 
 	if v.typecode.Kind() == Interface && x.typecode.Kind() != Interface {
 		// move the value of x back into the interface, if possible
@@ -1378,8 +1392,8 @@ func convertOp(src Value, typ Type) (Value, bool) {
 		}, true
 	}
 
-	if rtype := typ.(*RawType); rtype.Kind() == Interface && rtype.NumMethod() == 0 {
-		iface := composeInterface(unsafe.Pointer(src.typecode), src.value)
+	if rtype := typ.(*RawType); rtype.Kind() == Interface && src.typecode.AssignableTo(rtype) {
+		iface := valueInterfaceUnsafe(src)
 		return Value{
 			typecode: rtype,
 			value:    unsafe.Pointer(&iface),
@@ -1472,7 +1486,6 @@ func convertOp(src Value, typ Type) (Value, bool) {
 	// TODO(dgryski): Unimplemented:
 	// Chan
 	// Non-defined pointers types with same underlying base type
-	// Interface <-> Type conversions
 
 	return Value{}, false
 }
@@ -1735,7 +1748,7 @@ func (e *ValueError) Error() string {
 	return "reflect: call of " + e.Method + " on " + e.Kind.String() + " Value"
 }
 
-//go:linkname memcpy runtime.memcpy
+//go:linkname memcpy runtime.typedmemcpy
 func memcpy(dst, src unsafe.Pointer, size uintptr)
 
 //go:linkname memzero runtime.memzero
@@ -2090,24 +2103,504 @@ func MakeMap(typ Type) Value {
 	return MakeMapWithSize(typ, 8)
 }
 
+// Call calls the function v with the input arguments in. It panics if v's
+// Kind is not Func. The returned values are the results of the function.
 func (v Value) Call(in []Value) []Value {
-	panic("unimplemented: (reflect.Value).Call()")
+	if v.Kind() != Func {
+		panic(&ValueError{Method: "reflect.Value.Call", Kind: v.Kind()})
+	}
+	if v.isRO() {
+		panic("reflect: reflect.Value.Call using value obtained using unexported field")
+	}
+	return v.call("Call", in, false)
 }
 
+// CallSlice calls the variadic function v with the input arguments in,
+// assigning the slice in[len(in)-1] to v's final variadic argument.
 func (v Value) CallSlice(in []Value) []Value {
-	panic("unimplemented: (reflect.Value).CallSlice()")
+	if v.Kind() != Func {
+		panic(&ValueError{Method: "reflect.Value.CallSlice", Kind: v.Kind()})
+	}
+	if v.isRO() {
+		panic("reflect: reflect.Value.CallSlice using value obtained using unexported field")
+	}
+	return v.call("CallSlice", in, true)
+}
+
+// methodValue is the value of a Value created by Value.Method: the receiver
+// and the index of the method in its method set.
+type methodValue struct {
+	receiver Value
+	index    int
 }
 
+func (v Value) call(op string, in []Value, isSlice bool) []Value {
+	t := v.typecode
+	fn := v.value
+	if v.flags&valueFlagMethod != 0 {
+		// Call the method as a function with the receiver as the first
+		// parameter.
+		mv := (*methodValue)(v.value)
+		methodSet := mv.receiver.typecode.methodSet()
+		t = (*RawType)(methodSetEntry(methodSet, methodSetTypes, mv.index))
+		fn = unsafe.Pointer(&funcHeader{
+			Code: methodSetEntry(methodSet, methodSetFunctions, mv.index),
+		})
+		in = append([]Value{mv.receiver}, in...)
+	} else if v.IsNil() {
+		panic("reflect: call of nil function")
+	}
+	ft := (*funcType)(unsafe.Pointer(t.underlying()))
+
+	// Check the number and types of the arguments.
+	n := int(ft.numIn)
+	isVariadic := ft.numOut&funcTypeFlagVariadic != 0
+	if isSlice {
+		if !isVariadic {
+			panic("reflect: CallSlice of non-variadic function")
+		}
+		if len(in) < n {
+			panic("reflect: CallSlice with too few input arguments")
+		}
+		if len(in) > n {
+			panic("reflect: CallSlice with too many input arguments")
+		}
+	} else {
+		if isVariadic {
+			n--
+		}
+		if len(in) < n {
+			panic("reflect: Call with too few input arguments")
+		}
+		if !isVariadic && len(in) > n {
+			panic("reflect: Call with too many input arguments")
+		}
+	}
+	for _, x := range in {
+		if x.Kind() == Invalid {
+			panic("reflect: " + op + " using zero Value argument")
+		}
+	}
+	for i := 0; i < n; i++ {
+		if xt, targ := in[i].typecode, ft.param(i); !xt.AssignableTo(targ) {
+			panic("reflect: " + op + " using " + xt.String() + " as type " + targ.String())
+		}
+	}
+
+	if !isSlice && isVariadic {
+		// Pack the remaining arguments in a slice.
+		m := len(in) - n
+		slice := MakeSlice(ft.param(n), m, m)
+		elem := ft.param(n).elem()
+		for i := 0; i < m; i++ {
+			x := in[n+i]
+			if xt := x.typecode; !xt.AssignableTo(elem) {
+				panic("reflect: cannot use " + xt.String() + " as type " + elem.String() + " in " + op)
+			}
+			slice.Index(i).Set(x)
+		}
+		origIn := in
+		in = make([]Value, n+1)
+		copy(in[:n], origIn)
+		in[n] = slice
+	}
+
+	// Collect pointers to all arguments, copying them if needed.
+	params := make([]unsafe.Pointer, len(in))
+	for i, x := range in {
+		if x.isRO() {
+			panic("reflect: " + op + " using value obtained using unexported field")
+		}
+		targ := ft.param(i)
+		if x.typecode == targ && x.flags&valueFlagMethod == 0 && (x.isIndirect() || targ.Size() > unsafe.Sizeof(uintptr(0))) {
+			// The value is already stored in memory.
+			params[i] = x.value
+			continue
+		}
+		arg := Value{
+			typecode: targ,
+			value:    alloc(targ.Size(), targ.gcLayout()),
+			flags:    valueFlagExported | valueFlagIndirect,
+		}
+		arg.Set(x)
+		params[i] = arg.value
+	}
+
+	// Allocate space for the results.
+	numOut := int(ft.numOut &^ funcTypeFlagVariadic)
+	results := make([]unsafe.Pointer, numOut)
+	for i := range results {
+		tout := ft.param(int(ft.numIn) + i)
+		results[i] = alloc(tout.Size(), tout.gcLayout())
+	}
+
+	// Call the function through the wrapper for this function type.
+	var paramsPtr, resultsPtr unsafe.Pointer
+	if len(params) != 0 {
+		paramsPtr = unsafe.Pointer(&params[0])
+	}
+	if len(results) != 0 {
+		resultsPtr = unsafe.Pointer(&results[0])
+	}
+	var callWrapper func(fn, params, results unsafe.Pointer)
+	(*funcHeader)(unsafe.Pointer(&callWrapper)).Code = ft.call
+	callWrapper(fn, paramsPtr, resultsPtr)
+
+	out := make([]Value, numOut)
+	for i, result := range results {
+		tout := ft.param(int(ft.numIn) + i)
+		if size := tout.Size(); size <= unsafe.Sizeof(uintptr(0)) {
+			result = unsafe.Pointer(loadValue(result, size))
+		}
+		out[i] = Value{
+			typecode: tout,
+			value:    result,
+			flags:    valueFlagExported,
+		}
+	}
+	return out
+}
+
+// Method returns a function value corresponding to v's i'th exported method.
+// The arguments to a Call on the returned function should not include a
+// receiver; the returned function will always use v as the receiver.
 func (v Value) Method(i int) Value {
-	panic("unimplemented: (reflect.Value).Method()")
+	if v.typecode == nil {
+		panic(&ValueError{Method: "reflect.Value.Method", Kind: Invalid})
+	}
+	if v.Kind() == Interface {
+		sig := v.typecode.interfaceMethod(i)
+		if v.IsNil() {
+			panic("reflect: Method on nil interface value")
+		}
+		return v.Elem().methodBySignature(sig)
+	}
+	methodSet := v.typecode.methodSet()
+	if methodSet == nil {
+		panic("reflect: Method index out of range")
+	}
+	return v.method(methodSet, exportedMethod(methodSet, i))
 }
 
+// MethodByName returns a function value corresponding to the exported method
+// of v with the given name, or the zero Value if no method was found.
 func (v Value) MethodByName(name string) Value {
-	panic("unimplemented: (reflect.Value).MethodByName()")
+	if v.typecode == nil {
+		panic(&ValueError{Method: "reflect.Value.MethodByName", Kind: Invalid})
+	}
+	if v.Kind() == Interface {
+		// Only methods of the interface can be called on an interface value,
+		// even if the dynamic type has more methods.
+		i, ok := v.typecode.interfaceMethodByName(name)
+		if !ok {
+			return Value{}
+		}
+		if v.IsNil() {
+			panic("reflect: MethodByName on nil interface value")
+		}
+		return v.Elem().methodBySignature(v.typecode.interfaceMethod(i))
+	}
+	methodSet := v.typecode.methodSet()
+	index, ok := methodByName(methodSet, name)
+	if !ok {
+		return Value{}
+	}
+	return v.method(methodSet, index)
 }
 
+// methodBySignature returns the method of v with the given signature, which is
+// a method of an interface that v implements.
+func (v Value) methodBySignature(sig *methodSignature) Value {
+	methodSet := v.typecode.methodSet()
+	if methodSet != nil {
+		for index := 0; index < methodSetLen(methodSet); index++ {
+			if (*methodSignature)(methodSetEntry(methodSet, methodSetSignatures, index)) == sig {
+				return v.method(methodSet, index)
+			}
+		}
+	}
+	panic("reflect: method " + readStringZ(unsafe.Pointer(&sig.name[0])) + " not found in " + v.typecode.String())
+}
+
+func (v Value) method(methodSet unsafe.Pointer, index int) Value {
+	sig := (*methodSignature)(methodSetEntry(methodSet, methodSetSignatures, index))
+	return Value{
+		typecode: sig.typ,
+		value: unsafe.Pointer(&methodValue{
+			receiver: v,
+			index:    index,
+		}),
+		flags: v.flags.ro() | valueFlagExported | valueFlagMethod,
+	}
+}
+
+// These types must match the types of the same name in src/runtime/chan.go.
+type channelOp struct {
+	next  unsafe.Pointer
+	task  unsafe.Pointer
+	index uint32
+	value unsafe.Pointer
+}
+
+type chanSelectState struct {
+	ch    unsafe.Pointer
+	value unsafe.Pointer
+}
+
+//go:linkname chanMake runtime.chanMake
+func chanMake(elementSize uintptr, bufSize uintptr) unsafe.Pointer
+
+//go:linkname chanSend runtime.chanSend
+func chanSend(ch, value unsafe.Pointer, op *channelOp)
+
+//go:linkname chanRecv runtime.chanRecv
+func chanRecv(ch, value unsafe.Pointer, op *channelOp) bool
+
+//go:linkname chanClose runtime.chanClose
+func chanClose(ch unsafe.Pointer)
+
+//go:linkname chanSelect runtime.chanSelect
+func chanSelect(recvbuf unsafe.Pointer, states []chanSelectState, ops []channelOp) (uint32, bool)
+
+// Returned by chanSelect when no case can proceed in a non-blocking select.
+const selectNoIndex = ^uint32(0)
+
+// MakeChan creates a new channel with the specified type and buffer size.
+func MakeChan(typ Type, buffer int) Value {
+	if typ.Kind() != Chan {
+		panic("reflect.MakeChan of non-chan type")
+	}
+	if buffer < 0 {
+		panic("reflect.MakeChan: negative buffer size")
+	}
+	t := typ.(*RawType)
+	if t.ChanDir() != BothDir {
+		panic("reflect.MakeChan: unidirectional channel type")
+	}
+	return Value{
+		typecode: t,
+		value:    chanMake(t.elem().Size(), uintptr(buffer)),
+		flags:    valueFlagExported,
+	}
+}
+
+// checkChan panics if v is not a channel that can be used in the given
+// direction.
+func (v Value) checkChan(method string, dir ChanDir) {
+	if v.Kind() != Chan {
+		panic(&ValueError{Method: method, Kind: v.Kind()})
+	}
+	if v.isRO() {
+		panic("reflect: " + method + " using value obtained using unexported field")
+	}
+	if v.typecode.ChanDir()&dir == 0 {
+		if dir == SendDir {
+			panic("reflect: send on recv-only channel")
+		}
+		panic("reflect: recv on send-only channel")
+	}
+}
+
+// sendValue returns a pointer to a copy of x converted to the element type of
+// channel v, so that it can be sent on the channel.
+func (v Value) sendValue(method string, x Value) unsafe.Pointer {
+	if x.isRO() {
+		panic("reflect: " + method + " using value obtained using unexported field")
+	}
+	elem := v.typecode.elem()
+	if !x.typecode.AssignableTo(elem) {
+		panic(method + ": value of type " + x.typecode.String() + " is not assignable to type " + elem.String())
+	}
+	value := Value{
+		typecode: elem,
+		value:    alloc(elem.Size(), elem.gcLayout()),
+		flags:    valueFlagExported | valueFlagIndirect,
+	}
+	value.Set(x)
+	return value.value
+}
+
+// recvValue returns the Value of an element received from channel v into the
+// given buffer.
+func (v Value) recvValue(buf unsafe.Pointer) Value {
+	elem := v.typecode.elem()
+	if size := elem.Size(); size <= unsafe.Sizeof(uintptr(0)) {
+		buf = unsafe.Pointer(loadValue(buf, size))
+	}
+	return Value{
+		typecode: elem,
+		value:    buf,
+		flags:    valueFlagExported,
+	}
+}
+
+// Send sends x on the channel v. It panics if v's kind is not Chan or if x's
+// type is not the same type as v's element type. As in Go, x's value must be
+// assignable to the channel's element type.
+func (v Value) Send(x Value) {
+	v.checkChan("reflect.Value.Send", SendDir)
+	var op channelOp
+	chanSend(v.pointer(), v.sendValue("reflect.Value.Send", x), &op)
+}
+
+// TrySend attempts to send x on the channel v but will not block. It panics if
+// v's Kind is not Chan. It reports whether the value was sent.
+func (v Value) TrySend(x Value) bool {
+	v.checkChan("reflect.Value.TrySend", SendDir)
+	states := []chanSelectState{{
+		ch:    v.pointer(),
+		value: v.sendValue("reflect.Value.TrySend", x),
+	}}
+	index, _ := chanSelect(nil, states, nil)
+	return index != selectNoIndex
+}
+
+// Recv receives and returns a value from the channel v. It panics if v's Kind
+// is not Chan. The receive blocks until a value is ready. The boolean value ok
+// is true if the value x corresponds to a send on the channel, false if it is
+// a zero value received because the channel is closed.
 func (v Value) Recv() (x Value, ok bool) {
-	panic("unimplemented: (reflect.Value).Recv()")
+	v.checkChan("reflect.Value.Recv", RecvDir)
+	elem := v.typecode.elem()
+	buf := alloc(elem.Size(), elem.gcLayout())
+	var op channelOp
+	ok = chanRecv(v.pointer(), buf, &op)
+	return v.recvValue(buf), ok
+}
+
+// TryRecv attempts to receive a value from the channel v but will not block.
+// It panics if v's Kind is not Chan. If the receive delivers a value, x is the
+// transferred value and ok is true. If the receive cannot finish without
+// blocking, x is the zero Value and ok is false. If the channel is closed, x is
+// the zero value for the channel's element type and ok is false.
+func (v Value) TryRecv() (x Value, ok bool) {
+	v.checkChan("reflect.Value.TryRecv", RecvDir)
+	elem := v.typecode.elem()
+	buf := alloc(elem.Size(), elem.gcLayout())
+	states := []chanSelectState{{ch: v.pointer()}}
+	index, ok := chanSelect(buf, states, nil)
+	if index == selectNoIndex {
+		return Value{}, false
+	}
+	return v.recvValue(buf), ok
+}
+
+// Close closes the channel v. It panics if v's Kind is not Chan or v is a
+// receive-only channel.
+func (v Value) Close() {
+	if v.Kind() != Chan {
+		panic(&ValueError{Method: "reflect.Value.Close", Kind: v.Kind()})
+	}
+	if v.isRO() {
+		panic("reflect: reflect.Value.Close using value obtained using unexported field")
+	}
+	if v.typecode.ChanDir()&SendDir == 0 {
+		panic("reflect: close of receive-only channel")
+	}
+	chanClose(v.pointer())
+}
+
+// A SelectDir describes the communication direction of a select case.
+type SelectDir int
+
+const (
+	_             SelectDir = iota
+	SelectSend              // case Chan <- Send
+	SelectRecv              // case <-Chan:
+	SelectDefault           // default
+)
+
+// A SelectCase describes a single case in a select operation. See
+// reflect.SelectCase for details.
+type SelectCase struct {
+	Dir  SelectDir // direction of case
+	Chan Value     // channel to use (for send or receive)
+	Send Value     // value to send (for send)
+}
+
+// Select executes a select operation described by the list of cases. Like the
+// Go select statement, it blocks until at least one of the cases can proceed,
+// makes a choice, and then executes that case. It returns the index of the
+// chosen case and, if that case was a receive operation, the value received
+// and a boolean indicating whether the value corresponds to a send on the
+// channel (as opposed to a zero value received because the channel is closed).
+func Select(cases []SelectCase) (chosen int, recv Value, recvOK bool) {
+	if len(cases) > 65536 {
+		panic("reflect.Select: too many cases (max 65536)")
+	}
+
+	// Convert the cases to the form used by the runtime. Cases without a
+	// channel are left as nil channels, which never proceed.
+	states := make([]chanSelectState, len(cases))
+	defaultIndex := -1
+	var recvSize uintptr
+	for i, c := range cases {
+		switch c.Dir {
+		case SelectDefault:
+			if defaultIndex >= 0 {
+				panic("reflect.Select: multiple default cases")
+			}
+			if c.Chan.IsValid() {
+				panic("reflect.Select: default case has Chan value")
+			}
+			if c.Send.IsValid() {
+				panic("reflect.Select: default case has Send value")
+			}
+			defaultIndex = i
+		case SelectSend:
+			if !c.Chan.IsValid() {
+				break
+			}
+			c.Chan.checkChan("reflect.Select", SendDir)
+			if !c.Send.IsValid() {
+				panic("reflect.Select: SendDir case missing Send value")
+			}
+			states[i] = chanSelectState{
+				ch:    c.Chan.pointer(),
+				value: c.Chan.sendValue("reflect.Select", c.Send),
+			}
+		case SelectRecv:
+			if c.Send.IsValid() {
+				panic("reflect.Select: RecvDir case has Send value")
+			}
+			if !c.Chan.IsValid() {
+				break
+			}
+			c.Chan.checkChan("reflect.Select", RecvDir)
+			states[i] = chanSelectState{ch: c.Chan.pointer()}
+			if size := c.Chan.typecode.elem().Size(); size > recvSize {
+				recvSize = size
+			}
+		default:
+			panic("reflect.Select: invalid Dir")
+		}
+	}
+
+	if len(cases) == 0 {
+		// An empty select blocks forever.
+		select {}
+	}
+
+	// All receive cases share a single buffer, so it may be used for values
+	// of different types. Therefore it is allocated without a layout, which
+	// means it is scanned conservatively.
+	recvbuf := alloc(recvSize, nil)
+
+	// The ops are only needed for a blocking select.
+	var ops []channelOp
+	if defaultIndex < 0 {
+		ops = make([]channelOp, len(cases))
+	}
+	index, ok := chanSelect(recvbuf, states, ops)
+	if index == selectNoIndex {
+		return defaultIndex, Value{}, false
+	}
+	chosen = int(index)
+	if cases[chosen].Dir == SelectRecv {
+		recv = cases[chosen].Chan.recvValue(recvbuf)
+		recvOK = ok
+	}
+	return chosen, recv, recvOK
 }
 
 func NewAt(typ Type, p unsafe.Pointer) Value {
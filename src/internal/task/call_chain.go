@@ -0,0 +1,18 @@
+//go:build symtab.full && tinygo.wasm
+
+package task
+
+import "unsafe"
+
+//go:linkname swapCallChain runtime.swapCallChain
+func swapCallChain(dst *unsafe.Pointer)
+
+// callChain holds the call frame chain (used for runtime.Callers) of a paused
+// goroutine.
+type callChain struct {
+	top unsafe.Pointer
+}
+
+func (c *callChain) swap() {
+	swapCallChain(&c.top)
+}
@@ -0,0 +1,8 @@
+//go:build !symtab.full || !tinygo.wasm
+
+package task
+
+type callChain struct{}
+
+func (c *callChain) swap() {
+}
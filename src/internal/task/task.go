@@ -18,6 +18,9 @@ type Task struct {
 	// gcData holds data for the GC.
 	gcData gcData
 
+	// callChain holds the call frames for runtime.Callers (WebAssembly only).
+	callChain callChain
+
 	// state is the underlying running state of the task.
 	state state
 
@@ -63,6 +66,27 @@ func (t *Task) DataAtomicUint32() *Uint32 {
 	return (*Uint32)(unsafe.Pointer(&t.Data))
 }
 
+// MaxStackSize is the maximum stack size of a goroutine, as set by
+// runtime/debug.SetMaxStack. Goroutine stacks do not grow, so it is checked
+// when a goroutine is started.
+var MaxStackSize = defaultMaxStackSize()
+
+// defaultMaxStackSize returns the same default limit as the gc toolchain: 1GB
+// on 64-bit systems and 250MB on other systems.
+func defaultMaxStackSize() uint64 {
+	if unsafe.Sizeof(uintptr(0)) >= 8 {
+		return 1000000000
+	}
+	return 250000000
+}
+
+// checkStackSize panics if a new goroutine would exceed the maximum stack size.
+func checkStackSize(stackSize uintptr) {
+	if uint64(stackSize) > MaxStackSize {
+		runtimePanic("goroutine stack exceeds limit set by debug.SetMaxStack")
+	}
+}
+
 // getGoroutineStackSize is a compiler intrinsic that returns the stack size for
 // the given function and falls back to the default stack size. It is replaced
 // with a load from a special section just before codegen.
@@ -73,3 +97,9 @@ func runtime_alloc(size uintptr, layout unsafe.Pointer) unsafe.Pointer
 
 //go:linkname scheduleTask runtime.scheduleTask
 func scheduleTask(*Task)
+
+//go:linkname traceGoCreate runtime.traceGoCreate
+func traceGoCreate(*Task)
+
+//go:linkname traceGoEnd runtime.traceGoEnd
+func traceGoEnd()
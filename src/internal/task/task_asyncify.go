@@ -28,6 +28,13 @@ type state struct {
 	stackState
 
 	launched bool
+
+	// paused is set when the task unwinds its stack to return to the
+	// scheduler. If it is still unset after resuming, the task has exited.
+	paused bool
+
+	// id is the goroutine ID, as shown in stack traces.
+	id uintptr
 }
 
 // stackState is the saved state of a stack while unwound.
@@ -50,11 +57,29 @@ type stackState struct {
 // start creates and starts a new goroutine with the given function and arguments.
 // The new goroutine is immediately started.
 func start(fn uintptr, args unsafe.Pointer, stackSize uintptr) {
+	checkStackSize(stackSize)
 	t := &Task{}
 	t.state.initialize(fn, args, stackSize)
+	goroutineID++
+	t.state.id = goroutineID
+	numGoroutines++
+	traceGoCreate(t)
 	scheduleTask(t)
 }
 
+// goroutineID is the ID of the most recently started goroutine. The main
+// goroutine is started first, so it gets ID 1 like in the gc toolchain.
+var goroutineID uintptr
+
+// numGoroutines is the number of goroutines that have been started and have
+// not yet exited.
+var numGoroutines uint32
+
+// NumGoroutines returns the number of goroutines that currently exist.
+func NumGoroutines() int {
+	return int(numGoroutines)
+}
+
 //export tinygo_launch
 func (*state) launch()
 
@@ -90,6 +115,11 @@ func Current() *Task {
 	return currentTask
 }
 
+// ID returns the goroutine ID of the task.
+func (t *Task) ID() uint64 {
+	return uint64(t.state.id)
+}
+
 // Pause suspends the current task and returns to the scheduler.
 // This function may only be called when running on a goroutine stack, not when running on the system stack.
 func Pause() {
@@ -97,6 +127,7 @@ func Pause() {
 		runtimePanic("stack overflow")
 	}
 
+	currentTask.state.paused = true
 	currentTask.state.unwind()
 }
 
@@ -113,15 +144,23 @@ func (t *Task) Resume() {
 		saveStackPointer()
 	}
 	t.gcData.swap()
+	t.callChain.swap()
 	currentTask = t
+	t.state.paused = false
 	if !t.state.launched {
 		t.state.launch()
 		t.state.launched = true
 	} else {
 		t.state.rewind()
 	}
+	if !t.state.paused {
+		// The task returned instead of unwinding, so it has exited.
+		numGoroutines--
+		traceGoEnd()
+	}
 	currentTask = prevTask
 	t.gcData.swap()
+	t.callChain.swap()
 	if uintptr(t.state.asyncifysp) > uintptr(t.state.csp) {
 		runtimePanic("stack overflow")
 	}
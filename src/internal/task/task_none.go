@@ -27,6 +27,18 @@ func start(fn uintptr, args unsafe.Pointer, stackSize uintptr) {
 
 type state struct{}
 
+// NumGoroutines returns the number of goroutines that currently exist, which
+// is always one without a scheduler.
+func NumGoroutines() int {
+	return 1
+}
+
+// ID returns the goroutine ID of the task, which is always the ID of the main
+// goroutine.
+func (t *Task) ID() uint64 {
+	return 1
+}
+
 func (t *Task) Resume() {
 	runtimePanic("scheduler is disabled")
 }
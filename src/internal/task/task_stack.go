@@ -29,10 +29,28 @@ type state struct {
 	// When initializing the goroutine, the stackCanary constant is stored there.
 	// If the stack overflowed, the word will likely no longer equal stackCanary.
 	canaryPtr *uintptr
+
+	// id is the goroutine ID, as shown in stack traces.
+	id uintptr
+}
+
+// goroutineID is the ID of the most recently started goroutine. The main
+// goroutine is started first, so it gets ID 1 like in the gc toolchain.
+var goroutineID Uintptr
+
+// numGoroutines is the number of goroutines that have been started and have
+// not yet exited.
+var numGoroutines Uint32
+
+// NumGoroutines returns the number of goroutines that currently exist.
+func NumGoroutines() int {
+	return int(numGoroutines.Load())
 }
 
 //export tinygo_task_exit
 func taskExit() {
+	numGoroutines.Add(^uint32(0))
+	traceGoEnd()
 	// TODO: explicitly free the stack after switching back to the scheduler.
 	Pause()
 }
@@ -72,11 +90,20 @@ var startTask [0]uint8
 // start creates and starts a new goroutine with the given function and arguments.
 // The new goroutine is scheduled to run later.
 func start(fn uintptr, args unsafe.Pointer, stackSize uintptr) {
+	checkStackSize(stackSize)
 	t := &Task{}
 	t.state.initialize(fn, args, stackSize)
+	t.state.id = goroutineID.Add(1)
+	numGoroutines.Add(1)
+	traceGoCreate(t)
 	scheduleTask(t)
 }
 
+// ID returns the goroutine ID of the task.
+func (t *Task) ID() uint64 {
+	return uint64(t.state.id)
+}
+
 // OnSystemStack returns whether the caller is running on the system stack.
 func OnSystemStack() bool {
 	// If there is not an active goroutine, then this must be running on the system stack.
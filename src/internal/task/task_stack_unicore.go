@@ -34,4 +34,11 @@ func (t *Task) Resume() {
 	t.state.resume()
 	t.gcData.swap()
 	currentTask = nil
+	gcTaskPaused(t.state.sp)
 }
+
+// gcTaskPaused is called after a task was paused, with its saved stack
+// pointer. The incremental GC uses it to scan the stack of the task.
+//
+//go:linkname gcTaskPaused runtime.gcTaskPaused
+func gcTaskPaused(sp uintptr)
@@ -66,6 +66,12 @@ func Current() *Task {
 	return t
 }
 
+// ID returns the goroutine ID of the task. Goroutines are numbered starting at
+// 1 for the main goroutine, like in the gc toolchain.
+func (t *Task) ID() uint64 {
+	return uint64(t.state.id) + 1
+}
+
 // Pause pauses the current task, until it is resumed by another task.
 // It is possible that another task has called Resume() on the task before it
 // hits Pause(), in which case the task won't be paused but continues
@@ -98,8 +104,17 @@ func (t *Task) Resume() {
 // otherGoroutines is the total number of live goroutines minus one.
 var otherGoroutines uint32
 
+// NumGoroutines returns the number of goroutines that currently exist.
+func NumGoroutines() int {
+	activeTaskLock.Lock()
+	n := otherGoroutines + 1
+	activeTaskLock.Unlock()
+	return int(n)
+}
+
 // Start a new OS thread.
 func start(fn uintptr, args unsafe.Pointer, stackSize uintptr) {
+	checkStackSize(stackSize)
 	t := &Task{}
 	t.state.id = atomic.AddUintptr(&goroutineID, 1)
 	if verbose {
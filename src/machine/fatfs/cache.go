@@ -0,0 +1,119 @@
+package fatfs
+
+// cacheSize is the number of sectors kept in memory. Four sectors are enough
+// to keep the FAT sector, a directory sector and a data sector of an open file
+// in memory at the same time.
+const cacheSize = 4
+
+// cache is a small write-back sector cache. The least recently used sector is
+// evicted (and written if it is dirty) when a new sector is needed.
+type cache struct {
+	entries [cacheSize]cacheEntry
+	clock   uint32
+}
+
+type cacheEntry struct {
+	sector uint32
+	used   uint32 // clock value of the last use, 0 if the entry is empty
+	dirty  bool
+	data   []byte
+}
+
+func (c *cache) init(sectorSize uint32) {
+	buf := make([]byte, cacheSize*sectorSize)
+	for i := range c.entries {
+		c.entries[i] = cacheEntry{data: buf[uint32(i)*sectorSize : uint32(i+1)*sectorSize]}
+	}
+}
+
+// get returns the contents of the given sector of the volume. The returned
+// slice is only valid until the next call to the cache.
+func (c *cache) get(fs *FS, sector uint32) ([]byte, error) {
+	e, err := c.lookup(fs, sector, true)
+	if err != nil {
+		return nil, err
+	}
+	return e.data, nil
+}
+
+// getForWrite is like get, but marks the sector as changed so that it will be
+// written to the device later.
+func (c *cache) getForWrite(fs *FS, sector uint32) ([]byte, error) {
+	e, err := c.lookup(fs, sector, true)
+	if err != nil {
+		return nil, err
+	}
+	e.dirty = true
+	return e.data, nil
+}
+
+// overwrite returns a zeroed buffer for the given sector, for callers that will
+// overwrite the whole sector. The old contents are not read from the device.
+func (c *cache) overwrite(fs *FS, sector uint32) ([]byte, error) {
+	e, err := c.lookup(fs, sector, false)
+	if err != nil {
+		return nil, err
+	}
+	e.dirty = true
+	for i := range e.data {
+		e.data[i] = 0
+	}
+	return e.data, nil
+}
+
+func (c *cache) lookup(fs *FS, sector uint32, read bool) (*cacheEntry, error) {
+	c.clock++
+	victim := &c.entries[0]
+	for i := range c.entries {
+		e := &c.entries[i]
+		if e.used != 0 && e.sector == sector {
+			e.used = c.clock
+			return e, nil
+		}
+		if e.used < victim.used {
+			victim = e
+		}
+	}
+	if err := c.writeBack(fs, victim); err != nil {
+		return nil, err
+	}
+	victim.used = 0
+	if read {
+		if _, err := fs.dev.ReadAt(victim.data, fs.sectorOffset(sector)); err != nil {
+			return nil, err
+		}
+	}
+	victim.sector = sector
+	victim.used = c.clock
+	return victim, nil
+}
+
+// flush writes all dirty sectors to the device.
+func (c *cache) flush(fs *FS) error {
+	for i := range c.entries {
+		if err := c.writeBack(fs, &c.entries[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *cache) writeBack(fs *FS, e *cacheEntry) error {
+	if e.used == 0 || !e.dirty {
+		return nil
+	}
+	if _, err := fs.dev.WriteAt(e.data, fs.sectorOffset(e.sector)); err != nil {
+		return err
+	}
+	e.dirty = false
+
+	// Keep all copies of the FAT in sync.
+	if e.sector >= fs.fatStart && e.sector < fs.fatStart+fs.fatSectors {
+		for i := uint32(1); i < fs.numFATs; i++ {
+			if _, err := fs.dev.WriteAt(e.data, fs.sectorOffset(e.sector+i*fs.fatSectors)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
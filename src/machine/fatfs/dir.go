@@ -0,0 +1,659 @@
+package fatfs
+
+import (
+	"io"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf16"
+)
+
+const (
+	dirEntrySize  = 32
+	maxDirEntries = 65536 // a directory may not be larger than 2MB
+
+	attrReadOnly  = 0x01
+	attrHidden    = 0x02
+	attrSystem    = 0x04
+	attrVolumeID  = 0x08
+	attrDirectory = 0x10
+	attrArchive   = 0x20
+	attrLFN       = attrReadOnly | attrHidden | attrSystem | attrVolumeID
+	attrLFNMask   = attrLFN | attrDirectory | attrArchive
+
+	// Flags in the NTRes field of short entries for short names in lower case.
+	lowerBase = 0x08
+	lowerExt  = 0x10
+
+	deletedMark   = 0xe5
+	lfnLast       = 0x40
+	lfnChars      = 13 // UTF-16 code units per LFN entry
+	maxNameLength = 255
+)
+
+var (
+	dotName    = [11]byte{'.', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' '}
+	dotDotName = [11]byte{'.', '.', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' ', ' '}
+)
+
+// Byte offsets of the characters in an LFN entry.
+var lfnOffsets = [lfnChars]uint8{1, 3, 5, 7, 9, 14, 16, 18, 20, 22, 24, 28, 30}
+
+// dirEntry is a file or directory found in a directory.
+type dirEntry struct {
+	name      string
+	shortName [11]byte
+	attr      uint8
+	cluster   uint32 // first cluster, 0 for an empty file
+	size      uint32
+	modified  uint32 // FAT date and time, see fatTimestamp
+
+	dir    uint32 // directory that contains the entry
+	first  uint32 // index of the first (LFN) entry in the directory
+	index  uint32 // index of the short entry in the directory
+	sector uint32 // sector of the short entry, 0 for the root directory
+	offset uint32 // byte offset of the short entry in the sector
+}
+
+func (e *dirEntry) isDir() bool {
+	return e.attr&attrDirectory != 0
+}
+
+func (e *dirEntry) isRoot() bool {
+	return e.sector == 0
+}
+
+// rootEntry returns an entry for the root directory, which does not have an
+// entry of its own.
+func (fs *FS) rootEntry() *dirEntry {
+	return &dirEntry{name: "/", attr: attrDirectory, cluster: fs.rootCluster}
+}
+
+// dirIter returns the locations of the entries of a directory in order.
+// Directories are identified by their first cluster, or 0 for the fixed size
+// root directory of FAT12 and FAT16.
+type dirIter struct {
+	fs      *FS
+	dir     uint32
+	cluster uint32 // cluster of the previous entry
+	index   uint32 // index of the next entry
+}
+
+func (fs *FS) dirIter(dir uint32) dirIter {
+	return dirIter{fs: fs, dir: dir, cluster: dir}
+}
+
+// next returns the sector and byte offset of the next entry, or io.EOF at the
+// end of the directory.
+func (it *dirIter) next() (sector, offset uint32, err error) {
+	fs := it.fs
+	perSector := fs.sectorSize / dirEntrySize
+	if it.dir == 0 {
+		if it.index >= fs.rootEntries {
+			return 0, 0, io.EOF
+		}
+		sector = fs.rootStart + it.index/perSector
+	} else {
+		perCluster := fs.clusterSize / dirEntrySize
+		if it.index != 0 && it.index%perCluster == 0 {
+			next, err := fs.nextCluster(it.cluster)
+			if err != nil {
+				return 0, 0, err
+			}
+			if next == 0 {
+				return 0, 0, io.EOF
+			}
+			it.cluster = next
+		}
+		sector = fs.clusterSector(it.cluster) + it.index%perCluster/perSector
+	}
+	offset = it.index % perSector * dirEntrySize
+	it.index++
+	return sector, offset, nil
+}
+
+// extend adds an empty cluster to the directory, after next returned io.EOF.
+func (it *dirIter) extend() error {
+	fs := it.fs
+	if it.dir == 0 || it.index >= maxDirEntries {
+		return syscall.ENOSPC
+	}
+	cluster, err := fs.allocCluster(it.cluster)
+	if err != nil {
+		return err
+	}
+	return fs.zeroCluster(cluster)
+}
+
+// skip advances the iterator to the entry with the given index.
+func (it *dirIter) skip(index uint32) error {
+	for it.index < index {
+		if _, _, err := it.next(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readDir calls fn for every file and directory in the given directory, until
+// fn returns false.
+func (fs *FS) readDir(dir uint32, fn func(e *dirEntry) bool) error {
+	var lfn [maxNameLength/lfnChars*lfnChars + lfnChars]uint16
+	var lfnNext uint8 // the ordinal of the next LFN entry, 0 after the last one
+	var lfnSum uint8
+	var lfnFirst uint32
+	var lfnLen int
+	haveLFN := false
+
+	it := fs.dirIter(dir)
+	for {
+		index := it.index
+		sector, offset, err := it.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		b, err := fs.cache.get(fs, sector)
+		if err != nil {
+			return err
+		}
+		raw := b[offset : offset+dirEntrySize]
+
+		switch {
+		case raw[0] == 0:
+			// End of the directory.
+			return nil
+		case raw[0] == deletedMark:
+			haveLFN = false
+			continue
+		case raw[11]&attrLFNMask == attrLFN:
+			ord := raw[0] &^ lfnLast
+			if raw[0]&lfnLast != 0 && ord >= 1 && int(ord) <= len(lfn)/lfnChars {
+				haveLFN = true
+				lfnNext = ord
+				lfnSum = raw[13]
+				lfnFirst = index
+				lfnLen = int(ord) * lfnChars
+			}
+			if !haveLFN || ord != lfnNext || raw[13] != lfnSum {
+				haveLFN = false
+				continue
+			}
+			chars := lfn[int(ord-1)*lfnChars:]
+			for i, pos := range lfnOffsets {
+				chars[i] = le16(raw[pos:])
+			}
+			lfnNext--
+			continue
+		case raw[11]&attrVolumeID != 0:
+			haveLFN = false
+			continue
+		}
+
+		e := &dirEntry{
+			attr:     raw[11],
+			cluster:  uint32(le16(raw[26:])) | uint32(le16(raw[20:]))<<16,
+			size:     le32(raw[28:]),
+			modified: uint32(le16(raw[24:]))<<16 | uint32(le16(raw[22:])),
+			dir:      dir,
+			first:    index,
+			index:    index,
+			sector:   sector,
+			offset:   offset,
+		}
+		copy(e.shortName[:], raw[:11])
+		if haveLFN && lfnNext == 0 && lfnChecksum(e.shortName) == lfnSum {
+			n := 0
+			for n < lfnLen && lfn[n] != 0 {
+				n++
+			}
+			e.name = string(utf16.Decode(lfn[:n]))
+			e.first = lfnFirst
+		} else {
+			e.name = shortNameString(e.shortName, raw[12])
+		}
+		haveLFN = false
+		if !fn(e) {
+			return nil
+		}
+	}
+}
+
+// find returns the entry with the given name (ignoring case) in a directory,
+// or nil if there is none. The name may also be the short name of the entry.
+func (fs *FS) find(dir uint32, name string) (*dirEntry, error) {
+	var found *dirEntry
+	err := fs.readDir(dir, func(e *dirEntry) bool {
+		if strings.EqualFold(e.name, name) || strings.EqualFold(shortNameString(e.shortName, 0), name) {
+			found = e
+			return false
+		}
+		return true
+	})
+	return found, err
+}
+
+// lookup resolves a path. It returns the directory that contains the last
+// element of the path and the entry of that element, or a nil entry and the
+// name of the last element if it does not exist.
+func (fs *FS) lookup(name string) (parent uint32, entry *dirEntry, base string, err error) {
+	name = path.Clean("/" + name)
+	entry = fs.rootEntry()
+	parent = fs.rootCluster
+	for name != "/" {
+		name = name[1:]
+		elem := name
+		if i := strings.IndexByte(name, '/'); i >= 0 {
+			elem, name = name[:i], name[i:]
+		} else {
+			name = "/"
+		}
+		if !entry.isDir() {
+			return 0, nil, "", syscall.ENOTDIR
+		}
+		parent = entry.cluster
+		entry, err = fs.find(parent, elem)
+		if err != nil {
+			return 0, nil, "", err
+		}
+		if entry == nil {
+			if name != "/" {
+				return 0, nil, "", os.ErrNotExist
+			}
+			return parent, nil, elem, nil
+		}
+		base = elem
+	}
+	return parent, entry, base, nil
+}
+
+// dirEmpty returns whether a directory has no entries other than "." and "..".
+func (fs *FS) dirEmpty(dir uint32) (bool, error) {
+	empty := true
+	err := fs.readDir(dir, func(e *dirEntry) bool {
+		if e.shortName != dotName && e.shortName != dotDotName {
+			empty = false
+		}
+		return empty
+	})
+	return empty, err
+}
+
+// createEntry adds an entry to a directory, with LFN entries if the name is
+// not a valid short name.
+func (fs *FS) createEntry(dir uint32, name string, attr uint8, cluster uint32) (*dirEntry, error) {
+	if !validName(name) {
+		return nil, ErrInvalidName
+	}
+	short, caseFlags, needLFN := shortName(name)
+	var units []uint16
+	count := uint32(1)
+	if needLFN {
+		units = utf16.Encode([]rune(name))
+		if len(units) > maxNameLength {
+			return nil, ErrInvalidName
+		}
+		count += uint32(len(units)+lfnChars-1) / lfnChars
+		var err error
+		short, err = fs.uniqueShortName(dir, short)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Find enough consecutive free entries, growing the directory if needed.
+	it := fs.dirIter(dir)
+	var start, run uint32
+	for run < count {
+		index := it.index
+		sector, offset, err := it.next()
+		if err == io.EOF {
+			if err := it.extend(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		b, err := fs.cache.get(fs, sector)
+		if err != nil {
+			return nil, err
+		}
+		if b[offset] == 0 || b[offset] == deletedMark {
+			if run == 0 {
+				start = index
+			}
+			run++
+		} else {
+			run = 0
+		}
+	}
+
+	// Write the LFN entries, from the end of the name to the start, and
+	// then the short entry.
+	e := &dirEntry{
+		name:      name,
+		shortName: short,
+		attr:      attr,
+		cluster:   cluster,
+		modified:  fatTimestamp(time.Now()),
+		dir:       dir,
+		first:     start,
+		index:     start + count - 1,
+	}
+	sum := lfnChecksum(short)
+	it = fs.dirIter(dir)
+	if err := it.skip(start); err != nil {
+		return nil, err
+	}
+	for i := uint32(0); i < count; i++ {
+		sector, offset, err := it.next()
+		if err != nil {
+			return nil, err
+		}
+		b, err := fs.cache.getForWrite(fs, sector)
+		if err != nil {
+			return nil, err
+		}
+		raw := b[offset : offset+dirEntrySize]
+		if i == count-1 {
+			putShortEntry(raw, short, attr, cluster, 0, e.modified)
+			raw[12] = caseFlags
+			e.sector = sector
+			e.offset = offset
+			break
+		}
+		ord := uint8(count - 1 - i)
+		if i == 0 {
+			ord |= lfnLast
+		}
+		putLFNEntry(raw, ord, sum, units)
+	}
+	return e, nil
+}
+
+// updateEntry writes the first cluster, size and modification time of a file
+// to its short entry.
+func (fs *FS) updateEntry(e *dirEntry) error {
+	b, err := fs.cache.getForWrite(fs, e.sector)
+	if err != nil {
+		return err
+	}
+	raw := b[e.offset : e.offset+dirEntrySize]
+	raw[11] = e.attr
+	put16(raw[20:], uint16(e.cluster>>16))
+	put16(raw[26:], uint16(e.cluster))
+	put32(raw[28:], e.size)
+	put16(raw[22:], uint16(e.modified))
+	put16(raw[24:], uint16(e.modified>>16))
+	put16(raw[18:], uint16(e.modified>>16)) // last access date
+	return nil
+}
+
+// removeEntry marks the short entry and the LFN entries of a file as deleted.
+func (fs *FS) removeEntry(e *dirEntry) error {
+	it := fs.dirIter(e.dir)
+	if err := it.skip(e.first); err != nil {
+		return err
+	}
+	for it.index <= e.index {
+		sector, offset, err := it.next()
+		if err != nil {
+			return err
+		}
+		b, err := fs.cache.getForWrite(fs, sector)
+		if err != nil {
+			return err
+		}
+		b[offset] = deletedMark
+	}
+	return nil
+}
+
+// uniqueShortName returns a short name with a numeric tail ("~1") that is not
+// used yet in the directory.
+func (fs *FS) uniqueShortName(dir uint32, short [11]byte) ([11]byte, error) {
+	baseLen := 8
+	for baseLen > 1 && short[baseLen-1] == ' ' {
+		baseLen--
+	}
+	var digits [8]byte
+	for n := 1; n < 1000000; n++ {
+		tail := strconvItoa(digits[:], n)
+		candidate := short
+		pos := baseLen
+		if pos > 8-1-len(tail) {
+			pos = 8 - 1 - len(tail)
+		}
+		candidate[pos] = '~'
+		copy(candidate[pos+1:8], tail)
+		exists := false
+		err := fs.readDir(dir, func(e *dirEntry) bool {
+			exists = e.shortName == candidate
+			return !exists
+		})
+		if err != nil {
+			return short, err
+		}
+		if !exists {
+			return candidate, nil
+		}
+	}
+	return short, os.ErrExist
+}
+
+// strconvItoa formats a positive number into buf and returns the digits.
+func strconvItoa(buf []byte, n int) []byte {
+	i := len(buf)
+	for n > 0 || i == len(buf) {
+		i--
+		buf[i] = byte('0' + n%10)
+		n /= 10
+	}
+	return buf[i:]
+}
+
+// validName returns whether the name can be used for a file or directory.
+func validName(name string) bool {
+	if name == "" || name == "." || name == ".." {
+		return false
+	}
+	if c := name[len(name)-1]; c == '.' || c == ' ' {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c < 0x20 || strings.IndexByte(`"*/:<>?\|`, c) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// validShortChar returns whether the character may be used in short names, in
+// upper case.
+func validShortChar(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		strings.IndexByte("$%'-_@~`!(){}^#&", c) >= 0
+}
+
+// shortName returns the short name for a name, with the case flags of the
+// short entry. If the name is not a valid short name, an approximation is
+// returned (without numeric tail) and needLFN is true.
+func shortName(name string) (short [11]byte, caseFlags uint8, needLFN bool) {
+	for i := range short {
+		short[i] = ' '
+	}
+	base, ext := name, ""
+	if i := strings.LastIndexByte(name, '.'); i > 0 {
+		base, ext = name[:i], name[i+1:]
+	}
+
+	// Names that are a valid short name except for their case are stored
+	// without LFN entries, using the case flags.
+	if len(base) >= 1 && len(base) <= 8 && len(ext) <= 3 {
+		baseFlags, okBase := shortPart(short[:8], base, lowerBase)
+		extFlags, okExt := shortPart(short[8:], ext, lowerExt)
+		if okBase && okExt {
+			return short, baseFlags | extFlags, false
+		}
+	}
+
+	for i := range short {
+		short[i] = ' '
+	}
+	lossyCopy(short[:8], strings.TrimLeft(base, "."))
+	lossyCopy(short[8:], ext)
+	if short[0] == ' ' {
+		short[0] = '_'
+	}
+	return short, 0, true
+}
+
+// shortPart stores one part (base or extension) of a short name in dst. It
+// returns the case flag if the part is in lower case, and false if it is not a
+// valid short name or has mixed case.
+func shortPart(dst []byte, s string, lowerFlag uint8) (uint8, bool) {
+	upper, lower := false, false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+			lower = true
+			c -= 'a' - 'A'
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		}
+		if !validShortChar(c) {
+			return 0, false
+		}
+		dst[i] = c
+	}
+	if upper && lower {
+		return 0, false
+	}
+	if lower {
+		return lowerFlag, true
+	}
+	return 0, true
+}
+
+// lossyCopy copies a name to a part of a short name in upper case, leaving
+// out spaces and dots and replacing other invalid characters.
+func lossyCopy(dst []byte, s string) {
+	n := 0
+	for _, r := range s {
+		if n == len(dst) {
+			return
+		}
+		if r == ' ' || r == '.' {
+			continue
+		}
+		c := byte('_')
+		if r < 0x80 {
+			c = byte(r)
+			if c >= 'a' && c <= 'z' {
+				c -= 'a' - 'A'
+			}
+			if !validShortChar(c) {
+				c = '_'
+			}
+		}
+		dst[n] = c
+		n++
+	}
+}
+
+// shortNameString returns the name stored in a short entry, applying the case
+// flags.
+func shortNameString(short [11]byte, caseFlags uint8) string {
+	var buf [12]byte
+	n := 0
+	for i := 0; i < 11; i++ {
+		c := short[i]
+		if c == ' ' {
+			continue
+		}
+		if i == 8 && n != 0 {
+			buf[n] = '.'
+			n++
+		}
+		if i == 0 && c == 0x05 {
+			c = deletedMark // a name that really starts with 0xe5
+		}
+		if c >= 'A' && c <= 'Z' && (i < 8 && caseFlags&lowerBase != 0 || i >= 8 && caseFlags&lowerExt != 0) {
+			c += 'a' - 'A'
+		}
+		buf[n] = c
+		n++
+	}
+	return string(buf[:n])
+}
+
+// lfnChecksum returns the checksum of a short name that is stored in the LFN
+// entries of the name.
+func lfnChecksum(short [11]byte) uint8 {
+	var sum uint8
+	for _, c := range short {
+		sum = (sum&1)<<7 + sum>>1 + c
+	}
+	return sum
+}
+
+func putShortEntry(b []byte, name [11]byte, attr uint8, cluster, size, timestamp uint32) {
+	for i := range b[:dirEntrySize] {
+		b[i] = 0
+	}
+	copy(b[:11], name[:])
+	b[11] = attr
+	put16(b[14:], uint16(timestamp))     // creation time
+	put16(b[16:], uint16(timestamp>>16)) // creation date
+	put16(b[18:], uint16(timestamp>>16)) // last access date
+	put16(b[20:], uint16(cluster>>16))
+	put16(b[22:], uint16(timestamp))
+	put16(b[24:], uint16(timestamp>>16))
+	put16(b[26:], uint16(cluster))
+	put32(b[28:], size)
+}
+
+// putLFNEntry stores the part of the name for the LFN entry with the given
+// ordinal. The name is terminated by a zero and padded with 0xffff.
+func putLFNEntry(b []byte, ord, sum uint8, name []uint16) {
+	b[0] = ord
+	b[11] = attrLFN
+	b[12] = 0
+	b[13] = sum
+	put16(b[26:], 0)
+	start := int(ord&^lfnLast-1) * lfnChars
+	for i, pos := range lfnOffsets {
+		c := uint16(0xffff)
+		switch k := start + i; {
+		case k < len(name):
+			c = name[k]
+		case k == len(name):
+			c = 0
+		}
+		put16(b[pos:], c)
+	}
+}
+
+// fatTimestamp returns a time in the FAT date and time format, with the date
+// in the upper 16 bits. Times before 1980 (which cannot be stored) are stored
+// as the start of 1980.
+func fatTimestamp(t time.Time) uint32 {
+	year, month, day := t.Date()
+	if year < 1980 {
+		return (1<<5 | 1) << 16
+	}
+	if year > 2107 {
+		year = 2107
+	}
+	hour, min, sec := t.Clock()
+	date := uint32(year-1980)<<9 | uint32(month)<<5 | uint32(day)
+	return date<<16 | uint32(hour)<<11 | uint32(min)<<5 | uint32(sec/2)
+}
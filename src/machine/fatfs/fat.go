@@ -0,0 +1,246 @@
+package fatfs
+
+import (
+	"syscall"
+)
+
+// Cluster numbers of at least eocMin mark the end of a cluster chain. Values
+// of the other FAT types are converted to the FAT32 values when read.
+const (
+	clusterFree = 0
+	eocMin      = 0x0ffffff8
+	eocMark     = 0x0fffffff
+)
+
+// sectorOffset returns the byte offset of a sector of the volume on the device.
+func (fs *FS) sectorOffset(sector uint32) int64 {
+	return fs.volumeStart + int64(sector)*int64(fs.sectorSize)
+}
+
+// clusterSector returns the first sector of the given cluster.
+func (fs *FS) clusterSector(cluster uint32) uint32 {
+	return fs.dataStart + (cluster-2)*fs.sectorsPerCluster
+}
+
+// validCluster returns whether the cluster number refers to a data cluster.
+func (fs *FS) validCluster(cluster uint32) bool {
+	return cluster >= 2 && cluster < fs.numClusters+2
+}
+
+// fatGet returns the FAT entry of the given cluster: the next cluster in the
+// chain, clusterFree, or a value of at least eocMin for the last cluster.
+func (fs *FS) fatGet(cluster uint32) (uint32, error) {
+	switch fs.fatType {
+	case 12:
+		offset := cluster + cluster/2
+		lo, err := fs.fatByte(offset)
+		if err != nil {
+			return 0, err
+		}
+		hi, err := fs.fatByte(offset + 1)
+		if err != nil {
+			return 0, err
+		}
+		value := uint32(lo) | uint32(hi)<<8
+		if cluster&1 != 0 {
+			value >>= 4
+		}
+		value &= 0xfff
+		if value >= 0xff7 {
+			value |= 0x0ffff000
+		}
+		return value, nil
+	case 16:
+		b, err := fs.cache.get(fs, fs.fatStart+cluster*2/fs.sectorSize)
+		if err != nil {
+			return 0, err
+		}
+		value := uint32(le16(b[cluster*2%fs.sectorSize:]))
+		if value >= 0xfff7 {
+			value |= 0x0fff0000
+		}
+		return value, nil
+	default:
+		b, err := fs.cache.get(fs, fs.fatStart+cluster*4/fs.sectorSize)
+		if err != nil {
+			return 0, err
+		}
+		return le32(b[cluster*4%fs.sectorSize:]) & 0x0fffffff, nil
+	}
+}
+
+// fatSet changes the FAT entry of the given cluster. All copies of the FAT are
+// updated when the sector is written.
+func (fs *FS) fatSet(cluster, value uint32) error {
+	switch fs.fatType {
+	case 12:
+		// A FAT12 entry may straddle two sectors, so update it a byte at a
+		// time.
+		offset := cluster + cluster/2
+		value &= 0xfff
+		if cluster&1 != 0 {
+			lo, err := fs.fatByte(offset)
+			if err != nil {
+				return err
+			}
+			if err := fs.setFATByte(offset, lo&0x0f|byte(value<<4)); err != nil {
+				return err
+			}
+			return fs.setFATByte(offset+1, byte(value>>4))
+		}
+		if err := fs.setFATByte(offset, byte(value)); err != nil {
+			return err
+		}
+		hi, err := fs.fatByte(offset + 1)
+		if err != nil {
+			return err
+		}
+		return fs.setFATByte(offset+1, hi&0xf0|byte(value>>8))
+	case 16:
+		b, err := fs.cache.getForWrite(fs, fs.fatStart+cluster*2/fs.sectorSize)
+		if err != nil {
+			return err
+		}
+		put16(b[cluster*2%fs.sectorSize:], uint16(value))
+		return nil
+	default:
+		b, err := fs.cache.getForWrite(fs, fs.fatStart+cluster*4/fs.sectorSize)
+		if err != nil {
+			return err
+		}
+		// The upper four bits are reserved and must be preserved.
+		entry := b[cluster*4%fs.sectorSize:]
+		put32(entry, le32(entry)&0xf0000000|value&0x0fffffff)
+		return nil
+	}
+}
+
+func (fs *FS) fatByte(offset uint32) (byte, error) {
+	b, err := fs.cache.get(fs, fs.fatStart+offset/fs.sectorSize)
+	if err != nil {
+		return 0, err
+	}
+	return b[offset%fs.sectorSize], nil
+}
+
+func (fs *FS) setFATByte(offset uint32, value byte) error {
+	b, err := fs.cache.getForWrite(fs, fs.fatStart+offset/fs.sectorSize)
+	if err != nil {
+		return err
+	}
+	b[offset%fs.sectorSize] = value
+	return nil
+}
+
+// nextCluster returns the cluster after the given cluster in a chain, or 0 if
+// it is the last cluster.
+func (fs *FS) nextCluster(cluster uint32) (uint32, error) {
+	next, err := fs.fatGet(cluster)
+	if err != nil {
+		return 0, err
+	}
+	if next >= eocMin {
+		return 0, nil
+	}
+	if !fs.validCluster(next) {
+		return 0, ErrCorrupt
+	}
+	return next, nil
+}
+
+// allocCluster allocates a free cluster and marks it as the end of a chain. If
+// prev is not zero, the new cluster is appended to the chain ending in prev.
+func (fs *FS) allocCluster(prev uint32) (uint32, error) {
+	if fs.freeCount == 0 {
+		return 0, syscall.ENOSPC
+	}
+	cluster := fs.nextFree
+	for i := uint32(0); i < fs.numClusters; i++ {
+		if !fs.validCluster(cluster) {
+			cluster = 2
+		}
+		value, err := fs.fatGet(cluster)
+		if err != nil {
+			return 0, err
+		}
+		if value == clusterFree {
+			if err := fs.fatSet(cluster, eocMark); err != nil {
+				return 0, err
+			}
+			if prev != 0 {
+				if err := fs.fatSet(prev, cluster); err != nil {
+					return 0, err
+				}
+			}
+			fs.nextFree = cluster + 1
+			if fs.freeCount > 0 {
+				fs.freeCount--
+			}
+			fs.fsInfoDirty = true
+			return cluster, nil
+		}
+		cluster++
+	}
+	fs.freeCount = 0
+	fs.fsInfoDirty = true
+	return 0, syscall.ENOSPC
+}
+
+// freeChain marks all clusters of the chain starting at cluster as free.
+func (fs *FS) freeChain(cluster uint32) error {
+	for i := uint32(0); cluster != 0; i++ {
+		if !fs.validCluster(cluster) || i >= fs.numClusters {
+			return ErrCorrupt
+		}
+		next, err := fs.fatGet(cluster)
+		if err != nil {
+			return err
+		}
+		if err := fs.fatSet(cluster, clusterFree); err != nil {
+			return err
+		}
+		if fs.freeCount >= 0 {
+			fs.freeCount++
+		}
+		fs.fsInfoDirty = true
+		if next >= eocMin {
+			break
+		}
+		cluster = next
+	}
+	return nil
+}
+
+// zeroCluster fills the given cluster with zeroes.
+func (fs *FS) zeroCluster(cluster uint32) error {
+	sector := fs.clusterSector(cluster)
+	for i := uint32(0); i < fs.sectorsPerCluster; i++ {
+		if _, err := fs.cache.overwrite(fs, sector+i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FreeSpace returns the number of free bytes on the volume. On FAT12 and FAT16
+// volumes, and on FAT32 volumes without a valid free cluster count, the whole
+// FAT is read the first time.
+func (fs *FS) FreeSpace() (int64, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.freeCount < 0 {
+		free := int64(0)
+		for cluster := uint32(2); cluster < fs.numClusters+2; cluster++ {
+			value, err := fs.fatGet(cluster)
+			if err != nil {
+				return 0, err
+			}
+			if value == clusterFree {
+				free++
+			}
+		}
+		fs.freeCount = free
+		fs.fsInfoDirty = true
+	}
+	return fs.freeCount * int64(fs.clusterSize), nil
+}
@@ -0,0 +1,562 @@
+// Package fatfs implements the FAT12, FAT16 and FAT32 filesystems, including
+// long filenames, on top of a block device such as an SD card or the flash
+// chip of a board.
+//
+// A filesystem implements os.FilesystemV2, so it can be mounted in the os
+// package and used with the usual functions:
+//
+//	fs, err := fatfs.Mount(dev)
+//	if err != nil {
+//		...
+//	}
+//	os.Mount("/sd/", fs)
+//	f, err := os.Create("/sd/log.txt")
+//
+// The device must allow sectors to be written again without erasing them
+// first, like SD cards do. Changes are cached in memory until a file is synced
+// or closed. A file should not be open more than once while it is written.
+//
+// The same block device can be exported to a host with USB mass storage
+// (machine/usb/msc). Neither side knows about the changes made by the other,
+// so call Sync before the host accesses the volume, and mount it again after
+// the host has changed it.
+package fatfs
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrNotFAT         = errors.New("fatfs: no FAT filesystem found")
+	ErrUnsupported    = errors.New("fatfs: unsupported FAT filesystem")
+	ErrCorrupt        = errors.New("fatfs: filesystem is corrupt")
+	ErrInvalidName    = errors.New("fatfs: invalid file name")
+	ErrDeviceTooSmall = errors.New("fatfs: device too small for this FAT type")
+	ErrDeviceTooLarge = errors.New("fatfs: device too large for this FAT type")
+)
+
+// BlockDevice is the storage of a filesystem. It is implemented by
+// machine.BlockDevice. Reads and writes are done in whole sectors.
+type BlockDevice interface {
+	io.ReaderAt
+	io.WriterAt
+
+	// Size returns the size of the device in bytes.
+	Size() int64
+}
+
+// FS is a mounted FAT filesystem.
+type FS struct {
+	mu  sync.Mutex
+	dev BlockDevice
+
+	fatType           uint8 // 12, 16 or 32
+	sectorSize        uint32
+	sectorsPerCluster uint32
+	clusterSize       uint32
+	volumeStart       int64  // byte offset of the volume on the device
+	fatStart          uint32 // first sector of the first FAT
+	fatSectors        uint32 // sectors per FAT
+	numFATs           uint32
+	rootStart         uint32 // first sector of the root directory (FAT12/16)
+	rootEntries       uint32 // number of entries in the root directory (FAT12/16)
+	dataStart         uint32 // first sector of cluster 2
+	numClusters       uint32 // number of data clusters
+	rootCluster       uint32 // first cluster of the root directory (FAT32)
+
+	// FAT32 only: the FSInfo sector, and the free cluster count (-1 if
+	// unknown) and next free cluster hint stored in it.
+	fsInfoSector uint32
+	freeCount    int64
+	nextFree     uint32
+	fsInfoDirty  bool
+
+	cache cache
+}
+
+// Mount reads the filesystem on the given device. The device may hold a
+// single FAT volume, or a partition table (MBR) with a FAT volume in the first
+// partition.
+func Mount(dev BlockDevice) (*FS, error) {
+	fs := &FS{dev: dev, freeCount: -1}
+	var boot [512]byte
+	if _, err := dev.ReadAt(boot[:], 0); err != nil {
+		return nil, err
+	}
+	if boot[510] != 0x55 || boot[511] != 0xaa {
+		return nil, ErrNotFAT
+	}
+	if !isBootSector(boot[:]) {
+		// Look for a FAT partition in the partition table.
+		found := false
+		for i := 0; i < 4; i++ {
+			entry := boot[446+i*16:]
+			switch entry[4] {
+			case 0x01, 0x04, 0x06, 0x0b, 0x0c, 0x0e:
+				fs.volumeStart = int64(le32(entry[8:])) * 512
+				found = true
+			}
+			if found {
+				break
+			}
+		}
+		if !found {
+			return nil, ErrNotFAT
+		}
+		if _, err := dev.ReadAt(boot[:], fs.volumeStart); err != nil {
+			return nil, err
+		}
+		if !isBootSector(boot[:]) {
+			return nil, ErrNotFAT
+		}
+	}
+	if err := fs.parseBootSector(boot[:]); err != nil {
+		return nil, err
+	}
+	fs.cache.init(fs.sectorSize)
+
+	if fs.fsInfoSector != 0 {
+		b, err := fs.cache.get(fs, fs.fsInfoSector)
+		if err != nil {
+			return nil, err
+		}
+		if le32(b[0:]) == fsInfoLeadSig && le32(b[484:]) == fsInfoStructSig {
+			if free := le32(b[488:]); free <= fs.numClusters {
+				fs.freeCount = int64(free)
+			}
+			if next := le32(b[492:]); next >= 2 && next < fs.numClusters+2 {
+				fs.nextFree = next
+			}
+		} else {
+			fs.fsInfoSector = 0
+		}
+	}
+	if fs.nextFree == 0 {
+		fs.nextFree = 2
+	}
+	return fs, nil
+}
+
+// isBootSector returns whether the sector looks like the boot sector of a FAT
+// volume, as opposed to a partition table.
+func isBootSector(b []byte) bool {
+	if b[0] != 0xeb && b[0] != 0xe9 {
+		return false
+	}
+	sectorSize := le16(b[11:])
+	return sectorSize >= 512 && sectorSize <= 4096 && sectorSize&(sectorSize-1) == 0 &&
+		b[13] != 0 && b[13]&(b[13]-1) == 0 && // sectors per cluster
+		b[16] != 0 // number of FATs
+}
+
+func (fs *FS) parseBootSector(b []byte) error {
+	fs.sectorSize = uint32(le16(b[11:]))
+	fs.sectorsPerCluster = uint32(b[13])
+	fs.clusterSize = fs.sectorSize * fs.sectorsPerCluster
+	reserved := uint32(le16(b[14:]))
+	fs.numFATs = uint32(b[16])
+	fs.rootEntries = uint32(le16(b[17:]))
+	totalSectors := uint32(le16(b[19:]))
+	if totalSectors == 0 {
+		totalSectors = le32(b[32:])
+	}
+	fs.fatSectors = uint32(le16(b[22:]))
+	if fs.fatSectors == 0 {
+		fs.fatSectors = le32(b[36:])
+	}
+	if reserved == 0 || fs.fatSectors == 0 {
+		return ErrCorrupt
+	}
+
+	rootSectors := (fs.rootEntries*dirEntrySize + fs.sectorSize - 1) / fs.sectorSize
+	fs.fatStart = reserved
+	fs.rootStart = reserved + fs.numFATs*fs.fatSectors
+	fs.dataStart = fs.rootStart + rootSectors
+	if totalSectors <= fs.dataStart {
+		return ErrCorrupt
+	}
+	fs.numClusters = (totalSectors - fs.dataStart) / fs.sectorsPerCluster
+
+	// The FAT type is determined by the number of clusters only.
+	switch {
+	case fs.numClusters < 4085:
+		fs.fatType = 12
+	case fs.numClusters < 65525:
+		fs.fatType = 16
+	default:
+		fs.fatType = 32
+	}
+	if fs.fatType == 32 {
+		if le16(b[42:]) != 0 { // FAT32 version
+			return ErrUnsupported
+		}
+		fs.rootCluster = le32(b[44:])
+		fs.fsInfoSector = uint32(le16(b[48:]))
+		if fs.fsInfoSector >= reserved {
+			fs.fsInfoSector = 0
+		}
+		if fs.rootCluster < 2 || fs.rootCluster >= fs.numClusters+2 {
+			return ErrCorrupt
+		}
+	} else if fs.rootEntries == 0 {
+		return ErrCorrupt
+	}
+
+	// The FAT must be large enough for all clusters.
+	if uint64(fs.fatSectors)*uint64(fs.sectorSize)*8 < uint64(fs.numClusters+2)*uint64(fs.fatType) {
+		return ErrCorrupt
+	}
+	return nil
+}
+
+// Type returns the FAT type of the filesystem: 12, 16 or 32.
+func (fs *FS) Type() int {
+	return int(fs.fatType)
+}
+
+// Sync writes all cached changes to the device.
+func (fs *FS) Sync() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.flush()
+}
+
+// flush writes the FSInfo sector (if changed) and all dirty sectors.
+func (fs *FS) flush() error {
+	if fs.fsInfoSector != 0 && fs.fsInfoDirty {
+		b, err := fs.cache.getForWrite(fs, fs.fsInfoSector)
+		if err != nil {
+			return err
+		}
+		free := uint32(0xffffffff)
+		if fs.freeCount >= 0 {
+			free = uint32(fs.freeCount)
+		}
+		put32(b[488:], free)
+		put32(b[492:], fs.nextFree)
+		fs.fsInfoDirty = false
+	}
+	return fs.cache.flush(fs)
+}
+
+// OpenFile opens the named file, as in os.OpenFile. It implements
+// os.Filesystem.
+func (fs *FS) OpenFile(name string, flag int, perm os.FileMode) (os.FileHandle, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	write := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	parent, entry, base, err := fs.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		if flag&os.O_CREATE == 0 {
+			return nil, os.ErrNotExist
+		}
+		if base == "" {
+			return nil, os.ErrInvalid
+		}
+		attr := uint8(attrArchive)
+		if perm&0o200 == 0 {
+			attr |= attrReadOnly
+		}
+		entry, err = fs.createEntry(parent, base, attr, 0)
+		if err != nil {
+			return nil, err
+		}
+		if err := fs.flush(); err != nil {
+			return nil, err
+		}
+	} else {
+		if flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL {
+			return nil, os.ErrExist
+		}
+		if entry.isDir() && write {
+			return nil, syscall.EISDIR
+		}
+		if entry.attr&attrReadOnly != 0 && write {
+			return nil, os.ErrPermission
+		}
+	}
+
+	f := &file{
+		fs:     fs,
+		entry:  *entry,
+		read:   flag&os.O_WRONLY == 0,
+		write:  write,
+		append: flag&os.O_APPEND != 0,
+	}
+	if flag&os.O_TRUNC != 0 && write && f.entry.size != 0 {
+		if err := f.truncate(0); err != nil {
+			return nil, err
+		}
+		if err := f.sync(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// Mkdir creates a new directory. The permissions are ignored, except for the
+// write permission of the owner. It implements os.Filesystem.
+func (fs *FS) Mkdir(name string, perm os.FileMode) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	parent, entry, base, err := fs.lookup(name)
+	if err != nil {
+		return err
+	}
+	if entry != nil {
+		return os.ErrExist
+	}
+	cluster, err := fs.allocCluster(0)
+	if err != nil {
+		return err
+	}
+	if err := fs.zeroCluster(cluster); err != nil {
+		return err
+	}
+
+	// The "." and ".." entries. The root directory is stored as cluster 0.
+	parentCluster := parent
+	if parentCluster == fs.rootCluster {
+		parentCluster = 0
+	}
+	now := fatTimestamp(time.Now())
+	sector := fs.clusterSector(cluster)
+	b, err := fs.cache.getForWrite(fs, sector)
+	if err != nil {
+		return err
+	}
+	putShortEntry(b[0:], dotName, attrDirectory, cluster, 0, now)
+	putShortEntry(b[dirEntrySize:], dotDotName, attrDirectory, parentCluster, 0, now)
+
+	attr := uint8(attrDirectory)
+	if perm&0o200 == 0 {
+		attr |= attrReadOnly
+	}
+	if _, err := fs.createEntry(parent, base, attr, cluster); err != nil {
+		fs.freeChain(cluster)
+		return err
+	}
+	return fs.flush()
+}
+
+// Remove removes the named file or empty directory. It implements
+// os.Filesystem.
+func (fs *FS) Remove(name string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	_, entry, _, err := fs.lookup(name)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return os.ErrNotExist
+	}
+	if entry.isRoot() {
+		return os.ErrInvalid
+	}
+	if entry.isDir() {
+		empty, err := fs.dirEmpty(entry.cluster)
+		if err != nil {
+			return err
+		}
+		if !empty {
+			return syscall.ENOTEMPTY
+		}
+	}
+	if err := fs.removeEntry(entry); err != nil {
+		return err
+	}
+	if entry.cluster != 0 {
+		if err := fs.freeChain(entry.cluster); err != nil {
+			return err
+		}
+	}
+	return fs.flush()
+}
+
+// Stat returns information about the named file or directory. It implements
+// os.FilesystemV2.
+func (fs *FS) Stat(name string) (os.FileInfo, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	_, entry, _, err := fs.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, os.ErrNotExist
+	}
+	return entry.info(), nil
+}
+
+// Chmod changes the read-only attribute of the named file or directory: it is
+// set if the mode has no write permission for the owner. It implements
+// os.FilesystemV2.
+func (fs *FS) Chmod(name string, mode os.FileMode) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	_, entry, _, err := fs.lookup(name)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return os.ErrNotExist
+	}
+	if entry.isRoot() {
+		return os.ErrInvalid
+	}
+	entry.attr = chmodAttr(entry.attr, mode)
+	if err := fs.updateEntry(entry); err != nil {
+		return err
+	}
+	return fs.flush()
+}
+
+func chmodAttr(attr uint8, mode os.FileMode) uint8 {
+	if mode&0o200 == 0 {
+		return attr | attrReadOnly
+	}
+	return attr &^ attrReadOnly
+}
+
+// Truncate changes the size of the named file. It implements
+// os.FilesystemV2.
+func (fs *FS) Truncate(name string, size int64) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	_, entry, _, err := fs.lookup(name)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return os.ErrNotExist
+	}
+	if entry.isDir() {
+		return syscall.EISDIR
+	}
+	if entry.attr&attrReadOnly != 0 {
+		return os.ErrPermission
+	}
+	f := &file{fs: fs, entry: *entry, write: true}
+	if err := f.truncate(size); err != nil {
+		return err
+	}
+	return f.sync()
+}
+
+// Rename renames (moves) a file or directory. An existing file at newname is
+// replaced, but an existing directory is not. It implements os.FilesystemV2.
+func (fs *FS) Rename(oldname, newname string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	_, entry, _, err := fs.lookup(oldname)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return os.ErrNotExist
+	}
+	if entry.isRoot() {
+		return os.ErrInvalid
+	}
+	oldPath := strings.ToLower(path.Clean("/" + oldname))
+	if strings.HasPrefix(strings.ToLower(path.Clean("/"+newname)), oldPath+"/") {
+		// A directory can't be moved into itself.
+		return os.ErrInvalid
+	}
+	parent, existing, base, err := fs.lookup(newname)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.sector == entry.sector && existing.offset == entry.offset {
+		// The same file, possibly with a different case.
+		if base == entry.name {
+			return nil
+		}
+		existing = nil
+	}
+	if existing != nil {
+		switch {
+		case existing.isDir():
+			return os.ErrExist
+		case entry.isDir():
+			return syscall.ENOTDIR
+		case existing.attr&attrReadOnly != 0:
+			return os.ErrPermission
+		}
+		if err := fs.removeEntry(existing); err != nil {
+			return err
+		}
+		if existing.cluster != 0 {
+			if err := fs.freeChain(existing.cluster); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Add the new entry before removing the old one, so that the file is
+	// not lost if the device is removed in between.
+	moved, err := fs.createEntry(parent, base, entry.attr, entry.cluster)
+	if err != nil {
+		return err
+	}
+	moved.size = entry.size
+	moved.modified = entry.modified
+	if err := fs.updateEntry(moved); err != nil {
+		return err
+	}
+	if err := fs.removeEntry(entry); err != nil {
+		return err
+	}
+	if entry.isDir() && entry.dir != parent {
+		// Update the ".." entry of a directory that was moved.
+		parentCluster := parent
+		if parentCluster == fs.rootCluster {
+			parentCluster = 0
+		}
+		b, err := fs.cache.getForWrite(fs, fs.clusterSector(entry.cluster))
+		if err != nil {
+			return err
+		}
+		put16(b[dirEntrySize+20:], uint16(parentCluster>>16))
+		put16(b[dirEntrySize+26:], uint16(parentCluster))
+	}
+	return fs.flush()
+}
+
+func le16(b []byte) uint16 {
+	return uint16(b[0]) | uint16(b[1])<<8
+}
+
+func le32(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
+}
+
+func put16(b []byte, v uint16) {
+	b[0] = byte(v)
+	b[1] = byte(v >> 8)
+}
+
+func put32(b []byte, v uint32) {
+	b[0] = byte(v)
+	b[1] = byte(v >> 8)
+	b[2] = byte(v >> 16)
+	b[3] = byte(v >> 24)
+}
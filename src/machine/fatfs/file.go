@@ -0,0 +1,399 @@
+package fatfs
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"time"
+)
+
+// maxFileSize is the largest size of a file on a FAT volume.
+const maxFileSize = 1<<32 - 1
+
+// file is an open file or directory. It implements os.FileHandleV2.
+type file struct {
+	fs     *FS
+	entry  dirEntry
+	offset int64
+	closed bool
+	dirty  bool // the directory entry must be updated
+
+	read   bool
+	write  bool
+	append bool
+
+	// The last cluster that was used, to avoid following the cluster chain
+	// from the start for every access.
+	cluster      uint32
+	clusterIndex uint32
+
+	dirPos int // number of directory entries returned by ReadDir
+}
+
+// seekCluster returns the cluster with the given index in the cluster chain of
+// the file. If alloc is set, clusters are added to the chain as needed.
+// Otherwise 0 is returned for a cluster after the end of the chain.
+func (f *file) seekCluster(index uint32, alloc bool) (uint32, error) {
+	fs := f.fs
+	if f.entry.cluster == 0 {
+		if !alloc {
+			return 0, nil
+		}
+		cluster, err := fs.allocCluster(0)
+		if err != nil {
+			return 0, err
+		}
+		f.entry.cluster = cluster
+		f.dirty = true
+	}
+	cluster, i := f.entry.cluster, uint32(0)
+	if f.cluster != 0 && f.clusterIndex <= index {
+		cluster, i = f.cluster, f.clusterIndex
+	}
+	for ; i < index; i++ {
+		next, err := fs.nextCluster(cluster)
+		if err != nil {
+			return 0, err
+		}
+		if next == 0 {
+			if !alloc {
+				return 0, nil
+			}
+			next, err = fs.allocCluster(cluster)
+			if err != nil {
+				return 0, err
+			}
+		}
+		cluster = next
+	}
+	f.cluster, f.clusterIndex = cluster, index
+	return cluster, nil
+}
+
+// sectorAt returns the sector that contains the given offset, and the offset
+// in that sector.
+func (f *file) sectorAt(offset int64, alloc bool) (sector, sectorOffset uint32, err error) {
+	fs := f.fs
+	cluster, err := f.seekCluster(uint32(offset/int64(fs.clusterSize)), alloc)
+	if err != nil {
+		return 0, 0, err
+	}
+	if cluster == 0 {
+		return 0, 0, ErrCorrupt // the cluster chain is shorter than the file
+	}
+	inCluster := uint32(offset % int64(fs.clusterSize))
+	return fs.clusterSector(cluster) + inCluster/fs.sectorSize, inCluster % fs.sectorSize, nil
+}
+
+func (f *file) check(write bool) error {
+	switch {
+	case f.closed:
+		return os.ErrClosed
+	case f.entry.isDir():
+		return syscall.EISDIR
+	case write && !f.write, !write && !f.read:
+		return syscall.EBADF
+	}
+	return nil
+}
+
+// Read reads up to len(b) bytes from the current offset.
+func (f *file) Read(b []byte) (n int, err error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if err := f.check(false); err != nil {
+		return 0, err
+	}
+	n, err = f.readAt(b, f.offset)
+	f.offset += int64(n)
+	if err == io.EOF && n != 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// ReadAt reads up to len(b) bytes from the given offset.
+func (f *file) ReadAt(b []byte, offset int64) (n int, err error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if err := f.check(false); err != nil {
+		return 0, err
+	}
+	return f.readAt(b, offset)
+}
+
+func (f *file) readAt(b []byte, offset int64) (n int, err error) {
+	size := int64(f.entry.size)
+	if offset >= size {
+		return 0, io.EOF
+	}
+	if int64(len(b)) > size-offset {
+		b = b[:size-offset]
+		err = io.EOF
+	}
+	for len(b) > 0 {
+		sector, sectorOffset, err := f.sectorAt(offset, false)
+		if err != nil {
+			return n, err
+		}
+		data, err := f.fs.cache.get(f.fs, sector)
+		if err != nil {
+			return n, err
+		}
+		m := copy(b, data[sectorOffset:])
+		b = b[m:]
+		n += m
+		offset += int64(m)
+	}
+	return n, err
+}
+
+// Write writes b at the current offset, or at the end of the file if it was
+// opened with os.O_APPEND.
+func (f *file) Write(b []byte) (n int, err error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if err := f.check(true); err != nil {
+		return 0, err
+	}
+	if f.append {
+		f.offset = int64(f.entry.size)
+	}
+	n, err = f.writeAt(b, f.offset)
+	f.offset += int64(n)
+	return n, err
+}
+
+// WriteAt writes b at the given offset.
+func (f *file) WriteAt(b []byte, offset int64) (n int, err error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if err := f.check(true); err != nil {
+		return 0, err
+	}
+	return f.writeAt(b, offset)
+}
+
+func (f *file) writeAt(b []byte, offset int64) (n int, err error) {
+	if offset+int64(len(b)) > maxFileSize {
+		return 0, syscall.EFBIG
+	}
+	if len(b) == 0 {
+		return 0, nil
+	}
+	// Writing after the end of the file leaves a gap, that must read as
+	// zeroes.
+	if err := f.zeroFill(offset); err != nil {
+		return 0, err
+	}
+	f.dirty = true
+	fs := f.fs
+	for len(b) > 0 {
+		sector, sectorOffset, err := f.sectorAt(offset, true)
+		if err != nil {
+			return n, err
+		}
+		var data []byte
+		if sectorOffset == 0 && uint32(len(b)) >= fs.sectorSize {
+			data, err = fs.cache.overwrite(fs, sector)
+		} else {
+			data, err = fs.cache.getForWrite(fs, sector)
+		}
+		if err != nil {
+			return n, err
+		}
+		m := copy(data[sectorOffset:], b)
+		b = b[m:]
+		n += m
+		offset += int64(m)
+		if offset > int64(f.entry.size) {
+			f.entry.size = uint32(offset)
+		}
+	}
+	return n, nil
+}
+
+// zeroFill extends the file with zeroes up to the given size, if it is
+// smaller.
+func (f *file) zeroFill(size int64) error {
+	var zero [64]byte
+	for int64(f.entry.size) < size {
+		chunk := size - int64(f.entry.size)
+		if chunk > int64(len(zero)) {
+			chunk = int64(len(zero))
+		}
+		if _, err := f.writeAt(zero[:chunk], int64(f.entry.size)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// truncate changes the size of the file, freeing the clusters after the new
+// end or filling the new part with zeroes.
+func (f *file) truncate(size int64) error {
+	if size > maxFileSize {
+		return syscall.EFBIG
+	}
+	if size >= int64(f.entry.size) {
+		return f.zeroFill(size)
+	}
+	fs := f.fs
+	f.dirty = true
+	f.entry.size = uint32(size)
+	f.cluster, f.clusterIndex = 0, 0
+	if f.entry.cluster == 0 {
+		return nil
+	}
+	if size == 0 {
+		cluster := f.entry.cluster
+		f.entry.cluster = 0
+		return fs.freeChain(cluster)
+	}
+	last, err := f.seekCluster(uint32((size-1)/int64(fs.clusterSize)), false)
+	if err != nil {
+		return err
+	}
+	if last == 0 {
+		return ErrCorrupt
+	}
+	next, err := fs.nextCluster(last)
+	if err != nil {
+		return err
+	}
+	if next == 0 {
+		return nil
+	}
+	if err := fs.fatSet(last, eocMark); err != nil {
+		return err
+	}
+	return fs.freeChain(next)
+}
+
+// Seek sets the offset for the next Read or Write.
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(f.entry.size)
+	default:
+		return 0, syscall.EINVAL
+	}
+	if offset < 0 {
+		return 0, syscall.EINVAL
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+// Sync writes the changes to the file, and all other cached changes of the
+// filesystem, to the device.
+func (f *file) Sync() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if f.closed {
+		return os.ErrClosed
+	}
+	return f.sync()
+}
+
+func (f *file) sync() error {
+	if f.dirty {
+		f.entry.modified = fatTimestamp(time.Now())
+		f.entry.attr |= attrArchive
+		if err := f.fs.updateEntry(&f.entry); err != nil {
+			return err
+		}
+		f.dirty = false
+	}
+	return f.fs.flush()
+}
+
+// ReadDir reads the next n entries of a directory, or all remaining entries if
+// n <= 0.
+func (f *file) ReadDir(n int) ([]os.DirEntry, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if f.closed {
+		return nil, os.ErrClosed
+	}
+	if !f.entry.isDir() {
+		return nil, syscall.ENOTDIR
+	}
+	var entries []os.DirEntry
+	skip := f.dirPos
+	err := f.fs.readDir(f.entry.cluster, func(e *dirEntry) bool {
+		if e.shortName == dotName || e.shortName == dotDotName {
+			return true
+		}
+		if skip > 0 {
+			skip--
+			return true
+		}
+		entries = append(entries, e.info())
+		return n <= 0 || len(entries) < n
+	})
+	f.dirPos += len(entries)
+	if err == nil && n > 0 && len(entries) == 0 {
+		err = io.EOF
+	}
+	return entries, err
+}
+
+// Stat returns information about the file.
+func (f *file) Stat() (os.FileInfo, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if f.closed {
+		return nil, os.ErrClosed
+	}
+	return f.entry.info(), nil
+}
+
+// Truncate changes the size of the file.
+func (f *file) Truncate(size int64) error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if err := f.check(true); err != nil {
+		return err
+	}
+	if size < 0 {
+		return syscall.EINVAL
+	}
+	return f.truncate(size)
+}
+
+// Chmod changes the read-only attribute of the file, like FS.Chmod.
+func (f *file) Chmod(mode os.FileMode) error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if f.closed {
+		return os.ErrClosed
+	}
+	if f.entry.isRoot() {
+		return os.ErrInvalid
+	}
+	f.entry.attr = chmodAttr(f.entry.attr, mode)
+	if err := f.fs.updateEntry(&f.entry); err != nil {
+		return err
+	}
+	return f.fs.flush()
+}
+
+// Close writes the changes to the device and closes the file.
+func (f *file) Close() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if f.closed {
+		return os.ErrClosed
+	}
+	f.closed = true
+	return f.sync()
+}
@@ -0,0 +1,232 @@
+package fatfs
+
+import (
+	"time"
+)
+
+const (
+	formatSectorSize = 512
+	fsInfoLeadSig    = 0x41615252
+	fsInfoStructSig  = 0x61417272
+	fsInfoTrailSig   = 0xaa550000
+)
+
+// FormatConfig is the configuration of a new filesystem.
+type FormatConfig struct {
+	// FAT type: 12, 16 or 32. The default (0) picks a type based on the size
+	// of the device.
+	Type int
+
+	// Number of sectors per cluster, a power of two up to 128. The default
+	// (0) picks a value based on the size of the device.
+	SectorsPerCluster int
+
+	// Volume label, up to 11 characters. It is optional.
+	Label string
+}
+
+// Format creates an empty filesystem that uses the whole device, with
+// 512-byte sectors. Any data on the device is lost.
+func Format(dev BlockDevice, config FormatConfig) error {
+	totalSectors64 := dev.Size() / formatSectorSize
+	if totalSectors64 > 0xffffffff {
+		totalSectors64 = 0xffffffff
+	}
+	totalSectors := uint32(totalSectors64)
+
+	fatType := uint32(config.Type)
+	if fatType == 0 {
+		switch {
+		case totalSectors < 8400: // about 4MB
+			fatType = 12
+		case totalSectors < 1048576: // 512MB
+			fatType = 16
+		default:
+			fatType = 32
+		}
+	}
+	if fatType != 12 && fatType != 16 && fatType != 32 {
+		return ErrUnsupported
+	}
+
+	reserved, rootEntries := uint32(1), uint32(512)
+	if fatType == 32 {
+		reserved, rootEntries = 32, 0
+	}
+	rootSectors := rootEntries * dirEntrySize / formatSectorSize
+	const numFATs = 2
+
+	sectorsPerCluster := uint32(config.SectorsPerCluster)
+	if sectorsPerCluster == 0 {
+		sectorsPerCluster = 1
+		switch fatType {
+		case 12, 16:
+			// The smallest clusters that keep the cluster count within the
+			// limits of the type.
+			limit := uint32(4085)
+			if fatType == 16 {
+				limit = 65525
+			}
+			for sectorsPerCluster < 128 && totalSectors/sectorsPerCluster >= limit {
+				sectorsPerCluster *= 2
+			}
+		case 32:
+			switch {
+			case totalSectors <= 16777216: // 8GB
+				sectorsPerCluster = 8
+			case totalSectors <= 33554432: // 16GB
+				sectorsPerCluster = 16
+			case totalSectors <= 67108864: // 32GB
+				sectorsPerCluster = 32
+			default:
+				sectorsPerCluster = 64
+			}
+			// Small volumes need smaller clusters to have enough of them.
+			for sectorsPerCluster > 1 && totalSectors/sectorsPerCluster < 65536 {
+				sectorsPerCluster /= 2
+			}
+		}
+	}
+	if sectorsPerCluster == 0 || sectorsPerCluster > 128 || sectorsPerCluster&(sectorsPerCluster-1) != 0 {
+		return ErrUnsupported
+	}
+
+	// The size of the FAT depends on the number of clusters, which depends on
+	// the size of the FAT. Iterate until the size is stable.
+	var fatSectors, numClusters uint32
+	for {
+		overhead := reserved + rootSectors + numFATs*fatSectors
+		if totalSectors <= overhead {
+			return ErrDeviceTooSmall
+		}
+		numClusters = (totalSectors - overhead) / sectorsPerCluster
+		needed := (((numClusters+2)*fatType+7)/8 + formatSectorSize - 1) / formatSectorSize
+		if needed <= fatSectors {
+			break
+		}
+		fatSectors = needed
+	}
+	switch {
+	case fatType == 12 && numClusters >= 4085,
+		fatType == 16 && numClusters >= 65525,
+		fatType == 32 && numClusters >= 0x0ffffff5:
+		return ErrDeviceTooLarge
+	case fatType == 16 && numClusters < 4085,
+		fatType == 32 && numClusters < 65525,
+		numClusters < 1:
+		return ErrDeviceTooSmall
+	}
+
+	// Clear the reserved sectors, the FATs and the root directory.
+	var sector [formatSectorSize]byte
+	rootDirSectors := rootSectors
+	if fatType == 32 {
+		rootDirSectors = sectorsPerCluster // the root directory is cluster 2
+	}
+	for i := uint32(0); i < reserved+numFATs*fatSectors+rootDirSectors; i++ {
+		if _, err := dev.WriteAt(sector[:], int64(i)*formatSectorSize); err != nil {
+			return err
+		}
+	}
+
+	// Boot sector.
+	volumeID := fatTimestamp(time.Now())
+	label := [11]byte{'N', 'O', ' ', 'N', 'A', 'M', 'E', ' ', ' ', ' ', ' '}
+	if config.Label != "" {
+		for i := range label {
+			label[i] = ' '
+		}
+		lossyCopy(label[:], config.Label)
+	}
+	b := sector[:]
+	b[0], b[1], b[2] = 0xeb, 0x3c, 0x90 // jump to the (missing) boot code
+	copy(b[3:11], "MSWIN4.1")
+	put16(b[11:], formatSectorSize)
+	b[13] = uint8(sectorsPerCluster)
+	put16(b[14:], uint16(reserved))
+	b[16] = numFATs
+	put16(b[17:], uint16(rootEntries))
+	if totalSectors < 0x10000 && fatType != 32 {
+		put16(b[19:], uint16(totalSectors))
+	} else {
+		put32(b[32:], totalSectors)
+	}
+	b[21] = 0xf8       // media: fixed disk
+	put16(b[24:], 63)  // sectors per track
+	put16(b[26:], 255) // heads
+	ext := b[36:]
+	if fatType == 32 {
+		b[1] = 0x58
+		put32(b[36:], fatSectors)
+		put32(b[44:], 2) // root cluster
+		put16(b[48:], 1) // FSInfo sector
+		put16(b[50:], 6) // backup boot sector
+		ext = b[64:]
+	} else {
+		put16(b[22:], uint16(fatSectors))
+	}
+	ext[0] = 0x80 // drive number
+	ext[2] = 0x29 // extended boot signature
+	put32(ext[3:], volumeID)
+	copy(ext[7:18], label[:])
+	copy(ext[18:26], "FAT     ")
+	ext[21], ext[22] = '0'+byte(fatType/10), '0'+byte(fatType%10)
+	b[510], b[511] = 0x55, 0xaa
+	if _, err := dev.WriteAt(b, 0); err != nil {
+		return err
+	}
+	if fatType == 32 {
+		if _, err := dev.WriteAt(b, 6*formatSectorSize); err != nil {
+			return err
+		}
+
+		// FSInfo sector, and its backup.
+		for i := range sector {
+			sector[i] = 0
+		}
+		put32(b[0:], fsInfoLeadSig)
+		put32(b[484:], fsInfoStructSig)
+		put32(b[488:], numClusters-1) // cluster 2 is used by the root directory
+		put32(b[492:], 3)
+		put32(b[508:], fsInfoTrailSig)
+		if _, err := dev.WriteAt(b, 1*formatSectorSize); err != nil {
+			return err
+		}
+		if _, err := dev.WriteAt(b, 7*formatSectorSize); err != nil {
+			return err
+		}
+	}
+
+	// The first two FAT entries hold the media type and the end of chain
+	// marker. On FAT32, the third is the root directory.
+	for i := range sector {
+		sector[i] = 0
+	}
+	switch fatType {
+	case 12:
+		copy(b, []byte{0xf8, 0xff, 0xff})
+	case 16:
+		copy(b, []byte{0xf8, 0xff, 0xff, 0xff})
+	case 32:
+		put32(b[0:], 0x0ffffff8)
+		put32(b[4:], eocMark)
+		put32(b[8:], eocMark)
+	}
+	for i := uint32(0); i < numFATs; i++ {
+		if _, err := dev.WriteAt(b, int64(reserved+i*fatSectors)*formatSectorSize); err != nil {
+			return err
+		}
+	}
+
+	// Volume label entry in the root directory.
+	if config.Label != "" {
+		for i := range sector {
+			sector[i] = 0
+		}
+		putShortEntry(b, label, attrVolumeID, 0, 0, volumeID)
+		if _, err := dev.WriteAt(b, int64(reserved+numFATs*fatSectors)*formatSectorSize); err != nil {
+			return err
+		}
+	}
+	return nil
+}
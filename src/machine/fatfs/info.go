@@ -0,0 +1,77 @@
+package fatfs
+
+import (
+	"io/fs"
+	"time"
+)
+
+// fileInfo describes a file or directory. It implements fs.FileInfo and
+// fs.DirEntry.
+type fileInfo struct {
+	name     string
+	size     int64
+	attr     uint8
+	modified uint32
+}
+
+func (e *dirEntry) info() *fileInfo {
+	return &fileInfo{
+		name:     e.name,
+		size:     int64(e.size),
+		attr:     e.attr,
+		modified: e.modified,
+	}
+}
+
+func (fi *fileInfo) Name() string {
+	return fi.name
+}
+
+func (fi *fileInfo) Size() int64 {
+	if fi.IsDir() {
+		return 0
+	}
+	return fi.size
+}
+
+// Mode returns the file mode bits. FAT has no permissions other than the
+// read-only attribute, which clears the write bits.
+func (fi *fileInfo) Mode() fs.FileMode {
+	mode := fs.FileMode(0o666)
+	if fi.IsDir() {
+		mode = fs.ModeDir | 0o777
+	}
+	if fi.attr&attrReadOnly != 0 {
+		mode &^= 0o222
+	}
+	return mode
+}
+
+func (fi *fileInfo) ModTime() time.Time {
+	return fatTime(fi.modified)
+}
+
+func (fi *fileInfo) IsDir() bool {
+	return fi.attr&attrDirectory != 0
+}
+
+// Sys returns the FAT attributes of the file, as a uint8.
+func (fi *fileInfo) Sys() any {
+	return fi.attr
+}
+
+func (fi *fileInfo) Type() fs.FileMode {
+	return fi.Mode().Type()
+}
+
+func (fi *fileInfo) Info() (fs.FileInfo, error) {
+	return fi, nil
+}
+
+// fatTime converts a FAT date and time (see fatTimestamp) to a time.Time. FAT
+// times have no time zone, so they are assumed to be in UTC.
+func fatTime(t uint32) time.Time {
+	date, clock := t>>16, t&0xffff
+	return time.Date(int(date>>9)+1980, time.Month(date>>5&0xf), int(date&0x1f),
+		int(clock>>11), int(clock>>5&0x3f), int(clock&0x1f)*2, 0, time.UTC)
+}
@@ -0,0 +1,240 @@
+package flashfs
+
+import (
+	"io"
+	"os"
+	"syscall"
+)
+
+// maxFileSize is the largest size of a file.
+const maxFileSize = 1<<32 - 1
+
+// file is an open file or directory. It implements os.FileHandleV2.
+//
+// All handles of a file share its uncommitted changes: syncing or closing any
+// of them commits the changes of all of them.
+type file struct {
+	fs     *FS
+	node   *node
+	offset int64
+	closed bool
+
+	read   bool
+	write  bool
+	append bool
+
+	dirPos int // number of directory entries returned by ReadDir
+}
+
+func (f *file) check(write bool) error {
+	switch {
+	case f.closed:
+		return os.ErrClosed
+	case f.node.dir:
+		return syscall.EISDIR
+	case write && !f.write, !write && !f.read:
+		return syscall.EBADF
+	case f.node.deleted:
+		return os.ErrNotExist
+	}
+	return nil
+}
+
+// Read reads up to len(b) bytes from the current offset.
+func (f *file) Read(b []byte) (n int, err error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if err := f.check(false); err != nil {
+		return 0, err
+	}
+	n, err = f.readAt(b, f.offset)
+	f.offset += int64(n)
+	if err == io.EOF && n != 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// ReadAt reads up to len(b) bytes from the given offset.
+func (f *file) ReadAt(b []byte, offset int64) (n int, err error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if err := f.check(false); err != nil {
+		return 0, err
+	}
+	return f.readAt(b, offset)
+}
+
+func (f *file) readAt(b []byte, offset int64) (n int, err error) {
+	size := int64(f.node.currentSize())
+	if offset >= size {
+		return 0, io.EOF
+	}
+	if int64(len(b)) > size-offset {
+		b = b[:size-offset]
+		err = io.EOF
+	}
+	if rerr := f.fs.readAt(f.node, b, uint32(offset)); rerr != nil {
+		return 0, rerr
+	}
+	return len(b), err
+}
+
+// Write writes b at the current offset, or at the end of the file if it was
+// opened with os.O_APPEND.
+func (f *file) Write(b []byte) (n int, err error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if err := f.check(true); err != nil {
+		return 0, err
+	}
+	if f.append {
+		f.offset = int64(f.node.currentSize())
+	}
+	n, err = f.writeAt(b, f.offset)
+	f.offset += int64(n)
+	return n, err
+}
+
+// WriteAt writes b at the given offset.
+func (f *file) WriteAt(b []byte, offset int64) (n int, err error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if err := f.check(true); err != nil {
+		return 0, err
+	}
+	return f.writeAt(b, offset)
+}
+
+func (f *file) writeAt(b []byte, offset int64) (n int, err error) {
+	if offset < 0 {
+		return 0, syscall.EINVAL
+	}
+	if offset+int64(len(b)) > maxFileSize {
+		return 0, syscall.EFBIG
+	}
+	if len(b) == 0 {
+		return 0, nil
+	}
+	if err := f.fs.writeData(f.node, b, uint32(offset)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Seek sets the offset for the next Read or Write.
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(f.node.currentSize())
+	default:
+		return 0, syscall.EINVAL
+	}
+	if offset < 0 {
+		return 0, syscall.EINVAL
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+// Sync commits the changes to the file. When power is lost after Sync
+// returns, the file keeps these contents.
+func (f *file) Sync() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if f.closed {
+		return os.ErrClosed
+	}
+	return f.fs.sync(f.node)
+}
+
+// ReadDir reads the next n entries of a directory, or all remaining entries if
+// n <= 0. Entries are returned in the order in which they were created.
+func (f *file) ReadDir(n int) ([]os.DirEntry, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if f.closed {
+		return nil, os.ErrClosed
+	}
+	if !f.node.dir {
+		return nil, syscall.ENOTDIR
+	}
+	children := f.fs.children(f.node)
+	if f.dirPos < len(children) {
+		children = children[f.dirPos:]
+	} else {
+		children = nil
+	}
+	if n > 0 && len(children) > n {
+		children = children[:n]
+	}
+	if n > 0 && len(children) == 0 {
+		return nil, io.EOF
+	}
+	entries := make([]os.DirEntry, len(children))
+	for i, child := range children {
+		entries[i] = child.info()
+	}
+	f.dirPos += len(entries)
+	return entries, nil
+}
+
+// Stat returns information about the file, including uncommitted changes.
+func (f *file) Stat() (os.FileInfo, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if f.closed {
+		return nil, os.ErrClosed
+	}
+	return f.node.info(), nil
+}
+
+// Truncate changes the size of the file. Like other changes, it is committed
+// by Sync or Close.
+func (f *file) Truncate(size int64) error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if err := f.check(true); err != nil {
+		return err
+	}
+	if size < 0 {
+		return syscall.EINVAL
+	}
+	if size > maxFileSize {
+		return syscall.EFBIG
+	}
+	f.fs.truncate(f.node, uint32(size))
+	return nil
+}
+
+// Chmod changes the read-only flag of the file, like FS.Chmod.
+func (f *file) Chmod(mode os.FileMode) error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if f.closed {
+		return os.ErrClosed
+	}
+	if f.node.deleted {
+		return os.ErrNotExist
+	}
+	return f.fs.chmod(f.node, mode)
+}
+
+// Close commits the changes to the file and closes it.
+func (f *file) Close() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if f.closed {
+		return os.ErrClosed
+	}
+	f.closed = true
+	return f.fs.sync(f.node)
+}
@@ -0,0 +1,539 @@
+// Package flashfs implements a small filesystem for flash memory, such as the
+// internal flash of a microcontroller (machine.Flash).
+//
+// The filesystem is a circular log: all changes are appended to it, and the
+// oldest block of the log is moved to the head (leaving out all data that is
+// no longer used) and erased when free blocks run out. This makes it:
+//
+//   - power loss safe: changes to a file are committed when the file is synced
+//     or closed. When power is lost before that, or while it happens, the
+//     file keeps its previous contents after the next Mount.
+//   - wear leveling: every block is erased once per cycle of the log, even
+//     when it contains files that never change.
+//
+// The directory tree and the location of all file data is kept in memory, so
+// it is meant for configuration files, calibration data and small logs, not
+// for large amounts of data. The format on flash is specific to this package;
+// it is not compatible with littlefs. There are no permissions, other than a
+// read-only flag that is set when a file is created or changed with os.Chmod
+// without write permission, and no modification times.
+//
+// Example:
+//
+//	fs, err := flashfs.Mount(machine.Flash)
+//	if err == flashfs.ErrNotFormatted {
+//		err = flashfs.Format(machine.Flash, flashfs.Config{})
+//		if err == nil {
+//			fs, err = flashfs.Mount(machine.Flash)
+//		}
+//	}
+//	if err != nil {
+//		...
+//	}
+//	os.Mount("/flash/", fs)
+//	os.WriteFile("/flash/config.json", data, 0666)
+//
+// FS implements os.FilesystemV2.
+package flashfs
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+var (
+	ErrNotFormatted = errors.New("flashfs: no filesystem found")
+	ErrBlockSize    = errors.New("flashfs: invalid block size")
+	ErrTooSmall     = errors.New("flashfs: device too small")
+	ErrNameTooLong  = errors.New("flashfs: file name too long")
+	ErrCorrupt      = errors.New("flashfs: filesystem is corrupt")
+)
+
+// BlockDevice is the flash memory that stores a filesystem. It has the same
+// methods as machine.BlockDevice, so machine.Flash can be used directly.
+type BlockDevice interface {
+	io.ReaderAt
+	io.WriterAt
+
+	// Size returns the size of the device in bytes.
+	Size() int64
+
+	// WriteBlockSize returns the alignment of writes, in bytes.
+	WriteBlockSize() int64
+
+	// EraseBlockSize returns the size of the smallest area that can be
+	// erased, in bytes.
+	EraseBlockSize() int64
+
+	// EraseBlocks erases the given erase blocks, setting all bytes to 0xff.
+	EraseBlocks(start, len int64) error
+}
+
+// Config is the configuration of a new filesystem.
+type Config struct {
+	// Size of the blocks of the log, in bytes. It must be a multiple of the
+	// erase block size of the device. The default is the erase block size,
+	// but at least 4096 bytes (more for devices with large write blocks).
+	BlockSize int
+}
+
+// Stats describes the usage and wear of a filesystem.
+type Stats struct {
+	BlockSize  int
+	Blocks     int   // number of blocks
+	FreeBlocks int   // number of erased blocks
+	MinErases  int   // lowest erase count of a block
+	MaxErases  int   // highest erase count of a block
+	Used       int64 // bytes used by files and directories
+	Capacity   int64 // bytes available for files and directories
+}
+
+// FS is a mounted filesystem.
+type FS struct {
+	mu  sync.Mutex
+	dev BlockDevice
+
+	blockSize  uint32
+	writeSize  uint32 // alignment of writes
+	headerSize uint32 // size of the block header, aligned
+	maxData    uint32 // maximum number of data bytes in a record
+	blocks     []block
+	used       []uint32 // blocks in the log, oldest first
+	head       uint32   // offset of the next record in the last block of the log
+	seq        uint32   // sequence number of the next block
+	collecting bool
+
+	nodes  map[uint32]*node
+	root   *node
+	nextID uint32
+	txn    uint32 // transaction number of the next file change
+
+	buf []byte // record buffer
+}
+
+// Mount reads the filesystem on the given device. It returns ErrNotFormatted
+// if the device does not contain a filesystem.
+func Mount(dev BlockDevice) (*FS, error) {
+	fs := &FS{dev: dev}
+	blockSize, err := findBlockSize(dev)
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.init(blockSize); err != nil {
+		return nil, err
+	}
+	if err := fs.replay(); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+// Format creates an empty filesystem on the device. All data on the device is
+// lost, except for the erase counts of the blocks of an existing filesystem.
+func Format(dev BlockDevice, config Config) error {
+	fs := &FS{dev: dev}
+	if err := fs.init(uint32(config.BlockSize)); err != nil {
+		return err
+	}
+	for i := range fs.blocks {
+		b := &fs.blocks[i]
+		if hdr, ok := fs.readBlockHeader(uint32(i)); ok {
+			b.erases = hdr.erases
+		}
+		if err := fs.erase(uint32(i)); err != nil {
+			return err
+		}
+	}
+	fs.seq = 1
+	fs.txn = 1
+	return fs.nextBlock()
+}
+
+// init sets up the filesystem for the given block size, or the default block
+// size if it is 0.
+func (fs *FS) init(blockSize uint32) error {
+	eraseSize := uint32(fs.dev.EraseBlockSize())
+	fs.writeSize = uint32(fs.dev.WriteBlockSize())
+	if fs.writeSize == 0 {
+		fs.writeSize = 1
+	}
+	fs.maxData = 256
+	if fs.writeSize > fs.maxData {
+		fs.maxData = fs.writeSize
+	}
+	fs.maxData -= recordHeaderSize + dataHeaderSize
+	fs.buf = make([]byte, fs.align(recordHeaderSize+dataHeaderSize+fs.maxData+maxRecordSize))
+	fs.headerSize = fs.align(blockHeaderSize)
+	minSize := fs.headerSize + 8*fs.align(maxRecordSize)
+	if blockSize == 0 && eraseSize != 0 {
+		blockSize = eraseSize
+		for blockSize < 4096 || blockSize < minSize {
+			blockSize *= 2
+		}
+	}
+	if eraseSize == 0 || blockSize%eraseSize != 0 || blockSize < minSize {
+		return ErrBlockSize
+	}
+	fs.blockSize = blockSize
+	numBlocks := fs.dev.Size() / int64(blockSize)
+	if numBlocks < reserveBlocks+3 {
+		return ErrTooSmall
+	}
+	if numBlocks > 1<<31/int64(blockSize) {
+		numBlocks = 1 << 31 / int64(blockSize)
+	}
+	fs.blocks = make([]block, numBlocks)
+	fs.root = &node{dir: true}
+	fs.nodes = map[uint32]*node{0: fs.root}
+	fs.nextID = 1
+	return nil
+}
+
+// Stats returns the usage and wear of the filesystem.
+func (fs *FS) Stats() Stats {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	s := Stats{
+		BlockSize: int(fs.blockSize),
+		Blocks:    len(fs.blocks),
+		MinErases: int(fs.blocks[0].erases),
+		Used:      int64(fs.usage()),
+		Capacity:  int64(fs.capacity()),
+	}
+	for _, b := range fs.blocks {
+		if b.free {
+			s.FreeBlocks++
+		}
+		if int(b.erases) < s.MinErases {
+			s.MinErases = int(b.erases)
+		}
+		if int(b.erases) > s.MaxErases {
+			s.MaxErases = int(b.erases)
+		}
+	}
+	return s
+}
+
+// lookup resolves a path. It returns the directory that contains the last
+// element of the path and the node of that element, or a nil node and the name
+// of the last element if it does not exist.
+func (fs *FS) lookup(name string) (parent, n *node, base string, err error) {
+	name = path.Clean("/" + name)
+	n = fs.root
+	parent = fs.root
+	for name != "/" {
+		name = name[1:]
+		elem := name
+		if i := strings.IndexByte(name, '/'); i >= 0 {
+			elem, name = name[:i], name[i:]
+		} else {
+			name = "/"
+		}
+		if !n.dir {
+			return nil, nil, "", syscall.ENOTDIR
+		}
+		parent = n
+		n = fs.find(parent.id, elem)
+		if n == nil {
+			if name != "/" {
+				return nil, nil, "", os.ErrNotExist
+			}
+			return parent, nil, elem, nil
+		}
+		base = elem
+	}
+	return parent, n, base, nil
+}
+
+// find returns the node with the given name in a directory, or nil.
+func (fs *FS) find(dir uint32, name string) *node {
+	for _, n := range fs.nodes {
+		if n.parent == dir && n.name == name && n != fs.root {
+			return n
+		}
+	}
+	return nil
+}
+
+// OpenFile opens the named file, as in os.OpenFile. It implements
+// os.Filesystem.
+func (fs *FS) OpenFile(name string, flag int, perm os.FileMode) (os.FileHandle, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	write := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	parent, n, base, err := fs.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if n == nil {
+		if flag&os.O_CREATE == 0 {
+			return nil, os.ErrNotExist
+		}
+		n, err = fs.create(parent, base, false, perm)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL {
+			return nil, os.ErrExist
+		}
+		if n.dir && write {
+			return nil, syscall.EISDIR
+		}
+		if n.readOnly && write {
+			return nil, os.ErrPermission
+		}
+	}
+
+	f := &file{
+		fs:     fs,
+		node:   n,
+		read:   flag&os.O_WRONLY == 0,
+		write:  write,
+		append: flag&os.O_APPEND != 0,
+	}
+	if flag&os.O_TRUNC != 0 && write && n.currentSize() != 0 {
+		fs.truncate(n, 0)
+	}
+	return f, nil
+}
+
+// Mkdir creates a new directory. It implements os.Filesystem.
+func (fs *FS) Mkdir(name string, perm os.FileMode) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	parent, n, base, err := fs.lookup(name)
+	if err != nil {
+		return err
+	}
+	if n != nil {
+		return os.ErrExist
+	}
+	_, err = fs.create(parent, base, true, perm)
+	return err
+}
+
+// Remove removes the named file or empty directory. It implements
+// os.Filesystem.
+func (fs *FS) Remove(name string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	_, n, _, err := fs.lookup(name)
+	if err != nil {
+		return err
+	}
+	if n == nil {
+		return os.ErrNotExist
+	}
+	if n == fs.root {
+		return os.ErrInvalid
+	}
+	if n.dir && len(fs.children(n)) != 0 {
+		return syscall.ENOTEMPTY
+	}
+	return fs.remove(n)
+}
+
+// remove removes a file or directory from the filesystem.
+func (fs *FS) remove(n *node) error {
+	if err := fs.writeDelete(n); err != nil {
+		return err
+	}
+	n.deleted = true
+	delete(fs.nodes, n.id)
+	return nil
+}
+
+// Stat returns information about the named file or directory. It implements
+// os.FilesystemV2.
+func (fs *FS) Stat(name string) (os.FileInfo, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	_, n, _, err := fs.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if n == nil {
+		return nil, os.ErrNotExist
+	}
+	return n.info(), nil
+}
+
+// Rename renames a file or directory, replacing a file or empty directory
+// with the new name. It implements os.FilesystemV2.
+//
+// When power is lost while a file is replaced, the replaced file may be
+// removed without the other file being renamed yet.
+func (fs *FS) Rename(oldname, newname string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	_, n, _, err := fs.lookup(oldname)
+	if err != nil {
+		return err
+	}
+	if n == nil {
+		return os.ErrNotExist
+	}
+	parent, target, base, err := fs.lookup(newname)
+	if err != nil {
+		return err
+	}
+	if n == fs.root || target == fs.root {
+		return os.ErrInvalid
+	}
+	if target == n {
+		return nil
+	}
+	if target == nil && (base == "" || base == "." || base == "..") {
+		return os.ErrInvalid
+	}
+	if len(base) > maxNameLength {
+		return ErrNameTooLong
+	}
+	// A directory can't be moved into itself.
+	for p := parent; p != fs.root; p = fs.nodes[p.parent] {
+		if p == n {
+			return os.ErrInvalid
+		}
+	}
+	if target != nil {
+		switch {
+		case target.dir && !n.dir:
+			return syscall.EISDIR
+		case !target.dir && n.dir:
+			return syscall.ENOTDIR
+		case target.dir && len(fs.children(target)) != 0:
+			return syscall.ENOTEMPTY
+		}
+	}
+	if err := fs.reserve(inodeHeaderSize + uint32(len(base))); err != nil {
+		return err
+	}
+	if target != nil {
+		if err := fs.remove(target); err != nil {
+			return err
+		}
+	}
+	oldParent, oldName := n.parent, n.name
+	n.parent, n.name = parent.id, base
+	if err := fs.writeInode(n, false); err != nil {
+		n.parent, n.name = oldParent, oldName
+		return err
+	}
+	return nil
+}
+
+// Truncate changes the size of the named file and commits the change. It
+// implements os.FilesystemV2.
+func (fs *FS) Truncate(name string, size int64) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	_, n, _, err := fs.lookup(name)
+	if err != nil {
+		return err
+	}
+	switch {
+	case n == nil:
+		return os.ErrNotExist
+	case n.dir:
+		return syscall.EISDIR
+	case n.readOnly:
+		return os.ErrPermission
+	case size < 0:
+		return syscall.EINVAL
+	case size > maxFileSize:
+		return syscall.EFBIG
+	}
+	fs.truncate(n, uint32(size))
+	return fs.sync(n)
+}
+
+// Chmod sets the read-only flag of the named file or directory when mode has
+// no write permission, and clears it otherwise. Other mode bits are ignored.
+// It implements os.FilesystemV2.
+func (fs *FS) Chmod(name string, mode os.FileMode) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	_, n, _, err := fs.lookup(name)
+	if err != nil {
+		return err
+	}
+	if n == nil {
+		return os.ErrNotExist
+	}
+	return fs.chmod(n, mode)
+}
+
+func (fs *FS) chmod(n *node, mode os.FileMode) error {
+	if n == fs.root {
+		return os.ErrInvalid
+	}
+	readOnly := mode&0o222 == 0
+	if n.readOnly == readOnly {
+		return nil
+	}
+	if err := fs.reserve(inodeHeaderSize + uint32(len(n.name))); err != nil {
+		return err
+	}
+	n.readOnly = readOnly
+	if err := fs.writeInode(n, false); err != nil {
+		n.readOnly = !readOnly
+		return err
+	}
+	return nil
+}
+
+// children returns the files and directories in a directory, in the order in
+// which they were created.
+func (fs *FS) children(dir *node) []*node {
+	var list []*node
+	for _, n := range fs.nodes {
+		if n.parent == dir.id && n != fs.root {
+			list = append(list, n)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].id < list[j].id
+	})
+	return list
+}
+
+// create adds a new file or directory to the given directory. It is read-only
+// if perm has no write permission.
+func (fs *FS) create(parent *node, name string, dir bool, perm os.FileMode) (*node, error) {
+	if name == "" || name == "." || name == ".." {
+		return nil, os.ErrInvalid
+	}
+	if len(name) > maxNameLength {
+		return nil, ErrNameTooLong
+	}
+	if err := fs.reserve(inodeHeaderSize + uint32(len(name))); err != nil {
+		return nil, err
+	}
+	n := &node{
+		id:       fs.nextID,
+		parent:   parent.id,
+		name:     name,
+		dir:      dir,
+		readOnly: perm&0o222 == 0,
+	}
+	if err := fs.writeInode(n, true); err != nil {
+		return nil, err
+	}
+	fs.nextID++
+	fs.nodes[n.id] = n
+	return n, nil
+}
@@ -0,0 +1,73 @@
+package flashfs
+
+import (
+	"io/fs"
+	"time"
+)
+
+// fileInfo describes a file or directory. It implements fs.FileInfo and
+// fs.DirEntry.
+type fileInfo struct {
+	name     string
+	size     int64
+	dir      bool
+	readOnly bool
+}
+
+func (n *node) info() *fileInfo {
+	name := n.name
+	if n.id == 0 {
+		name = "/"
+	}
+	return &fileInfo{
+		name:     name,
+		size:     int64(n.currentSize()),
+		dir:      n.dir,
+		readOnly: n.readOnly,
+	}
+}
+
+func (fi *fileInfo) Name() string {
+	return fi.name
+}
+
+func (fi *fileInfo) Size() int64 {
+	if fi.dir {
+		return 0
+	}
+	return fi.size
+}
+
+// Mode returns the file mode bits. The write bits are cleared for read-only
+// files.
+func (fi *fileInfo) Mode() fs.FileMode {
+	mode := fs.FileMode(0o666)
+	if fi.dir {
+		mode = fs.ModeDir | 0o777
+	}
+	if fi.readOnly {
+		mode &^= 0o222
+	}
+	return mode
+}
+
+// ModTime returns the zero time: modification times are not stored.
+func (fi *fileInfo) ModTime() time.Time {
+	return time.Time{}
+}
+
+func (fi *fileInfo) IsDir() bool {
+	return fi.dir
+}
+
+func (fi *fileInfo) Sys() any {
+	return nil
+}
+
+func (fi *fileInfo) Type() fs.FileMode {
+	return fi.Mode().Type()
+}
+
+func (fi *fileInfo) Info() (fs.FileInfo, error) {
+	return fi, nil
+}
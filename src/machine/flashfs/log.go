@@ -0,0 +1,661 @@
+package flashfs
+
+import (
+	"hash/crc32"
+	"syscall"
+)
+
+// Every block of the log starts with a block header:
+//
+//	magic      uint32
+//	seq        uint32 // position of the block in the log
+//	erases     uint32 // number of times the block was erased
+//	blockSize  uint32
+//	txn        uint32 // transaction number of the next file change
+//	crc        uint32 // CRC-32 of the previous fields
+//
+// It is followed by records, each aligned to the write block size:
+//
+//	type   uint8
+//	flags  uint8
+//	length uint16 // length of the payload
+//	id     uint32 // the file or directory
+//	crc    uint32 // CRC-32 of the header (without crc) and the payload
+//	payload
+//
+// The payload of an inode record (the metadata of a file or directory) is:
+//
+//	parent uint32
+//	size   uint32
+//	trunc  uint32 // size the committed data is truncated to first
+//	txn    uint32 // transaction that is committed
+//	name
+//
+// The payload of a data record is:
+//
+//	offset uint32
+//	txn    uint32
+//	data
+//
+// Data records are part of a transaction that is applied when an inode record
+// with flagCommit and the same transaction number follows, which happens when
+// a file is synced or closed. The rest of the block after the records is
+// erased. All numbers are little endian.
+const (
+	blockMagic       = 0x53464754 // "TGFS"
+	blockHeaderSize  = 24
+	recordHeaderSize = 12
+	inodeHeaderSize  = 16
+	dataHeaderSize   = 8
+	maxNameLength    = 255
+	maxRecordSize    = recordHeaderSize + inodeHeaderSize + maxNameLength
+
+	// Number of blocks that are kept free to move the data of the oldest
+	// block to.
+	reserveBlocks = 2
+)
+
+// Record types.
+const (
+	recordInode  = 1
+	recordData   = 2
+	recordDelete = 3
+	recordErase  = 4 // the block with the sequence number in the id was moved
+)
+
+// Record flags.
+const (
+	flagDir       = 1 << 0 // inode: the node is a directory
+	flagCommit    = 1 << 1 // inode: apply the transaction
+	flagCommitted = 1 << 2 // data: moved data that was already committed
+	flagReadOnly  = 1 << 3 // inode: the node has no write permission
+)
+
+type block struct {
+	seq    uint32
+	erases uint32
+	free   bool
+	blank  bool // erased and unused since
+}
+
+type blockHeader struct {
+	seq       uint32
+	erases    uint32
+	blockSize uint32
+	txn       uint32
+}
+
+func (fs *FS) align(n uint32) uint32 {
+	return (n + fs.writeSize - 1) / fs.writeSize * fs.writeSize
+}
+
+// findBlockSize looks for a valid block header, and returns the block size
+// stored in it.
+func findBlockSize(dev BlockDevice) (uint32, error) {
+	eraseSize := dev.EraseBlockSize()
+	var buf [blockHeaderSize]byte
+	for offset := int64(0); offset+blockHeaderSize <= dev.Size(); offset += eraseSize {
+		if _, err := dev.ReadAt(buf[:], offset); err != nil {
+			return 0, err
+		}
+		hdr, ok := parseBlockHeader(buf[:])
+		if ok && hdr.blockSize != 0 && offset%int64(hdr.blockSize) == 0 {
+			return hdr.blockSize, nil
+		}
+	}
+	return 0, ErrNotFormatted
+}
+
+func parseBlockHeader(b []byte) (blockHeader, bool) {
+	if le32(b[0:]) != blockMagic || le32(b[20:]) != crc32.ChecksumIEEE(b[:20]) {
+		return blockHeader{}, false
+	}
+	return blockHeader{seq: le32(b[4:]), erases: le32(b[8:]), blockSize: le32(b[12:]), txn: le32(b[16:])}, true
+}
+
+func (fs *FS) readBlockHeader(blk uint32) (blockHeader, bool) {
+	var buf [blockHeaderSize]byte
+	if _, err := fs.dev.ReadAt(buf[:], int64(blk)*int64(fs.blockSize)); err != nil {
+		return blockHeader{}, false
+	}
+	hdr, ok := parseBlockHeader(buf[:])
+	return hdr, ok && hdr.blockSize == fs.blockSize
+}
+
+// erase erases a block and marks it as free.
+func (fs *FS) erase(blk uint32) error {
+	b := &fs.blocks[blk]
+	b.free = true
+	b.blank = false
+	eraseSize := uint32(fs.dev.EraseBlockSize())
+	err := fs.dev.EraseBlocks(int64(blk*(fs.blockSize/eraseSize)), int64(fs.blockSize/eraseSize))
+	if err != nil {
+		return err
+	}
+	b.erases++
+	b.blank = true
+	return nil
+}
+
+func (fs *FS) freeBlocks() int {
+	free := 0
+	for _, b := range fs.blocks {
+		if b.free {
+			free++
+		}
+	}
+	return free
+}
+
+// nextBlock starts a new block at the head of the log. If there are not
+// enough free blocks left, the oldest blocks are collected first.
+func (fs *FS) nextBlock() error {
+	if !fs.collecting {
+		for i := 0; fs.freeBlocks() <= reserveBlocks; i++ {
+			if i >= len(fs.blocks) {
+				return syscall.ENOSPC
+			}
+			if err := fs.collect(); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Use the free block that was erased the least.
+	blk := -1
+	for i, b := range fs.blocks {
+		if b.free && (blk < 0 || b.erases < fs.blocks[blk].erases) {
+			blk = i
+		}
+	}
+	if blk < 0 {
+		return syscall.ENOSPC
+	}
+	b := &fs.blocks[blk]
+	if !b.blank {
+		if err := fs.erase(uint32(blk)); err != nil {
+			return err
+		}
+	}
+
+	hdr := fs.buf[:fs.headerSize]
+	for i := range hdr {
+		hdr[i] = 0xff
+	}
+	put32(hdr[0:], blockMagic)
+	put32(hdr[4:], fs.seq)
+	put32(hdr[8:], b.erases)
+	put32(hdr[12:], fs.blockSize)
+	put32(hdr[16:], fs.txn)
+	put32(hdr[20:], crc32.ChecksumIEEE(hdr[:20]))
+	b.blank = false
+	if _, err := fs.dev.WriteAt(hdr, int64(blk)*int64(fs.blockSize)); err != nil {
+		return err
+	}
+	b.seq = fs.seq
+	b.free = false
+	fs.seq++
+	fs.used = append(fs.used, uint32(blk))
+	fs.head = fs.headerSize
+	return nil
+}
+
+// appendRecord completes the record in fs.buf (with the given payload length)
+// and appends it to the log. It returns the address of the record.
+func (fs *FS) appendRecord(typ, flags uint8, id uint32, length uint32) (uint32, error) {
+	size := fs.align(recordHeaderSize + length)
+	if len(fs.used) == 0 || fs.head+size > fs.blockSize {
+		// Starting a new block may collect the oldest block, which uses
+		// the record buffer.
+		payload := make([]byte, length)
+		copy(payload, fs.buf[recordHeaderSize:])
+		if err := fs.nextBlock(); err != nil {
+			return 0, err
+		}
+		copy(fs.buf[recordHeaderSize:], payload)
+	}
+	rec := fs.buf[:size]
+	rec[0] = typ
+	rec[1] = flags
+	put16(rec[2:], uint16(length))
+	put32(rec[4:], id)
+	crc := crc32.ChecksumIEEE(rec[:8])
+	crc = crc32.Update(crc, crc32.IEEETable, rec[recordHeaderSize:recordHeaderSize+length])
+	put32(rec[8:], crc)
+	for i := recordHeaderSize + length; i < size; i++ {
+		rec[i] = 0xff
+	}
+	blk := fs.used[len(fs.used)-1]
+	addr := blk*fs.blockSize + fs.head
+	if _, err := fs.dev.WriteAt(rec, int64(addr)); err != nil {
+		// The rest of the block may be partially written, so don't use it
+		// anymore.
+		fs.head = fs.blockSize
+		return 0, err
+	}
+	fs.head += size
+	return addr, nil
+}
+
+// writeInode appends an inode record for a node. A commit record also applies
+// the current transaction of the node.
+func (fs *FS) writeInode(n *node, commit bool) error {
+	p := fs.buf[recordHeaderSize:]
+	put32(p[0:], n.parent)
+	flags := uint8(0)
+	if n.dir {
+		flags |= flagDir
+	}
+	if n.readOnly {
+		flags |= flagReadOnly
+	}
+	if commit {
+		flags |= flagCommit
+		size, trunc := n.size, n.size
+		if n.dirty {
+			size, trunc = n.pendingSize, n.trunc
+		}
+		put32(p[4:], size)
+		put32(p[8:], trunc)
+		put32(p[12:], n.txn)
+	} else {
+		put32(p[4:], n.size)
+		put32(p[8:], n.size)
+		put32(p[12:], 0)
+	}
+	copy(p[inodeHeaderSize:], n.name)
+	addr, err := fs.appendRecord(recordInode, flags, n.id, inodeHeaderSize+uint32(len(n.name)))
+	if err != nil {
+		return err
+	}
+	n.inode = addr
+	return nil
+}
+
+// dataBuf returns the part of the record buffer for the data of a data
+// record.
+func (fs *FS) dataBuf() []byte {
+	return fs.buf[recordHeaderSize+dataHeaderSize : recordHeaderSize+dataHeaderSize+fs.maxData]
+}
+
+// writeDataRecord appends a data record with the data in dataBuf. It returns
+// the address of the data.
+func (fs *FS) writeDataRecord(n *node, offset, length uint32, committed bool) (uint32, error) {
+	p := fs.buf[recordHeaderSize:]
+	put32(p[0:], offset)
+	put32(p[4:], n.txn)
+	flags := uint8(0)
+	if committed {
+		flags = flagCommitted
+	}
+	addr, err := fs.appendRecord(recordData, flags, n.id, dataHeaderSize+length)
+	if err != nil {
+		return 0, err
+	}
+	return addr + recordHeaderSize + dataHeaderSize, nil
+}
+
+func (fs *FS) writeDelete(n *node) error {
+	_, err := fs.appendRecord(recordDelete, 0, n.id, 0)
+	return err
+}
+
+// recordSize returns the size of the record for an extent or an inode.
+func (fs *FS) recordSize(length uint32) uint32 {
+	return fs.align(recordHeaderSize + length)
+}
+
+// usage returns the number of bytes that are needed to store all data that is
+// still in use.
+func (fs *FS) usage() uint32 {
+	total := uint32(0)
+	for _, n := range fs.nodes {
+		if n == fs.root {
+			continue
+		}
+		total += fs.recordSize(inodeHeaderSize + uint32(len(n.name)))
+		for _, e := range n.data {
+			total += fs.recordSize(dataHeaderSize + e.length)
+		}
+		for _, e := range n.pending {
+			total += fs.recordSize(dataHeaderSize + e.length)
+		}
+	}
+	return total
+}
+
+// capacity returns the number of bytes that can be used for files, leaving
+// room for the reserved blocks, the head block of the log, and the unused
+// space and erase record at the end of blocks.
+func (fs *FS) capacity() uint32 {
+	perBlock := fs.blockSize - fs.headerSize - fs.align(recordHeaderSize+dataHeaderSize+fs.maxData) - fs.align(recordHeaderSize)
+	return uint32(len(fs.blocks)-reserveBlocks-2) * perBlock
+}
+
+// reserve checks that a new record with the given payload length fits. Only
+// records that add data are checked, so that files can always be committed
+// and removed.
+func (fs *FS) reserve(length uint32) error {
+	if fs.usage()+fs.recordSize(length) > fs.capacity() {
+		return syscall.ENOSPC
+	}
+	return nil
+}
+
+// collect moves all data that is still used in the oldest block of the log to
+// the head of the log, and erases the block.
+func (fs *FS) collect() error {
+	fs.collecting = true
+	defer func() {
+		fs.collecting = false
+	}()
+	victim := fs.used[0]
+	start := victim * fs.blockSize
+	end := start + fs.blockSize
+	in := func(addr uint32) bool {
+		return addr >= start && addr < end
+	}
+
+	for _, n := range fs.nodes {
+		if n == fs.root {
+			continue
+		}
+		if in(n.inode) {
+			if err := fs.writeInode(n, false); err != nil {
+				return err
+			}
+		}
+		if err := fs.move(n, false, in); err != nil {
+			return err
+		}
+		if n.dirty {
+			if err := fs.move(n, true, in); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Record that the block was moved, so that a block that was only
+	// partially erased when power was lost is not used at the next mount.
+	if _, err := fs.appendRecord(recordErase, 0, fs.blocks[victim].seq, 0); err != nil {
+		return err
+	}
+	fs.used = fs.used[1:]
+	return fs.erase(victim)
+}
+
+// move rewrites the committed or pending data of a node that is stored in the
+// block that is collected. Nearby data is rewritten together with it, to
+// undo fragmentation.
+func (fs *FS) move(n *node, pending bool, in func(uint32) bool) error {
+	for {
+		l := n.data
+		if pending {
+			l = n.pending
+		}
+		i := 0
+		for i < len(l) && !in(l[i].addr) {
+			i++
+		}
+		if i == len(l) {
+			return nil
+		}
+		spanStart, spanEnd := l[i].offset, l[i].end()
+		for j := i + 1; j < len(l) && l[j].offset < spanStart+fs.maxData; j++ {
+			if in(l[j].addr) {
+				spanEnd = l[j].end()
+			}
+		}
+		for offset := spanStart; offset < spanEnd; {
+			length := spanEnd - offset
+			if length > fs.maxData {
+				length = fs.maxData
+			}
+			buf := fs.dataBuf()[:length]
+			var err error
+			if pending {
+				err = fs.readAt(n, buf, offset)
+			} else {
+				err = fs.readExtents(n.data, buf, offset, n.size)
+			}
+			if err != nil {
+				return err
+			}
+			addr, err := fs.writeDataRecord(n, offset, length, !pending)
+			if err != nil {
+				return err
+			}
+			e := extent{offset, length, addr}
+			if pending {
+				n.pending = n.pending.insert(e)
+			} else {
+				n.data = n.data.insert(e)
+			}
+			offset += length
+		}
+	}
+}
+
+// replay reads the log and rebuilds the directory tree.
+func (fs *FS) replay() error {
+	// Find the blocks of the log, in order.
+	for i := range fs.blocks {
+		b := &fs.blocks[i]
+		hdr, ok := fs.readBlockHeader(uint32(i))
+		if !ok {
+			b.free = true
+			b.blank = fs.isErased(uint32(i)*fs.blockSize, fs.blockSize)
+			continue
+		}
+		b.seq = hdr.seq
+		b.erases = hdr.erases
+		pos := len(fs.used)
+		for pos > 0 && fs.blocks[fs.used[pos-1]].seq > hdr.seq {
+			pos--
+		}
+		fs.used = append(fs.used, 0)
+		copy(fs.used[pos+1:], fs.used[pos:])
+		fs.used[pos] = uint32(i)
+	}
+	if len(fs.used) == 0 {
+		return ErrNotFormatted
+	}
+	fs.seq = fs.blocks[fs.used[len(fs.used)-1]].seq + 1
+
+	// Transactions that were used before the head block was started may
+	// have been collected already, so continue from the number stored in
+	// its header.
+	hdr, _ := fs.readBlockHeader(fs.used[len(fs.used)-1])
+	fs.txn = hdr.txn
+
+	// The erase count of free blocks is lost with their header. Use the
+	// highest erase count for them.
+	maxErases := uint32(0)
+	for _, b := range fs.blocks {
+		if b.erases > maxErases {
+			maxErases = b.erases
+		}
+	}
+	for i := range fs.blocks {
+		if b := &fs.blocks[i]; b.free {
+			b.erases = maxErases
+		}
+	}
+
+	// If the last record is a block erase record, power may have been lost
+	// while the block was erased: erase it again.
+	headBlock := fs.used[len(fs.used)-1]
+	var lastType uint8
+	var lastID uint32
+	fs.head, _ = fs.scanBlock(headBlock, func(typ, flags uint8, id, addr, length uint32) {
+		lastType, lastID = typ, id
+	})
+	if lastType == recordErase && len(fs.used) > 1 && fs.blocks[fs.used[0]].seq == lastID {
+		victim := fs.used[0]
+		fs.used = fs.used[1:]
+		if err := fs.erase(victim); err != nil {
+			return err
+		}
+	}
+	if !fs.isErased(headBlock*fs.blockSize+fs.head, fs.blockSize-fs.head) {
+		// Power was lost while writing a record.
+		fs.head = fs.blockSize
+	}
+
+	for _, blk := range fs.used {
+		var err error
+		_, err = fs.scanBlock(blk, func(typ, flags uint8, id, addr, length uint32) {
+			if txn := fs.replayRecord(typ, flags, id, addr, length); txn >= fs.txn {
+				fs.txn = txn + 1
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Forget changes that were not committed, and data of files that were
+	// never created.
+	for id, n := range fs.nodes {
+		if n != fs.root && n.inode == 0 {
+			delete(fs.nodes, id)
+			continue
+		}
+		n.dirty = false
+		n.pending = nil
+		if id >= fs.nextID {
+			fs.nextID = id + 1
+		}
+	}
+	return nil
+}
+
+// scanBlock calls fn for all valid records in a block, and returns the offset
+// after the last one.
+func (fs *FS) scanBlock(blk uint32, fn func(typ, flags uint8, id, addr, length uint32)) (uint32, error) {
+	offset := fs.headerSize
+	for offset+recordHeaderSize <= fs.blockSize {
+		addr := blk*fs.blockSize + offset
+		hdr := fs.buf[:recordHeaderSize]
+		if _, err := fs.dev.ReadAt(hdr, int64(addr)); err != nil {
+			return offset, err
+		}
+		typ, flags, length, id, crc := hdr[0], hdr[1], uint32(le16(hdr[2:])), le32(hdr[4:]), le32(hdr[8:])
+		size := fs.align(recordHeaderSize + length)
+		if typ == 0xff || length > uint32(len(fs.buf))-recordHeaderSize || offset+size > fs.blockSize {
+			break
+		}
+		check := crc32.ChecksumIEEE(hdr[:8])
+		payload := fs.buf[recordHeaderSize : recordHeaderSize+length]
+		if _, err := fs.dev.ReadAt(payload, int64(addr+recordHeaderSize)); err != nil {
+			return offset, err
+		}
+		if crc32.Update(check, crc32.IEEETable, payload) != crc {
+			break
+		}
+		fn(typ, flags, id, addr, length)
+		offset += size
+	}
+	return offset, nil
+}
+
+// replayRecord applies a record that is in fs.buf. It returns the
+// transaction number of the record, if any.
+func (fs *FS) replayRecord(typ, flags uint8, id, addr, length uint32) uint32 {
+	p := fs.buf[recordHeaderSize : recordHeaderSize+length]
+	n := fs.nodes[id]
+	switch typ {
+	case recordInode:
+		if length < inodeHeaderSize || id == 0 {
+			return 0
+		}
+		if n == nil {
+			n = &node{id: id}
+			fs.nodes[id] = n
+		}
+		n.parent = le32(p[0:])
+		n.dir = flags&flagDir != 0
+		n.readOnly = flags&flagReadOnly != 0
+		n.name = string(p[inodeHeaderSize:])
+		n.inode = addr
+		size, trunc, txn := le32(p[4:]), le32(p[8:]), le32(p[12:])
+		if flags&flagCommit != 0 {
+			if !n.dirty || n.txn != txn {
+				n.pending = nil
+			}
+			n.commit(size, trunc)
+			return txn
+		}
+		n.size = size
+		n.data = n.data.cut(size)
+	case recordData:
+		if length < dataHeaderSize || id == 0 {
+			return 0
+		}
+		if n == nil {
+			// The data was written before the oldest inode record of the
+			// file that is still in the log.
+			n = &node{id: id}
+			fs.nodes[id] = n
+		}
+		offset, txn := le32(p[0:]), le32(p[4:])
+		e := extent{offset, length - dataHeaderSize, addr + recordHeaderSize + dataHeaderSize}
+		if flags&flagCommitted != 0 {
+			n.data = n.data.insert(e)
+			return 0
+		}
+		if !n.dirty || n.txn != txn {
+			// A new transaction: the previous one was never committed.
+			n.dirty = true
+			n.txn = txn
+			n.pending = nil
+		}
+		n.pending = n.pending.insert(e)
+		return txn
+	case recordDelete:
+		delete(fs.nodes, id)
+	}
+	return 0
+}
+
+// isErased returns whether the given range of the device is erased.
+func (fs *FS) isErased(addr, length uint32) bool {
+	var buf [64]byte
+	for length > 0 {
+		chunk := length
+		if chunk > uint32(len(buf)) {
+			chunk = uint32(len(buf))
+		}
+		if _, err := fs.dev.ReadAt(buf[:chunk], int64(addr)); err != nil {
+			return false
+		}
+		for _, c := range buf[:chunk] {
+			if c != 0xff {
+				return false
+			}
+		}
+		addr += chunk
+		length -= chunk
+	}
+	return true
+}
+
+func le16(b []byte) uint16 {
+	return uint16(b[0]) | uint16(b[1])<<8
+}
+
+func le32(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
+}
+
+func put16(b []byte, v uint16) {
+	b[0] = byte(v)
+	b[1] = byte(v >> 8)
+}
+
+func put32(b []byte, v uint32) {
+	b[0] = byte(v)
+	b[1] = byte(v >> 8)
+	b[2] = byte(v >> 16)
+	b[3] = byte(v >> 24)
+}
@@ -0,0 +1,215 @@
+package flashfs
+
+// extent is a range of a file that is stored in a data record.
+type extent struct {
+	offset uint32 // offset in the file
+	length uint32
+	addr   uint32 // address of the data on the device
+}
+
+func (e extent) end() uint32 {
+	return e.offset + e.length
+}
+
+// extents is a list of non-overlapping extents, sorted by offset.
+type extents []extent
+
+// insert adds an extent, replacing the parts of the existing extents that it
+// overlaps.
+func (l extents) insert(e extent) extents {
+	i := 0
+	for i < len(l) && l[i].end() <= e.offset {
+		i++
+	}
+	j := i
+	for j < len(l) && l[j].offset < e.end() {
+		j++
+	}
+	replacement := make(extents, 0, 3)
+	if i < j && l[i].offset < e.offset {
+		first := l[i]
+		first.length = e.offset - first.offset
+		replacement = append(replacement, first)
+	}
+	replacement = append(replacement, e)
+	if i < j && l[j-1].end() > e.end() {
+		last := l[j-1]
+		cut := e.end() - last.offset
+		last.offset += cut
+		last.addr += cut
+		last.length -= cut
+		replacement = append(replacement, last)
+	}
+	result := make(extents, 0, len(l)-(j-i)+len(replacement))
+	result = append(result, l[:i]...)
+	result = append(result, replacement...)
+	return append(result, l[j:]...)
+}
+
+// cut removes everything at or after the given size.
+func (l extents) cut(size uint32) extents {
+	for i := len(l) - 1; i >= 0; i-- {
+		if l[i].offset >= size {
+			l = l[:i]
+			continue
+		}
+		if l[i].end() > size {
+			l[i].length = size - l[i].offset
+		}
+		break
+	}
+	return l
+}
+
+// node is a file or directory.
+type node struct {
+	id       uint32
+	parent   uint32
+	name     string
+	dir      bool
+	readOnly bool
+	deleted  bool
+	inode    uint32 // address of the last inode record
+
+	// Committed contents.
+	size uint32
+	data extents
+
+	// Changes since the last commit: data that was written, the size of the
+	// file after the changes, and the size that the committed data was
+	// truncated to before the data was written.
+	dirty       bool
+	txn         uint32
+	pending     extents
+	pendingSize uint32
+	trunc       uint32
+}
+
+// currentSize returns the size of the file including uncommitted changes.
+func (n *node) currentSize() uint32 {
+	if n.dirty {
+		return n.pendingSize
+	}
+	return n.size
+}
+
+// commit applies the pending changes to the committed contents.
+func (n *node) commit(size, trunc uint32) {
+	n.data = n.data.cut(trunc)
+	for _, e := range n.pending {
+		n.data = n.data.insert(e)
+	}
+	n.data = n.data.cut(size)
+	n.size = size
+	n.dirty = false
+	n.pending = nil
+}
+
+// readAt reads file data, including uncommitted changes. Parts of the file
+// that were never written read as zero.
+func (fs *FS) readAt(n *node, b []byte, offset uint32) error {
+	for i := range b {
+		b[i] = 0
+	}
+	limit := n.size
+	if n.dirty && n.trunc < limit {
+		limit = n.trunc
+	}
+	if err := fs.readExtents(n.data, b, offset, limit); err != nil {
+		return err
+	}
+	if n.dirty {
+		return fs.readExtents(n.pending, b, offset, n.pendingSize)
+	}
+	return nil
+}
+
+// readExtents copies the data of the extents that overlap b, up to limit.
+func (fs *FS) readExtents(l extents, b []byte, offset, limit uint32) error {
+	end := offset + uint32(len(b))
+	if limit < end {
+		end = limit
+	}
+	for _, e := range l {
+		if e.offset >= end {
+			break
+		}
+		if e.end() <= offset {
+			continue
+		}
+		start, stop := e.offset, e.end()
+		if start < offset {
+			start = offset
+		}
+		if stop > end {
+			stop = end
+		}
+		if _, err := fs.dev.ReadAt(b[start-offset:stop-offset], int64(e.addr+start-e.offset)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// begin starts a new transaction for changes to a file, if needed.
+func (fs *FS) begin(n *node) {
+	if !n.dirty {
+		n.dirty = true
+		n.txn = fs.txn
+		fs.txn++
+		n.pendingSize = n.size
+		n.trunc = n.size
+	}
+}
+
+// writeData appends data to a file at the given offset, as uncommitted
+// changes.
+func (fs *FS) writeData(n *node, b []byte, offset uint32) error {
+	fs.begin(n)
+	for len(b) > 0 {
+		chunk := b
+		if uint32(len(chunk)) > fs.maxData {
+			chunk = chunk[:fs.maxData]
+		}
+		if err := fs.reserve(dataHeaderSize + uint32(len(chunk))); err != nil {
+			return err
+		}
+		copy(fs.dataBuf(), chunk)
+		addr, err := fs.writeDataRecord(n, offset, uint32(len(chunk)), false)
+		if err != nil {
+			return err
+		}
+		n.pending = n.pending.insert(extent{offset, uint32(len(chunk)), addr})
+		offset += uint32(len(chunk))
+		if offset > n.pendingSize {
+			n.pendingSize = offset
+		}
+		b = b[len(chunk):]
+	}
+	return nil
+}
+
+// truncate changes the size of a file, as an uncommitted change.
+func (fs *FS) truncate(n *node, size uint32) {
+	fs.begin(n)
+	n.pending = n.pending.cut(size)
+	if size < n.trunc {
+		n.trunc = size
+	}
+	n.pendingSize = size
+}
+
+// sync commits the changes to a file.
+func (fs *FS) sync(n *node) error {
+	if !n.dirty {
+		return nil
+	}
+	if n.deleted {
+		return nil
+	}
+	if err := fs.writeInode(n, true); err != nil {
+		return err
+	}
+	n.commit(n.pendingSize, n.trunc)
+	return nil
+}
@@ -24,6 +24,8 @@ const (
 )
 
 var (
+	usbLimits = usb.LimitsSAMD51
+
 	endPoints = []uint32{
 		usb.CONTROL_ENDPOINT:  usb.ENDPOINT_TYPE_CONTROL,
 		usb.CDC_ENDPOINT_ACM:  (usb.ENDPOINT_TYPE_INTERRUPT | usb.EndpointIn),
@@ -34,6 +36,10 @@ var (
 		usb.MIDI_ENDPOINT_IN:  (usb.ENDPOINT_TYPE_DISABLE), // Bulk In
 		usb.MIDI_ENDPOINT_OUT: (usb.ENDPOINT_TYPE_DISABLE), // Bulk Out
 	}
+
+	// Buffers of the endpoints with packets larger than
+	// usb.EndpointPacketSize, allocated by setEndpointPacketSize.
+	usbLargeBuffer [NumberOfUSBEndpoints][]byte
 )
 
 // Configure the USB peripheral. The config is here for compatibility with the UART interface.
@@ -167,9 +173,7 @@ func handleUSBIRQ(intr interrupt.Interrupt) {
 			ok = handleStandardSetup(setup)
 		} else {
 			// Class Interface Requests
-			if setup.WIndex < uint16(len(usbSetupHandler)) && usbSetupHandler[setup.WIndex] != nil {
-				ok = usbSetupHandler[setup.WIndex](setup)
-			}
+			ok = handleClassSetup(setup)
 		}
 
 		if ok {
@@ -275,6 +279,46 @@ func initEndpoint(ep, config uint32) {
 
 		setEPINTENSET(ep, sam.USB_DEVICE_ENDPOINT_EPINTENSET_TRCPT1)
 
+	case usb.ENDPOINT_TYPE_ISOCHRONOUS | usb.EndpointOut:
+		buf := endpointOutBuffer(ep)
+
+		// set packet size
+		usbEndpointDescriptors[ep].DeviceDescBank[0].PCKSIZE.ClearBits(usb_DEVICE_PCKSIZE_SIZE_Mask << usb_DEVICE_PCKSIZE_SIZE_Pos)
+		usbEndpointDescriptors[ep].DeviceDescBank[0].PCKSIZE.SetBits(epPacketSize(uint16(len(buf))) << usb_DEVICE_PCKSIZE_SIZE_Pos)
+
+		// set data buffer address
+		usbEndpointDescriptors[ep].DeviceDescBank[0].ADDR.Set(uint32(uintptr(unsafe.Pointer(&buf[0]))))
+
+		// set endpoint type
+		setEPCFG(ep, ((usb.ENDPOINT_TYPE_ISOCHRONOUS + 1) << sam.USB_DEVICE_ENDPOINT_EPCFG_EPTYPE0_Pos))
+
+		// receive interrupts when current transfer complete
+		setEPINTENSET(ep, sam.USB_DEVICE_ENDPOINT_EPINTENSET_TRCPT0)
+
+		// set byte count to zero, we have not received anything yet
+		usbEndpointDescriptors[ep].DeviceDescBank[0].PCKSIZE.ClearBits(usb_DEVICE_PCKSIZE_BYTE_COUNT_Mask << usb_DEVICE_PCKSIZE_BYTE_COUNT_Pos)
+
+		// ready for next transfer
+		setEPSTATUSCLR(ep, sam.USB_DEVICE_ENDPOINT_EPSTATUSCLR_BK0RDY)
+
+	case usb.ENDPOINT_TYPE_ISOCHRONOUS | usb.EndpointIn:
+		buf := endpointInBuffer(ep)
+
+		// set packet size
+		usbEndpointDescriptors[ep].DeviceDescBank[1].PCKSIZE.ClearBits(usb_DEVICE_PCKSIZE_SIZE_Mask << usb_DEVICE_PCKSIZE_SIZE_Pos)
+		usbEndpointDescriptors[ep].DeviceDescBank[1].PCKSIZE.SetBits(epPacketSize(uint16(len(buf))) << usb_DEVICE_PCKSIZE_SIZE_Pos)
+
+		// set data buffer address
+		usbEndpointDescriptors[ep].DeviceDescBank[1].ADDR.Set(uint32(uintptr(unsafe.Pointer(&buf[0]))))
+
+		// set endpoint type
+		setEPCFG(ep, ((usb.ENDPOINT_TYPE_ISOCHRONOUS + 1) << sam.USB_DEVICE_ENDPOINT_EPCFG_EPTYPE1_Pos))
+
+		// NAK on endpoint IN, the bank is not yet filled in.
+		setEPSTATUSCLR(ep, sam.USB_DEVICE_ENDPOINT_EPSTATUSCLR_BK1RDY)
+
+		setEPINTENSET(ep, sam.USB_DEVICE_ENDPOINT_EPINTENSET_TRCPT1)
+
 	case usb.ENDPOINT_TYPE_CONTROL:
 		// Control OUT
 		// set packet size
@@ -365,8 +409,9 @@ func sendUSBPacket(ep uint32, data []byte, maxsize uint16) {
 		copy(udd_ep_control_cache_buffer[:], data[:l])
 		usbEndpointDescriptors[ep].DeviceDescBank[1].ADDR.Set(uint32(uintptr(unsafe.Pointer(&udd_ep_control_cache_buffer))))
 	} else {
-		copy(udd_ep_in_cache_buffer[ep][:], data[:l])
-		usbEndpointDescriptors[ep].DeviceDescBank[1].ADDR.Set(uint32(uintptr(unsafe.Pointer(&udd_ep_in_cache_buffer[ep]))))
+		buf := endpointInBuffer(ep)
+		copy(buf, data[:l])
+		usbEndpointDescriptors[ep].DeviceDescBank[1].ADDR.Set(uint32(uintptr(unsafe.Pointer(&buf[0]))))
 	}
 
 	// clear multi-packet size which is total bytes already sent
@@ -402,11 +447,13 @@ func ReceiveUSBControlPacket() ([cdcLineInfoSize]byte, error) {
 	bytesread := uint32((usbEndpointDescriptors[0].DeviceDescBank[0].PCKSIZE.Get() >>
 		usb_DEVICE_PCKSIZE_BYTE_COUNT_Pos) & usb_DEVICE_PCKSIZE_BYTE_COUNT_Mask)
 
-	if bytesread != cdcLineInfoSize {
+	// Control requests with a data stage shorter than the CDC line info (like
+	// audio class requests) are also received here.
+	if bytesread > cdcLineInfoSize {
 		return b, ErrUSBBytesRead
 	}
 
-	copy(b[:7], udd_ep_out_cache_buffer[0][:7])
+	copy(b[:bytesread], udd_ep_out_cache_buffer[0][:bytesread])
 
 	return b, nil
 }
@@ -416,7 +463,7 @@ func handleEndpointRx(ep uint32) []byte {
 	count := int((usbEndpointDescriptors[ep].DeviceDescBank[0].PCKSIZE.Get() >>
 		usb_DEVICE_PCKSIZE_BYTE_COUNT_Pos) & usb_DEVICE_PCKSIZE_BYTE_COUNT_Mask)
 
-	return udd_ep_out_cache_buffer[ep][:count]
+	return endpointOutBuffer(ep)[:count]
 }
 
 // AckUsbOutTransfer is called to acknowledge the completion of a USB OUT transfer.
@@ -424,8 +471,9 @@ func AckUsbOutTransfer(ep uint32) {
 	// set byte count to zero
 	usbEndpointDescriptors[ep].DeviceDescBank[0].PCKSIZE.ClearBits(usb_DEVICE_PCKSIZE_BYTE_COUNT_Mask << usb_DEVICE_PCKSIZE_BYTE_COUNT_Pos)
 
-	// set multi packet size to 64
-	usbEndpointDescriptors[ep].DeviceDescBank[0].PCKSIZE.SetBits(64 << usb_DEVICE_PCKSIZE_MULTI_PACKET_SIZE_Pos)
+	// set multi packet size to the size of the buffer
+	usbEndpointDescriptors[ep].DeviceDescBank[0].PCKSIZE.ClearBits(usb_DEVICE_PCKSIZE_MULTI_PACKET_SIZE_Mask << usb_DEVICE_PCKSIZE_MULTI_PACKET_SIZE_Pos)
+	usbEndpointDescriptors[ep].DeviceDescBank[0].PCKSIZE.SetBits(uint32(len(endpointOutBuffer(ep))) << usb_DEVICE_PCKSIZE_MULTI_PACKET_SIZE_Pos)
 
 	// set ready for next data
 	setEPSTATUSCLR(ep, sam.USB_DEVICE_ENDPOINT_EPSTATUSCLR_BK0RDY)
@@ -435,27 +483,61 @@ func SendZlp() {
 	usbEndpointDescriptors[0].DeviceDescBank[1].PCKSIZE.ClearBits(usb_DEVICE_PCKSIZE_BYTE_COUNT_Mask << usb_DEVICE_PCKSIZE_BYTE_COUNT_Pos)
 }
 
+// epPacketSize returns the PCKSIZE.SIZE value for the smallest packet size
+// that is at least size bytes.
 func epPacketSize(size uint16) uint32 {
-	switch size {
-	case 8:
+	switch {
+	case size <= 8:
 		return 0
-	case 16:
+	case size <= 16:
 		return 1
-	case 32:
+	case size <= 32:
 		return 2
-	case 64:
+	case size <= 64:
 		return 3
-	case 128:
+	case size <= 128:
 		return 4
-	case 256:
+	case size <= 256:
 		return 5
-	case 512:
+	case size <= 512:
 		return 6
-	case 1023:
-		return 7
 	default:
-		return 0
+		return 7 // 1023 bytes, isochronous endpoints only
+	}
+}
+
+// setEndpointPacketSize sets the maximum packet size of an endpoint. Endpoints
+// with packets larger than usb.EndpointPacketSize (isochronous endpoints) get a
+// buffer on the heap, which is as large as the packet size used by the USB
+// controller so that it cannot write past the end.
+func setEndpointPacketSize(ep uint8, size uint16) {
+	if size <= usb.EndpointPacketSize {
+		usbLargeBuffer[ep] = nil
+		return
+	}
+	n := 128
+	for n < int(size) {
+		n *= 2
+	}
+	if len(usbLargeBuffer[ep]) != n {
+		usbLargeBuffer[ep] = make([]byte, n)
+	}
+}
+
+// endpointInBuffer returns the buffer of an IN endpoint other than endpoint 0.
+func endpointInBuffer(ep uint32) []byte {
+	if buf := usbLargeBuffer[ep]; buf != nil {
+		return buf
+	}
+	return udd_ep_in_cache_buffer[ep][:]
+}
+
+// endpointOutBuffer returns the buffer of an OUT endpoint.
+func endpointOutBuffer(ep uint32) []byte {
+	if buf := usbLargeBuffer[ep]; buf != nil {
+		return buf
 	}
+	return udd_ep_out_cache_buffer[ep][:]
 }
 
 func getEPCFG(ep uint32) uint8 {
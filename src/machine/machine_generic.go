@@ -41,15 +41,6 @@ func (p Pin) Get() bool {
 	return gpioGet(p)
 }
 
-//export __tinygo_gpio_configure
-func gpioConfigure(pin Pin, config PinConfig)
-
-//export __tinygo_gpio_set
-func gpioSet(pin Pin, value bool)
-
-//export __tinygo_gpio_get
-func gpioGet(pin Pin) bool
-
 // Generic PWM/timer peripheral. Properties can be configured depending on the
 // hardware.
 type timerType struct {
@@ -138,15 +129,6 @@ func (t *timerType) Top() uint32 {
 	return t.top
 }
 
-//export __tinygo_pwm_configure
-func pwmConfigure(instance int32, frequency float64, top uint32)
-
-//export __tinygo_pwm_channel_configure
-func pwmChannelConfigure(instance, channel int32, pin Pin)
-
-//export __tinygo_pwm_channel_set
-func pwmChannelSet(instance int32, channel uint8, value uint32)
-
 type SPI struct {
 	Bus uint8
 }
@@ -201,15 +183,6 @@ func (spi *SPI) Tx(w, r []byte) error {
 	return nil
 }
 
-//export __tinygo_spi_configure
-func spiConfigure(bus uint8, sck Pin, SDO Pin, SDI Pin)
-
-//export __tinygo_spi_transfer
-func spiTransfer(bus uint8, w uint8) uint8
-
-//export __tinygo_spi_tx
-func spiTX(bus uint8, wptr *byte, wlen int, rptr *byte, rlen int) uint8
-
 // InitADC enables support for ADC peripherals.
 func InitADC() {
 	// Nothing to do here.
@@ -224,9 +197,6 @@ func (adc ADC) Get() uint16 {
 	return adcRead(adc.Pin)
 }
 
-//export __tinygo_adc_read
-func adcRead(pin Pin) uint16
-
 // I2C is a generic implementation of the Inter-IC communication protocol.
 type I2C struct {
 	Bus     uint8
@@ -294,15 +264,6 @@ func (i2c *I2C) Tx(addr uint16, w, r []byte) error {
 	}
 }
 
-//export __tinygo_i2c_configure
-func i2cConfigure(bus uint8, scl Pin, sda Pin, frequency uint32)
-
-//export __tinygo_i2c_set_baud_rate
-func i2cSetBaudRate(bus uint8, br uint32)
-
-//export __tinygo_i2c_transfer
-func i2cTransfer(bus uint8, addr uint16, w *byte, wlen int, r *byte, rlen int) int
-
 type UART struct {
 	Bus uint8
 }
@@ -324,7 +285,7 @@ func (uart *UART) Write(data []byte) (n int, err error) {
 
 // Buffered returns the number of bytes currently stored in the RX buffer.
 func (uart *UART) Buffered() int {
-	return 0
+	return uartBuffered(uart.Bus)
 }
 
 // ReadByte reads a single byte from the UART.
@@ -340,15 +301,6 @@ func (uart *UART) WriteByte(b byte) error {
 	return nil
 }
 
-//export __tinygo_uart_configure
-func uartConfigure(bus uint8, tx Pin, rx Pin)
-
-//export __tinygo_uart_read
-func uartRead(bus uint8, buf *byte, bufLen int) int
-
-//export __tinygo_uart_write
-func uartWrite(bus uint8, buf *byte, bufLen int) int
-
 var (
 	hardwareUART0 = &UART{0}
 	hardwareUART1 = &UART{1}
@@ -0,0 +1,117 @@
+//go:build !baremetal && !tinygo.wasm
+
+package machine
+
+import (
+	_ "machine/sim" // link in the simulated board
+	"unsafe"
+)
+
+// Hooks for the generic machine package when running on the host (for
+// example, with tinygo test). They're implemented by the simulated board in
+// the machine/sim package, so that tests can inspect and control the
+// peripherals. The sim functions are unexported so that they're not part of
+// its API, which is why they're reached through go:linkname.
+
+//go:linkname simConfigurePin machine/sim.configurePin
+func simConfigurePin(pin uint8, mode uint8)
+
+//go:linkname simSetPinOutput machine/sim.setPinOutput
+func simSetPinOutput(pin uint8, level bool)
+
+//go:linkname simGetPinInput machine/sim.getPinInput
+func simGetPinInput(pin uint8) bool
+
+//go:linkname simConfigurePWM machine/sim.configurePWM
+func simConfigurePWM(instance int32, frequency float64, top uint32)
+
+//go:linkname simConfigurePWMChannel machine/sim.configurePWMChannel
+func simConfigurePWMChannel(instance, channel int32, pin uint8)
+
+//go:linkname simSetPWMChannel machine/sim.setPWMChannel
+func simSetPWMChannel(instance, channel int32, value uint32)
+
+//go:linkname simTxSPI machine/sim.txSPI
+func simTxSPI(bus uint8, w, r []byte)
+
+//go:linkname simTxI2C machine/sim.txI2C
+func simTxI2C(bus uint8, addr uint16, w, r []byte) int
+
+//go:linkname simReadUART machine/sim.readUART
+func simReadUART(bus uint8, buf []byte) int
+
+//go:linkname simWriteUART machine/sim.writeUART
+func simWriteUART(bus uint8, data []byte) int
+
+//go:linkname simUARTBuffered machine/sim.uartBuffered
+func simUARTBuffered(bus uint8) int
+
+//go:linkname simReadADC machine/sim.readADC
+func simReadADC(pin uint8) uint16
+
+func gpioConfigure(pin Pin, config PinConfig) {
+	simConfigurePin(uint8(pin), uint8(config.Mode))
+}
+
+func gpioSet(pin Pin, value bool) {
+	simSetPinOutput(uint8(pin), value)
+}
+
+func gpioGet(pin Pin) bool {
+	return simGetPinInput(uint8(pin))
+}
+
+func pwmConfigure(instance int32, frequency float64, top uint32) {
+	simConfigurePWM(instance, frequency, top)
+}
+
+func pwmChannelConfigure(instance, channel int32, pin Pin) {
+	simConfigurePWMChannel(instance, channel, uint8(pin))
+}
+
+func pwmChannelSet(instance int32, channel uint8, value uint32) {
+	simSetPWMChannel(instance, int32(channel), value)
+}
+
+func spiConfigure(bus uint8, sck Pin, SDO Pin, SDI Pin) {
+}
+
+func spiTransfer(bus uint8, w uint8) uint8 {
+	r := []byte{0}
+	simTxSPI(bus, []byte{w}, r)
+	return r[0]
+}
+
+func spiTX(bus uint8, wptr *byte, wlen int, rptr *byte, rlen int) uint8 {
+	simTxSPI(bus, unsafe.Slice(wptr, wlen), unsafe.Slice(rptr, rlen))
+	return 0
+}
+
+func adcRead(pin Pin) uint16 {
+	return simReadADC(uint8(pin))
+}
+
+func i2cConfigure(bus uint8, scl Pin, sda Pin, frequency uint32) {
+}
+
+func i2cSetBaudRate(bus uint8, br uint32) {
+}
+
+func i2cTransfer(bus uint8, addr uint16, w *byte, wlen int, r *byte, rlen int) int {
+	return simTxI2C(bus, addr, unsafe.Slice(w, wlen), unsafe.Slice(r, rlen))
+}
+
+func uartConfigure(bus uint8, tx Pin, rx Pin) {
+}
+
+func uartRead(bus uint8, buf *byte, bufLen int) int {
+	return simReadUART(bus, unsafe.Slice(buf, bufLen))
+}
+
+func uartWrite(bus uint8, buf *byte, bufLen int) int {
+	return simWriteUART(bus, unsafe.Slice(buf, bufLen))
+}
+
+func uartBuffered(bus uint8) int {
+	return simUARTBuffered(bus)
+}
@@ -0,0 +1,59 @@
+//go:build !baremetal && tinygo.wasm
+
+package machine
+
+// Hooks for the generic machine package on WebAssembly. These are implemented
+// by the host, for example by the simulator in the TinyGo playground.
+
+//export __tinygo_gpio_configure
+func gpioConfigure(pin Pin, config PinConfig)
+
+//export __tinygo_gpio_set
+func gpioSet(pin Pin, value bool)
+
+//export __tinygo_gpio_get
+func gpioGet(pin Pin) bool
+
+//export __tinygo_pwm_configure
+func pwmConfigure(instance int32, frequency float64, top uint32)
+
+//export __tinygo_pwm_channel_configure
+func pwmChannelConfigure(instance, channel int32, pin Pin)
+
+//export __tinygo_pwm_channel_set
+func pwmChannelSet(instance int32, channel uint8, value uint32)
+
+//export __tinygo_spi_configure
+func spiConfigure(bus uint8, sck Pin, SDO Pin, SDI Pin)
+
+//export __tinygo_spi_transfer
+func spiTransfer(bus uint8, w uint8) uint8
+
+//export __tinygo_spi_tx
+func spiTX(bus uint8, wptr *byte, wlen int, rptr *byte, rlen int) uint8
+
+//export __tinygo_adc_read
+func adcRead(pin Pin) uint16
+
+//export __tinygo_i2c_configure
+func i2cConfigure(bus uint8, scl Pin, sda Pin, frequency uint32)
+
+//export __tinygo_i2c_set_baud_rate
+func i2cSetBaudRate(bus uint8, br uint32)
+
+//export __tinygo_i2c_transfer
+func i2cTransfer(bus uint8, addr uint16, w *byte, wlen int, r *byte, rlen int) int
+
+//export __tinygo_uart_configure
+func uartConfigure(bus uint8, tx Pin, rx Pin)
+
+//export __tinygo_uart_read
+func uartRead(bus uint8, buf *byte, bufLen int) int
+
+//export __tinygo_uart_write
+func uartWrite(bus uint8, buf *byte, bufLen int) int
+
+func uartBuffered(bus uint8) int {
+	// There is no hook for this, so assume no data is buffered.
+	return 0
+}
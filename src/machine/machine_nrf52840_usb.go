@@ -23,6 +23,8 @@ var (
 	epouten     uint32
 	easyDMABusy volatile.Register8
 
+	usbLimits = usb.LimitsNRF52840
+
 	endPoints = []uint32{
 		usb.CONTROL_ENDPOINT:  usb.ENDPOINT_TYPE_CONTROL,
 		usb.CDC_ENDPOINT_ACM:  (usb.ENDPOINT_TYPE_INTERRUPT | usb.EndpointIn),
@@ -166,9 +168,7 @@ func handleUSBIRQ(interrupt.Interrupt) {
 			ok = handleStandardSetup(setup)
 		} else {
 			// Class Interface Requests
-			if setup.WIndex < uint16(len(usbSetupHandler)) && usbSetupHandler[setup.WIndex] != nil {
-				ok = usbSetupHandler[setup.WIndex](setup)
-			}
+			ok = handleClassSetup(setup)
 		}
 
 		if !ok {
@@ -243,6 +243,11 @@ func initEndpoint(ep, config uint32) {
 	case usb.ENDPOINT_TYPE_BULK | usb.EndpointIn:
 		enableEPIn(ep)
 
+	case usb.ENDPOINT_TYPE_ISOCHRONOUS | usb.EndpointIn, usb.ENDPOINT_TYPE_ISOCHRONOUS | usb.EndpointOut:
+		// The isochronous endpoint of the USBD peripheral (endpoint 8) is not
+		// supported, so these endpoints stay disabled. usb.LimitsNRF52840
+		// reports this, so ConfigureUSBComposite returns usb.ErrIsochronous.
+
 	case usb.ENDPOINT_TYPE_CONTROL:
 		enableEPIn(0)
 		enableEPOut(0)
@@ -254,6 +259,11 @@ func initEndpoint(ep, config uint32) {
 	}
 }
 
+// setEndpointPacketSize does nothing: all endpoints use packets of
+// usb.EndpointPacketSize bytes.
+func setEndpointPacketSize(ep uint8, size uint16) {
+}
+
 // SendUSBInPacket sends a packet for USBHID (interrupt in / bulk in).
 func SendUSBInPacket(ep uint32, data []byte) bool {
 	sendUSBPacket(ep, data, 0)
@@ -17,6 +17,8 @@ var (
 		pid    uint32
 	}
 
+	usbLimits = usb.LimitsRP2040
+
 	endPoints = []uint32{
 		usb.CONTROL_ENDPOINT:  usb.ENDPOINT_TYPE_CONTROL,
 		usb.CDC_ENDPOINT_ACM:  (usb.ENDPOINT_TYPE_INTERRUPT | usb.EndpointIn),
@@ -31,7 +33,7 @@ var (
 
 func initEndpoint(ep, config uint32) {
 	val := uint32(usbEpControlEnable) | uint32(usbEpControlInterruptPerBuff)
-	offset := ep*2*usbBufferLen + 0x100
+	offset := endpointBufferOffset(ep)
 	val |= offset
 
 	// Bulk and interrupt endpoints must have their Packet ID reset to DATA0 when un-stalled.
@@ -62,6 +64,16 @@ func initEndpoint(ep, config uint32) {
 		_usbDPSRAM.EPxControl[ep].In.Set(val)
 		epXPIDReset[ep] = true
 
+	case usb.ENDPOINT_TYPE_ISOCHRONOUS | usb.EndpointIn:
+		val |= usbEpControlEndpointTypeISO
+		_usbDPSRAM.EPxControl[ep].In.Set(val)
+
+	case usb.ENDPOINT_TYPE_ISOCHRONOUS | usb.EndpointOut:
+		val |= usbEpControlEndpointTypeISO
+		_usbDPSRAM.EPxControl[ep].Out.Set(val)
+		_usbDPSRAM.EPxBufferControl[ep].Out.Set(uint32(len(endpointBuffer(ep))) & usbBuf0CtrlLenMask)
+		_usbDPSRAM.EPxBufferControl[ep].Out.SetBits(usbBuf0CtrlAvail)
+
 	case usb.ENDPOINT_TYPE_CONTROL:
 		val |= usbEpControlEndpointTypeControl
 		_usbDPSRAM.EPxBufferControl[ep].Out.Set(usbBuf0CtrlData1Pid)
@@ -121,11 +133,12 @@ func ReceiveUSBControlPacket() ([cdcLineInfoSize]byte, error) {
 }
 
 func handleEndpointRx(ep uint32) []byte {
+	buf := endpointBuffer(ep)
 	ctrl := _usbDPSRAM.EPxBufferControl[ep].Out.Get()
-	_usbDPSRAM.EPxBufferControl[ep].Out.Set(usbBufferLen & usbBuf0CtrlLenMask)
+	_usbDPSRAM.EPxBufferControl[ep].Out.Set(uint32(len(buf)) & usbBuf0CtrlLenMask)
 	sz := ctrl & usbBuf0CtrlLenMask
 
-	return _usbDPSRAM.EPxBuffer[ep].Buffer0[:sz]
+	return buf[:sz]
 }
 
 // AckUsbOutTransfer is called to acknowledge the completion of a USB OUT transfer.
@@ -161,7 +174,7 @@ func sendViaEPIn(ep uint32, data []byte, count int) {
 	// Mark as full
 	val |= usbBuf0CtrlFull
 
-	copy(_usbDPSRAM.EPxBuffer[ep&0x7F].Buffer0[:], data[:count])
+	copy(endpointBuffer(ep&0x7F), data[:count])
 	_usbDPSRAM.EPxBufferControl[ep&0x7F].In.Set(val)
 }
 
@@ -238,8 +251,45 @@ var (
 	epXdata0    [16]bool
 	epXPIDReset [16]bool
 	setupBytes  [8]byte
+
+	// Maximum packet size of the endpoints, or zero for usbBufferLen.
+	usbPacketSize [NumberOfUSBEndpoints]uint16
 )
 
+// setEndpointPacketSize sets the maximum packet size of an endpoint. Zero
+// selects the default of usbBufferLen bytes.
+func setEndpointPacketSize(ep uint8, size uint16) {
+	usbPacketSize[ep] = size
+}
+
+// endpointBufferOffset returns the offset of the buffer of an endpoint in the
+// USB memory. Endpoints with packets larger than usbBufferLen (isochronous
+// endpoints) have their buffers after the buffers of all other endpoints, each
+// rounded up to 64 bytes. usb.LimitsRP2040 reports how much memory is left
+// there.
+func endpointBufferOffset(ep uint32) uint32 {
+	if usbPacketSize[ep] <= usbBufferLen {
+		return ep*2*usbBufferLen + 0x100
+	}
+	offset := uint32(NumberOfUSBEndpoints*2*usbBufferLen + 0x100)
+	for i := uint32(0); i < ep; i++ {
+		if usbPacketSize[i] > usbBufferLen {
+			offset += (uint32(usbPacketSize[i]) + 63) &^ 63
+		}
+	}
+	return offset
+}
+
+// endpointBuffer returns the buffer of an endpoint in the USB memory.
+func endpointBuffer(ep uint32) []byte {
+	size := uint16(usbBufferLen)
+	if usbPacketSize[ep] > size {
+		size = usbPacketSize[ep]
+	}
+	p := unsafe.Add(unsafe.Pointer(_usbDPSRAM), endpointBufferOffset(ep))
+	return unsafe.Slice((*byte)(p), size)
+}
+
 func (d *usbDPSRAM) setupBytes() []byte {
 
 	data := d.EPxControl[usb.CONTROL_ENDPOINT].In.Get()
@@ -0,0 +1,189 @@
+package sim
+
+// I2CDevice is a simulated device on an I2C bus.
+type I2CDevice interface {
+	// Tx handles a single transaction: the program writes w to the device,
+	// and then reads len(r) bytes into r. Returning an error results in a bus
+	// error in the program.
+	Tx(w, r []byte) error
+}
+
+// I2CTransaction is a single transaction on an I2C bus, as recorded by the
+// simulator.
+type I2CTransaction struct {
+	Addr uint16
+	W    []byte // data written by the program
+	R    []byte // data read by the program
+}
+
+// SPIDevice is a simulated device on an SPI bus.
+type SPIDevice interface {
+	// Tx handles a single transfer: w contains the bytes written by the
+	// program, and the device must fill r (which has the same length) with
+	// the bytes that are read back at the same time.
+	Tx(w, r []byte)
+}
+
+// SPITransaction is a single transfer on an SPI bus, as recorded by the
+// simulator.
+type SPITransaction struct {
+	W []byte // data written by the program
+	R []byte // data read by the program
+}
+
+func getI2C(bus uint8) *i2cState {
+	b := i2cs[bus]
+	if b == nil {
+		b = &i2cState{devices: map[uint16]I2CDevice{}}
+		i2cs[bus] = b
+	}
+	return b
+}
+
+// AddI2CDevice attaches a device at the given address to an I2C bus.
+func AddI2CDevice(bus uint8, addr uint16, dev I2CDevice) {
+	lock.Lock()
+	getI2C(bus).devices[addr] = dev
+	lock.Unlock()
+}
+
+// I2CTransactions returns all transactions on the given I2C bus since the last
+// call to I2CTransactions, including transactions to addresses without a
+// device.
+func I2CTransactions(bus uint8) []I2CTransaction {
+	lock.Lock()
+	defer lock.Unlock()
+	b := getI2C(bus)
+	transactions := b.transactions
+	b.transactions = nil
+	return transactions
+}
+
+// I2C error codes, as returned to the machine package which converts them to
+// its own error values.
+const (
+	i2cOK           = 0
+	i2cNoDevices    = 1 // the bus has no devices
+	i2cWrongAddress = 3 // the bus has devices but none with this address
+	i2cDeviceError  = 4 // the device returned an error
+)
+
+// txI2C is called by the machine package for an I2C transaction, and returns
+// one of the I2C error codes. The device is called without holding the
+// simulator lock, so it can use the simulator itself (for example, to set an
+// interrupt pin).
+func txI2C(bus uint8, addr uint16, w, r []byte) int {
+	lock.Lock()
+	b := getI2C(bus)
+	dev := b.devices[addr]
+	numDevices := len(b.devices)
+	lock.Unlock()
+
+	code := i2cOK
+	switch {
+	case dev != nil:
+		if dev.Tx(w, r) != nil {
+			code = i2cDeviceError
+		}
+	case numDevices == 0:
+		code = i2cNoDevices
+	default:
+		code = i2cWrongAddress
+	}
+
+	lock.Lock()
+	b.transactions = append(b.transactions, I2CTransaction{
+		Addr: addr,
+		W:    append([]byte(nil), w...),
+		R:    append([]byte(nil), r...),
+	})
+	lock.Unlock()
+	return code
+}
+
+// Registers is a simple I2C device with a register map, like many sensors: the
+// first byte written selects the register, the following bytes are written to
+// consecutive registers, and reads start at the selected register. The address
+// wraps around at the end of the register map.
+type Registers struct {
+	Data []byte
+	reg  int
+}
+
+// NewRegisters returns a register map of the given size, initialized to zero.
+func NewRegisters(size int) *Registers {
+	return &Registers{Data: make([]byte, size)}
+}
+
+// Tx implements I2CDevice.
+func (d *Registers) Tx(w, r []byte) error {
+	if len(d.Data) == 0 {
+		return nil
+	}
+	if len(w) != 0 {
+		d.reg = int(w[0]) % len(d.Data)
+		for _, b := range w[1:] {
+			d.Data[d.reg] = b
+			d.reg = (d.reg + 1) % len(d.Data)
+		}
+	}
+	for i := range r {
+		r[i] = d.Data[d.reg]
+		d.reg = (d.reg + 1) % len(d.Data)
+	}
+	return nil
+}
+
+func getSPI(bus uint8) *spiState {
+	b := spis[bus]
+	if b == nil {
+		b = &spiState{}
+		spis[bus] = b
+	}
+	return b
+}
+
+// SetSPIDevice attaches a device to an SPI bus. Without a device, the program
+// reads zeroes.
+func SetSPIDevice(bus uint8, dev SPIDevice) {
+	lock.Lock()
+	getSPI(bus).device = dev
+	lock.Unlock()
+}
+
+// SPITransactions returns all transfers on the given SPI bus since the last
+// call to SPITransactions.
+func SPITransactions(bus uint8) []SPITransaction {
+	lock.Lock()
+	defer lock.Unlock()
+	b := getSPI(bus)
+	transactions := b.transactions
+	b.transactions = nil
+	return transactions
+}
+
+// txSPI is called by the machine package for an SPI transfer. The w and r
+// slices have the same length, and either may be nil.
+func txSPI(bus uint8, w, r []byte) {
+	n := len(w)
+	if len(r) > n {
+		n = len(r)
+	}
+	tx := make([]byte, n)
+	copy(tx, w)
+	rx := make([]byte, n)
+
+	lock.Lock()
+	b := getSPI(bus)
+	dev := b.device
+	lock.Unlock()
+
+	if dev != nil {
+		dev.Tx(tx, rx)
+	}
+	copy(r, rx)
+
+	lock.Lock()
+	b.transactions = append(b.transactions, SPITransaction{W: tx, R: rx})
+	lock.Unlock()
+}
@@ -0,0 +1,327 @@
+// Package sim is the simulated board that the machine package uses when a
+// program is built for the host (for example, with tinygo test). It makes it
+// possible to unit test driver code without hardware: tests can set the level
+// of input pins, inspect output pins and PWM channels, feed data to UARTs, and
+// attach simulated I2C and SPI devices.
+//
+// A typical test looks like this:
+//
+//	func TestLED(t *testing.T) {
+//		sim.Reset()
+//		led := machine.Pin(13)
+//		led.Configure(machine.PinConfig{Mode: machine.PinOutput})
+//		led.High()
+//		if !sim.Pin(13) {
+//			t.Error("expected the LED to be on")
+//		}
+//	}
+//
+// All functions are safe to call from multiple goroutines.
+package sim
+
+import (
+	"sync"
+)
+
+// Pin modes, as passed by the machine package. They're the same as the
+// machine.PinInput etc. constants when building for the host.
+const (
+	PinInput uint8 = iota
+	PinOutput
+	PinInputPullup
+	PinInputPulldown
+)
+
+// The state of the simulated board.
+var (
+	lock  sync.Mutex
+	pins  = map[uint8]*pinState{}
+	pwms  = map[int32]*pwmState{}
+	uarts = map[uint8]*uartState{}
+	i2cs  = map[uint8]*i2cState{}
+	spis  = map[uint8]*spiState{}
+	adcs  = map[uint8]uint16{}
+)
+
+type pinState struct {
+	configured bool
+	mode       uint8
+	output     bool // level set by the program
+	input      bool // level set by the test
+	driven     bool // whether input was set by the test
+	watchers   []func(level bool)
+}
+
+type pwmState struct {
+	frequency float64
+	top       uint32
+	channels  map[int32]*pwmChannel
+}
+
+type pwmChannel struct {
+	pin   uint8
+	value uint32
+}
+
+type uartState struct {
+	rx []byte // data to be read by the program
+	tx []byte // data written by the program
+}
+
+type i2cState struct {
+	devices      map[uint16]I2CDevice
+	transactions []I2CTransaction
+}
+
+type spiState struct {
+	device       SPIDevice
+	transactions []SPITransaction
+}
+
+// Reset resets the simulated board to its initial state: all pins are
+// unconfigured, and there are no I2C or SPI devices. Tests should call it at
+// the start so they're independent of each other.
+func Reset() {
+	lock.Lock()
+	defer lock.Unlock()
+	pins = map[uint8]*pinState{}
+	pwms = map[int32]*pwmState{}
+	uarts = map[uint8]*uartState{}
+	i2cs = map[uint8]*i2cState{}
+	spis = map[uint8]*spiState{}
+	adcs = map[uint8]uint16{}
+}
+
+func getPin(pin uint8) *pinState {
+	p := pins[pin]
+	if p == nil {
+		p = &pinState{}
+		pins[pin] = p
+	}
+	return p
+}
+
+// SetPin sets the level of a pin as seen by the program, as if an external
+// signal drives the pin. It has no effect on the value read from a pin that is
+// configured as an output.
+func SetPin(pin uint8, level bool) {
+	lock.Lock()
+	p := getPin(pin)
+	p.input = level
+	p.driven = true
+	lock.Unlock()
+}
+
+// ReleasePin stops driving a pin that was set with SetPin, so that its level
+// depends on the pull-up or pull-down resistor again.
+func ReleasePin(pin uint8) {
+	lock.Lock()
+	getPin(pin).driven = false
+	lock.Unlock()
+}
+
+// Pin returns the current level of the pin: the value set by the program for
+// an output pin, and the value that the program would read for an input pin.
+func Pin(pin uint8) bool {
+	lock.Lock()
+	defer lock.Unlock()
+	return getPin(pin).level()
+}
+
+// PinMode returns the mode the pin was configured with, and whether it was
+// configured at all.
+func PinMode(pin uint8) (mode uint8, configured bool) {
+	lock.Lock()
+	defer lock.Unlock()
+	p := getPin(pin)
+	return p.mode, p.configured
+}
+
+// WatchPin calls fn each time the program sets the given output pin, with the
+// new level. It is called even if the level doesn't change. This can be used
+// to record bit-banged protocols.
+func WatchPin(pin uint8, fn func(level bool)) {
+	lock.Lock()
+	p := getPin(pin)
+	p.watchers = append(p.watchers, fn)
+	lock.Unlock()
+}
+
+func (p *pinState) level() bool {
+	switch {
+	case p.configured && p.mode == PinOutput:
+		return p.output
+	case p.driven:
+		return p.input
+	default:
+		return p.configured && p.mode == PinInputPullup
+	}
+}
+
+// The functions below are the other side of the hooks in the machine package.
+// They're not part of the API of this package: the machine package calls them
+// through go:linkname, so that tests can't call them by accident.
+
+// configurePin is called by the machine package when the program configures a
+// pin.
+func configurePin(pin uint8, mode uint8) {
+	lock.Lock()
+	p := getPin(pin)
+	p.configured = true
+	p.mode = mode
+	lock.Unlock()
+}
+
+// setPinOutput is called by the machine package when the program sets the
+// level of a pin.
+func setPinOutput(pin uint8, level bool) {
+	lock.Lock()
+	p := getPin(pin)
+	p.output = level
+	watchers := p.watchers
+	lock.Unlock()
+	for _, fn := range watchers {
+		fn(level)
+	}
+}
+
+// getPinInput is called by the machine package when the program reads the
+// level of a pin.
+func getPinInput(pin uint8) bool {
+	return Pin(pin)
+}
+
+func getPWM(instance int32) *pwmState {
+	p := pwms[instance]
+	if p == nil {
+		p = &pwmState{channels: map[int32]*pwmChannel{}}
+		pwms[instance] = p
+	}
+	return p
+}
+
+// PWM returns the duty cycle of the PWM channel connected to the given pin,
+// as a value between 0 and top (inclusive), and the frequency of the timer in
+// Hz. It returns ok=false if no PWM channel was configured for the pin.
+func PWM(pin uint8) (value, top uint32, frequency float64, ok bool) {
+	lock.Lock()
+	defer lock.Unlock()
+	for _, p := range pwms {
+		for _, ch := range p.channels {
+			if ch.pin == pin {
+				return ch.value, p.top, p.frequency, true
+			}
+		}
+	}
+	return 0, 0, 0, false
+}
+
+// configurePWM is called by the machine package when the program configures a
+// PWM peripheral.
+func configurePWM(instance int32, frequency float64, top uint32) {
+	lock.Lock()
+	p := getPWM(instance)
+	p.frequency = frequency
+	p.top = top
+	lock.Unlock()
+}
+
+// configurePWMChannel is called by the machine package when the program
+// connects a pin to a PWM channel.
+func configurePWMChannel(instance, channel int32, pin uint8) {
+	lock.Lock()
+	p := getPWM(instance)
+	ch := p.channels[channel]
+	if ch == nil {
+		ch = &pwmChannel{}
+		p.channels[channel] = ch
+	}
+	ch.pin = pin
+	lock.Unlock()
+}
+
+// setPWMChannel is called by the machine package when the program sets the
+// duty cycle of a PWM channel.
+func setPWMChannel(instance, channel int32, value uint32) {
+	lock.Lock()
+	p := getPWM(instance)
+	ch := p.channels[channel]
+	if ch == nil {
+		// Not connected to a pin.
+		ch = &pwmChannel{pin: 0xff}
+		p.channels[channel] = ch
+	}
+	ch.value = value
+	lock.Unlock()
+}
+
+func getUART(bus uint8) *uartState {
+	u := uarts[bus]
+	if u == nil {
+		u = &uartState{}
+		uarts[bus] = u
+	}
+	return u
+}
+
+// FeedUART adds data that the program can read from the given UART.
+func FeedUART(bus uint8, data []byte) {
+	lock.Lock()
+	u := getUART(bus)
+	u.rx = append(u.rx, data...)
+	lock.Unlock()
+}
+
+// UARTOutput returns all data the program wrote to the given UART since the
+// last call to UARTOutput (or since the start of the test).
+func UARTOutput(bus uint8) []byte {
+	lock.Lock()
+	defer lock.Unlock()
+	u := getUART(bus)
+	data := u.tx
+	u.tx = nil
+	return data
+}
+
+// readUART is called by the machine package when the program reads from a
+// UART. It doesn't block, so it returns 0 if no data was fed with FeedUART.
+func readUART(bus uint8, buf []byte) int {
+	lock.Lock()
+	defer lock.Unlock()
+	u := getUART(bus)
+	n := copy(buf, u.rx)
+	u.rx = u.rx[n:]
+	return n
+}
+
+// writeUART is called by the machine package when the program writes to a
+// UART.
+func writeUART(bus uint8, data []byte) int {
+	lock.Lock()
+	defer lock.Unlock()
+	u := getUART(bus)
+	u.tx = append(u.tx, data...)
+	return len(data)
+}
+
+// uartBuffered is called by the machine package to return the number of bytes
+// that the program can read without blocking.
+func uartBuffered(bus uint8) int {
+	lock.Lock()
+	defer lock.Unlock()
+	return len(getUART(bus).rx)
+}
+
+// SetADC sets the value that the program reads from the ADC on the given pin.
+func SetADC(pin uint8, value uint16) {
+	lock.Lock()
+	adcs[pin] = value
+	lock.Unlock()
+}
+
+// readADC is called by the machine package when the program reads an ADC.
+func readADC(pin uint8) uint16 {
+	lock.Lock()
+	defer lock.Unlock()
+	return adcs[pin]
+}
@@ -110,8 +110,8 @@ var (
 	isEndpointHalt        = false
 	isRemoteWakeUpEnabled = false
 
-	usbConfiguration uint8
-	usbSetInterface  uint8
+	usbConfiguration    uint8
+	usbAlternateSetting [usb.NumberOfInterfaces]uint8
 )
 
 //go:align 4
@@ -134,6 +134,7 @@ var (
 	usbTxHandler    [NumberOfUSBEndpoints]func()
 	usbRxHandler    [NumberOfUSBEndpoints]func([]byte) bool
 	usbSetupHandler [usb.NumberOfInterfaces]func(usb.Setup) bool
+	usbAltHandler   [usb.NumberOfInterfaces]func(uint8) bool
 	usbStallHandler [NumberOfUSBEndpoints]func(usb.Setup) bool
 )
 
@@ -177,6 +178,16 @@ func sendDescriptor(setup usb.Setup) {
 				strToUTF16LEDescriptor(usbSerial(), b)
 				sendUSBPacket(0, b, setup.WLength)
 			}
+
+		default:
+			str, ok := usbDescriptor.Strings[setup.WValueL]
+			if !ok {
+				SendZlp()
+				return
+			}
+			b := usb_trans_buffer[:(len(str)<<1)+2]
+			strToUTF16LEDescriptor(str, b)
+			sendUSBPacket(0, b, setup.WLength)
 		}
 		return
 	case descriptor.TypeHIDReport:
@@ -184,6 +195,11 @@ func sendDescriptor(setup usb.Setup) {
 			sendUSBPacket(0, h, setup.WLength)
 			return
 		}
+	case descriptor.TypeBOS:
+		if len(usbDescriptor.BOS) != 0 {
+			sendUSBPacket(0, usbDescriptor.BOS, setup.WLength)
+			return
+		}
 	case descriptor.TypeDeviceQualifier:
 		// skip
 	default:
@@ -270,12 +286,20 @@ func handleStandardSetup(setup usb.Setup) bool {
 		}
 
 	case usb.GET_INTERFACE:
-		usb_trans_buffer[0] = usbSetInterface
+		usb_trans_buffer[0] = 0
+		if setup.WIndex < uint16(len(usbAlternateSetting)) {
+			usb_trans_buffer[0] = usbAlternateSetting[setup.WIndex]
+		}
 		sendUSBPacket(0, usb_trans_buffer[:1], setup.WLength)
 		return true
 
 	case usb.SET_INTERFACE:
-		usbSetInterface = setup.WValueL
+		if setup.WIndex < uint16(len(usbAlternateSetting)) {
+			if h := usbAltHandler[setup.WIndex]; h != nil && !h(setup.WValueL) {
+				return false
+			}
+			usbAlternateSetting[setup.WIndex] = setup.WValueL
+		}
 
 		SendZlp()
 		return true
@@ -285,6 +309,48 @@ func handleStandardSetup(setup usb.Setup) bool {
 	}
 }
 
+// handleClassSetup passes a class or vendor specific setup request to the
+// handler of the interface it is addressed to. The interface number is in the
+// low byte of wIndex; some classes (like audio) use the high byte to address an
+// entity within the interface.
+func handleClassSetup(setup usb.Setup) bool {
+	if setup.BmRequestType&usb.REQUEST_TYPE == usb.REQUEST_VENDOR &&
+		setup.BmRequestType&usb.REQUEST_RECIPIENT == usb.REQUEST_DEVICE {
+		return handleVendorSetup(setup)
+	}
+	index := setup.WIndex
+	if setup.BmRequestType&usb.REQUEST_RECIPIENT == usb.REQUEST_INTERFACE {
+		index &= 0xff
+	}
+	if index < uint16(len(usbSetupHandler)) && usbSetupHandler[index] != nil {
+		return usbSetupHandler[index](setup)
+	}
+	return false
+}
+
+// handleVendorSetup handles the vendor requests to the device for the WebUSB
+// landing page and the Microsoft OS 2.0 descriptors.
+func handleVendorSetup(setup usb.Setup) bool {
+	if setup.BmRequestType&usb.REQUEST_DIRECTION != usb.REQUEST_DEVICETOHOST {
+		return false
+	}
+	switch {
+	case setup.BRequest == descriptor.WebUSBVendorCode && setup.WIndex == descriptor.WebUSBRequestGetURL:
+		if setup.WValueL != descriptor.WebUSBLandingPage || len(usbDescriptor.WebUSBURL) == 0 {
+			return false
+		}
+		sendUSBPacket(0, usbDescriptor.WebUSBURL, setup.WLength)
+		return true
+	case setup.BRequest == descriptor.MSOS20VendorCode && setup.WIndex == descriptor.MSOS20RequestDescriptor:
+		if len(usbDescriptor.MSOS20) == 0 {
+			return false
+		}
+		sendUSBPacket(0, usbDescriptor.MSOS20, setup.WLength)
+		return true
+	}
+	return false
+}
+
 func EnableCDC(txHandler func(), rxHandler func([]byte), setupHandler func(usb.Setup) bool) {
 	if len(usbDescriptor.Device) == 0 {
 		usbDescriptor = descriptor.CDC
@@ -322,6 +388,7 @@ func ConfigureUSBEndpoint(desc descriptor.Descriptor, epSettings []usb.EndpointC
 	usbDescriptor = desc
 
 	for _, ep := range epSettings {
+		setEndpointPacketSize(ep.Index, ep.MaxPacketSize)
 		if ep.IsIn {
 			endPoints[ep.Index] = uint32(ep.Type | usb.EndpointIn)
 			if ep.TxHandler != nil {
@@ -345,5 +412,48 @@ func ConfigureUSBEndpoint(desc descriptor.Descriptor, epSettings []usb.EndpointC
 
 	for _, s := range setup {
 		usbSetupHandler[s.Index] = s.Handler
+		usbAltHandler[s.Index] = s.AlternateHandler
+	}
+}
+
+// USBLimits returns what the USB device controller of the chip supports, for
+// example to check whether isochronous endpoints can be used.
+func USBLimits() usb.Limits {
+	return usbLimits
+}
+
+// ConfigureUSBComposite configures the USB device with the functions of a
+// composite device. It replaces the descriptors and endpoints set up before,
+// and returns an error if the device does not fit within the limits of the
+// USB controller.
+//
+// The handlers of the USB serial console are kept, so the console keeps
+// working if the composite device includes it (see usb.Composite.AddCDC). The
+// handlers of all other endpoints and interfaces are removed.
+func ConfigureUSBComposite(c *usb.Composite) error {
+	desc, eps, setup, err := c.Build(usbLimits)
+	if err != nil {
+		return err
+	}
+	for i := range endPoints {
+		switch i {
+		case usb.CONTROL_ENDPOINT:
+		case usb.CDC_ENDPOINT_ACM, usb.CDC_ENDPOINT_OUT, usb.CDC_ENDPOINT_IN:
+			endPoints[i] = usb.ENDPOINT_TYPE_DISABLE
+		default:
+			endPoints[i] = usb.ENDPOINT_TYPE_DISABLE
+			setEndpointPacketSize(uint8(i), 0)
+			usbTxHandler[i] = nil
+			usbRxHandler[i] = nil
+			usbStallHandler[i] = nil
+		}
+	}
+	for i := range usbSetupHandler {
+		if i != usb.CDC_ACM_INTERFACE {
+			usbSetupHandler[i] = nil
+		}
+		usbAltHandler[i] = nil
 	}
+	ConfigureUSBEndpoint(desc, eps, setup)
+	return nil
 }
@@ -0,0 +1,330 @@
+// Package audio implements a USB Audio Class 2.0 device, with an optional
+// microphone stream (from the device to the host) and an optional speaker
+// stream (from the host to the device).
+//
+// Both streams use isochronous endpoints, which are supported on the RP2040,
+// RP2350, SAMD21 and SAMD51, but not on the nRF52840 (Configure returns
+// usb.ErrIsochronous there). The endpoints send one full speed packet of at
+// most 1023 bytes per millisecond, which is enough for 16-bit stereo at 48kHz
+// (192 bytes per packet) or 24-bit audio with 8 channels at 32kHz.
+//
+// Example of a microphone:
+//
+//	mic := audio.Port()
+//	err := mic.Configure(audio.Config{
+//		SampleRates:   []uint32{16000},
+//		InputChannels: 1,
+//	})
+//	...
+//	for {
+//		samples := readMicrophone() // little endian 16-bit samples
+//		mic.Write(samples)
+//	}
+package audio
+
+import (
+	"errors"
+	"machine"
+	"machine/usb"
+	"machine/usb/descriptor"
+)
+
+// Stream identifies one of the audio streams, for example in a volume
+// handler.
+type Stream uint8
+
+const (
+	// Microphone is the stream from the device to the host (isochronous IN).
+	Microphone Stream = iota
+
+	// Speaker is the stream from the host to the device (isochronous OUT).
+	Speaker
+)
+
+// Maximum number of sample rates that can be reported to the host.
+const maxSampleRates = 5
+
+var (
+	errNoStreams          = errors.New("audio: no input or output channels configured")
+	errInvalidBitDepth    = errors.New("audio: bit depth must be 16, 24 or 32")
+	errInvalidSampleRates = errors.New("audio: between 1 and 5 sample rates must be configured")
+	errPacketTooLarge     = errors.New("audio: sample rate and format need packets larger than the endpoint size")
+	errBufferFull         = errors.New("audio: buffer full")
+)
+
+// Config is the configuration of the audio device.
+type Config struct {
+	// Sample rates in Hz that the host can choose from. The first is used
+	// until the host selects a different one. The default is 16000.
+	SampleRates []uint32
+
+	// Number of channels of the microphone (InputChannels) and speaker
+	// (OutputChannels). A stream is disabled if it has zero channels.
+	InputChannels  uint8
+	OutputChannels uint8
+
+	// Number of bits per sample: 16 (the default), 24 or 32. Samples are
+	// stored as little endian signed integers of 2, 3 or 4 bytes.
+	BitDepth uint8
+}
+
+var Audio *audio
+
+type audio struct {
+	sampleRates []uint32
+	sampleRate  uint32
+	frameSize   int    // bytes per frame (one sample of every channel)
+	remainder   uint32 // fractional frames accumulated for the next packet
+
+	in  *RingBuffer
+	out *RingBuffer
+
+	inStreaming  bool
+	outStreaming bool
+	mute         [2]bool
+	volume       [2]int16 // in 1/256 dB
+
+	rxHandler     func([]byte)
+	rateHandler   func(rate uint32)
+	volumeHandler func(stream Stream, volume int16, mute bool)
+
+	packet   []byte
+	setupBuf [2 + 12*maxSampleRates]byte
+}
+
+// Port returns the USB audio port. It must be configured with Configure
+// before it can be used.
+func Port() *audio {
+	if Audio == nil {
+		Audio = &audio{
+			in:  NewRingBuffer(),
+			out: NewRingBuffer(),
+		}
+	}
+	return Audio
+}
+
+// Configure sets up the USB descriptors and endpoints of the audio device.
+func (a *audio) Configure(config Config) error {
+	if config.InputChannels == 0 && config.OutputChannels == 0 {
+		return errNoStreams
+	}
+	if config.BitDepth == 0 {
+		config.BitDepth = 16
+	}
+	if config.BitDepth != 16 && config.BitDepth != 24 && config.BitDepth != 32 {
+		return errInvalidBitDepth
+	}
+	if config.SampleRates == nil {
+		config.SampleRates = []uint32{16000}
+	}
+	if len(config.SampleRates) == 0 || len(config.SampleRates) > maxSampleRates {
+		return errInvalidSampleRates
+	}
+	channels := config.InputChannels
+	if config.OutputChannels > channels {
+		channels = config.OutputChannels
+	}
+	limits := machine.USBLimits()
+	if limits.MaxIsochronousPacketSize == 0 {
+		return usb.ErrIsochronous
+	}
+	subslot := config.BitDepth / 8
+	packetSize := uint32(0)
+	for _, rate := range config.SampleRates {
+		frames := (rate + 999) / 1000
+		if size := frames * uint32(channels) * uint32(subslot); size > packetSize {
+			packetSize = size
+		}
+	}
+	if packetSize > uint32(limits.MaxIsochronousPacketSize) {
+		return errPacketTooLarge
+	}
+	if config.InputChannels != 0 && len(a.packet) < int(packetSize) {
+		a.packet = make([]byte, packetSize)
+	}
+
+	a.sampleRates = config.SampleRates
+	a.sampleRate = config.SampleRates[0]
+	a.frameSize = int(config.InputChannels) * int(subslot)
+
+	desc := descriptor.AudioConfig{
+		FirstInterface: usb.AUDIO_CONTROL_INTERFACE,
+		InputChannels:  config.InputChannels,
+		OutputChannels: config.OutputChannels,
+		SubslotSize:    subslot,
+		BitResolution:  config.BitDepth,
+		EndpointIn:     usb.AUDIO_ENDPOINT_IN,
+		EndpointOut:    usb.AUDIO_ENDPOINT_OUT,
+		MaxPacketSize:  uint16(packetSize),
+	}
+
+	endpoints := []usb.EndpointConfig{}
+	setup := []usb.SetupConfig{
+		{
+			Index:   usb.AUDIO_CONTROL_INTERFACE,
+			Handler: a.setupHandler,
+		},
+	}
+	iface := uint8(usb.AUDIO_CONTROL_INTERFACE + 1)
+	if config.InputChannels != 0 {
+		endpoints = append(endpoints, usb.EndpointConfig{
+			Index:         usb.AUDIO_ENDPOINT_IN,
+			IsIn:          true,
+			Type:          usb.ENDPOINT_TYPE_ISOCHRONOUS,
+			MaxPacketSize: uint16(packetSize),
+			TxHandler:     a.txHandler,
+		})
+		setup = append(setup, usb.SetupConfig{
+			Index:            iface,
+			AlternateHandler: a.setInputAlternate,
+		})
+		iface++
+	}
+	if config.OutputChannels != 0 {
+		endpoints = append(endpoints, usb.EndpointConfig{
+			Index:         usb.AUDIO_ENDPOINT_OUT,
+			IsIn:          false,
+			Type:          usb.ENDPOINT_TYPE_ISOCHRONOUS,
+			MaxPacketSize: uint16(packetSize),
+			RxHandler:     a.rxHandlerFunc,
+		})
+		setup = append(setup, usb.SetupConfig{
+			Index:            iface,
+			AlternateHandler: a.setOutputAlternate,
+		})
+	}
+
+	machine.ConfigureUSBEndpoint(descriptor.CDCAudio(desc), endpoints, setup)
+	return nil
+}
+
+// SampleRate returns the sample rate in Hz that is currently selected by the
+// host.
+func (a *audio) SampleRate() uint32 {
+	return a.sampleRate
+}
+
+// Streaming returns whether the host has started the given stream. The host
+// only starts a stream while an application is recording or playing audio.
+func (a *audio) Streaming(stream Stream) bool {
+	if stream == Microphone {
+		return a.inStreaming
+	}
+	return a.outStreaming
+}
+
+// Volume returns the volume of the stream in 1/256 dB (between -60dB and 0dB)
+// and whether it is muted, as set by the host.
+func (a *audio) Volume(stream Stream) (volume int16, mute bool) {
+	return a.volume[stream], a.mute[stream]
+}
+
+// SetSampleRateHandler sets a function that is called when the host selects a
+// different sample rate. It is called from an interrupt.
+func (a *audio) SetSampleRateHandler(handler func(rate uint32)) {
+	a.rateHandler = handler
+}
+
+// SetVolumeHandler sets a function that is called when the host changes the
+// volume or mute control of a stream. It is called from an interrupt.
+func (a *audio) SetVolumeHandler(handler func(stream Stream, volume int16, mute bool)) {
+	a.volumeHandler = handler
+}
+
+// SetRxHandler sets a function that is called for every packet received on
+// the speaker stream, instead of storing the packet for Read. It is called from
+// an interrupt.
+func (a *audio) SetRxHandler(handler func([]byte)) {
+	a.rxHandler = handler
+}
+
+// Write queues samples for the microphone stream. The samples of all channels
+// are interleaved. It returns an error if not all samples fit in the buffer.
+func (a *audio) Write(b []byte) (n int, err error) {
+	n = a.in.Write(b)
+	if n != len(b) {
+		return n, errBufferFull
+	}
+	return n, nil
+}
+
+// Buffered returns the number of bytes received on the speaker stream that
+// can be read with Read.
+func (a *audio) Buffered() int {
+	return a.out.Used()
+}
+
+// Read reads samples received on the speaker stream. It returns 0 if no
+// samples are available.
+func (a *audio) Read(b []byte) (n int, err error) {
+	return a.out.Read(b), nil
+}
+
+func (a *audio) setInputAlternate(alt uint8) bool {
+	if alt > 1 {
+		return false
+	}
+	a.inStreaming = alt == 1
+	if a.inStreaming {
+		// Start sending packets. Each following packet is sent when the
+		// previous one completed.
+		a.remainder = 0
+		a.sendPacket()
+	} else {
+		a.in.Clear()
+	}
+	return true
+}
+
+func (a *audio) setOutputAlternate(alt uint8) bool {
+	if alt > 1 {
+		return false
+	}
+	a.outStreaming = alt == 1
+	return true
+}
+
+// sendPacket sends the samples for the next USB frame (one millisecond) on
+// the microphone stream. Missing samples are sent as silence, to keep the
+// stream going.
+func (a *audio) sendPacket() {
+	frames := a.sampleRate / 1000
+	a.remainder += a.sampleRate % 1000
+	if a.remainder >= 1000 {
+		a.remainder -= 1000
+		frames++
+	}
+	size := int(frames) * a.frameSize
+	if size > len(a.packet) {
+		size = len(a.packet)
+	}
+	packet := a.packet[:size]
+	n := a.in.Used()
+	n -= n % a.frameSize
+	if n > size {
+		n = size
+	}
+	a.in.Read(packet[:n])
+	for i := n; i < len(packet); i++ {
+		packet[i] = 0
+	}
+	machine.SendUSBInPacket(usb.AUDIO_ENDPOINT_IN, packet)
+}
+
+func (a *audio) txHandler() {
+	if a.inStreaming {
+		a.sendPacket()
+	}
+}
+
+func (a *audio) rxHandlerFunc(b []byte) {
+	if !a.outStreaming {
+		return
+	}
+	if a.rxHandler != nil {
+		a.rxHandler(b)
+		return
+	}
+	a.out.Write(b)
+}
@@ -0,0 +1,63 @@
+package audio
+
+import (
+	"runtime/volatile"
+)
+
+// Size of each stream buffer in bytes. It must be a power of two, so that the
+// indices can wrap around.
+const bufferSize = 1024
+
+// RingBuffer is a byte ring buffer that is shared between the USB interrupt
+// and the application. There may be one reader and one writer at a time.
+type RingBuffer struct {
+	buf  [bufferSize]byte
+	head volatile.Register16
+	tail volatile.Register16
+}
+
+// NewRingBuffer returns a new ring buffer.
+func NewRingBuffer() *RingBuffer {
+	return &RingBuffer{}
+}
+
+// Used returns how many bytes in buffer have been used.
+func (rb *RingBuffer) Used() int {
+	return int(uint16(rb.head.Get() - rb.tail.Get()))
+}
+
+// Write stores as many bytes from p in the buffer as fit, and returns the
+// number of bytes stored.
+func (rb *RingBuffer) Write(p []byte) int {
+	n := bufferSize - rb.Used()
+	if n > len(p) {
+		n = len(p)
+	}
+	head := rb.head.Get()
+	for i := 0; i < n; i++ {
+		rb.buf[(head+uint16(i))%bufferSize] = p[i]
+	}
+	rb.head.Set(head + uint16(n))
+	return n
+}
+
+// Read removes up to len(p) bytes from the buffer and stores them in p. It
+// returns the number of bytes read.
+func (rb *RingBuffer) Read(p []byte) int {
+	n := rb.Used()
+	if n > len(p) {
+		n = len(p)
+	}
+	tail := rb.tail.Get()
+	for i := 0; i < n; i++ {
+		p[i] = rb.buf[(tail+uint16(i))%bufferSize]
+	}
+	rb.tail.Set(tail + uint16(n))
+	return n
+}
+
+// Clear resets the head and tail pointer to zero.
+func (rb *RingBuffer) Clear() {
+	rb.head.Set(0)
+	rb.tail.Set(0)
+}
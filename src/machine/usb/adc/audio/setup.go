@@ -0,0 +1,139 @@
+package audio
+
+import (
+	"internal/binary"
+	"machine"
+	"machine/usb"
+	"machine/usb/descriptor"
+)
+
+// Class specific request codes (USB Audio 2.0, appendix A.14).
+const (
+	requestCur   = 0x01
+	requestRange = 0x02
+)
+
+// Control selectors (USB Audio 2.0, appendix A.17).
+const (
+	clockSampleRateControl = 0x01
+	clockValidControl      = 0x02
+
+	featureMuteControl   = 0x01
+	featureVolumeControl = 0x02
+)
+
+// Volume range reported to the host, in 1/256 dB.
+const (
+	volumeMin        = -60 * 256
+	volumeMax        = 0
+	volumeResolution = 256
+)
+
+// setupHandler handles the class specific requests to the entities of the
+// AudioControl interface: the clock source and the feature units.
+func (a *audio) setupHandler(setup usb.Setup) bool {
+	in := setup.BmRequestType&usb.REQUEST_DIRECTION == usb.REQUEST_DEVICETOHOST
+	selector := setup.WValueH
+	channel := setup.WValueL
+	entity := uint8(setup.WIndex >> 8)
+
+	switch entity {
+	case descriptor.AudioClockSourceID:
+		switch {
+		case selector == clockSampleRateControl && setup.BRequest == requestCur && in:
+			binary.LittleEndian.PutUint32(a.setupBuf[:], a.sampleRate)
+			return a.reply(setup, 4)
+		case selector == clockSampleRateControl && setup.BRequest == requestRange && in:
+			// Each sample rate is reported as a range with a single value.
+			binary.LittleEndian.PutUint16(a.setupBuf[:], uint16(len(a.sampleRates)))
+			for i, rate := range a.sampleRates {
+				binary.LittleEndian.PutUint32(a.setupBuf[2+i*12:], rate)
+				binary.LittleEndian.PutUint32(a.setupBuf[6+i*12:], rate)
+				binary.LittleEndian.PutUint32(a.setupBuf[10+i*12:], 0)
+			}
+			return a.reply(setup, 2+12*len(a.sampleRates))
+		case selector == clockSampleRateControl && setup.BRequest == requestCur && !in:
+			b, err := machine.ReceiveUSBControlPacket()
+			if err != nil {
+				return false
+			}
+			rate := binary.LittleEndian.Uint32(b[:])
+			if !a.supportsSampleRate(rate) {
+				return false
+			}
+			a.sampleRate = rate
+			machine.SendZlp()
+			if a.rateHandler != nil {
+				a.rateHandler(rate)
+			}
+			return true
+		case selector == clockValidControl && setup.BRequest == requestCur && in:
+			a.setupBuf[0] = 1
+			return a.reply(setup, 1)
+		}
+
+	case descriptor.AudioInputFeatureID, descriptor.AudioOutputFeatureID:
+		if channel != 0 {
+			// Only the master channel has controls.
+			return false
+		}
+		stream := Microphone
+		if entity == descriptor.AudioOutputFeatureID {
+			stream = Speaker
+		}
+		switch {
+		case selector == featureMuteControl && setup.BRequest == requestCur && in:
+			a.setupBuf[0] = 0
+			if a.mute[stream] {
+				a.setupBuf[0] = 1
+			}
+			return a.reply(setup, 1)
+		case selector == featureVolumeControl && setup.BRequest == requestCur && in:
+			binary.LittleEndian.PutUint16(a.setupBuf[:], uint16(a.volume[stream]))
+			return a.reply(setup, 2)
+		case selector == featureVolumeControl && setup.BRequest == requestRange && in:
+			min := int16(volumeMin)
+			binary.LittleEndian.PutUint16(a.setupBuf[0:], 1)
+			binary.LittleEndian.PutUint16(a.setupBuf[2:], uint16(min))
+			binary.LittleEndian.PutUint16(a.setupBuf[4:], volumeMax)
+			binary.LittleEndian.PutUint16(a.setupBuf[6:], volumeResolution)
+			return a.reply(setup, 8)
+		case (selector == featureMuteControl || selector == featureVolumeControl) && setup.BRequest == requestCur && !in:
+			b, err := machine.ReceiveUSBControlPacket()
+			if err != nil {
+				return false
+			}
+			if selector == featureMuteControl {
+				a.mute[stream] = b[0] != 0
+			} else {
+				a.volume[stream] = int16(binary.LittleEndian.Uint16(b[:]))
+			}
+			machine.SendZlp()
+			if a.volumeHandler != nil {
+				a.volumeHandler(stream, a.volume[stream], a.mute[stream])
+			}
+			return true
+		}
+	}
+
+	return false
+}
+
+// reply sends the first n bytes of the setup buffer, limited to the number of
+// bytes requested by the host.
+func (a *audio) reply(setup usb.Setup, n int) bool {
+	if n > int(setup.WLength) {
+		n = int(setup.WLength)
+	}
+	machine.SendUSBInPacket(0, a.setupBuf[:n])
+	return true
+}
+
+func (a *audio) supportsSampleRate(rate uint32) bool {
+	for _, r := range a.sampleRates {
+		if r == rate {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,459 @@
+package usb
+
+import (
+	"errors"
+	"machine/usb/descriptor"
+)
+
+var (
+	ErrTooManyInterfaces  = errors.New("usb: too many interfaces")
+	ErrTooManyEndpoints   = errors.New("usb: not enough endpoints")
+	ErrEndpointInUse      = errors.New("usb: endpoint number already in use")
+	ErrInterfaceInUse     = errors.New("usb: interface number already in use")
+	ErrIsochronous        = errors.New("usb: isochronous endpoints are not supported on this chip")
+	ErrIsochronousMemory  = errors.New("usb: not enough memory for the isochronous endpoint buffers")
+	ErrMaxPacketSize      = errors.New("usb: endpoint packet size too large")
+	ErrInvalidEndpoint    = errors.New("usb: invalid endpoint number")
+	ErrDescriptorTooLarge = errors.New("usb: configuration descriptor too large")
+)
+
+// Limits describes what the USB device controller of a chip (and its driver
+// in the machine package) supports.
+type Limits struct {
+	// Number of endpoint numbers, including the control endpoint 0. Every
+	// endpoint number is used in a single direction.
+	Endpoints uint8
+
+	// Number of interfaces that can have a setup handler.
+	Interfaces uint8
+
+	// Maximum packet size of the bulk and interrupt endpoints.
+	MaxPacketSize uint16
+
+	// Maximum packet size of the isochronous endpoints, or zero if they are
+	// not supported. Full speed isochronous packets are up to 1023 bytes.
+	MaxIsochronousPacketSize uint16
+
+	// Memory for the buffers of isochronous endpoints with packets larger
+	// than MaxPacketSize, which are rounded up to a multiple of 64 bytes. Zero
+	// means the buffers are allocated on the heap, without a fixed limit.
+	IsochronousMemory uint16
+}
+
+// Limits of the chips with USB device support. The RP2350 has the same limits
+// as the RP2040, which has 4kB of USB memory for all endpoint buffers.
+var (
+	LimitsNRF52840 = Limits{Endpoints: 8, Interfaces: NumberOfInterfaces, MaxPacketSize: EndpointPacketSize}
+	LimitsRP2040   = Limits{Endpoints: 8, Interfaces: NumberOfInterfaces, MaxPacketSize: EndpointPacketSize, MaxIsochronousPacketSize: MaxIsochronousPacketSize, IsochronousMemory: 2816}
+	LimitsSAMD21   = Limits{Endpoints: 8, Interfaces: NumberOfInterfaces, MaxPacketSize: EndpointPacketSize, MaxIsochronousPacketSize: MaxIsochronousPacketSize}
+	LimitsSAMD51   = Limits{Endpoints: 8, Interfaces: NumberOfInterfaces, MaxPacketSize: EndpointPacketSize, MaxIsochronousPacketSize: MaxIsochronousPacketSize}
+)
+
+// Endpoint is an endpoint of an interface in a composite device.
+type Endpoint struct {
+	// Endpoint number. Leave it at zero to have Build allocate a number;
+	// Build stores the allocated number here.
+	Number uint8
+
+	IsIn bool
+	Type uint8 // ENDPOINT_TYPE_BULK, ENDPOINT_TYPE_INTERRUPT or ENDPOINT_TYPE_ISOCHRONOUS
+
+	// Maximum packet size. The default is EndpointPacketSize. Isochronous
+	// endpoints may use packets up to Limits.MaxIsochronousPacketSize.
+	MaxPacketSize uint16
+
+	// Polling interval of interrupt and isochronous endpoints, in frames.
+	Interval uint8
+
+	// ClassSpecific returns the class specific descriptors that follow the
+	// endpoint descriptor, if any. It is called by Build after all numbers
+	// have been allocated.
+	ClassSpecific func() []byte
+
+	TxHandler      func()
+	RxHandler      func([]byte)
+	DelayRxHandler func([]byte) bool
+	StallHandler   func(Setup) bool
+}
+
+// Address returns the endpoint address as used in descriptors: the endpoint
+// number, with the highest bit set for IN endpoints.
+func (e *Endpoint) Address() uint8 {
+	if e.IsIn {
+		return e.Number | EndpointIn
+	}
+	return e.Number
+}
+
+// Interface is an interface of a function in a composite device.
+type Interface struct {
+	// Interface number, allocated by Build.
+	Number uint8
+
+	// Name of the interface, reported to the host in a string descriptor.
+	Name string
+
+	Class    uint8
+	SubClass uint8
+	Protocol uint8
+
+	// ClassSpecific returns the class specific descriptors that follow the
+	// interface descriptor, if any. It is called by Build after all numbers
+	// have been allocated, so it can refer to other interfaces and endpoints.
+	ClassSpecific func() []byte
+
+	Endpoints []*Endpoint
+
+	// IdleAlternate adds an alternate setting 0 without endpoints, and moves
+	// the endpoints to alternate setting 1. Streaming interfaces use this so
+	// the host can disable the stream when it is not in use.
+	IdleAlternate bool
+
+	// Handler of the class specific setup requests to this interface.
+	Setup func(Setup) bool
+
+	// Handler of SET_INTERFACE requests. See SetupConfig.AlternateHandler.
+	Alternate func(alt uint8) bool
+
+	// WinUSB makes Windows use its generic WinUSB driver for the interface,
+	// using the Microsoft OS 2.0 descriptors. This is needed for vendor
+	// specific interfaces, for example to access them with WebUSB.
+	WinUSB bool
+
+	// DeviceInterfaceGUID is the GUID that Windows applications use to find a
+	// WinUSB interface, in the form "{XXXXXXXX-XXXX-XXXX-XXXX-XXXXXXXXXXXX}".
+	DeviceInterfaceGUID string
+
+	fixed bool // Number is not allocated by Build
+}
+
+// Function is a group of interfaces that together implement a single device
+// function, like a CDC serial port with a communications and a data interface.
+// The interfaces of a function get consecutive numbers, and functions with more
+// than one interface get an interface association descriptor.
+type Function struct {
+	// Name of the function, reported to the host in a string descriptor.
+	Name string
+
+	Class    uint8
+	SubClass uint8
+	Protocol uint8
+
+	Interfaces []*Interface
+}
+
+// Composite builds the descriptors and endpoint configuration of a composite
+// device: a device with any combination of functions. Interface and endpoint
+// numbers are allocated by Build, in the order in which the functions were
+// added, and the result is checked against the limits of the chip.
+//
+// Example of a device with a serial port and a vendor specific interface:
+//
+//	c := usb.NewComposite()
+//	c.AddCDC()
+//	in := &usb.Endpoint{IsIn: true, Type: usb.ENDPOINT_TYPE_BULK, TxHandler: tx}
+//	out := &usb.Endpoint{Type: usb.ENDPOINT_TYPE_BULK, RxHandler: rx}
+//	c.AddInterface(&usb.Interface{
+//		Class:     usb.DEVICE_CLASS_VENDOR_SPECIFIC,
+//		Endpoints: []*usb.Endpoint{in, out},
+//	})
+//	err := machine.ConfigureUSBComposite(c)
+type Composite struct {
+	// LandingPage is the URL of a web page to access the device with WebUSB.
+	// Browsers may show it when the device is connected.
+	LandingPage string
+
+	functions []*Function
+}
+
+// NewComposite returns an empty composite device.
+func NewComposite() *Composite {
+	return &Composite{}
+}
+
+// AddFunction adds a function to the device.
+func (c *Composite) AddFunction(f *Function) {
+	c.functions = append(c.functions, f)
+}
+
+// AddInterface adds a function with a single interface to the device.
+func (c *Composite) AddInterface(i *Interface) {
+	c.AddFunction(&Function{
+		Class:      i.Class,
+		SubClass:   i.SubClass,
+		Protocol:   i.Protocol,
+		Interfaces: []*Interface{i},
+	})
+}
+
+// AddCDC adds the CDC-ACM serial port that is used for the USB serial console.
+// It uses the same interface and endpoint numbers as the machine package, so
+// that the handlers of the console keep working.
+func (c *Composite) AddCDC() {
+	data := &Interface{
+		Number: CDC_DATA_INTERFACE,
+		Class:  descriptor.InterfaceClassCDCData,
+		Endpoints: []*Endpoint{
+			{Number: CDC_ENDPOINT_OUT, Type: ENDPOINT_TYPE_BULK},
+			{Number: CDC_ENDPOINT_IN, IsIn: true, Type: ENDPOINT_TYPE_BULK},
+		},
+		fixed: true,
+	}
+	comm := &Interface{
+		Number:   CDC_ACM_INTERFACE,
+		Class:    descriptor.InterfaceClassCDC,
+		SubClass: descriptor.CDCSubclassACM,
+		Protocol: 0x01, // AT commands
+		Endpoints: []*Endpoint{
+			{Number: CDC_ENDPOINT_ACM, IsIn: true, Type: ENDPOINT_TYPE_INTERRUPT, MaxPacketSize: 0x10, Interval: 0x10},
+		},
+		fixed: true,
+	}
+	comm.ClassSpecific = func() []byte {
+		return descriptor.CDCACMFunctional(comm.Number, data.Number)
+	}
+	c.AddFunction(&Function{
+		Class:      descriptor.InterfaceClassCDC,
+		SubClass:   descriptor.CDCSubclassACM,
+		Protocol:   0x01,
+		Interfaces: []*Interface{comm, data},
+	})
+}
+
+// Build allocates the interface and endpoint numbers, and returns the
+// descriptors and the configuration of the endpoints and interfaces, for use
+// with machine.ConfigureUSBEndpoint. It returns an error if the device does
+// not fit within the given limits.
+func (c *Composite) Build(limits Limits) (descriptor.Descriptor, []EndpointConfig, []SetupConfig, error) {
+	var desc descriptor.Descriptor
+	if err := c.allocate(limits); err != nil {
+		return desc, nil, nil, err
+	}
+
+	strings := map[uint8]string{}
+	nextString := uint8(ISERIAL + 1)
+	addString := func(s string) uint8 {
+		if s == "" {
+			return 0
+		}
+		strings[nextString] = s
+		nextString++
+		return nextString - 1
+	}
+
+	numInterfaces := 0
+	var winUSB []descriptor.MSOS20Function
+	var eps []EndpointConfig
+	var setup []SetupConfig
+	b := []byte{
+		9, descriptor.TypeConfiguration,
+		0x00, 0x00, // TotalLength
+		0x00, // NumInterfaces
+		0x01, // ConfigurationValue
+		0x00, // Configuration
+		0xa0, // Attributes: bus powered, remote wakeup
+		0x32, // MaxPower: 100mA
+	}
+	for _, f := range c.functions {
+		if len(f.Interfaces) > 1 {
+			b = append(b,
+				8, descriptor.TypeInterfaceAssociation,
+				f.Interfaces[0].Number,
+				uint8(len(f.Interfaces)),
+				f.Class,
+				f.SubClass,
+				f.Protocol,
+				addString(f.Name),
+			)
+		}
+		for _, i := range f.Interfaces {
+			numInterfaces++
+			name := addString(i.Name)
+			alt := uint8(0)
+			if i.IdleAlternate {
+				b = appendInterface(b, i, 0, 0, name)
+				alt = 1
+			}
+			b = appendInterface(b, i, alt, uint8(len(i.Endpoints)), name)
+			if i.ClassSpecific != nil {
+				b = append(b, i.ClassSpecific()...)
+			}
+			for _, e := range i.Endpoints {
+				b = append(b,
+					7, descriptor.TypeEndpoint,
+					e.Address(),
+					endpointAttributes(e),
+					uint8(e.MaxPacketSize), uint8(e.MaxPacketSize>>8),
+					e.Interval,
+				)
+				if e.ClassSpecific != nil {
+					b = append(b, e.ClassSpecific()...)
+				}
+				eps = append(eps, EndpointConfig{
+					Index:          e.Number,
+					IsIn:           e.IsIn,
+					Type:           e.Type,
+					MaxPacketSize:  e.MaxPacketSize,
+					TxHandler:      e.TxHandler,
+					RxHandler:      e.RxHandler,
+					DelayRxHandler: e.DelayRxHandler,
+					StallHandler:   e.StallHandler,
+				})
+			}
+			if i.WinUSB {
+				winUSB = append(winUSB, descriptor.MSOS20Function{
+					FirstInterface:      i.Number,
+					CompatibleID:        "WINUSB",
+					DeviceInterfaceGUID: i.DeviceInterfaceGUID,
+				})
+			}
+			if i.Setup != nil || i.Alternate != nil {
+				setup = append(setup, SetupConfig{
+					Index:            i.Number,
+					Handler:          i.Setup,
+					AlternateHandler: i.Alternate,
+				})
+			}
+		}
+	}
+	if len(b) > 0xffff {
+		return desc, nil, nil, ErrDescriptorTooLarge
+	}
+	b[2], b[3] = uint8(len(b)), uint8(len(b)>>8)
+	b[4] = uint8(numInterfaces)
+
+	desc.Device = append([]byte(nil), descriptor.DeviceCDC.Bytes()...)
+	desc.Configuration = b
+	if len(strings) != 0 {
+		desc.Strings = strings
+	}
+
+	var capabilities [][]byte
+	if c.LandingPage != "" {
+		desc.WebUSBURL = descriptor.WebUSBURL(c.LandingPage)
+		capabilities = append(capabilities, descriptor.WebUSBCapability(descriptor.WebUSBLandingPage))
+	}
+	if len(winUSB) != 0 {
+		desc.MSOS20 = descriptor.MSOS20DescriptorSet(winUSB)
+		capabilities = append(capabilities, descriptor.MSOS20Capability(uint16(len(desc.MSOS20))))
+	}
+	if len(capabilities) != 0 {
+		desc.BOS = descriptor.BOS(capabilities...)
+	}
+	return desc, eps, setup, nil
+}
+
+// allocate assigns numbers to the interfaces and endpoints that do not have a
+// fixed number, and checks that everything fits within the limits.
+func (c *Composite) allocate(limits Limits) error {
+	var usedInterfaces, usedEndpoints uint32
+	usedEndpoints |= 1 << CONTROL_ENDPOINT
+
+	// Fixed interfaces and endpoints first.
+	for _, f := range c.functions {
+		for _, i := range f.Interfaces {
+			if i.fixed {
+				if usedInterfaces&(1<<i.Number) != 0 {
+					return ErrInterfaceInUse
+				}
+				usedInterfaces |= 1 << i.Number
+			}
+			for _, e := range i.Endpoints {
+				if e.Number == 0 {
+					continue
+				}
+				if e.Number >= limits.Endpoints {
+					return ErrInvalidEndpoint
+				}
+				if usedEndpoints&(1<<e.Number) != 0 {
+					return ErrEndpointInUse
+				}
+				usedEndpoints |= 1 << e.Number
+			}
+		}
+	}
+
+	// The interfaces of a function must be consecutive, so each function
+	// gets the first free range that is large enough.
+	for _, f := range c.functions {
+		if len(f.Interfaces) == 0 || f.Interfaces[0].fixed {
+			continue
+		}
+		n := uint8(len(f.Interfaces))
+		first := uint8(0)
+		for usedInterfaces&(((1<<n)-1)<<first) != 0 {
+			first++
+		}
+		if first+n > limits.Interfaces {
+			return ErrTooManyInterfaces
+		}
+		for k, i := range f.Interfaces {
+			i.Number = first + uint8(k)
+			usedInterfaces |= 1 << i.Number
+		}
+	}
+
+	var isochronousMemory int
+	for _, f := range c.functions {
+		for _, i := range f.Interfaces {
+			if i.Number >= limits.Interfaces {
+				return ErrTooManyInterfaces
+			}
+			for _, e := range i.Endpoints {
+				if e.MaxPacketSize == 0 {
+					e.MaxPacketSize = EndpointPacketSize
+				}
+				if e.Type == ENDPOINT_TYPE_ISOCHRONOUS {
+					if limits.MaxIsochronousPacketSize == 0 {
+						return ErrIsochronous
+					}
+					if e.MaxPacketSize > limits.MaxIsochronousPacketSize {
+						return ErrMaxPacketSize
+					}
+					if e.MaxPacketSize > limits.MaxPacketSize {
+						isochronousMemory += (int(e.MaxPacketSize) + 63) &^ 63
+					}
+				} else if e.MaxPacketSize > limits.MaxPacketSize {
+					return ErrMaxPacketSize
+				}
+				if e.Number != 0 {
+					continue
+				}
+				e.Number = 1
+				for usedEndpoints&(1<<e.Number) != 0 {
+					e.Number++
+				}
+				if e.Number >= limits.Endpoints {
+					return ErrTooManyEndpoints
+				}
+				usedEndpoints |= 1 << e.Number
+			}
+		}
+	}
+	if limits.IsochronousMemory != 0 && isochronousMemory > int(limits.IsochronousMemory) {
+		return ErrIsochronousMemory
+	}
+	return nil
+}
+
+func appendInterface(b []byte, i *Interface, alt, endpoints, name uint8) []byte {
+	return append(b,
+		9, descriptor.TypeInterface,
+		i.Number,
+		alt,
+		endpoints,
+		i.Class,
+		i.SubClass,
+		i.Protocol,
+		name,
+	)
+}
+
+// endpointAttributes returns the bmAttributes field of the endpoint
+// descriptor. Isochronous endpoints are asynchronous.
+func endpointAttributes(e *Endpoint) uint8 {
+	if e.Type == ENDPOINT_TYPE_ISOCHRONOUS {
+		return e.Type | 0x04
+	}
+	return e.Type
+}
@@ -0,0 +1,247 @@
+package usb
+
+import (
+	"machine/usb/descriptor"
+	"testing"
+)
+
+// testLimits are the limits of a typical full speed device controller.
+var testLimits = Limits{Endpoints: 8, Interfaces: NumberOfInterfaces, MaxPacketSize: EndpointPacketSize, MaxIsochronousPacketSize: MaxIsochronousPacketSize}
+
+func vendorInterface(endpoints ...*Endpoint) *Interface {
+	return &Interface{
+		Class:     DEVICE_CLASS_VENDOR_SPECIFIC,
+		Endpoints: endpoints,
+	}
+}
+
+func bulkIn() *Endpoint  { return &Endpoint{IsIn: true, Type: ENDPOINT_TYPE_BULK} }
+func bulkOut() *Endpoint { return &Endpoint{Type: ENDPOINT_TYPE_BULK} }
+
+// splitDescriptors splits a configuration descriptor into its descriptors.
+func splitDescriptors(t *testing.T, b []byte) [][]byte {
+	t.Helper()
+	var descs [][]byte
+	for len(b) != 0 {
+		n := int(b[0])
+		if n < 2 || n > len(b) {
+			t.Fatalf("invalid descriptor length %d with %d bytes left", n, len(b))
+		}
+		descs = append(descs, b[:n])
+		b = b[n:]
+	}
+	return descs
+}
+
+func TestCompositeCDCEndpoints(t *testing.T) {
+	// The console must keep its numbers, even if it is not the first function.
+	vendor := vendorInterface(bulkIn(), bulkOut())
+	c := NewComposite()
+	c.AddInterface(vendor)
+	c.AddCDC()
+	_, eps, _, err := c.Build(testLimits)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	cdc := c.functions[1].Interfaces
+	if cdc[0].Number != CDC_ACM_INTERFACE || cdc[1].Number != CDC_DATA_INTERFACE {
+		t.Errorf("CDC interfaces are %d and %d, expected %d and %d",
+			cdc[0].Number, cdc[1].Number, CDC_ACM_INTERFACE, CDC_DATA_INTERFACE)
+	}
+	if vendor.Number != 2 {
+		t.Errorf("vendor interface is %d, expected 2", vendor.Number)
+	}
+
+	want := map[uint8]struct {
+		isIn bool
+		typ  uint8
+	}{
+		CDC_ENDPOINT_ACM: {true, ENDPOINT_TYPE_INTERRUPT},
+		CDC_ENDPOINT_OUT: {false, ENDPOINT_TYPE_BULK},
+		CDC_ENDPOINT_IN:  {true, ENDPOINT_TYPE_BULK},
+	}
+	found := 0
+	for _, ep := range eps {
+		w, ok := want[ep.Index]
+		if !ok {
+			continue
+		}
+		found++
+		if ep.IsIn != w.isIn || ep.Type != w.typ {
+			t.Errorf("endpoint %d: IsIn=%v Type=%d, expected IsIn=%v Type=%d",
+				ep.Index, ep.IsIn, ep.Type, w.isIn, w.typ)
+		}
+	}
+	if found != len(want) {
+		t.Errorf("found %d of the %d CDC endpoints", found, len(want))
+	}
+}
+
+func TestCompositeAllocate(t *testing.T) {
+	in, out := bulkIn(), bulkOut()
+	fixed := &Endpoint{Number: 6, IsIn: true, Type: ENDPOINT_TYPE_INTERRUPT}
+	pair := &Function{
+		Class: DEVICE_CLASS_VENDOR_SPECIFIC,
+		Interfaces: []*Interface{
+			vendorInterface(fixed),
+			vendorInterface(),
+		},
+	}
+	c := NewComposite()
+	c.AddCDC()
+	c.AddInterface(vendorInterface(in, out))
+	c.AddFunction(pair)
+	_, eps, _, err := c.Build(testLimits)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// Endpoints 1-3 are used by the console, so the other endpoints get the
+	// first free numbers, skipping the fixed endpoint.
+	if in.Number != 4 || out.Number != 5 {
+		t.Errorf("endpoints are %d and %d, expected 4 and 5", in.Number, out.Number)
+	}
+	if fixed.Number != 6 {
+		t.Errorf("fixed endpoint moved to %d", fixed.Number)
+	}
+	if in.MaxPacketSize != EndpointPacketSize {
+		t.Errorf("default packet size is %d, expected %d", in.MaxPacketSize, EndpointPacketSize)
+	}
+	if in.Address() != 4|EndpointIn || out.Address() != 5 {
+		t.Errorf("endpoint addresses are %#x and %#x", in.Address(), out.Address())
+	}
+	if n := pair.Interfaces; n[0].Number != 3 || n[1].Number != 4 {
+		t.Errorf("interfaces of the function are %d and %d, expected 3 and 4", n[0].Number, n[1].Number)
+	}
+	if len(eps) != 6 {
+		t.Errorf("got %d endpoint configs, expected 6", len(eps))
+	}
+}
+
+func TestCompositeDescriptor(t *testing.T) {
+	c := NewComposite()
+	c.AddCDC()
+	c.AddInterface(&Interface{
+		Name:          "Stream",
+		Class:         DEVICE_CLASS_VENDOR_SPECIFIC,
+		IdleAlternate: true,
+		Endpoints: []*Endpoint{
+			{IsIn: true, Type: ENDPOINT_TYPE_ISOCHRONOUS, Interval: 1},
+		},
+	})
+	desc, _, _, err := c.Build(testLimits)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	b := desc.Configuration
+	if total := int(b[2]) | int(b[3])<<8; total != len(b) {
+		t.Errorf("wTotalLength is %d, expected %d", total, len(b))
+	}
+	if b[4] != 3 {
+		t.Errorf("bNumInterfaces is %d, expected 3", b[4])
+	}
+
+	var types []uint8
+	for _, d := range splitDescriptors(t, b) {
+		switch d[1] {
+		case descriptor.TypeInterfaceAssociation:
+			if d[2] != CDC_ACM_INTERFACE || d[3] != 2 {
+				t.Errorf("association covers %d interfaces from %d, expected 2 from %d", d[3], d[2], CDC_ACM_INTERFACE)
+			}
+		case descriptor.TypeEndpoint:
+			if d[2]&^EndpointIn == 0 {
+				t.Errorf("endpoint descriptor without a number: %v", d)
+			}
+		}
+		if d[1] != descriptor.TypeClassSpecific {
+			types = append(types, d[1])
+		}
+	}
+
+	// The association comes before the CDC interfaces, and the single
+	// interface function has none. The streaming interface has an idle
+	// alternate setting without endpoints.
+	want := []uint8{
+		descriptor.TypeConfiguration,
+		descriptor.TypeInterfaceAssociation,
+		descriptor.TypeInterface, descriptor.TypeEndpoint,
+		descriptor.TypeInterface, descriptor.TypeEndpoint, descriptor.TypeEndpoint,
+		descriptor.TypeInterface,
+		descriptor.TypeInterface, descriptor.TypeEndpoint,
+	}
+	if len(types) != len(want) {
+		t.Fatalf("descriptor types are %v, expected %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("descriptor types are %v, expected %v", types, want)
+		}
+	}
+
+	if desc.Strings[ISERIAL+1] != "Stream" {
+		t.Errorf("interface name not in the string descriptors: %v", desc.Strings)
+	}
+}
+
+func TestCompositeLimits(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		limits Limits
+		add    func(c *Composite)
+		err    error
+	}{
+		{"too many endpoints", testLimits, func(c *Composite) {
+			// Endpoints 4-7 are free, one endpoint too few.
+			c.AddInterface(vendorInterface(bulkIn(), bulkOut(), bulkIn(), bulkOut(), bulkIn()))
+		}, ErrTooManyEndpoints},
+		{"all endpoints", testLimits, func(c *Composite) {
+			c.AddInterface(vendorInterface(bulkIn(), bulkOut(), bulkIn(), bulkOut()))
+		}, nil},
+		{"endpoint in use", testLimits, func(c *Composite) {
+			c.AddInterface(vendorInterface(&Endpoint{Number: CDC_ENDPOINT_IN, IsIn: true, Type: ENDPOINT_TYPE_BULK}))
+		}, ErrEndpointInUse},
+		{"invalid endpoint", testLimits, func(c *Composite) {
+			c.AddInterface(vendorInterface(&Endpoint{Number: 8, Type: ENDPOINT_TYPE_BULK}))
+		}, ErrInvalidEndpoint},
+		{"too many interfaces", testLimits, func(c *Composite) {
+			for i := 0; i < NumberOfInterfaces-1; i++ {
+				c.AddInterface(vendorInterface())
+			}
+		}, ErrTooManyInterfaces},
+		{"isochronous", LimitsNRF52840, func(c *Composite) {
+			c.AddInterface(vendorInterface(&Endpoint{IsIn: true, Type: ENDPOINT_TYPE_ISOCHRONOUS}))
+		}, ErrIsochronous},
+		{"packet size", testLimits, func(c *Composite) {
+			c.AddInterface(vendorInterface(&Endpoint{Type: ENDPOINT_TYPE_BULK, MaxPacketSize: testLimits.MaxPacketSize + 1}))
+		}, ErrMaxPacketSize},
+		{"isochronous packet size", testLimits, func(c *Composite) {
+			c.AddInterface(vendorInterface(&Endpoint{IsIn: true, Type: ENDPOINT_TYPE_ISOCHRONOUS, MaxPacketSize: MaxIsochronousPacketSize}))
+		}, nil},
+		{"isochronous packet too large", testLimits, func(c *Composite) {
+			c.AddInterface(vendorInterface(&Endpoint{IsIn: true, Type: ENDPOINT_TYPE_ISOCHRONOUS, MaxPacketSize: MaxIsochronousPacketSize + 1}))
+		}, ErrMaxPacketSize},
+		{"isochronous memory", LimitsRP2040, func(c *Composite) {
+			c.AddInterface(vendorInterface(
+				&Endpoint{IsIn: true, Type: ENDPOINT_TYPE_ISOCHRONOUS, MaxPacketSize: MaxIsochronousPacketSize},
+				&Endpoint{Type: ENDPOINT_TYPE_ISOCHRONOUS, MaxPacketSize: MaxIsochronousPacketSize},
+			))
+		}, nil},
+		{"isochronous memory exhausted", LimitsRP2040, func(c *Composite) {
+			c.AddInterface(vendorInterface(
+				&Endpoint{IsIn: true, Type: ENDPOINT_TYPE_ISOCHRONOUS, MaxPacketSize: MaxIsochronousPacketSize},
+				&Endpoint{Type: ENDPOINT_TYPE_ISOCHRONOUS, MaxPacketSize: MaxIsochronousPacketSize},
+				&Endpoint{IsIn: true, Type: ENDPOINT_TYPE_ISOCHRONOUS, MaxPacketSize: 800},
+			))
+		}, ErrIsochronousMemory},
+	} {
+		c := NewComposite()
+		c.AddCDC()
+		tc.add(c)
+		_, _, _, err := c.Build(tc.limits)
+		if err != tc.err {
+			t.Errorf("%s: got error %v, expected %v", tc.name, err, tc.err)
+		}
+	}
+}
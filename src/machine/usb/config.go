@@ -8,9 +8,19 @@ type EndpointConfig struct {
 	DelayRxHandler func([]byte) bool
 	StallHandler   func(Setup) bool
 	Type           uint8
+
+	// MaxPacketSize is the maximum packet size of the endpoint. The default
+	// is EndpointPacketSize. Only isochronous endpoints support larger
+	// packets, up to MaxIsochronousPacketSize.
+	MaxPacketSize uint16
 }
 
 type SetupConfig struct {
 	Index   uint8
 	Handler func(Setup) bool
+
+	// AlternateHandler is called when the host selects an alternate setting
+	// of the interface with SET_INTERFACE. It returns false if the alternate
+	// setting is not supported.
+	AlternateHandler func(alt uint8) bool
 }
@@ -0,0 +1,281 @@
+package descriptor
+
+import (
+	"internal/binary"
+)
+
+/* USB Audio Class 2.0
+USB Device Class Definition for Audio Devices, Release 2.0
+*/
+
+const (
+	interfaceClassAudio             = 0x01
+	audioSubclassControl            = 0x01
+	audioSubclassStreaming          = 0x02
+	audioFunctionProtocolUAC2       = 0x20
+	audioFunctionSubclassUndef      = 0x00
+	audioFunctionCategoryIOBox      = 0x08
+	audioFunctionCategoryMicrophone = 0x03
+	audioFunctionCategorySpeaker    = 0x01
+
+	// Class-specific AudioControl interface descriptor subtypes.
+	audioACHeader         = 0x01
+	audioACInputTerminal  = 0x02
+	audioACOutputTerminal = 0x03
+	audioACFeatureUnit    = 0x06
+	audioACClockSource    = 0x0a
+
+	// Class-specific AudioStreaming interface descriptor subtypes.
+	audioASGeneral    = 0x01
+	audioASFormatType = 0x02
+
+	// Class-specific endpoint descriptor subtype.
+	audioEPGeneral = 0x01
+
+	audioFormatTypeI = 0x01
+	audioFormatPCM   = 0x00000001
+
+	// Terminal types (Universal Serial Bus Device Class Definition for
+	// Terminal Types, Release 2.0).
+	audioTerminalUSBStreaming = 0x0101
+	audioTerminalMicrophone   = 0x0201
+	audioTerminalSpeaker      = 0x0301
+)
+
+// Entity IDs of the audio function built by AudioConfig. Class specific
+// requests address these in the high byte of wIndex.
+const (
+	AudioClockSourceID = 1 // clock source, shared by both directions
+
+	AudioInputTerminalID  = 2 // microphone
+	AudioInputFeatureID   = 3 // volume and mute of the microphone
+	AudioInputStreamingID = 4 // USB streaming terminal of the microphone
+
+	AudioOutputStreamingID = 5 // USB streaming terminal of the speaker
+	AudioOutputFeatureID   = 6 // volume and mute of the speaker
+	AudioOutputTerminalID  = 7 // speaker
+)
+
+// AudioConfig describes a USB Audio Class 2.0 function with an optional
+// microphone (isochronous IN) and an optional speaker (isochronous OUT)
+// stream. Both streams use the same clock and the same PCM sample format.
+type AudioConfig struct {
+	// First interface number of the function. The AudioControl interface
+	// uses this number, followed by the AudioStreaming interfaces of the
+	// microphone (if any) and the speaker (if any).
+	FirstInterface uint8
+
+	// Number of channels of each stream. A zero value disables the stream.
+	InputChannels  uint8
+	OutputChannels uint8
+
+	// Number of bytes per sample (2, 3 or 4), and the number of bits that
+	// are actually used.
+	SubslotSize   uint8
+	BitResolution uint8
+
+	// Endpoint addresses and maximum packet size of the isochronous
+	// endpoints.
+	EndpointIn    uint8
+	EndpointOut   uint8
+	MaxPacketSize uint16
+}
+
+// NumInterfaces returns the number of interfaces used by the audio function.
+func (c AudioConfig) NumInterfaces() uint8 {
+	n := uint8(1)
+	if c.InputChannels != 0 {
+		n++
+	}
+	if c.OutputChannels != 0 {
+		n++
+	}
+	return n
+}
+
+// Bytes returns the interface association descriptor followed by all
+// interface, class specific and endpoint descriptors of the audio function.
+func (c AudioConfig) Bytes() []byte {
+	category := uint8(audioFunctionCategoryIOBox)
+	switch {
+	case c.OutputChannels == 0:
+		category = audioFunctionCategoryMicrophone
+	case c.InputChannels == 0:
+		category = audioFunctionCategorySpeaker
+	}
+
+	// The class specific AudioControl descriptors: a clock source, and a
+	// chain of input terminal, feature unit and output terminal per stream.
+	var ac []byte
+	ac = append(ac,
+		8, TypeClassSpecific, audioACClockSource,
+		AudioClockSourceID,
+		0x03, // internal programmable clock
+		0x07, // frequency control read/write, validity read-only
+		0x00, // associated terminal
+		0x00, // clock source string
+	)
+	if c.InputChannels != 0 {
+		ac = appendAudioInputTerminal(ac, AudioInputTerminalID, audioTerminalMicrophone, c.InputChannels)
+		ac = appendAudioFeatureUnit(ac, AudioInputFeatureID, AudioInputTerminalID, c.InputChannels)
+		ac = appendAudioOutputTerminal(ac, AudioInputStreamingID, audioTerminalUSBStreaming, AudioInputFeatureID)
+	}
+	if c.OutputChannels != 0 {
+		ac = appendAudioInputTerminal(ac, AudioOutputStreamingID, audioTerminalUSBStreaming, c.OutputChannels)
+		ac = appendAudioFeatureUnit(ac, AudioOutputFeatureID, AudioOutputStreamingID, c.OutputChannels)
+		ac = appendAudioOutputTerminal(ac, AudioOutputTerminalID, audioTerminalSpeaker, AudioOutputFeatureID)
+	}
+
+	var b []byte
+	b = append(b,
+		interfaceAssociationTypeLen, TypeInterfaceAssociation,
+		c.FirstInterface,
+		c.NumInterfaces(),
+		interfaceClassAudio,
+		audioFunctionSubclassUndef,
+		audioFunctionProtocolUAC2,
+		0x00, // Function
+	)
+	b = appendAudioInterface(b, c.FirstInterface, 0, 0, audioSubclassControl)
+	b = append(b, 9, TypeClassSpecific, audioACHeader, 0x00, 0x02, category, 0, 0, 0x00)
+	binary.LittleEndian.PutUint16(b[len(b)-3:], uint16(9+len(ac)))
+	b = append(b, ac...)
+
+	iface := c.FirstInterface + 1
+	if c.InputChannels != 0 {
+		b = c.appendStreaming(b, iface, AudioInputStreamingID, c.InputChannels, c.EndpointIn|0x80, 0x05) // asynchronous
+		iface++
+	}
+	if c.OutputChannels != 0 {
+		b = c.appendStreaming(b, iface, AudioOutputStreamingID, c.OutputChannels, c.EndpointOut&0x7f, 0x09) // adaptive
+	}
+	return b
+}
+
+// appendStreaming appends an AudioStreaming interface, with an idle alternate
+// setting 0 and a streaming alternate setting 1.
+func (c AudioConfig) appendStreaming(b []byte, iface, terminal, channels, endpoint, attributes uint8) []byte {
+	b = appendAudioInterface(b, iface, 0, 0, audioSubclassStreaming)
+	b = appendAudioInterface(b, iface, 1, 1, audioSubclassStreaming)
+	b = append(b,
+		16, TypeClassSpecific, audioASGeneral,
+		terminal,
+		0x00, // controls
+		audioFormatTypeI,
+		0, 0, 0, 0, // formats
+		channels,
+		0, 0, 0, 0, // channel config
+		0x00, // channel names
+	)
+	binary.LittleEndian.PutUint32(b[len(b)-10:], audioFormatPCM)
+	binary.LittleEndian.PutUint32(b[len(b)-5:], audioChannelConfig(channels))
+	b = append(b, 6, TypeClassSpecific, audioASFormatType, audioFormatTypeI, c.SubslotSize, c.BitResolution)
+	b = append(b,
+		endpointTypeLen, TypeEndpoint,
+		endpoint,
+		attributes,
+		0, 0, // MaxPacketSize
+		0x01, // Interval: every frame
+	)
+	binary.LittleEndian.PutUint16(b[len(b)-3:], c.MaxPacketSize)
+	b = append(b,
+		8, TypeClassSpecificEndpoint, audioEPGeneral,
+		0x00,       // attributes
+		0x00,       // controls
+		0x00,       // lock delay units
+		0x00, 0x00, // lock delay
+	)
+	return b
+}
+
+func appendAudioInterface(b []byte, number, alt, endpoints, subclass uint8) []byte {
+	return append(b,
+		interfaceTypeLen, TypeInterface,
+		number,
+		alt,
+		endpoints,
+		interfaceClassAudio,
+		subclass,
+		audioFunctionProtocolUAC2,
+		0x00, // Interface
+	)
+}
+
+func appendAudioInputTerminal(b []byte, id uint8, terminalType uint16, channels uint8) []byte {
+	b = append(b,
+		17, TypeClassSpecific, audioACInputTerminal,
+		id,
+		byte(terminalType), byte(terminalType>>8),
+		0x00, // associated terminal
+		AudioClockSourceID,
+		channels,
+		0, 0, 0, 0, // channel config
+		0x00,       // channel names
+		0x00, 0x00, // controls
+		0x00, // terminal string
+	)
+	binary.LittleEndian.PutUint32(b[len(b)-8:], audioChannelConfig(channels))
+	return b
+}
+
+func appendAudioOutputTerminal(b []byte, id uint8, terminalType uint16, source uint8) []byte {
+	return append(b,
+		12, TypeClassSpecific, audioACOutputTerminal,
+		id,
+		byte(terminalType), byte(terminalType>>8),
+		0x00, // associated terminal
+		source,
+		AudioClockSourceID,
+		0x00, 0x00, // controls
+		0x00, // terminal string
+	)
+}
+
+// appendAudioFeatureUnit appends a feature unit with mute and volume controls
+// on the master channel only.
+func appendAudioFeatureUnit(b []byte, id, source, channels uint8) []byte {
+	b = append(b,
+		6+(channels+1)*4, TypeClassSpecific, audioACFeatureUnit,
+		id,
+		source,
+		0x0f, 0x00, 0x00, 0x00, // master: mute and volume read/write
+	)
+	for i := uint8(0); i < channels; i++ {
+		b = append(b, 0x00, 0x00, 0x00, 0x00)
+	}
+	return append(b, 0x00) // feature unit string
+}
+
+// audioChannelConfig returns the spatial locations of the channels: front
+// left and front right for stereo, and none (mono) otherwise.
+func audioChannelConfig(channels uint8) uint32 {
+	if channels == 2 {
+		return 0x3
+	}
+	return 0
+}
+
+// CDCAudio returns a descriptor for a composite device with a CDC serial port
+// and the given audio function, which must start at interface 2.
+func CDCAudio(config AudioConfig) Descriptor {
+	conf := ConfigurationType{data: make([]byte, configurationTypeLen)}
+	copy(conf.data, configurationCDC[:])
+	conf.NumInterfaces(2 + config.NumInterfaces())
+	return Descriptor{
+		Device: DeviceCDC.Bytes(),
+		Configuration: Append([][]byte{
+			conf.Bytes(),
+			InterfaceAssociationCDC.Bytes(),
+			InterfaceCDCControl.Bytes(),
+			ClassSpecificCDCHeader.Bytes(),
+			ClassSpecificCDCACM.Bytes(),
+			ClassSpecificCDCUnion.Bytes(),
+			ClassSpecificCDCCallManagement.Bytes(),
+			EndpointEP1IN.Bytes(),
+			InterfaceCDCData.Bytes(),
+			EndpointEP2OUT.Bytes(),
+			EndpointEP3IN.Bytes(),
+			config.Bytes(),
+		}),
+	}
+}
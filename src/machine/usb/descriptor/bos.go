@@ -0,0 +1,201 @@
+package descriptor
+
+import (
+	"internal/binary"
+)
+
+/* Binary device object store (BOS), WebUSB and Microsoft OS 2.0 descriptors
+Universal Serial Bus 3.2 Specification, section 9.6.2
+WebUSB API, Draft Community Group Report, section 4
+Microsoft OS 2.0 Descriptors Specification, July 2018
+*/
+
+// Vendor request codes of the WebUSB and MS OS 2.0 requests. They are
+// reported to the host in the platform capability descriptors.
+const (
+	WebUSBVendorCode = 0x01
+	MSOS20VendorCode = 0x02
+)
+
+// Values of wIndex that identify the WebUSB and MS OS 2.0 vendor requests.
+const (
+	WebUSBRequestGetURL     = 0x02
+	MSOS20RequestDescriptor = 0x07
+)
+
+// WebUSBLandingPage is the index of the URL descriptor of the landing page.
+const WebUSBLandingPage = 1
+
+const (
+	bosTypeLen                 = 5
+	deviceCapabilityPlatform   = 0x05
+	platformCapabilityLen      = 20 // without the capability specific data
+	webUSBTypeURL              = 0x03
+	msos20SetHeader            = 0x00
+	msos20SubsetConfiguration  = 0x01
+	msos20SubsetFunction       = 0x02
+	msos20FeatureCompatibleID  = 0x03
+	msos20FeatureRegProperty   = 0x04
+	msos20RegMultiSZ           = 0x07
+	msos20SetHeaderLen         = 10
+	msos20SubsetHeaderLen      = 8
+	msos20CompatibleIDLen      = 20
+	msos20RegPropertyHeaderLen = 10
+)
+
+// Platform capability UUIDs, in the byte order of the descriptors.
+var (
+	webUSBPlatformUUID = [16]byte{
+		0x38, 0xb6, 0x08, 0x34, 0xa9, 0x09, 0xa0, 0x47,
+		0x8b, 0xfd, 0xa0, 0x76, 0x88, 0x15, 0xb6, 0x65,
+	}
+	msos20PlatformUUID = [16]byte{
+		0xdf, 0x60, 0xdd, 0xd8, 0x89, 0x45, 0xc7, 0x4c,
+		0x9c, 0xd2, 0x65, 0x9d, 0x9e, 0x64, 0x8a, 0x9f,
+	}
+)
+
+// BOS returns a binary device object store descriptor with the given device
+// capability descriptors.
+func BOS(capabilities ...[]byte) []byte {
+	b := []byte{
+		bosTypeLen, TypeBOS,
+		0x00, 0x00, // TotalLength
+		uint8(len(capabilities)),
+	}
+	for _, c := range capabilities {
+		b = append(b, c...)
+	}
+	binary.LittleEndian.PutUint16(b[2:], uint16(len(b)))
+	return b
+}
+
+func platformCapability(uuid [16]byte, data ...byte) []byte {
+	b := []byte{
+		uint8(platformCapabilityLen + len(data)),
+		TypeDeviceCapability,
+		deviceCapabilityPlatform,
+		0x00, // reserved
+	}
+	b = append(b, uuid[:]...)
+	return append(b, data...)
+}
+
+// WebUSBCapability returns the WebUSB platform capability descriptor. The
+// landing page is the index of a URL descriptor, or zero for none.
+func WebUSBCapability(landingPage uint8) []byte {
+	return platformCapability(webUSBPlatformUUID,
+		0x00, 0x01, // WebUSB 1.0
+		WebUSBVendorCode,
+		landingPage,
+	)
+}
+
+// MSOS20Capability returns the Microsoft OS 2.0 platform capability
+// descriptor, for a descriptor set of the given length.
+func MSOS20Capability(setLength uint16) []byte {
+	return platformCapability(msos20PlatformUUID,
+		0x00, 0x00, 0x03, 0x06, // Windows 8.1
+		uint8(setLength), uint8(setLength>>8),
+		MSOS20VendorCode,
+		0x00, // no alternate enumeration
+	)
+}
+
+// WebUSBURL returns a WebUSB URL descriptor for the given URL. An http:// or
+// https:// prefix is stored in the scheme field of the descriptor.
+func WebUSBURL(url string) []byte {
+	scheme := uint8(0xff) // the URL includes the scheme
+	switch {
+	case len(url) > 8 && url[:8] == "https://":
+		scheme, url = 0x01, url[8:]
+	case len(url) > 7 && url[:7] == "http://":
+		scheme, url = 0x00, url[7:]
+	}
+	b := []byte{uint8(3 + len(url)), webUSBTypeURL, scheme}
+	return append(b, url...)
+}
+
+// MSOS20Function describes the Windows driver of a function in an MS OS 2.0
+// descriptor set.
+type MSOS20Function struct {
+	// First interface of the function.
+	FirstInterface uint8
+
+	// Compatible ID of the driver, for example "WINUSB".
+	CompatibleID string
+
+	// Device interface GUID that applications use to find the function, in
+	// the form "{XXXXXXXX-XXXX-XXXX-XXXX-XXXXXXXXXXXX}". It is optional.
+	DeviceInterfaceGUID string
+}
+
+// MSOS20DescriptorSet returns a Microsoft OS 2.0 descriptor set for a
+// composite device, with the given functions in the first configuration.
+func MSOS20DescriptorSet(functions []MSOS20Function) []byte {
+	b := []byte{
+		msos20SetHeaderLen, 0x00,
+		msos20SetHeader, 0x00,
+		0x00, 0x00, 0x03, 0x06, // Windows 8.1
+		0x00, 0x00, // TotalLength
+	}
+	conf := len(b)
+	b = append(b,
+		msos20SubsetHeaderLen, 0x00,
+		msos20SubsetConfiguration, 0x00,
+		0x00,       // configuration index
+		0x00,       // reserved
+		0x00, 0x00, // SubsetLength
+	)
+	for _, f := range functions {
+		fn := len(b)
+		b = append(b,
+			msos20SubsetHeaderLen, 0x00,
+			msos20SubsetFunction, 0x00,
+			f.FirstInterface,
+			0x00,       // reserved
+			0x00, 0x00, // SubsetLength
+		)
+		var id [16]byte // compatible ID and sub-compatible ID
+		copy(id[:8], f.CompatibleID)
+		b = append(b,
+			msos20CompatibleIDLen, 0x00,
+			msos20FeatureCompatibleID, 0x00,
+		)
+		b = append(b, id[:]...)
+		if f.DeviceInterfaceGUID != "" {
+			b = appendMSOS20RegProperty(b, "DeviceInterfaceGUIDs", f.DeviceInterfaceGUID)
+		}
+		binary.LittleEndian.PutUint16(b[fn+6:], uint16(len(b)-fn))
+	}
+	binary.LittleEndian.PutUint16(b[conf+6:], uint16(len(b)-conf))
+	binary.LittleEndian.PutUint16(b[8:], uint16(len(b)))
+	return b
+}
+
+// appendMSOS20RegProperty appends a registry property feature descriptor with
+// a single string in a REG_MULTI_SZ value.
+func appendMSOS20RegProperty(b []byte, name, value string) []byte {
+	nameLen := (len(name) + 1) * 2
+	valueLen := (len(value) + 2) * 2
+	b = append(b,
+		0x00, 0x00, // Length
+		msos20FeatureRegProperty, 0x00,
+		msos20RegMultiSZ, 0x00,
+		uint8(nameLen), uint8(nameLen>>8),
+	)
+	binary.LittleEndian.PutUint16(b[len(b)-8:], uint16(msos20RegPropertyHeaderLen+nameLen+valueLen))
+	b = appendUTF16(b, name)
+	b = append(b, 0, 0)
+	b = append(b, uint8(valueLen), uint8(valueLen>>8))
+	b = appendUTF16(b, value)
+	return append(b, 0, 0, 0, 0)
+}
+
+// appendUTF16 appends an ASCII string as UTF-16LE.
+func appendUTF16(b []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		b = append(b, s[i], 0)
+	}
+	return b
+}
@@ -20,6 +20,24 @@ const (
 	cdcFunctionalATM            = 0x10
 )
 
+const (
+	InterfaceClassCDC     = 0x02
+	InterfaceClassCDCData = 0x0a
+	CDCSubclassACM        = 0x02
+)
+
+// CDCACMFunctional returns the class specific descriptors of the
+// communications interface of a CDC-ACM function, with the given
+// communications and data interface numbers.
+func CDCACMFunctional(comm, data uint8) []byte {
+	return []byte{
+		classSpecificTypeLen, TypeClassSpecific, cdcFunctionalHeader, 0x10, 0x01,
+		classSpecificTypeLen, TypeClassSpecific, cdcFunctionalCallManagement, 0x00, data,
+		4, TypeClassSpecific, cdcFunctionalACM, 0x02,
+		classSpecificTypeLen, TypeClassSpecific, cdcFunctionalUnion, comm, data,
+	}
+}
+
 var classSpecificCDCHeader = [classSpecificTypeLen]byte{
 	classSpecificTypeLen,
 	TypeClassSpecific,
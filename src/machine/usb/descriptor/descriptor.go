@@ -12,6 +12,8 @@ const (
 	TypeEndpoint              = 0x5
 	TypeDeviceQualifier       = 0x6
 	TypeInterfaceAssociation  = 0xb
+	TypeBOS                   = 0xf
+	TypeDeviceCapability      = 0x10
 	TypeClassHID              = 0x21
 	TypeHIDReport             = 0x22
 	TypeClassSpecific         = 0x24
@@ -35,12 +37,33 @@ type Descriptor struct {
 	Device        []byte
 	Configuration []byte
 	HID           map[uint16][]byte
+
+	// Strings holds additional string descriptors, by index, that are
+	// referenced by the configuration (for example the MAC address of a
+	// network function).
+	Strings map[uint8]string
+
+	// BOS is the binary device object store with the device capabilities,
+	// if any, as built by BOS.
+	BOS []byte
+
+	// WebUSBURL is the URL descriptor of the WebUSB landing page, returned
+	// for the WebUSB GET_URL vendor request.
+	WebUSBURL []byte
+
+	// MSOS20 is the Microsoft OS 2.0 descriptor set, returned for the MS OS
+	// 2.0 vendor request.
+	MSOS20 []byte
 }
 
 func (d *Descriptor) Configure(idVendor, idProduct uint16) {
 	dev := DeviceType{d.Device}
 	dev.VendorID(idVendor)
 	dev.ProductID(idProduct)
+	if len(d.BOS) != 0 {
+		// Hosts only request the BOS descriptor from USB 2.1 devices.
+		dev.USB(0x0210)
+	}
 
 	conf := ConfigurationType{d.Configuration}
 	conf.TotalLength(uint16(len(d.Configuration)))
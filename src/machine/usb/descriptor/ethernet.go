@@ -0,0 +1,179 @@
+package descriptor
+
+import (
+	"internal/binary"
+)
+
+/* CDC Ethernet Control Model (ECM) and Network Control Model (NCM)
+USB Communications Class Subclass Specification for Ethernet Control Model
+Devices, Revision 1.2, and for Network Control Model Devices, Revision 1.0
+*/
+
+const (
+	cdcSubclassECM      = 0x06
+	cdcSubclassNCM      = 0x0d
+	cdcDataProtocolNone = 0x00
+	cdcDataProtocolNTB  = 0x01
+
+	cdcFunctionalHeaderLen   = 5
+	cdcFunctionalEthernetLen = 13
+	cdcFunctionalNCM         = 0x1a
+	cdcFunctionalNCMLen      = 6
+)
+
+// EthernetConfig describes a CDC network function with a communications
+// interface (with an interrupt endpoint for notifications) and a data
+// interface (with a bulk endpoint in each direction). The data interface has
+// an alternate setting 0 without endpoints, which the host uses to disable the
+// network link, and an alternate setting 1 with the bulk endpoints.
+type EthernetConfig struct {
+	// First interface number of the function. The communications interface
+	// uses this number, and the data interface uses the next one.
+	FirstInterface uint8
+
+	// Use the Network Control Model instead of the Ethernet Control Model.
+	// NCM is supported by recent versions of Linux, macOS, and Windows 11,
+	// while ECM is not supported on Windows.
+	NCM bool
+
+	// Index of the string descriptor with the MAC address of the host side
+	// of the link, as 12 hexadecimal digits.
+	MACAddress uint8
+
+	// Maximum size of an Ethernet frame, excluding the CRC.
+	MaxSegmentSize uint16
+
+	// Endpoint addresses.
+	EndpointNotify uint8
+	EndpointIn     uint8
+	EndpointOut    uint8
+}
+
+// Bytes returns the interface association descriptor followed by all
+// interface, functional and endpoint descriptors of the network function.
+func (c EthernetConfig) Bytes() []byte {
+	subclass := uint8(cdcSubclassECM)
+	protocol := uint8(cdcDataProtocolNone)
+	if c.NCM {
+		subclass = cdcSubclassNCM
+		protocol = cdcDataProtocolNTB
+	}
+	comm, data := c.FirstInterface, c.FirstInterface+1
+
+	var b []byte
+	b = append(b,
+		interfaceAssociationTypeLen, TypeInterfaceAssociation,
+		comm,
+		0x02, // InterfaceCount
+		InterfaceClassCDC,
+		subclass,
+		0x00, // FunctionProtocol
+		0x00, // Function
+	)
+
+	// Communications interface.
+	b = append(b,
+		interfaceTypeLen, TypeInterface,
+		comm,
+		0x00, // AlternateSetting
+		0x01, // NumEndpoints
+		InterfaceClassCDC,
+		subclass,
+		0x00, // InterfaceProtocol
+		0x00, // Interface
+	)
+	b = append(b,
+		cdcFunctionalHeaderLen, TypeClassSpecific, cdcFunctionalHeader,
+		0x10, 0x01, // CDC 1.10
+	)
+	b = append(b,
+		cdcFunctionalHeaderLen, TypeClassSpecific, cdcFunctionalUnion,
+		comm, // control interface
+		data, // subordinate interface
+	)
+	b = append(b,
+		cdcFunctionalEthernetLen, TypeClassSpecific, cdcFunctionalEthernet,
+		c.MACAddress,
+		0x00, 0x00, 0x00, 0x00, // no statistics
+		0x00, 0x00, // MaxSegmentSize
+		0x00, 0x00, // no multicast filters
+		0x00, // no power filters
+	)
+	binary.LittleEndian.PutUint16(b[len(b)-5:], c.MaxSegmentSize)
+	if c.NCM {
+		b = append(b,
+			cdcFunctionalNCMLen, TypeClassSpecific, cdcFunctionalNCM,
+			0x00, 0x01, // NCM 1.00
+			0x00, // no optional capabilities
+		)
+	}
+	b = append(b,
+		endpointTypeLen, TypeEndpoint,
+		c.EndpointNotify|0x80,
+		TransferTypeInterrupt,
+		0x10, 0x00, // MaxPacketSize
+		0x10, // Interval
+	)
+
+	// Data interface, with and without endpoints.
+	b = append(b,
+		interfaceTypeLen, TypeInterface,
+		data,
+		0x00, // AlternateSetting
+		0x00, // NumEndpoints
+		InterfaceClassCDCData,
+		0x00, // InterfaceSubClass
+		protocol,
+		0x00, // Interface
+	)
+	b = append(b,
+		interfaceTypeLen, TypeInterface,
+		data,
+		0x01, // AlternateSetting
+		0x02, // NumEndpoints
+		InterfaceClassCDCData,
+		0x00, // InterfaceSubClass
+		protocol,
+		0x00, // Interface
+	)
+	b = append(b,
+		endpointTypeLen, TypeEndpoint,
+		c.EndpointIn|0x80,
+		TransferTypeBulk,
+		0x40, 0x00, // MaxPacketSize
+		0x00, // Interval
+	)
+	b = append(b,
+		endpointTypeLen, TypeEndpoint,
+		c.EndpointOut&0x7f,
+		TransferTypeBulk,
+		0x40, 0x00, // MaxPacketSize
+		0x00, // Interval
+	)
+	return b
+}
+
+// CDCEthernet returns a descriptor for a composite device with a CDC serial
+// port and the given network function, which must start at interface 2.
+func CDCEthernet(config EthernetConfig) Descriptor {
+	conf := ConfigurationType{data: make([]byte, configurationTypeLen)}
+	copy(conf.data, configurationCDC[:])
+	conf.NumInterfaces(4)
+	return Descriptor{
+		Device: DeviceCDC.Bytes(),
+		Configuration: Append([][]byte{
+			conf.Bytes(),
+			InterfaceAssociationCDC.Bytes(),
+			InterfaceCDCControl.Bytes(),
+			ClassSpecificCDCHeader.Bytes(),
+			ClassSpecificCDCACM.Bytes(),
+			ClassSpecificCDCUnion.Bytes(),
+			ClassSpecificCDCCallManagement.Bytes(),
+			EndpointEP1IN.Bytes(),
+			InterfaceCDCData.Bytes(),
+			EndpointEP2OUT.Bytes(),
+			EndpointEP3IN.Bytes(),
+			config.Bytes(),
+		}),
+	}
+}
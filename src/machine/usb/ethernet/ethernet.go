@@ -0,0 +1,375 @@
+// Package ethernet implements a USB network device, using the CDC Network
+// Control Model (NCM) or the CDC Ethernet Control Model (ECM). The host sees a
+// network interface, and the program sends and receives Ethernet frames: a
+// TCP/IP stack running on the device can use it like an Ethernet controller.
+//
+// NCM is supported by Linux, macOS and Windows 11, while ECM is supported by
+// Linux and macOS. Frames are received in an interrupt, but are passed to the
+// program from PollOne, which must be called regularly:
+//
+//	eth := ethernet.Port()
+//	err := eth.Configure(ethernet.Config{})
+//	...
+//	eth.RecvEthHandle(func(frame []byte) error {
+//		return stack.RecvEth(frame)
+//	})
+//	for {
+//		eth.PollOne()
+//		...
+//		eth.SendEth(frame)
+//	}
+package ethernet
+
+import (
+	"errors"
+	"machine"
+	"machine/usb"
+	"machine/usb/descriptor"
+	"machine/usb/ethernet/ncm"
+	"runtime/volatile"
+	"time"
+)
+
+// Mode selects the CDC subclass of the network function.
+type Mode uint8
+
+const (
+	// NCM is the Network Control Model, which wraps frames in transfer
+	// blocks.
+	NCM Mode = iota
+
+	// ECM is the Ethernet Control Model, which sends frames as they are.
+	ECM
+)
+
+// MTU is the maximum size of the payload of an Ethernet frame.
+const MTU = 1500
+
+// Maximum size of an Ethernet frame: the payload and the 14 byte header,
+// without the CRC.
+const maxFrameSize = MTU + 14
+
+// Maximum size of an NCM transfer block in either direction. It is also the
+// size of the receive buffer.
+const maxTransferSize = 2048
+
+// Time SendEth waits for the previous frame to be sent.
+const sendTimeout = 100 * time.Millisecond
+
+var (
+	ErrLinkDown      = errors.New("ethernet: link down")
+	ErrFrameTooLarge = errors.New("ethernet: frame too large")
+	ErrFrameTooSmall = errors.New("ethernet: frame too small")
+	ErrSendTimeout   = errors.New("ethernet: send timeout")
+	ErrInvalidBlock  = ncm.ErrInvalidBlock
+)
+
+// Device is the interface that a TCP/IP stack needs from an Ethernet
+// controller. It is implemented by the USB network port.
+type Device interface {
+	// HardwareAddr6 returns the MAC address of the device.
+	HardwareAddr6() ([6]byte, error)
+
+	// MTU returns the maximum payload size of a frame.
+	MTU() int
+
+	// SendEth sends a single Ethernet frame, without CRC.
+	SendEth(frame []byte) error
+
+	// RecvEthHandle sets the function that is called for every received
+	// frame. The frame is only valid during the call.
+	RecvEthHandle(handler func(frame []byte) error)
+
+	// PollOne passes the received frames (if any) to the handler. It returns
+	// whether there were frames, and the first error returned by the
+	// handler.
+	PollOne() (bool, error)
+}
+
+var _ Device = (*ethernet)(nil)
+
+// Config is the configuration of the network device.
+type Config struct {
+	// Mode selects NCM (the default) or ECM.
+	Mode Mode
+
+	// MAC address of the device. The host side of the link uses the same
+	// address with the lowest bit of the last byte flipped. The default is a
+	// locally administered address derived from the device ID of the chip.
+	HardwareAddr [6]byte
+}
+
+var Ethernet *ethernet
+
+type ethernet struct {
+	mode    Mode
+	mac     [6]byte
+	handler func(frame []byte) error
+	linkUp  bool
+
+	// Receive state. The interrupt fills rxBuf until the end of a transfer,
+	// and sets rxReady. The endpoint is not acknowledged until PollOne has
+	// processed the transfer.
+	rxBuf      [maxTransferSize]byte
+	rxLen      int
+	rxOverflow bool
+	rxReady    volatile.Register8
+
+	// Transmit state. SendEth fills txBuf and sends the first packet, and
+	// every following packet is sent when the previous one completed.
+	txBuf      [maxTransferSize]byte
+	txLen      int
+	txOffset   int
+	txZlp      bool
+	txBusy     volatile.Register8
+	txSequence uint16
+
+	// Notifications on the interrupt endpoint, sent when the host enables
+	// the data interface.
+	notifyBuf   [16]byte
+	notifyState uint8
+
+	ntbInputSize uint32
+	setupBuf     [ntbParametersLen]byte
+}
+
+// Port returns the USB network port. It must be configured with Configure
+// before it can be used.
+func Port() *ethernet {
+	if Ethernet == nil {
+		Ethernet = &ethernet{}
+	}
+	return Ethernet
+}
+
+// Configure sets up the USB descriptors and endpoints of the network device.
+func (e *ethernet) Configure(config Config) error {
+	e.mode = config.Mode
+	e.mac = config.HardwareAddr
+	if e.mac == [6]byte{} {
+		e.mac = defaultHardwareAddr()
+	}
+	e.ntbInputSize = maxTransferSize
+
+	// The host side of the link needs a different MAC address, which is
+	// passed in a string descriptor.
+	host := e.mac
+	host[5] ^= 1
+	const hex = "0123456789ABCDEF"
+	var str [12]byte
+	for i, b := range host {
+		str[i*2] = hex[b>>4]
+		str[i*2+1] = hex[b&0xf]
+	}
+
+	desc := descriptor.CDCEthernet(descriptor.EthernetConfig{
+		FirstInterface: usb.NET_COMM_INTERFACE,
+		NCM:            e.mode == NCM,
+		MACAddress:     usb.IMACADDRESS,
+		MaxSegmentSize: maxFrameSize,
+		EndpointNotify: usb.NET_ENDPOINT_NOTIFY,
+		EndpointIn:     usb.NET_ENDPOINT_IN,
+		EndpointOut:    usb.NET_ENDPOINT_OUT,
+	})
+	desc.Strings = map[uint8]string{
+		usb.IMACADDRESS: string(str[:]),
+	}
+
+	machine.ConfigureUSBEndpoint(desc,
+		[]usb.EndpointConfig{
+			{
+				Index:     usb.NET_ENDPOINT_NOTIFY,
+				IsIn:      true,
+				Type:      usb.ENDPOINT_TYPE_INTERRUPT,
+				TxHandler: e.notifyHandler,
+			},
+			{
+				Index:     usb.NET_ENDPOINT_IN,
+				IsIn:      true,
+				Type:      usb.ENDPOINT_TYPE_BULK,
+				TxHandler: e.txHandler,
+			},
+			{
+				Index:          usb.NET_ENDPOINT_OUT,
+				IsIn:           false,
+				Type:           usb.ENDPOINT_TYPE_BULK,
+				DelayRxHandler: e.rxHandler,
+			},
+		},
+		[]usb.SetupConfig{
+			{
+				Index:   usb.NET_COMM_INTERFACE,
+				Handler: e.setupHandler,
+			},
+			{
+				Index:            usb.NET_DATA_INTERFACE,
+				AlternateHandler: e.setDataAlternate,
+			},
+		})
+	return nil
+}
+
+// defaultHardwareAddr returns a locally administered unicast MAC address,
+// derived from the device ID of the chip.
+func defaultHardwareAddr() [6]byte {
+	mac := [6]byte{0x02}
+	for i, b := range machine.DeviceID() {
+		mac[1+i%5] ^= b
+	}
+	mac[5] &^= 1 // the host uses the address with this bit set
+	return mac
+}
+
+// HardwareAddr6 returns the MAC address of the device.
+func (e *ethernet) HardwareAddr6() ([6]byte, error) {
+	return e.mac, nil
+}
+
+// MTU returns the maximum payload size of a frame.
+func (e *ethernet) MTU() int {
+	return MTU
+}
+
+// LinkUp returns whether the host has enabled the network interface.
+func (e *ethernet) LinkUp() bool {
+	return e.linkUp
+}
+
+// RecvEthHandle sets the function that is called by PollOne for every
+// received frame. The frame is only valid during the call.
+func (e *ethernet) RecvEthHandle(handler func(frame []byte) error) {
+	e.handler = handler
+}
+
+// PollOne passes the frames of the last transfer from the host (if any) to
+// the handler. It returns whether a transfer was processed, and the first
+// error returned by the handler or found in the transfer.
+func (e *ethernet) PollOne() (bool, error) {
+	if e.rxReady.Get() == 0 {
+		return false, nil
+	}
+	var err error
+	if !e.rxOverflow {
+		if e.mode == NCM {
+			err = ncm.ParseBlock(e.rxBuf[:e.rxLen], e.receive)
+		} else {
+			err = e.receive(e.rxBuf[:e.rxLen])
+		}
+	}
+	e.rxLen = 0
+	e.rxOverflow = false
+	e.rxReady.Set(0)
+	machine.AckUsbOutTransfer(usb.NET_ENDPOINT_OUT)
+	return true, err
+}
+
+func (e *ethernet) receive(frame []byte) error {
+	if e.handler == nil || len(frame) < 14 {
+		return nil
+	}
+	return e.handler(frame)
+}
+
+// SendEth sends a single Ethernet frame to the host. It waits for the
+// previous frame to be sent, and returns an error if the host has not enabled
+// the network interface.
+func (e *ethernet) SendEth(frame []byte) error {
+	if len(frame) > maxFrameSize {
+		return ErrFrameTooLarge
+	}
+	if len(frame) < 14 {
+		return ErrFrameTooSmall
+	}
+	start := time.Now()
+	for e.txBusy.Get() != 0 {
+		if !e.linkUp {
+			return ErrLinkDown
+		}
+		if time.Since(start) > sendTimeout {
+			return ErrSendTimeout
+		}
+		time.Sleep(10 * time.Microsecond)
+	}
+	if !e.linkUp {
+		return ErrLinkDown
+	}
+
+	if e.mode == NCM {
+		e.txLen = ncm.BuildBlock(e.txBuf[:], frame, e.txSequence)
+		e.txSequence++
+	} else {
+		e.txLen = copy(e.txBuf[:], frame)
+	}
+	e.txOffset = 0
+	e.txZlp = false
+	e.txBusy.Set(1)
+	e.sendPacket()
+	return nil
+}
+
+// sendPacket sends the next packet of the transfer in txBuf, and a zero
+// length packet after a transfer that ends with a full packet.
+func (e *ethernet) sendPacket() {
+	if e.txOffset == e.txLen {
+		if e.txLen%usb.EndpointPacketSize == 0 && !e.txZlp {
+			e.txZlp = true
+			machine.SendUSBInPacket(usb.NET_ENDPOINT_IN, nil)
+			return
+		}
+		e.txBusy.Set(0)
+		return
+	}
+	n := e.txLen - e.txOffset
+	if n > usb.EndpointPacketSize {
+		n = usb.EndpointPacketSize
+	}
+	packet := e.txBuf[e.txOffset : e.txOffset+n]
+	e.txOffset += n
+	machine.SendUSBInPacket(usb.NET_ENDPOINT_IN, packet)
+}
+
+func (e *ethernet) txHandler() {
+	if e.txBusy.Get() != 0 {
+		e.sendPacket()
+	}
+}
+
+// rxHandler collects the packets of a transfer from the host. A transfer ends
+// with a short packet, or (with NCM) when the transfer block is complete.
+func (e *ethernet) rxHandler(b []byte) bool {
+	if e.rxLen+len(b) > len(e.rxBuf) {
+		e.rxOverflow = true
+	} else {
+		e.rxLen += copy(e.rxBuf[e.rxLen:], b)
+	}
+	done := len(b) < usb.EndpointPacketSize
+	if e.mode == NCM && !e.rxOverflow && e.rxLen >= ncm.HeaderLen {
+		done = done || e.rxLen >= int(ncm.BlockLength(e.rxBuf[:e.rxLen]))
+	}
+	if !done {
+		return true
+	}
+	if e.rxLen == 0 && !e.rxOverflow {
+		// A zero length packet after a transfer that was already complete.
+		return true
+	}
+	e.rxReady.Set(1)
+	return false
+}
+
+// setDataAlternate is called when the host enables (alternate setting 1) or
+// disables (alternate setting 0) the data interface.
+func (e *ethernet) setDataAlternate(alt uint8) bool {
+	if alt > 1 {
+		return false
+	}
+	e.linkUp = alt == 1
+	if e.linkUp {
+		e.txSequence = 0
+		e.notifyState = 0
+		e.notifyHandler()
+	} else {
+		e.txBusy.Set(0)
+	}
+	return true
+}
@@ -0,0 +1,118 @@
+// Package ncm encodes and decodes the transfer blocks (NTB) of the USB CDC
+// Network Control Model, using 16-bit offsets. Every block starts with a
+// transfer header (NTH16), which points to a chain of datagram pointer tables
+// (NDP16) with the offset and length of each Ethernet frame in the block.
+//
+// The package does not depend on machine, so it can be tested on the host.
+package ncm
+
+import (
+	"errors"
+	"internal/binary"
+)
+
+const (
+	nth16Signature = 0x484d434e // "NCMH"
+	ndp16Signature = 0x304d434e // "NCM0", without CRC
+	ndp16Len       = 8          // without the datagram pointers
+
+	// HeaderLen is the length of the transfer header.
+	HeaderLen = 12
+
+	// Divisor and Alignment of the frames in the blocks built by BuildBlock,
+	// as reported in the NTB parameters.
+	Divisor   = 4
+	Alignment = 4
+
+	// Overhead is the number of bytes BuildBlock adds in front of a frame.
+	Overhead = (HeaderLen + ndp16Len + 2*4 + Divisor - 1) / Divisor * Divisor
+)
+
+// ErrInvalidBlock is returned by ParseBlock for malformed transfer blocks.
+var ErrInvalidBlock = errors.New("ethernet: invalid NCM transfer block")
+
+// BlockLength returns the wBlockLength field of the transfer header at the
+// start of b, which must be at least HeaderLen bytes.
+func BlockLength(b []byte) uint16 {
+	return binary.LittleEndian.Uint16(b[8:])
+}
+
+// ParseBlock passes all frames in the transfer block b to receive. It returns
+// ErrInvalidBlock if the block is malformed, or otherwise the first error
+// returned by receive.
+func ParseBlock(b []byte, receive func(frame []byte) error) error {
+	if len(b) < HeaderLen ||
+		binary.LittleEndian.Uint32(b[0:]) != nth16Signature ||
+		binary.LittleEndian.Uint16(b[4:]) != HeaderLen {
+		return ErrInvalidBlock
+	}
+	if n := int(BlockLength(b)); n != 0 && n < len(b) {
+		b = b[:n]
+	}
+
+	var err error
+	ndp := int(binary.LittleEndian.Uint16(b[10:]))
+	for ndp != 0 {
+		if ndp%4 != 0 || ndp+ndp16Len > len(b) ||
+			binary.LittleEndian.Uint32(b[ndp:]) != ndp16Signature {
+			return ErrInvalidBlock
+		}
+		length := int(binary.LittleEndian.Uint16(b[ndp+4:]))
+		if length < ndp16Len+8 || ndp+length > len(b) {
+			return ErrInvalidBlock
+		}
+		for i := ndp + ndp16Len; i+4 <= ndp+length; i += 4 {
+			offset := int(binary.LittleEndian.Uint16(b[i:]))
+			size := int(binary.LittleEndian.Uint16(b[i+2:]))
+			if offset == 0 || size == 0 {
+				break
+			}
+			if offset+size > len(b) {
+				return ErrInvalidBlock
+			}
+			if rerr := receive(b[offset : offset+size]); rerr != nil && err == nil {
+				err = rerr
+			}
+		}
+
+		// The tables must be chained forward, so that a malformed block
+		// cannot make this loop run forever.
+		next := int(binary.LittleEndian.Uint16(b[ndp+6:]))
+		if next != 0 && (next <= ndp || next+ndp16Len > len(b)) {
+			return ErrInvalidBlock
+		}
+		ndp = next
+	}
+	return err
+}
+
+// BuildBlock wraps a frame in a transfer block with the given sequence
+// number, and returns the length of the block. The block is written to b,
+// which must be at least Overhead+len(frame) bytes.
+func BuildBlock(b, frame []byte, sequence uint16) int {
+	const (
+		ndp    = HeaderLen
+		ndpLen = ndp16Len + 2*4 // the frame and the terminating entry
+		offset = Overhead
+	)
+	n := offset + len(frame)
+
+	binary.LittleEndian.PutUint32(b[0:], nth16Signature)
+	binary.LittleEndian.PutUint16(b[4:], HeaderLen)
+	binary.LittleEndian.PutUint16(b[6:], sequence)
+	binary.LittleEndian.PutUint16(b[8:], uint16(n))
+	binary.LittleEndian.PutUint16(b[10:], ndp)
+
+	binary.LittleEndian.PutUint32(b[ndp:], ndp16Signature)
+	binary.LittleEndian.PutUint16(b[ndp+4:], ndpLen)
+	binary.LittleEndian.PutUint16(b[ndp+6:], 0) // no next table
+	binary.LittleEndian.PutUint16(b[ndp+8:], offset)
+	binary.LittleEndian.PutUint16(b[ndp+10:], uint16(len(frame)))
+	binary.LittleEndian.PutUint32(b[ndp+12:], 0) // end of table
+	for i := ndp + ndpLen; i < offset; i++ {
+		b[i] = 0
+	}
+
+	copy(b[offset:], frame)
+	return n
+}
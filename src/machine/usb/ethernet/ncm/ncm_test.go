@@ -0,0 +1,175 @@
+package ncm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func testFrame(n int, seed byte) []byte {
+	frame := make([]byte, n)
+	for i := range frame {
+		frame[i] = seed + byte(i)
+	}
+	return frame
+}
+
+// parse returns the frames in the block, or the error returned by ParseBlock.
+func parse(b []byte) ([][]byte, error) {
+	var frames [][]byte
+	err := ParseBlock(b, func(frame []byte) error {
+		frames = append(frames, append([]byte(nil), frame...))
+		return nil
+	})
+	return frames, err
+}
+
+func TestBuildParseBlock(t *testing.T) {
+	buf := make([]byte, 2048)
+	for _, size := range []int{14, 60, 1514} {
+		frame := testFrame(size, byte(size))
+		n := BuildBlock(buf, frame, 7)
+		if n != Overhead+size {
+			t.Errorf("size %d: block length is %d, expected %d", size, n, Overhead+size)
+		}
+		if got := int(BlockLength(buf)); got != n {
+			t.Errorf("size %d: wBlockLength is %d, expected %d", size, got, n)
+		}
+		if seq := binary.LittleEndian.Uint16(buf[6:]); seq != 7 {
+			t.Errorf("size %d: sequence is %d, expected 7", size, seq)
+		}
+		if Overhead%Divisor != 0 {
+			t.Errorf("frame offset %d is not aligned to %d", Overhead, Divisor)
+		}
+
+		frames, err := parse(buf[:n])
+		if err != nil {
+			t.Fatalf("size %d: unexpected error: %v", size, err)
+		}
+		if len(frames) != 1 || !bytes.Equal(frames[0], frame) {
+			t.Errorf("size %d: parsed frames %v, expected one frame", size, frames)
+		}
+	}
+}
+
+// twoTableBlock returns a block with one frame in each of two chained
+// datagram pointer tables.
+func twoTableBlock() (b []byte, frame1, frame2 []byte) {
+	frame1 = testFrame(20, 1)
+	frame2 = testFrame(30, 100)
+	b = make([]byte, 128)
+	const ndp1, ndp2, off1, off2 = 12, 28, 44, 64
+	binary.LittleEndian.PutUint32(b[0:], nth16Signature)
+	binary.LittleEndian.PutUint16(b[4:], HeaderLen)
+	binary.LittleEndian.PutUint16(b[8:], uint16(len(b)))
+	binary.LittleEndian.PutUint16(b[10:], ndp1)
+	for _, ndp := range []struct {
+		pos, next, offset int
+		frame             []byte
+	}{
+		{ndp1, ndp2, off1, frame1},
+		{ndp2, 0, off2, frame2},
+	} {
+		binary.LittleEndian.PutUint32(b[ndp.pos:], ndp16Signature)
+		binary.LittleEndian.PutUint16(b[ndp.pos+4:], 16)
+		binary.LittleEndian.PutUint16(b[ndp.pos+6:], uint16(ndp.next))
+		binary.LittleEndian.PutUint16(b[ndp.pos+8:], uint16(ndp.offset))
+		binary.LittleEndian.PutUint16(b[ndp.pos+10:], uint16(len(ndp.frame)))
+		copy(b[ndp.offset:], ndp.frame)
+	}
+	return b, frame1, frame2
+}
+
+func TestParseBlockChain(t *testing.T) {
+	b, frame1, frame2 := twoTableBlock()
+	frames, err := parse(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(frames) != 2 || !bytes.Equal(frames[0], frame1) || !bytes.Equal(frames[1], frame2) {
+		t.Errorf("parsed frames %v, expected the two frames of the block", frames)
+	}
+}
+
+func TestParseBlockReceiveError(t *testing.T) {
+	b, _, _ := twoTableBlock()
+	errFirst := errors.New("first")
+	calls := 0
+	err := ParseBlock(b, func(frame []byte) error {
+		calls++
+		if calls == 1 {
+			return errFirst
+		}
+		return errors.New("second")
+	})
+	if err != errFirst {
+		t.Errorf("got error %v, expected the first error of the handler", err)
+	}
+	if calls != 2 {
+		t.Errorf("handler called %d times, expected 2", calls)
+	}
+}
+
+func TestParseBlockInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		modify func(b []byte) []byte
+	}{
+		{"short", func(b []byte) []byte { return b[:HeaderLen-1] }},
+		{"header signature", func(b []byte) []byte {
+			b[0] = 'X'
+			return b
+		}},
+		{"header length", func(b []byte) []byte {
+			binary.LittleEndian.PutUint16(b[4:], 16)
+			return b
+		}},
+		{"table signature", func(b []byte) []byte {
+			b[12] = 'X'
+			return b
+		}},
+		{"unaligned table", func(b []byte) []byte {
+			binary.LittleEndian.PutUint16(b[10:], 13)
+			return b
+		}},
+		{"table out of range", func(b []byte) []byte {
+			binary.LittleEndian.PutUint16(b[10:], 124)
+			return b
+		}},
+		{"table too short", func(b []byte) []byte {
+			binary.LittleEndian.PutUint16(b[12+4:], 8)
+			return b
+		}},
+		{"table too long", func(b []byte) []byte {
+			binary.LittleEndian.PutUint16(b[12+4:], 200)
+			return b
+		}},
+		{"frame out of range", func(b []byte) []byte {
+			binary.LittleEndian.PutUint16(b[12+10:], 100)
+			return b
+		}},
+		{"block length truncates frame", func(b []byte) []byte {
+			binary.LittleEndian.PutUint16(b[8:], 70)
+			return b
+		}},
+		{"table points to itself", func(b []byte) []byte {
+			binary.LittleEndian.PutUint16(b[28+6:], 28)
+			return b
+		}},
+		{"table points backwards", func(b []byte) []byte {
+			binary.LittleEndian.PutUint16(b[28+6:], 12)
+			return b
+		}},
+		{"next table out of range", func(b []byte) []byte {
+			binary.LittleEndian.PutUint16(b[28+6:], 124)
+			return b
+		}},
+	} {
+		b, _, _ := twoTableBlock()
+		_, err := parse(tc.modify(b))
+		if err != ErrInvalidBlock {
+			t.Errorf("%s: got error %v, expected ErrInvalidBlock", tc.name, err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package ethernet
+
+import (
+	"internal/binary"
+	"machine"
+	"machine/usb"
+	"machine/usb/ethernet/ncm"
+)
+
+// Class specific request codes (CDC 1.2, table 19, and NCM 1.0, table 6-2).
+const (
+	setEthernetPacketFilter = 0x43
+	getNTBParameters        = 0x80
+	getNTBFormat            = 0x83
+	setNTBFormat            = 0x84
+	getNTBInputSize         = 0x85
+	setNTBInputSize         = 0x86
+)
+
+// Notification codes (CDC 1.2, table 20).
+const (
+	notificationNetworkConnection     = 0x00
+	notificationConnectionSpeedChange = 0x2a
+)
+
+// Length of the NTB parameter structure (NCM 1.0, table 6-3).
+const ntbParametersLen = 28
+
+// Link speed reported to the host, in bits per second: the speed of a full
+// speed USB link.
+const linkSpeed = 12000000
+
+// setupHandler handles the class specific requests to the communications
+// interface.
+func (e *ethernet) setupHandler(setup usb.Setup) bool {
+	in := setup.BmRequestType&usb.REQUEST_DIRECTION == usb.REQUEST_DEVICETOHOST
+
+	switch {
+	case setup.BRequest == setEthernetPacketFilter && !in:
+		// All frames are passed on, so the filter is ignored.
+		machine.SendZlp()
+		return true
+	}
+	if e.mode != NCM {
+		return false
+	}
+
+	switch {
+	case setup.BRequest == getNTBParameters && in:
+		b := e.setupBuf[:]
+		binary.LittleEndian.PutUint16(b[0:], ntbParametersLen)
+		binary.LittleEndian.PutUint16(b[2:], 0x0001) // 16-bit NTB only
+		binary.LittleEndian.PutUint32(b[4:], maxTransferSize)
+		binary.LittleEndian.PutUint16(b[8:], ncm.Divisor)
+		binary.LittleEndian.PutUint16(b[10:], 0) // payload remainder
+		binary.LittleEndian.PutUint16(b[12:], ncm.Alignment)
+		binary.LittleEndian.PutUint16(b[14:], 0) // reserved
+		binary.LittleEndian.PutUint32(b[16:], maxTransferSize)
+		binary.LittleEndian.PutUint16(b[20:], ncm.Divisor)
+		binary.LittleEndian.PutUint16(b[22:], 0) // payload remainder
+		binary.LittleEndian.PutUint16(b[24:], ncm.Alignment)
+		binary.LittleEndian.PutUint16(b[26:], 0) // no limit on the number of frames
+		return e.reply(setup, ntbParametersLen)
+	case setup.BRequest == getNTBFormat && in:
+		binary.LittleEndian.PutUint16(e.setupBuf[:], 0) // 16-bit NTB
+		return e.reply(setup, 2)
+	case setup.BRequest == setNTBFormat && !in:
+		if setup.WValueL != 0 || setup.WValueH != 0 {
+			return false
+		}
+		machine.SendZlp()
+		return true
+	case setup.BRequest == getNTBInputSize && in:
+		binary.LittleEndian.PutUint32(e.setupBuf[:], e.ntbInputSize)
+		return e.reply(setup, 4)
+	case setup.BRequest == setNTBInputSize && !in:
+		b, err := machine.ReceiveUSBControlPacket()
+		if err != nil {
+			return false
+		}
+		// The blocks sent by the device hold a single frame, so any size
+		// that fits a full frame works.
+		size := binary.LittleEndian.Uint32(b[:])
+		if size < maxFrameSize+32 {
+			return false
+		}
+		e.ntbInputSize = size
+		machine.SendZlp()
+		return true
+	}
+	return false
+}
+
+// reply sends the first n bytes of the setup buffer, limited to the number of
+// bytes requested by the host.
+func (e *ethernet) reply(setup usb.Setup, n int) bool {
+	if n > int(setup.WLength) {
+		n = int(setup.WLength)
+	}
+	machine.SendUSBInPacket(0, e.setupBuf[:n])
+	return true
+}
+
+// notifyHandler sends the notifications that tell the host that the link is
+// up: first the link speed, and then the connection state. Each notification
+// is sent when the previous one completed.
+func (e *ethernet) notifyHandler() {
+	if !e.linkUp {
+		return
+	}
+	b := e.notifyBuf[:]
+	b[0] = usb.REQUEST_DEVICETOHOST_CLASS_INTERFACE
+	binary.LittleEndian.PutUint16(b[4:], usb.NET_COMM_INTERFACE)
+	switch e.notifyState {
+	case 0:
+		b[1] = notificationConnectionSpeedChange
+		binary.LittleEndian.PutUint16(b[2:], 0)
+		binary.LittleEndian.PutUint16(b[6:], 8)
+		binary.LittleEndian.PutUint32(b[8:], linkSpeed)  // downstream
+		binary.LittleEndian.PutUint32(b[12:], linkSpeed) // upstream
+		machine.SendUSBInPacket(usb.NET_ENDPOINT_NOTIFY, b[:16])
+	case 1:
+		b[1] = notificationNetworkConnection
+		binary.LittleEndian.PutUint16(b[2:], 1) // connected
+		binary.LittleEndian.PutUint16(b[6:], 0)
+		machine.SendUSBInPacket(usb.NET_ENDPOINT_NOTIFY, b[:8])
+	default:
+		return
+	}
+	e.notifyState++
+}
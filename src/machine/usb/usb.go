@@ -16,6 +16,7 @@ const (
 	IMANUFACTURER = 1
 	IPRODUCT      = 2
 	ISERIAL       = 3
+	IMACADDRESS   = 4 // MAC address of a network function
 
 	ENDPOINT_TYPE_DISABLE     = 0xFF
 	ENDPOINT_TYPE_CONTROL     = 0x00
@@ -28,6 +29,10 @@ const (
 
 	EndpointPacketSize = 64 // 64 for Full Speed, EPT size max is 1024
 
+	// MaxIsochronousPacketSize is the largest packet of a full speed
+	// isochronous endpoint.
+	MaxIsochronousPacketSize = 1023
+
 	// bRequest - standard requests
 	GET_STATUS        = 0
 	CLEAR_FEATURE     = 1
@@ -64,23 +69,31 @@ const (
 	CONFIG_REMOTE_WAKEUP = 0x20
 
 	// Interface
-	NumberOfInterfaces = 3
-	CDC_ACM_INTERFACE  = 0 // CDC ACM
-	CDC_DATA_INTERFACE = 1 // CDC Data
-	CDC_FIRST_ENDPOINT = 1
-	HID_INTERFACE      = 2 // HID
+	NumberOfInterfaces      = 5
+	CDC_ACM_INTERFACE       = 0 // CDC ACM
+	CDC_DATA_INTERFACE      = 1 // CDC Data
+	CDC_FIRST_ENDPOINT      = 1
+	HID_INTERFACE           = 2 // HID
+	AUDIO_CONTROL_INTERFACE = 2 // Audio Control, followed by the Audio Streaming interfaces
+	NET_COMM_INTERFACE      = 2 // CDC ECM/NCM Communications
+	NET_DATA_INTERFACE      = 3 // CDC ECM/NCM Data
 
 	// Endpoint
-	CONTROL_ENDPOINT  = 0
-	CDC_ENDPOINT_ACM  = 1
-	CDC_ENDPOINT_OUT  = 2
-	CDC_ENDPOINT_IN   = 3
-	HID_ENDPOINT_IN   = 4 // for Interrupt In
-	HID_ENDPOINT_OUT  = 5 // for Interrupt Out
-	MIDI_ENDPOINT_IN  = 6 // for Bulk In
-	MIDI_ENDPOINT_OUT = 7 // for Bulk Out
-	MSC_ENDPOINT_IN   = 6 // for Bulk In
-	MSC_ENDPOINT_OUT  = 7 // for Bulk Out
+	CONTROL_ENDPOINT    = 0
+	CDC_ENDPOINT_ACM    = 1
+	CDC_ENDPOINT_OUT    = 2
+	CDC_ENDPOINT_IN     = 3
+	HID_ENDPOINT_IN     = 4 // for Interrupt In
+	HID_ENDPOINT_OUT    = 5 // for Interrupt Out
+	MIDI_ENDPOINT_IN    = 6 // for Bulk In
+	MIDI_ENDPOINT_OUT   = 7 // for Bulk Out
+	MSC_ENDPOINT_IN     = 6 // for Bulk In
+	MSC_ENDPOINT_OUT    = 7 // for Bulk Out
+	AUDIO_ENDPOINT_IN   = 6 // for Isochronous In
+	AUDIO_ENDPOINT_OUT  = 7 // for Isochronous Out
+	NET_ENDPOINT_NOTIFY = 4 // for Interrupt In
+	NET_ENDPOINT_IN     = 6 // for Bulk In
+	NET_ENDPOINT_OUT    = 7 // for Bulk Out
 
 	// bmRequestType
 	REQUEST_HOSTTODEVICE = 0x00
@@ -5,6 +5,7 @@
 package os
 
 import (
+	"io"
 	"io/fs"
 	"sort"
 )
@@ -38,7 +39,7 @@ func (f *File) Readdir(n int) ([]FileInfo, error) {
 	if f == nil {
 		return nil, ErrInvalid
 	}
-	_, _, infos, err := f.readdir(n, readdirFileInfo)
+	_, _, infos, err := f.readdirHandle(n, readdirFileInfo)
 	if infos == nil {
 		// Readdir has historically always returned a non-nil empty slice, never nil,
 		// even on error (except misuse with nil receiver above).
@@ -67,7 +68,7 @@ func (f *File) Readdirnames(n int) (names []string, err error) {
 	if f == nil {
 		return nil, ErrInvalid
 	}
-	names, _, _, err = f.readdir(n, readdirName)
+	names, _, _, err = f.readdirHandle(n, readdirName)
 	if names == nil {
 		// Readdirnames has historically always returned a non-nil empty slice, never nil,
 		// even on error (except misuse with nil receiver above).
@@ -95,7 +96,7 @@ func (f *File) ReadDir(n int) ([]DirEntry, error) {
 	if f == nil {
 		return nil, ErrInvalid
 	}
-	_, dirents, _, err := f.readdir(n, readdirDirEntry)
+	_, dirents, _, err := f.readdirHandle(n, readdirDirEntry)
 	if dirents == nil {
 		// Match Readdir and Readdirnames: don't return nil slices.
 		dirents = []DirEntry{}
@@ -103,6 +104,38 @@ func (f *File) ReadDir(n int) ([]DirEntry, error) {
 	return dirents, err
 }
 
+// readdirHandle reads the directory with the ReadDir method of the file
+// handle, if it has one (like files on a filesystem mounted with Mount), or
+// else in the platform specific way.
+func (f *File) readdirHandle(n int, mode readdirMode) (names []string, dirents []DirEntry, infos []FileInfo, err error) {
+	if f.handle == nil {
+		return nil, nil, nil, &PathError{Op: "readdir", Path: f.name, Err: ErrClosed}
+	}
+	handle, ok := f.handle.(readDirHandle)
+	if !ok {
+		return f.readdir(n, mode)
+	}
+	entries, err := handle.ReadDir(n)
+	if err != nil && err != io.EOF {
+		err = &PathError{Op: "readdir", Path: f.name, Err: err}
+	}
+	for _, entry := range entries {
+		switch mode {
+		case readdirName:
+			names = append(names, entry.Name())
+		case readdirDirEntry:
+			dirents = append(dirents, entry)
+		case readdirFileInfo:
+			info, infoErr := entry.Info()
+			if infoErr != nil {
+				return nil, nil, infos, &PathError{Op: "readdir", Path: f.name, Err: infoErr}
+			}
+			infos = append(infos, info)
+		}
+	}
+	return names, dirents, infos, err
+}
+
 // testingForceReadDirLstat forces ReadDir to call Lstat, for testing that code path.
 // This can be difficult to provoke on some Unix systems otherwise.
 var testingForceReadDirLstat bool
@@ -28,6 +28,11 @@ func (d *dirInfo) close() {
 }
 
 func (f *File) readdir(n int, mode readdirMode) (names []string, dirents []DirEntry, infos []FileInfo, err error) {
+	handle, ok := f.handle.(unixFileHandle)
+	if !ok {
+		return nil, nil, nil, &PathError{Op: "readdir", Path: f.name, Err: ErrNotImplemented}
+	}
+
 	// If this file has no dirinfo, create one.
 	if f.dirinfo == nil {
 		f.dirinfo = new(dirInfo)
@@ -52,7 +57,7 @@ func (f *File) readdir(n int, mode readdirMode) (names []string, dirents []DirEn
 		if d.bufp >= d.nbuf {
 			d.bufp = 0
 			var errno error
-			d.nbuf, errno = syscall.ReadDirent(syscallFd(f.handle.(unixFileHandle)), d.buf[:])
+			d.nbuf, errno = syscall.ReadDirent(syscallFd(handle), d.buf[:])
 			if d.nbuf < 0 {
 				errno = handleSyscallError(errno)
 			}
@@ -29,7 +29,11 @@ func (d *dirInfo) close() {
 
 func (f *File) readdir(n int, mode readdirMode) (names []string, dirents []DirEntry, infos []FileInfo, err error) {
 	if f.dirinfo == nil {
-		dir, errno := syscall.Fdopendir(syscallFd(f.handle.(unixFileHandle)))
+		handle, ok := f.handle.(unixFileHandle)
+		if !ok {
+			return nil, nil, nil, &PathError{Op: "readdir", Path: f.name, Err: ErrNotImplemented}
+		}
+		dir, errno := syscall.Fdopendir(syscallFd(handle))
 		if errno != nil {
 			return nil, nil, nil, &PathError{Op: "fdopendir", Path: f.name, Err: errno}
 		}
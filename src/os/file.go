@@ -75,6 +75,66 @@ func Remove(path string) error {
 	return nil
 }
 
+// Rename renames (moves) oldpath to newpath.
+// If newpath already exists and is not a directory, Rename replaces it.
+// OS-specific restrictions may apply when oldpath and newpath are in different directories.
+// If there is an error, it will be of type *LinkError.
+func Rename(oldpath, newpath string) error {
+	fs, suffix := findMount(oldpath)
+	if fs, ok := fs.(renameFilesystem); ok {
+		_, newsuffix := findMount(newpath)
+		if oldpath[:len(oldpath)-len(suffix)] != newpath[:len(newpath)-len(newsuffix)] {
+			// The paths are on different filesystems.
+			return &LinkError{"rename", oldpath, newpath, syscall.EXDEV}
+		}
+		if err := fs.Rename(suffix, newsuffix); err != nil {
+			return &LinkError{"rename", oldpath, newpath, err}
+		}
+		return nil
+	}
+	return rename(oldpath, newpath)
+}
+
+// Truncate changes the size of the named file.
+// If the file is a symbolic link, it changes the size of the link's target.
+// If there is an error, it will be of type *PathError.
+func Truncate(name string, size int64) error {
+	fs, suffix := findMount(name)
+	if fs, ok := fs.(truncateFilesystem); ok {
+		if err := fs.Truncate(suffix, size); err != nil {
+			return &PathError{Op: "truncate", Path: name, Err: err}
+		}
+		return nil
+	}
+	return truncate(name, size)
+}
+
+// Chmod changes the mode of the named file to mode.
+// If the file is a symbolic link, it changes the mode of the link's target.
+// If there is an error, it will be of type *PathError.
+//
+// A different subset of the mode bits are used, depending on the
+// operating system.
+//
+// On Unix, the mode's permission bits, ModeSetuid, ModeSetgid, and
+// ModeSticky are used.
+//
+// On Windows, only the 0200 bit (owner writable) of mode is used; it
+// controls whether the file's read-only attribute is set or cleared.
+// The other bits are currently unused. For compatibility with Go 1.12
+// and earlier, use a non-zero mode. Use mode 0400 for a read-only
+// file and 0600 for a readable+writable file.
+func Chmod(name string, mode FileMode) error {
+	fs, suffix := findMount(name)
+	if fs, ok := fs.(chmodFilesystem); ok {
+		if err := fs.Chmod(suffix, mode); err != nil {
+			return &PathError{Op: "chmod", Path: name, Err: err}
+		}
+		return nil
+	}
+	return chmod(name, mode)
+}
+
 // Name returns the name of the file with which it was opened.
 func (f *File) Name() string {
 	return f.name
@@ -91,7 +151,7 @@ func OpenFile(name string, flag int, perm FileMode) (*File, error) {
 	if err != nil {
 		return nil, &PathError{Op: "open", Path: name, Err: err}
 	}
-	f := NewFile(handle, name)
+	f := &File{&file{handle: handle, name: name}}
 	f.appendMode = (flag & O_APPEND) != 0
 	return f, nil
 }
@@ -312,12 +372,35 @@ func (f *File) Sync() (err error) {
 func (f *File) Chmod(mode FileMode) (err error) {
 	if f.handle == nil {
 		err = ErrClosed
+	} else if handle, ok := f.handle.(chmodHandle); ok {
+		if err = handle.Chmod(mode); err != nil {
+			err = &PathError{Op: "chmod", Path: f.name, Err: err}
+		}
 	} else {
 		err = f.chmod(mode)
 	}
 	return
 }
 
+// Truncate changes the size of the file.
+// It does not change the I/O offset.
+// If there is an error, it will be of type *PathError.
+func (f *File) Truncate(size int64) error {
+	if f == nil {
+		return ErrInvalid
+	}
+	if f.handle == nil {
+		return ErrClosed
+	}
+	if handle, ok := f.handle.(truncateHandle); ok {
+		if err := handle.Truncate(size); err != nil {
+			return &PathError{Op: "truncate", Path: f.name, Err: err}
+		}
+		return nil
+	}
+	return f.truncate(size)
+}
+
 // Chdir changes the current working directory to the file, which must be a
 // directory. If there is an error, it will be of type *PathError.
 func (f *File) Chdir() (err error) {
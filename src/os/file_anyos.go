@@ -38,14 +38,6 @@ func Chdir(dir string) error {
 	return nil
 }
 
-// Rename renames (moves) oldpath to newpath.
-// If newpath already exists and is not a directory, Rename replaces it.
-// OS-specific restrictions may apply when oldpath and newpath are in different directories.
-// If there is an error, it will be of type *LinkError.
-func Rename(oldpath, newpath string) error {
-	return rename(oldpath, newpath)
-}
-
 // unixFilesystem is an empty handle for a Unix/Linux filesystem. All operations
 // are relative to the current working directory.
 type unixFilesystem struct {
@@ -84,9 +76,12 @@ func (fs unixFilesystem) Remove(path string) error {
 	return &PathError{Op: "remove", Path: path, Err: e}
 }
 
-func (fs unixFilesystem) OpenFile(path string, flag int, perm FileMode) (uintptr, error) {
+func (fs unixFilesystem) OpenFile(path string, flag int, perm FileMode) (FileHandle, error) {
 	fp, err := syscall.Open(path, flag, uint32(perm))
-	return uintptr(fp), handleSyscallError(err)
+	if err != nil {
+		return nil, handleSyscallError(err)
+	}
+	return unixFileHandle(fp), nil
 }
 
 // unixFileHandle is a Unix file pointer with associated methods that implement
@@ -126,22 +121,7 @@ func (f unixFileHandle) Fd() uintptr {
 	return uintptr(f)
 }
 
-// Chmod changes the mode of the named file to mode.
-// If the file is a symbolic link, it changes the mode of the link's target.
-// If there is an error, it will be of type *PathError.
-//
-// A different subset of the mode bits are used, depending on the
-// operating system.
-//
-// On Unix, the mode's permission bits, ModeSetuid, ModeSetgid, and
-// ModeSticky are used.
-//
-// On Windows, only the 0200 bit (owner writable) of mode is used; it
-// controls whether the file's read-only attribute is set or cleared.
-// The other bits are currently unused. For compatibility with Go 1.12
-// and earlier, use a non-zero mode. Use mode 0400 for a read-only
-// file and 0600 for a readable+writable file.
-func Chmod(name string, mode FileMode) error {
+func chmod(name string, mode FileMode) error {
 	longName := fixLongPath(name)
 	e := ignoringEINTR(func() error {
 		return syscall.Chmod(longName, syscallMode(mode))
@@ -186,6 +186,13 @@ func TestClose(t *testing.T) {
 	}
 }
 
+func TestTruncateNilFile(t *testing.T) {
+	var f *File
+	if err := f.Truncate(0); err != ErrInvalid {
+		t.Errorf("Truncate on nil file: got %v, want %v", err, ErrInvalid)
+	}
+}
+
 func TestReadOnDir(t *testing.T) {
 	name := TempDir() + "/_os_test_TestReadOnDir"
 	defer Remove(name)
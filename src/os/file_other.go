@@ -48,11 +48,8 @@ func Chdir(dir string) error {
 	return ErrNotImplemented
 }
 
-// Rename renames (moves) oldpath to newpath.
-// If newpath already exists and is not a directory, Rename replaces it.
-// OS-specific restrictions may apply when oldpath and newpath are in different directories.
-// If there is an error, it will be of type *LinkError.
-func Rename(oldpath, newpath string) error {
+// rename is only supported for filesystems mounted with Mount.
+func rename(oldpath, newpath string) error {
 	return ErrNotImplemented
 }
 
@@ -146,18 +143,22 @@ func tempDir() string {
 	return "/tmp"
 }
 
-// Truncate is unsupported on this system.
-func Truncate(filename string, size int64) (err error) {
+// truncate is only supported for filesystems mounted with Mount.
+func truncate(filename string, size int64) (err error) {
 	return ErrUnsupported
 }
 
-// Truncate is unsupported on this system.
-func (f *File) Truncate(size int64) (err error) {
+func (f *File) truncate(size int64) (err error) {
 	if f.handle == nil {
 		return ErrClosed
 	}
 
-	return Truncate(f.name, size)
+	return truncate(f.name, size)
+}
+
+// chmod is only supported for filesystems mounted with Mount.
+func chmod(name string, mode FileMode) error {
+	return ErrUnsupported
 }
 
 func (f *File) chmod(mode FileMode) error {
@@ -55,10 +55,7 @@ func NewFile(fd uintptr, name string) *File {
 	return &File{&file{handle: unixFileHandle(fd), name: name}}
 }
 
-// Truncate changes the size of the named file.
-// If the file is a symbolic link, it changes the size of the link's target.
-// If there is an error, it will be of type *PathError.
-func Truncate(name string, size int64) error {
+func truncate(name string, size int64) error {
 	e := ignoringEINTR(func() error {
 		return syscall.Truncate(name, size)
 	})
@@ -138,16 +135,13 @@ func Readlink(name string) (string, error) {
 	}
 }
 
-// Truncate changes the size of the file.
-// It does not change the I/O offset.
-// If there is an error, it will be of type *PathError.
-// Alternatively just use 'raw' syscall by file name
-func (f *File) Truncate(size int64) (err error) {
+// truncate uses the 'raw' syscall by file name.
+func (f *File) truncate(size int64) (err error) {
 	if f.handle == nil {
 		return ErrClosed
 	}
 
-	return Truncate(f.name, size)
+	return truncate(f.name, size)
 }
 
 func (f *File) chmod(mode FileMode) error {
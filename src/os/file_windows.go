@@ -63,9 +63,7 @@ func (f *unixFileHandle) Truncate(size int64) error {
 	return ErrNotImplemented
 }
 
-// Truncate changes the size of the named file.
-// If the file is a symbolic link, it changes the size of the link's target.
-func Truncate(name string, size int64) error {
+func truncate(name string, size int64) error {
 	return &PathError{Op: "truncate", Path: name, Err: ErrNotImplemented}
 }
 
@@ -116,13 +114,11 @@ func (f unixFileHandle) Sync() error {
 	return ErrNotImplemented
 }
 
-// Truncate changes the size of the named file.
-// If the file is a symbolic link, it changes the size of the link's target.
-func (f *File) Truncate(size int64) error {
+func (f *File) truncate(size int64) error {
 	if f.handle == nil {
 		return &PathError{Op: "truncate", Path: f.name, Err: ErrClosed}
 	}
-	return Truncate(f.name, size)
+	return truncate(f.name, size)
 }
 
 // isWindowsNulName reports whether name is os.DevNull ('NUL') on Windows.
@@ -27,8 +27,9 @@ type mountPoint struct {
 //
 // WARNING: this interface is not finalized and may change in a future version.
 type Filesystem interface {
-	// OpenFile opens the named file.
-	OpenFile(name string, flag int, perm FileMode) (uintptr, error)
+	// OpenFile opens the named file. The returned handle is used for all
+	// operations on the resulting *File.
+	OpenFile(name string, flag int, perm FileMode) (FileHandle, error)
 
 	// Mkdir creates a new directory with the specified permission (before
 	// umask). Some filesystems may not support directories or permissions.
@@ -65,6 +66,81 @@ type FileHandle interface {
 	Close() (err error)
 }
 
+// FilesystemV2 extends Filesystem with operations on files by name. The os
+// package uses each of these methods that a mounted filesystem implements,
+// detected by a type assertion, so a filesystem may implement only some of
+// them. Errors follow the same rules as for Filesystem.
+//
+// Filesystems mounted with Mount have no symbolic links, so Lstat returns the
+// same as Stat.
+//
+// WARNING: this interface is not finalized and may change in a future version.
+type FilesystemV2 interface {
+	Filesystem
+
+	// Stat returns a FileInfo describing the named file.
+	Stat(name string) (FileInfo, error)
+
+	// Rename renames (moves) a file or directory. If newname already exists
+	// and is not a directory, it is replaced.
+	Rename(oldname, newname string) error
+
+	// Truncate changes the size of the named file.
+	Truncate(name string, size int64) error
+
+	// Chmod changes the mode of the named file.
+	Chmod(name string, mode FileMode) error
+}
+
+// FileHandleV2 extends FileHandle in the same way as FilesystemV2 extends
+// Filesystem: each of these methods is used if a handle implements it.
+//
+// WARNING: this interface is not finalized and may change in a future version.
+type FileHandleV2 interface {
+	FileHandle
+
+	// ReadDir reads the contents of a directory, like File.ReadDir. Entries
+	// for "." and ".." are not returned.
+	ReadDir(n int) ([]DirEntry, error)
+
+	// Stat returns a FileInfo describing the file.
+	Stat() (FileInfo, error)
+
+	// Truncate changes the size of the file. It does not change the offset.
+	Truncate(size int64) error
+
+	// Chmod changes the mode of the file.
+	Chmod(mode FileMode) error
+}
+
+// The methods of FilesystemV2 and FileHandleV2, as separate interfaces.
+type (
+	statFilesystem interface {
+		Stat(name string) (FileInfo, error)
+	}
+	renameFilesystem interface {
+		Rename(oldname, newname string) error
+	}
+	truncateFilesystem interface {
+		Truncate(name string, size int64) error
+	}
+	chmodFilesystem interface {
+		Chmod(name string, mode FileMode) error
+	}
+	readDirHandle interface {
+		ReadDir(n int) ([]DirEntry, error)
+	}
+	statHandle interface {
+		Stat() (FileInfo, error)
+	}
+	truncateHandle interface {
+		Truncate(size int64) error
+	}
+	chmodHandle interface {
+		Chmod(mode FileMode) error
+	}
+)
+
 // findMount returns the appropriate (mounted) filesystem to use for a given
 // filename plus the path relative to that filesystem.
 func findMount(path string) (Filesystem, string) {
@@ -0,0 +1,140 @@
+package os
+
+import (
+	"io"
+	"io/fs"
+	"path"
+	"syscall"
+)
+
+// MountFS mounts a read-only fs.FS, such as an embed.FS, in the filesystem
+// abstraction layer of the os package, like Mount. Files are read directly
+// from the fs.FS: for an embed.FS, they are read from the program image (flash
+// memory on most microcontrollers) without being copied to RAM first.
+//
+// Paths in fsys are relative to the prefix. For example, a file "static/a.css"
+// in an embed.FS that is mounted at "/www/" is opened as "/www/static/a.css".
+// Use fs.Sub to mount a subdirectory.
+//
+// All changes to the mounted files fail with syscall.EROFS.
+func MountFS(prefix string, fsys fs.FS) {
+	Mount(prefix, &ioFS{fsys})
+}
+
+// ioFS is a Filesystem for an fs.FS. It implements FilesystemV2.
+type ioFS struct {
+	fsys fs.FS
+}
+
+// path converts a path from the os package, which starts with a slash, to a
+// path for fs.FS.
+func (f *ioFS) path(name string) string {
+	name = path.Clean("/" + name)
+	if name == "/" {
+		return "."
+	}
+	return name[1:]
+}
+
+func (f *ioFS) OpenFile(name string, flag int, perm FileMode) (FileHandle, error) {
+	if flag&(O_WRONLY|O_RDWR|O_CREATE|O_TRUNC|O_APPEND) != 0 {
+		return nil, syscall.EROFS
+	}
+	file, err := f.fsys.Open(f.path(name))
+	if err != nil {
+		return nil, underlyingError(err)
+	}
+	return &ioFile{file}, nil
+}
+
+func (f *ioFS) Stat(name string) (FileInfo, error) {
+	info, err := fs.Stat(f.fsys, f.path(name))
+	if err != nil {
+		return nil, underlyingError(err)
+	}
+	return info, nil
+}
+
+func (f *ioFS) Mkdir(name string, perm FileMode) error {
+	return syscall.EROFS
+}
+
+func (f *ioFS) Remove(name string) error {
+	return syscall.EROFS
+}
+
+func (f *ioFS) Rename(oldname, newname string) error {
+	return syscall.EROFS
+}
+
+func (f *ioFS) Truncate(name string, size int64) error {
+	return syscall.EROFS
+}
+
+func (f *ioFS) Chmod(name string, mode FileMode) error {
+	return syscall.EROFS
+}
+
+// ioFile is a FileHandle for an fs.File. It implements FileHandleV2. Errors of
+// the fs.File are unwrapped, as the os package wraps them again.
+type ioFile struct {
+	file fs.File
+}
+
+func (f *ioFile) Read(b []byte) (int, error) {
+	n, err := f.file.Read(b)
+	return n, underlyingError(err)
+}
+
+func (f *ioFile) ReadAt(b []byte, offset int64) (int, error) {
+	if r, ok := f.file.(io.ReaderAt); ok {
+		n, err := r.ReadAt(b, offset)
+		return n, underlyingError(err)
+	}
+	return 0, ErrNotImplemented
+}
+
+func (f *ioFile) Seek(offset int64, whence int) (int64, error) {
+	if s, ok := f.file.(io.Seeker); ok {
+		offset, err := s.Seek(offset, whence)
+		return offset, underlyingError(err)
+	}
+	return 0, ErrNotImplemented
+}
+
+func (f *ioFile) Sync() error {
+	return nil
+}
+
+func (f *ioFile) Write(b []byte) (int, error) {
+	return 0, syscall.EBADF
+}
+
+func (f *ioFile) WriteAt(b []byte, offset int64) (int, error) {
+	return 0, syscall.EBADF
+}
+
+func (f *ioFile) Close() error {
+	return underlyingError(f.file.Close())
+}
+
+func (f *ioFile) ReadDir(n int) ([]DirEntry, error) {
+	if d, ok := f.file.(fs.ReadDirFile); ok {
+		entries, err := d.ReadDir(n)
+		return entries, underlyingError(err)
+	}
+	return nil, syscall.ENOTDIR
+}
+
+func (f *ioFile) Stat() (FileInfo, error) {
+	info, err := f.file.Stat()
+	return info, underlyingError(err)
+}
+
+func (f *ioFile) Truncate(size int64) error {
+	return syscall.EBADF
+}
+
+func (f *ioFile) Chmod(mode FileMode) error {
+	return syscall.EROFS
+}
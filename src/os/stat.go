@@ -7,6 +7,10 @@ package os
 // Stat returns a FileInfo describing the named file.
 // If there is an error, it will be of type *PathError.
 func Stat(name string) (FileInfo, error) {
+	fs, suffix := findMount(name)
+	if fs, ok := fs.(statFilesystem); ok {
+		return statMounted(fs, "stat", name, suffix)
+	}
 	return statNolog(name)
 }
 
@@ -15,5 +19,35 @@ func Stat(name string) (FileInfo, error) {
 // describes the symbolic link. Lstat makes no attempt to follow the link.
 // If there is an error, it will be of type *PathError.
 func Lstat(name string) (FileInfo, error) {
+	fs, suffix := findMount(name)
+	if fs, ok := fs.(statFilesystem); ok {
+		return statMounted(fs, "lstat", name, suffix)
+	}
 	return lstatNolog(name)
 }
+
+// statMounted stats a file on a filesystem mounted with Mount. Such
+// filesystems have no symbolic links, so it is used for both Stat and Lstat.
+func statMounted(fs statFilesystem, op, name, suffix string) (FileInfo, error) {
+	info, err := fs.Stat(suffix)
+	if err != nil {
+		return nil, &PathError{Op: op, Path: name, Err: err}
+	}
+	return info, nil
+}
+
+// Stat returns the FileInfo structure describing file.
+// If there is an error, it will be of type *PathError.
+func (f *File) Stat() (FileInfo, error) {
+	if f == nil {
+		return nil, ErrInvalid
+	}
+	if handle, ok := f.handle.(statHandle); ok {
+		info, err := handle.Stat()
+		if err != nil {
+			return nil, &PathError{Op: "stat", Path: f.name, Err: err}
+		}
+		return info, nil
+	}
+	return f.stat()
+}
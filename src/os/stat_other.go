@@ -6,8 +6,8 @@
 
 package os
 
-// Stat is a stub, not yet implemented
-func (f *File) Stat() (FileInfo, error) {
+// stat is a stub, not yet implemented
+func (f *File) stat() (FileInfo, error) {
 	return nil, ErrNotImplemented
 }
 
@@ -10,12 +10,15 @@ import (
 	"syscall"
 )
 
-// Stat returns the FileInfo structure describing file.
-// If there is an error, it will be of type *PathError.
-func (f *File) Stat() (FileInfo, error) {
+func (f *File) stat() (FileInfo, error) {
+	handle, ok := f.handle.(unixFileHandle)
+	if !ok {
+		// A file on a filesystem mounted with Mount.
+		return nil, &PathError{Op: "fstat", Path: f.name, Err: ErrNotImplemented}
+	}
 	var fs fileStat
 	err := ignoringEINTR(func() error {
-		return syscall.Fstat(int(f.handle.(unixFileHandle)), &fs.sys)
+		return syscall.Fstat(int(handle), &fs.sys)
 	})
 	if err != nil {
 		return nil, &PathError{Op: "fstat", Path: f.name, Err: err}
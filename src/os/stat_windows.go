@@ -10,18 +10,17 @@ import (
 	"unsafe"
 )
 
-// Stat returns the FileInfo structure describing file.
-// If there is an error, it will be of type *PathError.
-func (file *File) Stat() (FileInfo, error) {
-	if file == nil {
-		return nil, ErrInvalid
-	}
-
+func (file *File) stat() (FileInfo, error) {
 	if isWindowsNulName(file.name) {
 		return &devNullStat, nil
 	}
 
-	ft, err := syscall.GetFileType(syscallFd(file.handle.(unixFileHandle)))
+	handle, ok := file.handle.(unixFileHandle)
+	if !ok {
+		// A file on a filesystem mounted with Mount.
+		return nil, &PathError{Op: "stat", Path: file.name, Err: ErrNotImplemented}
+	}
+	ft, err := syscall.GetFileType(syscallFd(handle))
 	if err != nil {
 		return nil, &PathError{Op: "GetFileType", Path: file.name, Err: err}
 	}
@@ -30,7 +29,7 @@ func (file *File) Stat() (FileInfo, error) {
 		return &fileStat{name: basename(file.name), filetype: ft}, nil
 	}
 
-	fs, err := newFileStatFromGetFileInformationByHandle(file.name, syscallFd(file.handle.(unixFileHandle)))
+	fs, err := newFileStatFromGetFileInformationByHandle(file.name, syscallFd(handle))
 	if err != nil {
 		return nil, err
 	}
@@ -1,5 +1,26 @@
 package reflect
 
+import (
+	"internal/reflectlite"
+	"unsafe"
+)
+
+// MakeFunc returns a new function of the given Type
+// that wraps the function fn. When called, that new function
+// does the following:
+//
+//   - converts its arguments to a slice of Values.
+//   - runs results := fn(args).
+//   - returns the results as a slice of Values, one per formal result.
+//
+// In calling fn, the implementation does not guarantee that args are
+// addressable. The Value.Call method allows the caller to invoke a typed
+// function in terms of Values; in contrast, MakeFunc allows the caller to
+// implement a typed function in terms of Values.
 func MakeFunc(typ Type, fn func(args []Value) (results []Value)) Value {
-	panic("unimplemented: reflect.MakeFunc()")
+	return Value{reflectlite.MakeFunc(toRawType(typ), func(args []reflectlite.Value) []reflectlite.Value {
+		x := *(*[]Value)(unsafe.Pointer(&args))
+		y := fn(x)
+		return *(*[]reflectlite.Value)(unsafe.Pointer(&y))
+	})}
 }
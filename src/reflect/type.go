@@ -47,9 +47,15 @@
 // - interface types (this is missing the interface methods):
 //     meta         uint8
 //     ptrTo        *typeStruct
-// - signature types (this is missing input and output parameters):
+// - signature types (see funcType):
 //     meta         uint8
+//     nmethods     uint16 (0)
 //     ptrTo        *typeStruct
+//     numIn        uint16
+//     numOut       uint16      // top bit is set for variadic functions
+//     call         *byte       // wrapper used by Value.Call; null if unused
+//     makeFunc     *byte       // trampoline used by MakeFunc; null if unused
+//     params       [...]*typeStruct // numIn parameters followed by numOut results
 // - named types
 //     meta         uint8
 //     nmethods     uint16      // number of methods
@@ -60,6 +66,10 @@
 //
 // The type struct is essentially a union of all the above types. Which it is,
 // can be determined by looking at the meta byte.
+//
+// Types with methods are preceded by a pointer to their method set. It is
+// normally removed by the compiler, but kept when it is needed for
+// Type.Method or Value.Method (see methodSet in internal/reflectlite).
 
 package reflect
 
@@ -111,6 +121,7 @@ const (
 )
 
 // Method represents a single method.
+// This must be kept in sync with [reflectlite.Method].
 type Method struct {
 	// Name is the method name.
 	Name string
@@ -132,6 +143,16 @@ func (m Method) IsExported() bool {
 	return m.PkgPath == ""
 }
 
+func toMethod(m reflectlite.Method) Method {
+	return Method{
+		Name:    m.Name,
+		PkgPath: m.PkgPath,
+		Type:    toType(m.Type),
+		Func:    Value{m.Func},
+		Index:   m.Index,
+	}
+}
+
 // The following Type type has been copied almost entirely from
 // https://github.com/golang/go/blob/go1.15/src/reflect/type.go#L27-L212.
 // Some methods have been commented out as they haven't yet been implemented.
@@ -434,11 +455,11 @@ func (t *rawType) Implements(u Type) bool {
 }
 
 func (t *rawType) In(i int) Type {
-	panic("unimplemented: (reflect.Type).In()")
+	return toType(t.RawType.In(i))
 }
 
 func (t *rawType) IsVariadic() bool {
-	panic("unimplemented: (reflect.Type).IsVariadic()")
+	return t.RawType.IsVariadic()
 }
 
 func (t *rawType) Key() Type {
@@ -446,23 +467,24 @@ func (t *rawType) Key() Type {
 }
 
 func (t *rawType) Method(i int) Method {
-	panic("unimplemented: (reflect.Type).Method()")
+	return toMethod(t.RawType.Method(i))
 }
 
 func (t *rawType) MethodByName(name string) (Method, bool) {
-	panic("unimplemented: (reflect.Type).MethodByName()")
+	m, ok := t.RawType.MethodByName(name)
+	return toMethod(m), ok
 }
 
 func (t *rawType) NumIn() int {
-	panic("unimplemented: (reflect.Type).NumIn()")
+	return t.RawType.NumIn()
 }
 
 func (t *rawType) NumOut() int {
-	panic("unimplemented: (reflect.Type).NumOut()")
+	return t.RawType.NumOut()
 }
 
 func (t *rawType) Out(i int) Type {
-	panic("unimplemented: (reflect.Type).Out()")
+	return toType(t.RawType.Out(i))
 }
 
 // A StructField describes a single field in a struct.
@@ -535,6 +557,11 @@ func FuncOf(in, out []Type, variadic bool) Type {
 	return toType(reflectlite.FuncOf(rawIn, rawOut, variadic))
 }
 
+// ChanOf returns the channel type with the given direction and element type.
+// For example, if t represents int, ChanOf(RecvDir, t) represents <-chan int.
+//
+// TinyGo cannot create new types at runtime, so ChanOf panics if the channel
+// type is not used anywhere in the program.
 func ChanOf(dir ChanDir, t Type) Type {
-	panic("unimplemented: reflect.ChanOf")
+	return toType(reflectlite.ChanOf(dir, toRawType(t)))
 }
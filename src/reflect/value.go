@@ -155,35 +155,78 @@ func (v Value) FieldByNameFunc(match func(string) bool) Value {
 	return Value{v.Value.FieldByNameFunc(match)}
 }
 
-type SelectDir int
+// A SelectDir describes the communication direction of a select case.
+type SelectDir = reflectlite.SelectDir
 
 const (
-	_             SelectDir = iota
-	SelectSend              // case Chan <- Send
-	SelectRecv              // case <-Chan:
-	SelectDefault           // default
+	SelectSend    = reflectlite.SelectSend    // case Chan <- Send
+	SelectRecv    = reflectlite.SelectRecv    // case <-Chan:
+	SelectDefault = reflectlite.SelectDefault // default
 )
 
+// A SelectCase describes a single case in a select operation.
+// The kind of case depends on Dir, the communication direction.
+//
+// If Dir is SelectDefault, the case represents a default case.
+// Chan and Send must be zero Values.
+//
+// If Dir is SelectSend, the case represents a send operation.
+// Normally Chan's underlying value must be a channel, and Send's underlying value must be
+// assignable to the channel's element type. As a special case, if Chan is a zero Value,
+// then the case is ignored, and the field Send will also be ignored and may be either zero
+// or non-zero.
+//
+// If Dir is [SelectRecv], the case represents a receive operation.
+// Normally Chan's underlying value must be a channel and Send must be a zero Value.
+// If Chan is a zero Value, then the case is ignored, but Send must still be a zero Value.
+// When a receive operation is selected, the received Value is returned by Select.
+//
+// This must be kept in sync with [reflectlite.SelectCase].
 type SelectCase struct {
 	Dir  SelectDir // direction of case
 	Chan Value     // channel to use (for send or receive)
 	Send Value     // value to send (for send)
 }
 
+// Select executes a select operation described by the list of cases.
+// Like the Go select statement, it blocks until at least one of the cases
+// can proceed, makes a uniform pseudo-random choice,
+// and then executes that case. It returns the index of the chosen case
+// and, if that case was a receive operation, the value received and a
+// boolean indicating whether the value corresponds to a send on the channel
+// (as opposed to a zero value received because the channel is closed).
+// Select supports a maximum of 65536 cases.
 func Select(cases []SelectCase) (chosen int, recv Value, recvOK bool) {
-	panic("unimplemented: reflect.Select")
+	x := *(*[]reflectlite.SelectCase)(unsafe.Pointer(&cases))
+	chosen, y, recvOK := reflectlite.Select(x)
+	return chosen, Value{y}, recvOK
 }
 
+// MakeChan creates a new channel with the specified type and buffer size.
+func MakeChan(typ Type, buffer int) Value {
+	return Value{reflectlite.MakeChan(toRawType(typ), buffer)}
+}
+
+// Send sends x on the channel v.
+// It panics if v's kind is not [Chan] or if x's type is not the same type as v's element type.
+// As in Go, x's value must be assignable to the channel's element type.
 func (v Value) Send(x Value) {
-	panic("unimplemented: reflect.Value.Send()")
+	v.Value.Send(x.Value)
 }
 
+// TrySend attempts to send x on the channel v but will not block.
+// It panics if v's Kind is not [Chan].
+// It reports whether the value was sent.
+// As in Go, x's value must be assignable to the channel's element type.
 func (v Value) TrySend(x Value) bool {
-	panic("unimplemented: reflect.Value.TrySend()")
+	return v.Value.TrySend(x.Value)
 }
 
+// Close closes the channel v.
+// It panics if v's Kind is not [Chan] or
+// v is a receive-only channel.
 func (v Value) Close() {
-	panic("unimplemented: reflect.Value.Close()")
+	v.Value.Close()
 }
 
 // MakeMap creates a new map with the specified type.
@@ -197,32 +240,72 @@ func MakeMapWithSize(typ Type, n int) Value {
 	return Value{reflectlite.MakeMapWithSize(toRawType(typ), n)}
 }
 
+// Call calls the function v with the input arguments in.
+// For example, if len(in) == 3, v.Call(in) represents the Go call v(in[0], in[1], in[2]).
+// Call panics if v's Kind is not Func.
+// It returns the output results as Values.
+// As in Go, each input argument must be assignable to the
+// type of the function's corresponding input parameter.
+// If v is a variadic function, Call creates the variadic slice parameter
+// itself, copying in the corresponding values.
 func (v Value) Call(in []Value) []Value {
-	panic("unimplemented: (reflect.Value).Call()")
-}
-
+	x := *(*[]reflectlite.Value)(unsafe.Pointer(&in))
+	y := v.Value.Call(x)
+	return *(*[]Value)(unsafe.Pointer(&y))
+}
+
+// CallSlice calls the variadic function v with the input arguments in,
+// assigning the slice in[len(in)-1] to v's final variadic argument.
+// For example, if len(in) == 3, v.CallSlice(in) represents the Go call v(in[0], in[1], in[2]...).
+// CallSlice panics if v's Kind is not Func or if v is not variadic.
+// It returns the output results as Values.
+// As in Go, each input argument must be assignable to the
+// type of the function's corresponding input parameter.
 func (v Value) CallSlice(in []Value) []Value {
-	panic("unimplemented: (reflect.Value).CallSlice()")
+	x := *(*[]reflectlite.Value)(unsafe.Pointer(&in))
+	y := v.Value.CallSlice(x)
+	return *(*[]Value)(unsafe.Pointer(&y))
 }
 
 func (v Value) Equal(u Value) bool {
 	return v.Value.Equal(u.Value)
 }
 
+// Method returns a function value corresponding to v's i'th method.
+// The arguments to a Call on the returned function should not include
+// a receiver; the returned function will always use v as the receiver.
+// Method panics if i is out of range or if v is a nil interface value.
 func (v Value) Method(i int) Value {
-	panic("unimplemented: (reflect.Value).Method()")
+	return Value{v.Value.Method(i)}
 }
 
+// MethodByName returns a function value corresponding to the method
+// of v with the given name.
+// The arguments to a Call on the returned function should not include
+// a receiver; the returned function will always use v as the receiver.
+// It returns the zero Value if no method was found.
 func (v Value) MethodByName(name string) Value {
-	panic("unimplemented: (reflect.Value).MethodByName()")
+	return Value{v.Value.MethodByName(name)}
 }
 
+// Recv receives and returns a value from the channel v.
+// It panics if v's Kind is not [Chan].
+// The receive blocks until a value is ready.
+// The boolean value ok is true if the value x corresponds to a send
+// on the channel, false if it is a zero value received because the channel is closed.
 func (v Value) Recv() (x Value, ok bool) {
-	panic("unimplemented: (reflect.Value).Recv()")
+	y, ok := v.Value.Recv()
+	return Value{y}, ok
 }
 
+// TryRecv attempts to receive a value from the channel v but will not block.
+// It panics if v's Kind is not [Chan].
+// If the receive delivers a value, x is the transferred value and ok is true.
+// If the receive cannot finish without blocking, x is the zero Value and ok is false.
+// If the channel is closed, x is the zero value for the channel's element type and ok is false.
 func (v Value) TryRecv() (x Value, ok bool) {
-	panic("unimplemented: (reflect.Value).TryRecv()")
+	y, ok := v.Value.TryRecv()
+	return Value{y}, ok
 }
 
 func NewAt(typ Type, p unsafe.Pointer) Value {
@@ -97,3 +97,9 @@ func growHeap() bool {
 	// Heap has grown successfully.
 	return true
 }
+
+// shrinkHeap tries to shrink the heap to the given end address. WebAssembly
+// memory cannot shrink.
+func shrinkHeap(newHeapEnd uintptr) bool {
+	return false
+}
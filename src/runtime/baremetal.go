@@ -36,6 +36,12 @@ func growHeap() bool {
 	return false
 }
 
+// shrinkHeap tries to shrink the heap to the given end address. On baremetal,
+// all available RAM is used for the heap anyway.
+func shrinkHeap(newHeapEnd uintptr) bool {
+	return false
+}
+
 //export malloc
 func libc_malloc(size uintptr) unsafe.Pointer {
 	// Note: this zeroes the returned buffer which is not necessary.
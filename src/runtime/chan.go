@@ -175,6 +175,7 @@ func (ch *channel) bufferPush(value unsafe.Pointer) {
 	}
 
 	memcpy(elemAddr, value, ch.elementSize)
+	gcWriteBarrierRange(elemAddr, ch.elementSize)
 }
 
 // Pop a value from the channel buffer and store it in the 'value' pointer, for
@@ -190,6 +191,7 @@ func (ch *channel) bufferPop(value unsafe.Pointer) {
 	}
 
 	memcpy(value, elemAddr, ch.elementSize)
+	gcWriteBarrierRange(value, ch.elementSize)
 
 	// Zero the value to allow the GC to collect it.
 	memzero(elemAddr, ch.elementSize)
@@ -215,6 +217,7 @@ func (ch *channel) trySend(value unsafe.Pointer) bool {
 	if ch.bufLen == 0 {
 		if receiver := ch.receivers.pop(chanOperationOk); receiver != nil {
 			memcpy(receiver.task.Ptr, value, ch.elementSize)
+			gcWriteBarrierRange(receiver.task.Ptr, ch.elementSize)
 			scheduleTask(receiver.task)
 			return true
 		}
@@ -235,6 +238,8 @@ func chanSend(ch *channel, value unsafe.Pointer, op *channelOp) {
 		deadlock()
 	}
 
+	traceChan(traceEvChanSend, ch)
+
 	mask := interrupt.Disable()
 	ch.lock.Lock()
 
@@ -258,6 +263,7 @@ func chanSend(ch *channel, value unsafe.Pointer, op *channelOp) {
 	// Wait until this goroutine is resumed.
 	// It might be resumed after Unlock() and before Pause(). In that case,
 	// because we use semaphores, the Pause() will continue immediately.
+	traceGoBlock(traceBlockChanSend)
 	task.Pause()
 
 	// Check whether the sent happened normally (not because the channel was
@@ -300,6 +306,7 @@ func (ch *channel) tryRecv(value unsafe.Pointer) (received, ok bool) {
 	// immediately.
 	if sender := ch.senders.pop(chanOperationOk); sender != nil {
 		memcpy(value, sender.value, ch.elementSize)
+		gcWriteBarrierRange(value, ch.elementSize)
 		scheduleTask(sender.task)
 		return true, true
 	}
@@ -313,6 +320,8 @@ func chanRecv(ch *channel, value unsafe.Pointer, op *channelOp) bool {
 		deadlock()
 	}
 
+	traceChan(traceEvChanRecv, ch)
+
 	mask := interrupt.Disable()
 	ch.lock.Lock()
 
@@ -334,6 +343,7 @@ func chanRecv(ch *channel, value unsafe.Pointer, op *channelOp) bool {
 	interrupt.Restore(mask)
 
 	// Wait until the goroutine is resumed.
+	traceGoBlock(traceBlockChanRecv)
 	task.Pause()
 
 	// Return whether the receive happened from a closed channel.
@@ -348,6 +358,8 @@ func chanClose(ch *channel) {
 		runtimePanic("close of nil channel")
 	}
 
+	traceChan(traceEvChanClose, ch)
+
 	mask := interrupt.Disable()
 	ch.lock.Lock()
 
@@ -470,6 +482,9 @@ func chanSelect(recvbuf unsafe.Pointer, states []chanSelectState, ops []channelO
 		unlockAllStates(states)
 		chanSelectLock.Unlock()
 		interrupt.Restore(mask)
+		if selectIndex != selectNoIndex {
+			traceChanSelect(&states[selectIndex])
+		}
 		return selectIndex, selectOk
 	}
 
@@ -501,6 +516,7 @@ func chanSelect(recvbuf unsafe.Pointer, states []chanSelectState, ops []channelO
 	unlockAllStates(states)
 	chanSelectLock.Unlock()
 	interrupt.Restore(mask)
+	traceGoBlock(traceBlockSelect)
 	task.Pause()
 
 	// Resumed, so one channel operation must have progressed.
@@ -528,6 +544,7 @@ func chanSelect(recvbuf unsafe.Pointer, states []chanSelectState, ops []channelO
 	// Pull the return values out of t.Data (which contains two bitfields).
 	selectIndex = t.DataUint32() >> 2
 	selectOk = t.DataUint32()&chanOperationMask != chanOperationClosed
+	traceChanSelect(&states[selectIndex])
 
 	return selectIndex, selectOk
 }
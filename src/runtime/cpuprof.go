@@ -0,0 +1,56 @@
+package runtime
+
+// SetCPUProfileRate sets the CPU profiling rate to hz samples per second.
+// If hz <= 0, SetCPUProfileRate turns off profiling.
+// If the profiler is on, the rate cannot be changed without first turning it off.
+//
+// CPU profiling is currently only supported on Linux, and only when the
+// symbol table is included in the binary (-symtab=full). Most clients should
+// use the runtime/pprof package instead of calling SetCPUProfileRate directly.
+func SetCPUProfileRate(hz int) {
+	if !hasCPUProfile {
+		return
+	}
+	if hz < 0 {
+		hz = 0
+	}
+	if hz > 1000000 {
+		hz = 1000000
+	}
+	if hz != 0 && cpuProfileRunning() {
+		println("runtime: cannot set cpu profile rate until previous profile has finished.")
+		return
+	}
+	if errno := cpuProfileSetRate(hz); errno != 0 {
+		println("runtime: cannot start the cpu profiler: errno", errno)
+	}
+}
+
+//go:linkname pprof_cpuProfileSupported runtime/pprof.cpuProfileSupported
+func pprof_cpuProfileSupported() bool {
+	return hasCPUProfile
+}
+
+// Start the CPU profiler like SetCPUProfileRate, but return the error number
+// if it couldn't be started instead of printing it.
+//
+//go:linkname pprof_startCPUProfile runtime/pprof.startCPUProfile
+func pprof_startCPUProfile(hz int) (errno uintptr) {
+	if !hasCPUProfile {
+		return 0
+	}
+	return cpuProfileSetRate(hz)
+}
+
+// Read (and discard) the samples collected by the CPU profiler. The profiler
+// must have been stopped with SetCPUProfileRate(0) before calling this
+// function. The fn callback is called for each unique stack, and the number of
+// samples that could not be recorded is returned.
+//
+//go:linkname pprof_readCPUProfile runtime/pprof.readCPUProfile
+func pprof_readCPUProfile(fn func(count int, stack []uintptr)) (lost int) {
+	if !hasCPUProfile {
+		return 0
+	}
+	return cpuProfileRead(fn)
+}
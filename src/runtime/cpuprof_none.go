@@ -0,0 +1,21 @@
+//go:build !symtab.full || !linux || baremetal || nintendoswitch
+
+package runtime
+
+// CPU profiling is not supported on this system.
+const hasCPUProfile = false
+
+func cpuProfileRunning() bool {
+	return false
+}
+
+func cpuProfileSetRate(hz int) uintptr {
+	return 0
+}
+
+func cpuProfileRead(fn func(count int, stack []uintptr)) int {
+	return 0
+}
+
+func cpuProfileSignal(pc, sp, fp uintptr) {
+}
@@ -0,0 +1,157 @@
+//go:build symtab.full && linux && !baremetal && !nintendoswitch
+
+package runtime
+
+// CPU profiler based on SIGPROF. The signal is sent by the kernel at a regular
+// interval of consumed CPU time (see setitimer(2)), and the signal handler
+// walks the stack of the interrupted code and records it in a hash table of
+// unique stacks. The table is read after profiling has been stopped.
+//
+// The signal handler can't allocate memory or take locks, so the table has a
+// fixed size that is allocated when profiling starts. Samples that don't fit
+// are counted as lost.
+
+import "sync/atomic"
+
+const hasCPUProfile = true
+
+const (
+	cpuProfileMaxStack = 64   // maximum number of frames in a sample
+	cpuProfileEntries  = 1024 // number of unique stacks that can be stored
+	cpuProfileProbes   = 16   // maximum number of entries to probe in the hash table
+)
+
+// A single unique stack in the profile, with the number of times it was seen.
+type cpuProfileEntry struct {
+	count uintptr
+	depth uintptr
+	stack [cpuProfileMaxStack]uintptr
+}
+
+var cpuProfile struct {
+	hz      atomic.Uint32 // sampling rate, or 0 when the profiler is off
+	busy    atomic.Uint32 // set while the table is in use
+	entries []cpuProfileEntry
+	lost    atomic.Uintptr // updated from the signal handler
+}
+
+//export tinygo_profile_start
+func tinygo_profile_start(hz uint32) int32
+
+//export tinygo_profile_stop
+func tinygo_profile_stop()
+
+func cpuProfileRunning() bool {
+	return cpuProfile.hz.Load() != 0
+}
+
+// cpuProfileSetRate starts or stops the profiler. It returns the error number
+// if the profiler couldn't be started.
+func cpuProfileSetRate(hz int) (errno uintptr) {
+	if hz == 0 {
+		if cpuProfile.hz.Swap(0) != 0 {
+			tinygo_profile_stop()
+		}
+		return 0
+	}
+
+	// Start with an empty table.
+	cpuProfileLock()
+	cpuProfile.entries = make([]cpuProfileEntry, cpuProfileEntries)
+	cpuProfile.lost.Store(0)
+	cpuProfile.busy.Store(0)
+
+	cpuProfile.hz.Store(uint32(hz))
+	if errno := tinygo_profile_start(uint32(hz)); errno != 0 {
+		cpuProfile.hz.Store(0)
+		cpuProfile.entries = nil
+		cpuProfile.busy.Store(0)
+		return uintptr(errno)
+	}
+	return 0
+}
+
+func cpuProfileRead(fn func(count int, stack []uintptr)) int {
+	// Wait for signal handlers that might still be running.
+	cpuProfileLock()
+	entries := cpuProfile.entries
+	lost := cpuProfile.lost.Swap(0)
+	cpuProfile.entries = nil
+	cpuProfile.busy.Store(0)
+
+	for i := range entries {
+		entry := &entries[i]
+		if entry.count != 0 {
+			fn(int(entry.count), entry.stack[:entry.depth])
+		}
+	}
+	return int(lost)
+}
+
+// cpuProfileLock waits until no signal handler is using the table anymore and
+// claims it.
+func cpuProfileLock() {
+	for !cpuProfile.busy.CompareAndSwap(0, 1) {
+		Gosched()
+	}
+}
+
+// cpuProfileSignal is called from the SIGPROF signal handler, with the program
+// counter, stack pointer and frame pointer of the interrupted code.
+func cpuProfileSignal(pc, sp, fp uintptr) {
+	if cpuProfile.hz.Load() == 0 {
+		// A signal that was still pending when the profiler was stopped.
+		return
+	}
+	var stack [cpuProfileMaxStack]uintptr
+	depth := callersFrom(pc, sp, fp, stack[:])
+
+	if !cpuProfile.busy.CompareAndSwap(0, 1) {
+		// Another thread is recording a sample at the same time (or the table
+		// is being read).
+		cpuProfile.lost.Add(1)
+		return
+	}
+	if cpuProfile.entries != nil {
+		cpuProfileAdd(stack[:depth])
+	}
+	cpuProfile.busy.Store(0)
+}
+
+// cpuProfileAdd records a single sample in the hash table.
+func cpuProfileAdd(stack []uintptr) {
+	// FNV-1a hash of the stack.
+	hash := uintptr(2166136261)
+	for _, pc := range stack {
+		hash ^= pc
+		hash *= 16777619
+	}
+
+	entries := cpuProfile.entries
+	for i := uintptr(0); i < cpuProfileProbes; i++ {
+		entry := &entries[(hash+i)%uintptr(len(entries))]
+		if entry.count == 0 {
+			// Empty slot, claim it.
+			entry.count = 1
+			entry.depth = uintptr(len(stack))
+			copy(entry.stack[:], stack)
+			return
+		}
+		if entry.depth == uintptr(len(stack)) && cpuProfileStackEqual(entry.stack[:entry.depth], stack) {
+			entry.count++
+			return
+		}
+	}
+
+	// The table is (locally) full.
+	cpuProfile.lost.Add(1)
+}
+
+func cpuProfileStackEqual(a, b []uintptr) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
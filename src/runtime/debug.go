@@ -1,13 +1,15 @@
 package runtime
 
+import "internal/task"
+
 // Stub for NumCgoCall, does not return the real value
 func NumCgoCall() int {
 	return 0
 }
 
-// Stub for NumGoroutine, does not return the real value
+// NumGoroutine returns the number of goroutines that currently exist.
 func NumGoroutine() int {
-	return 1
+	return task.NumGoroutines()
 }
 
 // Stub for Breakpoint, does not do anything.
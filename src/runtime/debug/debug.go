@@ -7,40 +7,66 @@ package debug
 
 import (
 	"fmt"
+	"os"
 	"runtime"
 	"strconv"
 	"strings"
 )
 
 // SetMaxStack sets the maximum amount of memory that can be used by a single
-// goroutine stack.
+// goroutine stack. If a goroutine is started with a larger stack, the program
+// panics. SetMaxStack returns the previous setting. The initial setting is
+// 1 GB on 64-bit systems, 250 MB on 32-bit systems.
 //
-// Not implemented.
+// Goroutine stacks do not grow in TinyGo, so the limit is only checked when a
+// goroutine is started.
 func SetMaxStack(n int) int {
-	return n
+	return setMaxStack(n)
 }
 
+// Implemented in the runtime.
+func setMaxStack(int) int
+
 // PrintStack prints to standard error the stack trace returned by runtime.Stack.
-//
-// Not implemented.
-func PrintStack() {}
+func PrintStack() {
+	os.Stderr.Write(Stack())
+}
 
 // Stack returns a formatted stack trace of the goroutine that calls it.
-//
-// Not implemented.
+// It calls runtime.Stack with a large enough buffer to capture the entire trace.
+// The program must be built with -symtab=full for the trace to include
+// function names and source locations.
 func Stack() []byte {
-	return nil
+	buf := make([]byte, 1024)
+	for {
+		n := runtime.Stack(buf, false)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
 }
 
 // ReadBuildInfo returns the build information embedded
 // in the running binary. The information is available only
 // in binaries built with module support.
-//
-// Not implemented.
 func ReadBuildInfo() (info *BuildInfo, ok bool) {
-	return &BuildInfo{GoVersion: runtime.Compiler + runtime.Version()}, true
+	data := modinfo()
+	if len(data) < 32 {
+		return nil, false
+	}
+	data = data[16 : len(data)-16]
+	bi, err := ParseBuildInfo(data)
+	if err != nil {
+		return nil, false
+	}
+	bi.GoVersion = runtime.Compiler + runtime.Version()
+	return bi, true
 }
 
+// Implemented in the runtime.
+func modinfo() string
+
 // BuildInfo represents the build information read from
 // the running binary.
 type BuildInfo struct {
@@ -66,11 +92,26 @@ type Module struct {
 	Replace *Module // replaced by this module
 }
 
-// Not implemented.
+// SetGCPercent sets the garbage collection target percentage: a collection is
+// triggered when the ratio of freshly allocated data to live data remaining
+// after the previous collection reaches this percentage. SetGCPercent returns
+// the previous setting. The initial setting is the value of the GOGC
+// environment variable at startup, or 100 if the variable is not set. A
+// negative percentage disables garbage collection, unless the memory limit is
+// reached.
+//
+// Only the block-based garbage collectors (-gc=conservative, -gc=precise,
+// -gc=incremental and -gc=sizeclass) use this setting.
 func SetGCPercent(n int) int {
-	return n
+	if n > 1<<31-1 {
+		n = 1<<31 - 1
+	}
+	return int(setGCPercent(int32(n)))
 }
 
+// Implemented in the runtime.
+func setGCPercent(int32) int32
+
 // Start of stolen from big go. TODO: import/reuse without copy pasta.
 
 // quoteKey reports whether key is required to be quoted.
@@ -127,3 +168,147 @@ func (bi *BuildInfo) String() string {
 
 	return buf.String()
 }
+
+// ParseBuildInfo parses the string returned by [*BuildInfo.String],
+// restoring the original BuildInfo,
+// except that the GoVersion field is not set.
+// Programs should normally not call this function,
+// but instead call [ReadBuildInfo].
+func ParseBuildInfo(data string) (bi *BuildInfo, err error) {
+	lineNum := 1
+	defer func() {
+		if err != nil {
+			err = fmt.Errorf("could not parse Go build info: line %d: %w", lineNum, err)
+		}
+	}()
+
+	const (
+		pathLine  = "path\t"
+		modLine   = "mod\t"
+		depLine   = "dep\t"
+		repLine   = "=>\t"
+		buildLine = "build\t"
+		newline   = "\n"
+		tab       = "\t"
+	)
+
+	readModuleLine := func(elem []string) (Module, error) {
+		if len(elem) != 2 && len(elem) != 3 {
+			return Module{}, fmt.Errorf("expected 2 or 3 columns; got %d", len(elem))
+		}
+		version := elem[1]
+		sum := ""
+		if len(elem) == 3 {
+			sum = elem[2]
+		}
+		return Module{
+			Path:    elem[0],
+			Version: version,
+			Sum:     sum,
+		}, nil
+	}
+
+	bi = new(BuildInfo)
+	var (
+		last *Module
+		line string
+		ok   bool
+	)
+	// Reverse of BuildInfo.String(), except for go version.
+	for len(data) > 0 {
+		line, data, ok = strings.Cut(data, newline)
+		if !ok {
+			break
+		}
+		switch {
+		case strings.HasPrefix(line, pathLine):
+			bi.Path = line[len(pathLine):]
+		case strings.HasPrefix(line, modLine):
+			elem := strings.Split(line[len(modLine):], tab)
+			last = &bi.Main
+			*last, err = readModuleLine(elem)
+			if err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(line, depLine):
+			elem := strings.Split(line[len(depLine):], tab)
+			last = new(Module)
+			bi.Deps = append(bi.Deps, last)
+			*last, err = readModuleLine(elem)
+			if err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(line, repLine):
+			elem := strings.Split(line[len(repLine):], tab)
+			if len(elem) != 3 {
+				return nil, fmt.Errorf("expected 3 columns for replacement; got %d", len(elem))
+			}
+			if last == nil {
+				return nil, fmt.Errorf("replacement with no module on previous line")
+			}
+			last.Replace = &Module{
+				Path:    elem[0],
+				Version: elem[1],
+				Sum:     elem[2],
+			}
+			last = nil
+		case strings.HasPrefix(line, buildLine):
+			kv := line[len(buildLine):]
+			if len(kv) < 1 {
+				return nil, fmt.Errorf("build line missing '='")
+			}
+
+			var key, rawValue string
+			switch kv[0] {
+			case '=':
+				return nil, fmt.Errorf("build line with missing key")
+
+			case '`', '"':
+				rawKey, err := strconv.QuotedPrefix(kv)
+				if err != nil {
+					return nil, fmt.Errorf("invalid quoted key in build line")
+				}
+				if len(kv) == len(rawKey) {
+					return nil, fmt.Errorf("build line missing '=' after quoted key")
+				}
+				if c := kv[len(rawKey)]; c != '=' {
+					return nil, fmt.Errorf("unexpected character after quoted key: %q", c)
+				}
+				key, _ = strconv.Unquote(rawKey)
+				rawValue = kv[len(rawKey)+1:]
+
+			default:
+				var ok bool
+				key, rawValue, ok = strings.Cut(kv, "=")
+				if !ok {
+					return nil, fmt.Errorf("build line missing '=' after key")
+				}
+				if quoteKey(key) {
+					return nil, fmt.Errorf("unquoted key %q must be quoted", key)
+				}
+			}
+
+			var value string
+			if len(rawValue) > 0 {
+				switch rawValue[0] {
+				case '`', '"':
+					var err error
+					value, err = strconv.Unquote(rawValue)
+					if err != nil {
+						return nil, fmt.Errorf("invalid quoted value in build line")
+					}
+
+				default:
+					value = rawValue
+					if quoteValue(value) {
+						return nil, fmt.Errorf("unquoted value %q must be quoted", value)
+					}
+				}
+			}
+
+			bi.Settings = append(bi.Settings, BuildSetting{Key: key, Value: value})
+		}
+		lineNum++
+	}
+	return bi, nil
+}
@@ -30,8 +30,32 @@ func SetPanicOnFault(enabled bool) bool {
 
 func WriteHeapDump(fd uintptr)
 
+// SetTraceback sets the amount of detail printed by the runtime in the
+// traceback it prints before exiting due to a panic. The level argument takes
+// the same values as the GOTRACEBACK environment variable, and SetTraceback
+// cannot reduce the level below the one set in that environment variable.
+//
+// TinyGo can only print the stack of the current goroutine, so "all" is the
+// same as "single", and "crash" is the same as "system".
 func SetTraceback(level string)
 
+// SetMemoryLimit provides the runtime with a soft memory limit.
+//
+// The garbage collector runs more often when the heap gets close to the limit,
+// and returns free memory at the end of the heap to the operating system when
+// the heap is bigger than the limit. The limit is soft: the heap still grows
+// beyond it if the memory is needed.
+//
+// SetMemoryLimit returns the previously set memory limit. A negative input
+// does not adjust the limit, and allows for retrieval of the currently set
+// memory limit. The initial setting is the value of the GOMEMLIMIT environment
+// variable at startup, or math.MaxInt64 if the variable is not set.
+//
+// Only the block-based garbage collectors (-gc=conservative, -gc=precise,
+// -gc=incremental and -gc=sizeclass) use this setting.
 func SetMemoryLimit(limit int64) int64 {
-	return limit
+	return setMemoryLimit(limit)
 }
+
+// Implemented in the runtime.
+func setMemoryLimit(int64) int64
@@ -0,0 +1,24 @@
+//go:build !baremetal && !js && !wasm_unknown && !nintendoswitch && !wasip2
+
+package runtime
+
+import "unsafe"
+
+// char *getenv(const char *name);
+//
+//export getenv
+func libc_getenv(name *byte) *byte
+
+func init() {
+	readGCSettings()
+}
+
+// lookupEnv returns the value of an environment variable, if it is set.
+func lookupEnv(key string) (string, bool) {
+	keydata := cstring(key)
+	value := libc_getenv(&keydata[0])
+	if value == nil {
+		return "", false
+	}
+	return string(unsafe.Slice(value, strlen(unsafe.Pointer(value)))), true
+}
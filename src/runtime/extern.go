@@ -1,9 +1,5 @@
 package runtime
 
-func Callers(skip int, pc []uintptr) int {
-	return 0
-}
-
 // buildVersion is the Tinygo tree's version string at build time.
 //
 // This is set by the linker.
@@ -14,3 +10,14 @@ var buildVersion string
 func Version() string {
 	return buildVersion
 }
+
+// modinfo is the module information of the program, in the same format as
+// with the gc toolchain (including the markers at the start and end).
+//
+// This is set by the linker.
+var modinfo string
+
+//go:linkname debug_modinfo runtime/debug.modinfo
+func debug_modinfo() string {
+	return modinfo
+}
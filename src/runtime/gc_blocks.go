@@ -1,4 +1,4 @@
-//go:build gc.conservative || gc.precise
+//go:build gc.conservative || gc.precise || gc.incremental || gc.sizeclass
 
 package runtime
 
@@ -23,6 +23,14 @@ package runtime
 // metadataStart..heapEnd. The actual blocks are stored in
 // heapStart..metadataStart.
 //
+// With -gc=incremental, GC cycles are split into small steps that run during
+// allocation instead of stopping the program for the whole cycle. See
+// gc_incremental.go for details.
+//
+// With -gc=sizeclass, small objects are allocated in spans of blocks that only
+// contain objects of the same size, to avoid fragmentation of the heap. See
+// gc_sizeclass.go for details.
+//
 // More information:
 // https://aykevl.nl/2020/09/gc-tinygo
 // https://github.com/micropython/micropython/wiki/Memory-Manager
@@ -55,7 +63,21 @@ var (
 	endBlock      gcBlock        // the block just past the end of the available space
 	gcTotalAlloc  uint64         // total number of bytes allocated
 	gcMallocs     uint64         // total number of allocations
+	gcCycles      uint32         // number of completed GC cycles
+	gcForced      uint32         // number of GC cycles started by calling runtime.GC
 	gcLock        task.PMutex    // lock to avoid race conditions on multicore systems
+	gcHeapLive    uintptr        // bytes in use after the last GC cycle
+	gcHeapAlloc   uintptr        // bytes allocated since the last GC cycle
+)
+
+const (
+	// The minimum heap goal with GOGC=100, like in the gc toolchain. Smaller
+	// heaps are only collected when they are full (or when the memory limit is
+	// reached), so this doesn't change anything on most microcontrollers.
+	gcMinHeapGoal = 4 << 20
+
+	// The minimum amount of memory to return to the system at once.
+	gcMinRelease = 64 << 10
 )
 
 // zeroSizedAlloc is just a sentinel that gets returned when allocating 0 bytes.
@@ -321,40 +343,64 @@ func initHeap() {
 	buildFreeRanges()
 }
 
-// setHeapEnd is called to expand the heap. The heap can only grow, not shrink.
-// Also, the heap should grow substantially each time otherwise growing the heap
-// will be expensive.
+// setHeapEnd is called to grow or shrink the heap. The heap should grow
+// substantially each time otherwise growing the heap will be expensive. It
+// can only shrink as far as releaseHeap allows, as all blocks that are removed
+// must be free.
 func setHeapEnd(newHeapEnd uintptr) {
-	if gcAsserts && newHeapEnd <= heapEnd {
-		runtimePanic("gc: setHeapEnd didn't grow the heap")
+	if gcAsserts && newHeapEnd == heapEnd {
+		runtimePanic("gc: setHeapEnd didn't change the heap")
 	}
 
 	// Save some old variables we need later.
+	oldHeapEnd := heapEnd
 	oldMetadataStart := metadataStart
 	oldMetadataSize := heapEnd - uintptr(metadataStart)
 
-	// Increase the heap. After setting the new heapEnd, calculateHeapAddresses
-	// will update metadataStart and the memcpy will copy the metadata to the
-	// new location.
-	// The new metadata will be bigger than the old metadata, but a simple
-	// memcpy is fine as it only copies the old metadata and the new memory will
-	// have been zero initialized.
+	// Change the heap. After setting the new heapEnd, calculateHeapAddresses
+	// will update metadataStart, and the metadata is copied to the new
+	// location.
 	heapEnd = newHeapEnd
 	calculateHeapAddresses()
-	memcpy(metadataStart, oldMetadataStart, oldMetadataSize)
+	metadataSize := heapEnd - uintptr(metadataStart)
 
-	// Note: the memcpy above assumes the heap grows enough so that the new
-	// metadata does not overlap the old metadata. If that isn't true, memmove
-	// should be used to avoid corruption.
-	// This assert checks whether that's true.
-	if gcAsserts && uintptr(metadataStart) < uintptr(oldMetadataStart)+oldMetadataSize {
-		runtimePanic("gc: heap did not grow enough at once")
+	if newHeapEnd < oldHeapEnd {
+		// The heap shrinks, so the metadata moves down and may overlap the
+		// old metadata. Only the metadata of the remaining blocks is kept.
+		memmove(metadataStart, oldMetadataStart, metadataSize)
+	} else {
+		// The new metadata will be bigger than the old metadata, but a simple
+		// memcpy is fine as it only copies the old metadata. The rest is
+		// cleared, as memory that was released before may not be zero.
+		memcpy(metadataStart, oldMetadataStart, oldMetadataSize)
+		memzero(unsafe.Add(metadataStart, oldMetadataSize), metadataSize-oldMetadataSize)
+
+		// Note: the memcpy above assumes the heap grows enough so that the new
+		// metadata does not overlap the old metadata. If that isn't true,
+		// memmove should be used to avoid corruption.
+		// This assert checks whether that's true.
+		if gcAsserts && uintptr(metadataStart) < uintptr(oldMetadataStart)+oldMetadataSize {
+			runtimePanic("gc: heap did not grow enough at once")
+		}
 	}
 
 	// Rebuild the free ranges list.
 	buildFreeRanges()
 }
 
+// heapSizeFor returns the smallest heap size that has room for the given
+// number of blocks and their metadata.
+func heapSizeFor(blocks uintptr) uintptr {
+	size := blocks*bytesPerBlock + (blocks+blocksPerStateByte-1)/blocksPerStateByte
+	for {
+		metadataSize := (size + blocksPerStateByte*bytesPerBlock) / (1 + blocksPerStateByte*bytesPerBlock)
+		if (size-metadataSize)/bytesPerBlock >= blocks {
+			return size
+		}
+		size += bytesPerBlock
+	}
+}
+
 // calculateHeapAddresses initializes variables such as metadataStart and
 // numBlock based on heapStart and heapEnd.
 //
@@ -417,22 +463,52 @@ func alloc(size uintptr, layout unsafe.Pointer) unsafe.Pointer {
 	gcTotalAlloc += uint64(rawSize)
 	gcMallocs++
 
+	if gcIncremental {
+		// Do some work of an incremental GC cycle, or start a new one.
+		gcStep()
+	}
+
 	// Acquire a range of free blocks.
 	var ranGC bool
 	var grewHeap bool
 	var pointer unsafe.Pointer
 	for {
-		pointer = popFreeRange(neededBlocks)
+		if gcPacing && !ranGC && uint64(gcHeapLive)+uint64(gcHeapAlloc)+uint64(size) > gcHeapGoal() {
+			// The heap goal was reached, so run the collector first.
+			runGC()
+			ranGC = true
+		}
+
+		if gcSizeClasses {
+			// Small objects are allocated in the span of their size class.
+			pointer = sizeClassAlloc(neededBlocks)
+		}
+		if pointer == nil {
+			pointer = popFreeRange(neededBlocks)
+		}
 		if pointer != nil {
 			break
 		}
 
+		if gcIncremental && gcSweepAssist() {
+			// Part of the heap that wasn't swept yet was swept, which may have
+			// freed enough space.
+			continue
+		}
+
 		if !ranGC {
+			// Grow the heap instead of running the collector if the heap
+			// goal hasn't been reached yet.
+			if gcPacing && gcHeapCanGrow() && growHeap() {
+				grewHeap = true
+				continue
+			}
+
 			// Run the collector and try again.
 			freeBytes := runGC()
 			ranGC = true
 			heapSize := uintptr(metadataStart) - heapStart
-			if freeBytes < heapSize/3 {
+			if freeBytes < heapSize/3 && gcHeapCanGrow() {
 				// Ensure there is at least 33% headroom.
 				// This percentage was arbitrarily chosen, and may need to
 				// be tuned in the future.
@@ -456,6 +532,10 @@ func alloc(size uintptr, layout unsafe.Pointer) unsafe.Pointer {
 		runtimePanicAt(returnAddress(0), "out of memory")
 	}
 
+	if gcPacing {
+		gcHeapAlloc += size
+	}
+
 	// Set the backing blocks as being allocated.
 	block := blockFromAddr(uintptr(pointer))
 	block.setState(blockStateHead)
@@ -466,15 +546,24 @@ func alloc(size uintptr, layout unsafe.Pointer) unsafe.Pointer {
 	// Create the object header.
 	header := (*objHeader)(pointer)
 	header.layout = parseGCLayout(layout)
+	if gcIncremental {
+		gcAllocated(unsafe.Pointer(header))
+	}
+	add := align(unsafe.Sizeof(objHeader{}))
+	pointer = unsafe.Add(pointer, add)
+	size -= add
+
+	// Check whether this allocation should be sampled for the heap profile.
+	sampled := hasMemProfile && memProfileSample(pointer, rawSize)
 
 	// We've claimed this allocation, now we can unlock the heap.
 	gcLock.Unlock()
 
 	// Return a pointer to this allocation.
-	add := align(unsafe.Sizeof(objHeader{}))
-	pointer = unsafe.Add(pointer, add)
-	size -= add
 	memzero(pointer, size)
+	if sampled {
+		memProfileAlloc(pointer, rawSize)
+	}
 	return pointer
 }
 
@@ -508,7 +597,13 @@ func free(ptr unsafe.Pointer) {
 func GC() {
 	gcLock.Lock()
 	runGC()
+	gcForced++
 	gcLock.Unlock()
+
+	if !hasScheduler && !hasParallelism {
+		// There is no goroutine to run the finalizers, so run them now.
+		runFinalizers()
+	}
 }
 
 // runGC performs a garbage collection cycle. It is the internal implementation
@@ -518,9 +613,38 @@ func runGC() (freeBytes uintptr) {
 	if gcDebug {
 		println("running collection cycle...")
 	}
+	if !gcTakeOverCycle() {
+		traceGCStart()
+	}
 
 	// Mark phase: mark all reachable objects, recursively.
+	markPhase()
+
+	// If we're using threads, resume all other threads before starting the
+	// sweep.
+	gcResumeWorld()
+
+	// Sweep phase: free all non-marked objects and unmark marked objects for
+	// the next collection cycle.
+	sweep()
+
+	// Rebuild the free ranges list.
+	freeBytes = finishCycle(buildFreeRanges())
+
+	// Show how much has been sweeped, for debugging.
+	if gcDebug {
+		dumpHeap()
+	}
+
+	return
+}
+
+// markPhase marks all reachable objects, and handles the objects that weren't
+// reached: weak pointers to them are cleared and their finalizers are queued.
+// With the incremental GC, it finishes the mark phase of an incremental cycle.
+func markPhase() {
 	gcMarkReachable()
+	markFinalizers()
 
 	if baremetal && hasScheduler {
 		// Channel operations in interrupts may move task pointers around while we are marking.
@@ -554,23 +678,104 @@ func runGC() (freeBytes uintptr) {
 		finishMark()
 	}
 
-	// If we're using threads, resume all other threads before starting the
-	// sweep.
-	gcResumeWorld()
+	// Mark the pointers that interrupts stored during an incremental cycle.
+	// This ends the mark phase.
+	gcMarkTerminate()
 
-	// Sweep phase: free all non-marked objects and unmark marked objects for
-	// the next collection cycle.
-	sweep()
+	// Clear weak pointers to unreachable objects. This must be done before
+	// queueing finalizers, which may make these objects reachable again.
+	clearWeakPointers()
 
-	// Rebuild the free ranges list.
-	freeBytes = buildFreeRanges()
+	// Queue the finalizers of unreachable objects, which keeps these objects
+	// alive until the finalizers have run.
+	queueFinalizers()
 
-	// Show how much has been sweeped, for debugging.
-	if gcDebug {
-		dumpHeap()
+	// Update the heap profile with the sampled objects that will be freed.
+	if hasMemProfile {
+		memProfileSweep()
 	}
+}
 
-	return
+// finishCycle is called at the end of a GC cycle with the number of free bytes
+// in the heap. It updates the heap goal, returns memory to the system if the
+// heap is bigger than needed, and returns the number of free bytes afterwards.
+func finishCycle(freeBytes uintptr) uintptr {
+	if gcPacing {
+		gcHeapLive = uintptr(metadataStart) - heapStart - freeBytes
+		gcHeapAlloc = 0
+		freeBytes = releaseHeap(freeBytes)
+	}
+	gcCycles++
+	wakeFinalizers()
+	traceGCDone()
+	return freeBytes
+}
+
+// gcHeapGoal returns the number of bytes in use at which the next GC cycle
+// starts. It depends on the amount of memory in use after the last cycle and
+// GOGC, and is capped by the memory limit.
+func gcHeapGoal() uint64 {
+	goal := uint64(maxMemoryLimit)
+	if gcPercent >= 0 {
+		goal = uint64(gcHeapLive) + uint64(gcHeapLive)*uint64(gcPercent)/100
+		if minGoal := uint64(gcMinHeapGoal) * uint64(gcPercent) / 100; goal < minGoal {
+			goal = minGoal
+		}
+	}
+	if limit := uint64(gcMemoryLimit); goal > limit {
+		goal = limit
+	}
+	return goal
+}
+
+// gcHeapCanGrow returns whether the heap may grow without running the
+// collector first: the heap goal hasn't been reached and the heap is smaller
+// than the memory limit. Without pacing, the heap may always grow.
+func gcHeapCanGrow() bool {
+	if !gcPacing {
+		return true
+	}
+	inUse := uint64(gcHeapLive) + uint64(gcHeapAlloc)
+	return inUse < gcHeapGoal() && uint64(heapEnd-heapStart) < uint64(gcMemoryLimit)
+}
+
+// releaseHeap returns free memory at the end of the heap to the system, if the
+// heap is a lot bigger than needed for the next heap goal, or bigger than the
+// memory limit. It is called at the end of a GC cycle with the number of free
+// bytes in the heap, and returns the number of free bytes afterwards.
+func releaseHeap(freeBytes uintptr) uintptr {
+	heapSize := uint64(heapEnd - heapStart)
+	target := gcHeapGoal()
+	target += target / 2
+	if limit := uint64(gcMemoryLimit); target > limit {
+		target = limit
+	}
+	if heapSize <= target || heapSize-target < gcMinRelease {
+		return freeBytes
+	}
+
+	// Only free blocks at the end of the heap can be released.
+	used := endBlock
+	for used > 0 && (used-1).state() == blockStateFree {
+		used--
+	}
+	if gcSizeClasses && sizeClassSpans != nil && used < sizeClassSpans.end() {
+		// The last span may end with free slots, which can't be released.
+		used = sizeClassSpans.end()
+	}
+	newSize := heapSizeFor(uintptr(used))
+	if uint64(newSize) < target {
+		newSize = uintptr(target)
+	}
+	if heapSize-uint64(newSize) < gcMinRelease {
+		return freeBytes
+	}
+
+	oldEndBlock := endBlock
+	if !shrinkHeap(heapStart + newSize) {
+		return freeBytes
+	}
+	return freeBytes - uintptr(oldEndBlock-endBlock)*bytesPerBlock
 }
 
 // markRoots reads all pointers from start to end (exclusive) and if they look
@@ -609,6 +814,21 @@ func markCurrentGoroutineStack(sp uintptr) {
 	// This could be optimized by only marking the stack area that's currently
 	// in use.
 	markRoot(0, sp)
+
+	if gcIncremental {
+		// The stack may have been marked and scanned earlier in this cycle,
+		// so scan the part that is in use again.
+		scanGoroutineStack(sp)
+	}
+}
+
+// scanGoroutineStack scans the part of a goroutine stack that is in use, from
+// the stack pointer to the end of the stack object.
+func scanGoroutineStack(sp uintptr) {
+	if !isOnHeap(sp) {
+		return
+	}
+	markRoots(sp, blockFromAddr(sp).findNext().address())
 }
 
 // finishMark finishes the marking process by scanning all heap objects on scanList.
@@ -676,8 +896,15 @@ func markRoot(addr, root uintptr) {
 // Sweep goes through all memory and frees unmarked memory.
 func sweep() {
 	metadataEnd := unsafe.Add(metadataStart, (endBlock+(blocksPerStateByte-1))/blocksPerStateByte)
-	var carry byte
-	for meta := metadataStart; meta != metadataEnd; meta = unsafe.Add(meta, 1) {
+	sweepRange(metadataStart, metadataEnd, 0)
+}
+
+// sweepRange sweeps the blocks of the state bytes from start to end
+// (exclusive). The carry is the carry of the previous state byte (see below),
+// and the carry for the next state byte is returned. This allows the heap to be
+// swept in parts.
+func sweepRange(start, end unsafe.Pointer, carry byte) byte {
+	for meta := start; meta != end; meta = unsafe.Add(meta, 1) {
 		// Fetch the state byte.
 		stateBytePtr := (*byte)(unsafe.Pointer(meta))
 		stateByte := *stateBytePtr
@@ -723,6 +950,7 @@ func sweep() {
 		// Construct the new state byte.
 		*stateBytePtr = markedHeads | (tails << blocksPerStateByte)
 	}
+	return carry
 }
 
 // buildFreeRanges rebuilds the freeRanges list.
@@ -730,20 +958,38 @@ func sweep() {
 // It returns how many bytes are free in the heap.
 func buildFreeRanges() uintptr {
 	freeRanges = nil
-	block := endBlock
 	var totalBlocks uintptr
+	var span *sizeClassSpan
+	if gcSizeClasses {
+		// The free blocks in spans go to the free lists of their size class
+		// instead, so the spans are skipped below.
+		totalBlocks = sizeClassRebuild()
+		span = sizeClassSpans
+	}
+	block := endBlock
 	for {
-		// Skip backwards over occupied blocks.
-		for block > 0 && (block-1).state() != blockStateFree {
-			block--
+		// Skip backwards over occupied blocks and spans.
+		for block > 0 {
+			if span != nil && block <= span.end() {
+				block = span.start()
+				span = span.next
+			} else if (block - 1).state() != blockStateFree {
+				block--
+			} else {
+				break
+			}
 		}
 		if block == 0 {
 			break
 		}
 
-		// Find the start of the free range.
+		// Find the start of the free range, which ends at the next span.
+		limit := gcBlock(0)
+		if span != nil {
+			limit = span.end()
+		}
 		end := block
-		for block > 0 && (block-1).state() == blockStateFree {
+		for block > limit && (block-1).state() == blockStateFree {
 			block--
 		}
 
@@ -807,19 +1053,22 @@ func ReadMemStats(m *MemStats) {
 	metadataStart := metadataStart
 	// TODO: should GCSys include objHeaders?
 	m.GCSys = uint64(heapEnd - uintptr(metadataStart))
-	m.HeapReleased = 0 // always 0, we don't currently release memory back to the OS.
+	m.HeapReleased = 0 // always 0, released memory is not part of the heap anymore.
 
 	// Count live heads and tails.
 	var liveHeads, liveTails uintptr
 	endBlock := endBlock
 	metadataEnd := unsafe.Add(metadataStart, (endBlock+(blocksPerStateByte-1))/blocksPerStateByte)
 	for meta := metadataStart; meta != metadataEnd; meta = unsafe.Add(meta, 1) {
-		// Since we are outside of a GC, nothing is marked.
 		// A bit in the low nibble implies a head.
-		// A bit in the high nibble implies a tail.
+		// A bit in the high nibble but not the low nibble implies a tail.
+		// Marked heads (with both bits) only exist during an incremental
+		// GC cycle.
 		stateByte := *(*byte)(unsafe.Pointer(meta))
-		liveHeads += uintptr(count4LUT[stateByte&blockStateEach])
-		liveTails += uintptr(count4LUT[stateByte>>blocksPerStateByte])
+		low := stateByte & blockStateEach
+		high := stateByte >> blocksPerStateByte
+		liveHeads += uintptr(count4LUT[low])
+		liveTails += uintptr(count4LUT[high&^low])
 	}
 
 	// Add heads and tails to count live blocks.
@@ -843,9 +1092,35 @@ func ReadMemStats(m *MemStats) {
 	// Record the total allocated bytes.
 	m.TotalAlloc = gcTotalAlloc
 
+	// Record the number of GC cycles.
+	m.NumGC = gcCycles
+	m.NumForcedGC = gcForced
+	m.NextGC = gcHeapGoal()
+
 	gcLock.Unlock()
 }
 
+// heapFragmentation describes how the free blocks are fragmented, for the
+// runtime/metrics package: the number of ranges of contiguous free blocks, the
+// size of the largest range, and the size of the free slots for small objects
+// (with -gc=sizeclass), which are not part of a free range.
+//
+//go:linkname heapFragmentation runtime/metrics.runtime_heapFragmentation
+func heapFragmentation() (ranges, largest, sizeClassIdle uint64) {
+	gcLock.Lock()
+	for rangeWithLength := freeRanges; rangeWithLength != nil; rangeWithLength = rangeWithLength.nextLen {
+		ranges++
+		for nextWithLen := rangeWithLength.nextWithLen; nextWithLen != nil; nextWithLen = nextWithLen.next {
+			ranges++
+		}
+		// The list is sorted by length, so the last one is the largest.
+		largest = uint64(rangeWithLength.len * bytesPerBlock)
+	}
+	sizeClassIdle = uint64(sizeClassFreeBlocks * bytesPerBlock)
+	gcLock.Unlock()
+	return
+}
+
 // count4LUT is a lookup table used to count set bits in a 4-bit mask.
 // TODO: replace with popcnt when available
 var count4LUT = [16]uint8{
@@ -866,7 +1141,3 @@ var count4LUT = [16]uint8{
 	0b1110: 3,
 	0b1111: 4,
 }
-
-func SetFinalizer(obj interface{}, finalizer interface{}) {
-	// Unimplemented.
-}
@@ -31,6 +31,9 @@ var zeroSizedAlloc uint8
 
 var gcLock task.PMutex
 
+// Number of GC cycles started by calling runtime.GC.
+var gcForced uint32
+
 func initHeap() {
 	libgc_init()
 
@@ -102,8 +105,11 @@ func free(ptr unsafe.Pointer) {
 
 func GC() {
 	gcLock.Lock()
+	traceGCStart()
 	libgc_gcollect()
 	gcResumeWorld()
+	traceGCDone()
+	gcForced++
 	gcLock.Unlock()
 }
 
@@ -122,11 +128,15 @@ func ReadMemStats(m *MemStats) {
 	m.HeapInuse = uint64(gcMemStats.heapsize_full - gcMemStats.unmapped_bytes)
 	m.HeapReleased = uint64(gcMemStats.unmapped_bytes)
 	m.HeapSys = uint64(m.HeapInuse + m.HeapIdle)
+	m.HeapAlloc = uint64(gcMemStats.heapsize_full - gcMemStats.free_bytes_full)
+	m.Alloc = m.HeapAlloc
 	m.GCSys = 0 // not provided by bdwgc
 	m.TotalAlloc = uint64(gcMemStats.allocd_bytes_before_gc + gcMemStats.bytes_allocd_since_gc)
 	m.Mallocs = 0 // not provided by bdwgc
 	m.Frees = 0   // not provided by bdwgc
 	m.Sys = uint64(gcMemStats.obtained_from_os_bytes)
+	m.NumGC = uint32(gcMemStats.gc_no)
+	m.NumForcedGC = gcForced
 
 	gcLock.Unlock()
 }
@@ -33,11 +33,15 @@ package runtime
 // - func realloc(oldPtr unsafe.Pointer, size uintptr) unsafe.Pointer
 
 import (
+	"internal/task"
 	"unsafe"
 )
 
 const needsStaticHeap = false
 
+// Lock for the GC settings in gc_settings.go.
+var gcLock task.PMutex
+
 // initHeap is called when the heap is first initialized at program start.
 func initHeap()
 
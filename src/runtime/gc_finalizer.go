@@ -0,0 +1,271 @@
+//go:build gc.conservative || gc.precise || gc.incremental || gc.sizeclass
+
+package runtime
+
+// Finalizers and cleanups for the block-based garbage collectors.
+//
+// Every object with a finalizer or cleanup has an entry in the finalizers list.
+// The address of the object is stored inverted, so that the list itself
+// doesn't keep the object alive. After the mark phase, entries of objects that
+// weren't marked are moved to a queue, which is run by a separate goroutine
+// that is woken up at the end of the GC cycle.
+//
+// Like in the standard Go runtime, objects reachable from an object with a
+// finalizer are always marked, so that finalizers run in dependency order. The
+// object itself is kept alive until its finalizer has run, and is freed in a
+// later GC cycle if the finalizer doesn't make it reachable again. Cleanups
+// don't keep the object alive.
+
+import (
+	"internal/reflectlite"
+	"internal/task"
+	"unsafe"
+)
+
+// An object with a finalizer or a cleanup.
+type finalizerObject struct {
+	next      *finalizerObject
+	addr      uintptr        // inverted, so the GC doesn't see it as a pointer
+	typecode  unsafe.Pointer // type of the object, for finalizers
+	finalizer interface{}    // finalizer function, nil for a cleanup
+	cleanup   func()         // cleanup function with its argument
+	id        uint32         // cleanup ID, for Cleanup.Stop
+}
+
+var (
+	finalizers       *finalizerObject // objects with a finalizer or cleanup
+	finalizerQueue   *finalizerObject // finalizers and cleanups that are ready to run
+	finalizerFutex   task.Futex       // incremented when finalizers are queued
+	finalizerStarted bool             // SetFinalizer or AddCleanup has been called
+	cleanupID        uint32           // last cleanup ID
+)
+
+// SetFinalizer sets the finalizer associated with obj to finalizer. See the
+// documentation of runtime.SetFinalizer in the standard library.
+//
+// Finalizers run in a separate goroutine. Without a scheduler, they only run
+// when runtime.GC is called.
+func SetFinalizer(obj interface{}, finalizer interface{}) {
+	objValue := reflectlite.ValueOf(obj)
+	if objValue.Kind() == reflectlite.Invalid {
+		runtimePanic("runtime.SetFinalizer: first argument is nil")
+	}
+	if objValue.Kind() != reflectlite.Ptr {
+		runtimePanic("runtime.SetFinalizer: first argument is " + objValue.Type().String() + ", not pointer")
+	}
+	addr := uintptr(objValue.UnsafePointer())
+	if !isOnHeap(addr) {
+		// Global variables and zero-sized objects are never freed, so their
+		// finalizers would never run.
+		return
+	}
+	if blockFromAddr(addr).findHead().address()+align(unsafe.Sizeof(objHeader{})) != addr {
+		runtimePanic("runtime.SetFinalizer: pointer not at beginning of allocated block")
+	}
+
+	var f *finalizerObject
+	if finalizer != nil {
+		fnType := reflectlite.ValueOf(finalizer).RawType()
+		if fnType.Kind() != reflectlite.Func {
+			runtimePanic("runtime.SetFinalizer: second argument is " + fnType.String() + ", not a function")
+		}
+		if fnType.NumIn() != 1 || fnType.IsVariadic() || !objValue.Type().AssignableTo(fnType.In(0)) {
+			runtimePanic("runtime.SetFinalizer: cannot pass " + objValue.Type().String() + " to finalizer " + fnType.String())
+		}
+		typecode, _ := decomposeInterface(*(*_interface)(unsafe.Pointer(&obj)))
+		f = &finalizerObject{
+			addr:      ^addr,
+			typecode:  typecode,
+			finalizer: finalizer,
+		}
+		startFinalizers()
+	}
+
+	gcLock.Lock()
+	prev := &finalizers
+	for entry := finalizers; entry != nil; entry = entry.next {
+		if entry.finalizer != nil && entry.addr == ^addr {
+			if f != nil {
+				gcLock.Unlock()
+				runtimePanic("runtime.SetFinalizer: finalizer already set")
+			}
+			*prev = entry.next
+			break
+		}
+		prev = &entry.next
+	}
+	if f != nil {
+		f.next = finalizers
+		finalizers = f
+	}
+	gcLock.Unlock()
+}
+
+// addCleanup registers a cleanup for the object at ptr, and returns its ID.
+// An ID of 0 means that the cleanup will never run.
+func addCleanup(ptr unsafe.Pointer, cleanup func()) uint32 {
+	if !isOnHeap(uintptr(ptr)) {
+		return 0
+	}
+	f := &finalizerObject{
+		addr:    ^uintptr(ptr),
+		cleanup: cleanup,
+	}
+	startFinalizers()
+
+	gcLock.Lock()
+	cleanupID++
+	if cleanupID == 0 {
+		cleanupID++
+	}
+	f.id = cleanupID
+	f.next = finalizers
+	finalizers = f
+	gcLock.Unlock()
+	return f.id
+}
+
+// removeCleanup cancels a cleanup that was registered with addCleanup, if it
+// hasn't been queued yet.
+func removeCleanup(id uint32) {
+	if id == 0 {
+		return
+	}
+	gcLock.Lock()
+	prev := &finalizers
+	for entry := finalizers; entry != nil; entry = entry.next {
+		if entry.finalizer == nil && entry.id == id {
+			*prev = entry.next
+			break
+		}
+		prev = &entry.next
+	}
+	gcLock.Unlock()
+}
+
+// startFinalizers starts the goroutine that runs finalizers, if it isn't
+// running yet.
+func startFinalizers() {
+	gcLock.Lock()
+	start := !finalizerStarted
+	finalizerStarted = true
+	gcLock.Unlock()
+	if start && (hasScheduler || hasParallelism) {
+		go finalizerRunner()
+	}
+}
+
+// finalizerRunner is the goroutine that runs queued finalizers and cleanups.
+func finalizerRunner() {
+	for {
+		gcLock.Lock()
+		if finalizerQueue == nil {
+			val := finalizerFutex.Load()
+			gcLock.Unlock()
+			finalizerFutex.Wait(val)
+			continue
+		}
+		gcLock.Unlock()
+		runFinalizers()
+	}
+}
+
+// runFinalizers runs all queued finalizers and cleanups.
+func runFinalizers() {
+	if !finalizerStarted {
+		return
+	}
+	for {
+		gcLock.Lock()
+		f := finalizerQueue
+		if f == nil {
+			gcLock.Unlock()
+			return
+		}
+		finalizerQueue = f.next
+		gcLock.Unlock()
+
+		if f.finalizer == nil {
+			f.cleanup()
+			continue
+		}
+		i := composeInterface(f.typecode, unsafe.Pointer(^f.addr))
+		obj := *(*interface{})(unsafe.Pointer(&i))
+		reflectlite.ValueOf(f.finalizer).Call([]reflectlite.Value{reflectlite.ValueOf(obj)})
+	}
+}
+
+// markFinalizers marks all objects that are reachable from objects with a
+// finalizer, but not the objects themselves, and the objects of queued
+// finalizers that haven't run yet. It is called with gcLock held at the start
+// of the mark phase.
+func markFinalizers() {
+	if !finalizerStarted {
+		// This flag is never set in programs that don't use finalizers or
+		// cleanups, so that the compiler can remove all code that handles
+		// them.
+		return
+	}
+	for f := finalizerQueue; f != nil; f = f.next {
+		if f.finalizer != nil {
+			markRoot(0, ^f.addr)
+		}
+	}
+	for f := finalizers; f != nil; f = f.next {
+		if f.finalizer == nil {
+			continue
+		}
+		head := blockFromAddr(^f.addr)
+		header := (*objHeader)(head.pointer())
+		if header.layout.pointerFree() {
+			continue
+		}
+		start := head.address() + align(unsafe.Sizeof(objHeader{}))
+		end := head.findNext().address()
+		header.layout.scan(start, end-start)
+	}
+}
+
+// queueFinalizers queues the finalizers and cleanups of all objects that are
+// not marked. It is called with gcLock held at the end of the mark phase.
+// Objects with a finalizer are marked again, so that they stay alive until
+// their finalizer has run.
+func queueFinalizers() {
+	if !finalizerStarted {
+		return
+	}
+	// Queue finalizers first, as the objects they keep alive may have
+	// cleanups.
+	prev := &finalizers
+	for f := finalizers; f != nil; f = *prev {
+		if f.finalizer == nil || blockFromAddr(^f.addr).findHead().state() == blockStateMark {
+			prev = &f.next
+			continue
+		}
+		*prev = f.next
+		f.next = finalizerQueue
+		finalizerQueue = f
+		markRoot(0, ^f.addr)
+	}
+	finishMark()
+
+	prev = &finalizers
+	for f := finalizers; f != nil; f = *prev {
+		if f.finalizer != nil || blockFromAddr(^f.addr).findHead().state() == blockStateMark {
+			prev = &f.next
+			continue
+		}
+		*prev = f.next
+		f.next = finalizerQueue
+		finalizerQueue = f
+	}
+}
+
+// wakeFinalizers wakes up the finalizer goroutine if there are queued
+// finalizers. It is called with gcLock held at the end of the GC cycle.
+func wakeFinalizers() {
+	if finalizerStarted && finalizerQueue != nil && (hasScheduler || hasParallelism) {
+		finalizerFutex.Add(1)
+		finalizerFutex.Wake()
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !(gc.conservative || gc.precise || gc.incremental || gc.sizeclass)
+
+package runtime
+
+import "unsafe"
+
+// Cleanups are not supported with this garbage collector, so they never run.
+
+func addCleanup(ptr unsafe.Pointer, cleanup func()) uint32 {
+	return 0
+}
+
+func removeCleanup(id uint32) {
+}
@@ -0,0 +1,11 @@
+//go:build !(gc.conservative || gc.precise || gc.incremental || gc.sizeclass)
+
+package runtime
+
+// Only the block-based garbage collectors keep track of free ranges, so the
+// fragmentation metrics are always zero with this garbage collector.
+
+//go:linkname heapFragmentation runtime/metrics.runtime_heapFragmentation
+func heapFragmentation() (ranges, largest, sizeClassIdle uint64) {
+	return 0, 0, 0
+}
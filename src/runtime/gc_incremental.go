@@ -0,0 +1,386 @@
+//go:build gc.incremental
+
+package runtime
+
+// Incremental mode of the block-based garbage collector (-gc=incremental).
+//
+// Instead of stopping the program for a whole GC cycle, a cycle is split into
+// small steps that are done during allocation. Every step takes at most the
+// pause budget set with the -gc-pause flag (1ms by default). A cycle starts
+// when the heap is halfway to the heap goal, so that it usually finishes before
+// the goal is reached. If the heap runs out of space anyway, the rest of the
+// cycle is done at once like with the other block-based collectors.
+//
+// A cycle goes through these phases:
+//
+//   - mark: the globals and the current stack are scanned, and the objects
+//     found are put on scanList. In each step objects on scanList are scanned
+//     until the pause budget is used up. Large objects are scanned in parts.
+//     To make sure that objects can't be hidden from the GC by storing them in
+//     an object that was already scanned, the compiler emits a write barrier
+//     for every pointer store to the heap (a Dijkstra-style insertion
+//     barrier): the object that is stored is marked. Objects allocated during
+//     this phase are marked and scanned as well.
+//     Stores to globals and stacks don't have a write barrier. Instead, the
+//     stack of a goroutine is scanned every time it is paused, and the globals
+//     and the current stack are scanned again at the end of the mark phase.
+//     This last part isn't incremental, but typically has little work left to
+//     do.
+//   - sweep: in each step, a part of the heap is swept and the free ranges
+//     found in it are added to the free ranges list. Allocations that don't
+//     fit in the free ranges found so far sweep more of the heap.
+//
+// The incremental GC requires a single thread of execution: it only supports
+// -scheduler=none and -scheduler=tasks. Interrupts may store pointers, but
+// can't modify the heap metadata: their write barriers store the pointers in a
+// small buffer that is processed at the end of the mark phase.
+
+import (
+	"runtime/interrupt"
+	"unsafe"
+)
+
+const gcIncremental = true
+
+// Phases of an incremental GC cycle.
+const (
+	gcPhaseIdle = iota
+	gcPhaseMark
+	gcPhaseSweep
+)
+
+const (
+	// Number of bytes of an object to scan at once.
+	gcScanChunk = 1024
+
+	// Number of state bytes (blocksPerStateByte blocks each) to sweep at once.
+	gcSweepChunk = 64
+)
+
+var (
+	gcPhase uint8 // current phase of the incremental GC
+
+	// The object that is partially scanned in the mark phase.
+	gcScanning   bool
+	gcScanLayout gcLayout
+	gcScanAddr   uintptr // next address to scan
+	gcScanEnd    uintptr // end of the object
+
+	// State of the sweep phase.
+	gcSweepByte  uintptr // next state byte to sweep, relative to metadataStart
+	gcSweepCarry byte    // carry for the next state byte, see sweepRange
+	gcSweepInRun bool    // the last swept block is free
+	gcSweepRun   gcBlock // first block of the free range that ends at the last swept block
+	gcSweepFree  uintptr // number of free blocks found so far
+
+	// Pointers stored by interrupts during the mark phase.
+	gcShadeBuf      [16]uintptr
+	gcShadeLen      uintptr
+	gcShadeOverflow bool // the buffer was full, so all marked objects must be scanned again
+)
+
+// gcPauseBudget returns the maximum duration of a step in nanoseconds, as set
+// with the -gc-pause flag. It is a compiler intrinsic.
+func gcPauseBudget() int64
+
+// gcWriteBarrier is called by compiled code after a pointer was stored to the
+// heap. It marks the object the pointer points to during the mark phase.
+func gcWriteBarrier(ptr unsafe.Pointer) {
+	if gcPhase != gcPhaseMark {
+		return
+	}
+	gcShade(uintptr(ptr))
+}
+
+// gcWriteBarrierRange is like gcWriteBarrier, for all pointers in a range of
+// memory that was written to. It is called by compiled code for values that
+// aren't a single pointer, and by the runtime after copying memory that may
+// contain pointers.
+func gcWriteBarrierRange(ptr unsafe.Pointer, size uintptr) {
+	if gcPhase != gcPhaseMark {
+		return
+	}
+	addr := align(uintptr(ptr))
+	end := uintptr(ptr) + size
+	for addr+unsafe.Sizeof(addr) <= end {
+		gcShade(*(*uintptr)(unsafe.Pointer(addr)))
+		addr += unsafe.Alignof(addr)
+	}
+}
+
+// gcShade marks the object that ptr points to, if it is a heap object.
+func gcShade(ptr uintptr) {
+	if !isOnHeap(ptr) {
+		return
+	}
+	if interrupt.In() {
+		// The interrupted code may be using the heap metadata, so remember
+		// the pointer for the end of the mark phase.
+		if gcShadeLen < uintptr(len(gcShadeBuf)) {
+			gcShadeBuf[gcShadeLen] = ptr
+			gcShadeLen++
+		} else {
+			gcShadeOverflow = true
+		}
+		return
+	}
+	// Note: gcLock isn't needed (and may already be held) as there is only a
+	// single thread.
+	markRoot(0, ptr)
+}
+
+// gcTaskPaused is called by internal/task after a goroutine was paused, with
+// its stack pointer. The goroutine may have modified its stack, so it is
+// scanned again.
+func gcTaskPaused(sp uintptr) {
+	if gcPhase != gcPhaseMark {
+		return
+	}
+	gcLock.Lock()
+	scanGoroutineStack(sp)
+	gcLock.Unlock()
+}
+
+// gcAllocated is called by alloc with gcLock held for every new object, with
+// the object header. Objects allocated during the mark phase are marked, and
+// scanned later in the cycle.
+func gcAllocated(header unsafe.Pointer) {
+	if gcPhase != gcPhaseMark {
+		return
+	}
+	markRoot(0, uintptr(header))
+}
+
+// gcTrigger returns the number of bytes in use at which a new cycle starts:
+// halfway between the heap in use after the last cycle and the heap goal (or
+// the size of the heap, if that is smaller). With GOGC=off, cycles only start
+// halfway to the memory limit, and otherwise only when the heap is full.
+func gcTrigger() uint64 {
+	goal := gcHeapGoal()
+	if heapSize := uint64(uintptr(metadataStart) - heapStart); goal > heapSize && gcPercent >= 0 {
+		goal = heapSize
+	}
+	live := uint64(gcHeapLive)
+	if goal < live {
+		return live
+	}
+	return live + (goal-live)/2
+}
+
+// gcStep is called by alloc with gcLock held. It starts a new cycle when
+// needed, and does a part of the current cycle within the pause budget.
+func gcStep() {
+	if gcPhase == gcPhaseIdle {
+		if uint64(gcHeapLive)+uint64(gcHeapAlloc) < gcTrigger() {
+			return
+		}
+		gcStartMark()
+	}
+
+	deadline := ticks() + nanosecondsToTicks(gcPauseBudget())
+	if gcPhase == gcPhaseMark {
+		for !gcMarkChunk() {
+			if ticks() >= deadline {
+				return
+			}
+		}
+		// There is nothing left on scanList, so end the mark phase.
+		gcEndMark()
+	}
+	for !gcSweepChunkDone() {
+		if ticks() >= deadline {
+			return
+		}
+	}
+	gcEndSweep()
+}
+
+// gcSweepAssist is called by alloc when there is no free range that is big
+// enough. During the sweep phase it sweeps a part of the heap and returns
+// true, so that alloc tries again.
+func gcSweepAssist() bool {
+	if gcPhase != gcPhaseSweep {
+		return false
+	}
+	if gcSweepChunkDone() {
+		gcEndSweep()
+	}
+	return true
+}
+
+// gcTakeOverCycle is called by runGC to finish the incremental cycle that is in
+// progress. A sweep is finished, while the marking is continued by runGC, in
+// which case it returns true.
+func gcTakeOverCycle() bool {
+	switch gcPhase {
+	case gcPhaseMark:
+		if gcScanning {
+			gcScanning = false
+			gcScanLayout.scan(gcScanAddr, gcScanEnd-gcScanAddr)
+		}
+		return true
+	case gcPhaseSweep:
+		for !gcSweepChunkDone() {
+		}
+		gcEndSweep()
+	}
+	return false
+}
+
+// gcStartMark starts a new cycle by marking the roots.
+func gcStartMark() {
+	traceGCStart()
+	gcPhase = gcPhaseMark
+	gcMarkReachable()
+	markFinalizers()
+}
+
+// gcMarkChunk scans (a part of) an object on scanList, and returns true if
+// there is nothing left to scan.
+func gcMarkChunk() bool {
+	if !gcScanning {
+		obj := scanList
+		if obj == nil {
+			return true
+		}
+		scanList = obj.next
+		if obj.layout.pointerFree() {
+			return false
+		}
+		objAddr := uintptr(unsafe.Pointer(obj))
+		gcScanning = true
+		gcScanLayout = obj.layout
+		gcScanAddr = objAddr + align(unsafe.Sizeof(objHeader{}))
+		gcScanEnd = blockFromAddr(objAddr).findNext().address()
+	}
+
+	// Scan at most gcScanChunk bytes, rounded down to whole elements of the
+	// layout.
+	size := gcScanEnd - gcScanAddr
+	if elementSize := gcScanLayout.elementSize(); size > gcScanChunk && elementSize != 0 {
+		if elementSize < gcScanChunk {
+			size = gcScanChunk - gcScanChunk%elementSize
+		} else if elementSize < size {
+			size = elementSize
+		}
+	}
+	gcScanLayout.scan(gcScanAddr, size)
+	gcScanAddr += size
+	if gcScanAddr >= gcScanEnd {
+		gcScanning = false
+	}
+	return false
+}
+
+// gcMarkTerminate is called at the end of the mark phase, when scanList is
+// empty. It marks the pointers that interrupts stored, and ends the mark phase.
+func gcMarkTerminate() {
+	for {
+		state := interrupt.Disable()
+		n := gcShadeLen
+		overflow := gcShadeOverflow
+		if n == 0 && !overflow {
+			// Interrupts can't store pointers that need to be marked after
+			// this point.
+			gcPhase = gcPhaseIdle
+			interrupt.Restore(state)
+			return
+		}
+		var buf [len(gcShadeBuf)]uintptr
+		copy(buf[:n], gcShadeBuf[:n])
+		gcShadeLen = 0
+		gcShadeOverflow = false
+		interrupt.Restore(state)
+
+		for _, ptr := range buf[:n] {
+			markRoot(0, ptr)
+		}
+		if overflow {
+			// Some pointers were lost, so scan all marked objects again.
+			for block := gcBlock(0); block < endBlock; block++ {
+				if block.state() != blockStateMark {
+					continue
+				}
+				header := (*objHeader)(block.pointer())
+				if header.layout.pointerFree() {
+					continue
+				}
+				start := block.address() + align(unsafe.Sizeof(objHeader{}))
+				end := block.findNext().address()
+				header.layout.scan(start, end-start)
+			}
+		}
+		finishMark()
+	}
+}
+
+// gcEndMark ends the mark phase and starts the sweep phase.
+func gcEndMark() {
+	markPhase()
+	gcPhase = gcPhaseSweep
+	gcSweepByte = 0
+	gcSweepCarry = 0
+	gcSweepInRun = false
+	gcSweepFree = 0
+
+	// The free ranges are found again while sweeping.
+	freeRanges = nil
+}
+
+// gcSweepChunkDone sweeps the next part of the heap, and adds the free ranges
+// in it to the free ranges list. It returns true when the whole heap has been
+// swept.
+func gcSweepChunkDone() bool {
+	metadataSize := uintptr((endBlock + (blocksPerStateByte - 1)) / blocksPerStateByte)
+	if gcSweepByte < metadataSize {
+		end := gcSweepByte + gcSweepChunk
+		if end > metadataSize {
+			end = metadataSize
+		}
+		gcSweepCarry = sweepRange(unsafe.Add(metadataStart, gcSweepByte), unsafe.Add(metadataStart, end), gcSweepCarry)
+
+		// Find the free ranges in the blocks that were swept. A free range
+		// that continues in the next part is added once its end is known.
+		endSwept := gcBlock(end * blocksPerStateByte)
+		if endSwept > endBlock {
+			endSwept = endBlock
+		}
+		for block := gcBlock(gcSweepByte * blocksPerStateByte); block < endSwept; block++ {
+			if block.state() == blockStateFree {
+				if !gcSweepInRun {
+					gcSweepInRun = true
+					gcSweepRun = block
+				}
+			} else if gcSweepInRun {
+				gcSweepInRun = false
+				gcSweepAddFreeRange(gcSweepRun, block)
+			}
+		}
+		gcSweepByte = end
+	}
+	if gcSweepByte < metadataSize {
+		return false
+	}
+	if gcSweepInRun {
+		gcSweepInRun = false
+		gcSweepAddFreeRange(gcSweepRun, endBlock)
+	}
+	return true
+}
+
+// gcSweepAddFreeRange adds the free blocks from start to end (exclusive) to the
+// free ranges list.
+func gcSweepAddFreeRange(start, end gcBlock) {
+	len := uintptr(end - start)
+	gcSweepFree += len
+	insertFreeRange(start.pointer(), len)
+}
+
+// gcEndSweep ends the sweep phase, and with it the GC cycle.
+func gcEndSweep() {
+	gcPhase = gcPhaseIdle
+	finishCycle(gcSweepFree * bytesPerBlock)
+	if gcDebug {
+		dumpHeap()
+	}
+}
@@ -0,0 +1,33 @@
+//go:build !gc.incremental
+
+package runtime
+
+import "unsafe"
+
+// The incremental GC is not in use. These functions are called from the
+// block-based GCs, the runtime and internal/task, and do nothing.
+
+const gcIncremental = false
+
+func gcWriteBarrierRange(ptr unsafe.Pointer, size uintptr) {
+}
+
+func gcTaskPaused(sp uintptr) {
+}
+
+func gcAllocated(header unsafe.Pointer) {
+}
+
+func gcStep() {
+}
+
+func gcSweepAssist() bool {
+	return false
+}
+
+func gcTakeOverCycle() bool {
+	return false
+}
+
+func gcMarkTerminate() {
+}
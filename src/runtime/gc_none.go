@@ -7,6 +7,7 @@ package runtime
 // targets that have far too little RAM even for the leaking memory allocator.
 
 import (
+	"internal/task"
 	"unsafe"
 )
 
@@ -16,6 +17,9 @@ var gcTotalAlloc uint64 // for runtime.MemStats
 var gcMallocs uint64
 var gcFrees uint64
 
+// Lock for the GC settings in gc_settings.go.
+var gcLock task.PMutex
+
 func alloc(size uintptr, layout unsafe.Pointer) unsafe.Pointer
 
 func realloc(ptr unsafe.Pointer, size uintptr) unsafe.Pointer
@@ -32,6 +36,16 @@ func markRoots(start, end uintptr) {
 	runtimePanic("unreachable: markRoots")
 }
 
+// ReadMemStats populates m with memory statistics. There is no heap, so only
+// the allocation counters are reported.
+func ReadMemStats(m *MemStats) {
+	*m = MemStats{
+		TotalAlloc: gcTotalAlloc,
+		Mallocs:    gcMallocs,
+		Frees:      gcFrees,
+	}
+}
+
 func SetFinalizer(obj interface{}, finalizer interface{}) {
 	// Unimplemented.
 }
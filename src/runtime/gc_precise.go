@@ -1,4 +1,4 @@
-//go:build gc.precise
+//go:build gc.precise || gc.incremental || gc.sizeclass
 
 // This implements the block-based GC as a partially precise GC. This means that
 // for most heap allocations it is known which words contain a pointer and which
@@ -71,6 +71,19 @@ func (layout gcLayout) pointerFree() bool {
 	return layout&1 != 0 && layout>>(sizeFieldBits+1) == 0
 }
 
+// elementSize returns the size in bytes of the elements that scan works on.
+// Scanning an object in parts must split it at a multiple of this size.
+func (layout gcLayout) elementSize() uintptr {
+	switch {
+	case layout == 0:
+		return unsafe.Alignof(uintptr(0))
+	case layout&1 != 0:
+		return (uintptr(layout>>1) & (1<<sizeFieldBits - 1)) * unsafe.Alignof(uintptr(0))
+	default:
+		return *(*uintptr)(unsafe.Pointer(layout)) * unsafe.Alignof(uintptr(0))
+	}
+}
+
 // scan an object with this element layout.
 // The starting address must be valid and pointer-aligned.
 // The length is rounded down to a multiple of the element size.
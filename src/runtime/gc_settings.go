@@ -0,0 +1,123 @@
+package runtime
+
+// Settings of the garbage collector: the GC percentage (GOGC) and the soft
+// memory limit (GOMEMLIMIT). They are read from the environment at startup (see
+// readGCSettings), and can be changed with runtime/debug.SetGCPercent and
+// runtime/debug.SetMemoryLimit. Only the block-based garbage collectors use
+// them: see gc_blocks.go for how they affect the heap size.
+
+const maxMemoryLimit = 1<<63 - 1
+
+var (
+	gcPercent     int32 = 100            // GOGC, or -1 if the GC is off
+	gcMemoryLimit int64 = maxMemoryLimit // GOMEMLIMIT in bytes
+
+	// Whether the heap size is paced using these settings. The heap of
+	// baremetal systems can't grow, so there it is only done when the settings
+	// are changed (or with the incremental GC, which always needs it). This
+	// keeps the pacing code out of most baremetal programs.
+	gcPacing = !baremetal || gcIncremental
+)
+
+// readGCSettings reads GOGC and GOMEMLIMIT from the environment. It is called
+// from an init function once the environment is available: on non-hosted
+// systems, that is after the environment passed to the program is parsed.
+func readGCSettings() {
+	if value, ok := lookupEnv("GOGC"); ok {
+		gcPercent = parseGOGC(value)
+		gcPacing = true
+	}
+	if value, ok := lookupEnv("GOMEMLIMIT"); ok {
+		if limit, ok := parseByteCount(value); ok {
+			gcMemoryLimit = limit
+			gcPacing = true
+		}
+	}
+}
+
+// parseGOGC parses the value of GOGC. Invalid values result in the default.
+func parseGOGC(value string) int32 {
+	if value == "off" {
+		return -1
+	}
+	n, ok := parseUint(value)
+	if !ok || n > 1<<31-1 {
+		return 100
+	}
+	return int32(n)
+}
+
+// parseByteCount parses a byte count with an optional unit suffix (B, KiB,
+// MiB, GiB or TiB), like GOMEMLIMIT. The value "off" means no limit.
+func parseByteCount(value string) (int64, bool) {
+	if value == "off" {
+		return maxMemoryLimit, true
+	}
+	shift := 0
+	for _, unit := range [...]struct {
+		suffix string
+		shift  int
+	}{{"KiB", 10}, {"MiB", 20}, {"GiB", 30}, {"TiB", 40}, {"B", 0}} {
+		if len(value) > len(unit.suffix) && value[len(value)-len(unit.suffix):] == unit.suffix {
+			value = value[:len(value)-len(unit.suffix)]
+			shift = unit.shift
+			break
+		}
+	}
+	n, ok := parseUint(value)
+	if !ok || n > maxMemoryLimit>>shift {
+		return 0, false
+	}
+	return int64(n << shift), true
+}
+
+// parseUint parses a decimal number.
+func parseUint(s string) (uint64, bool) {
+	if s == "" {
+		return 0, false
+	}
+	var n uint64
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' || n > (1<<64-1)/10 {
+			return 0, false
+		}
+		n = n*10 + uint64(s[i]-'0')
+	}
+	return n, true
+}
+
+//go:linkname debug_setGCPercent runtime/debug.setGCPercent
+func debug_setGCPercent(percent int32) int32 {
+	if percent < 0 {
+		percent = -1
+	}
+	gcLock.Lock()
+	prev := gcPercent
+	gcPercent = percent
+	gcPacing = true
+	gcLock.Unlock()
+	return prev
+}
+
+//go:linkname debug_setMemoryLimit runtime/debug.setMemoryLimit
+func debug_setMemoryLimit(limit int64) int64 {
+	gcLock.Lock()
+	prev := gcMemoryLimit
+	if limit >= 0 {
+		gcMemoryLimit = limit
+		gcPacing = true
+	}
+	gcLock.Unlock()
+	return prev
+}
+
+// gcSettings returns the GC percentage and the memory limit, for the
+// runtime/metrics package.
+//
+//go:linkname gcSettings runtime/metrics.runtime_gcSettings
+func gcSettings() (percent int32, limit int64) {
+	gcLock.Lock()
+	percent, limit = gcPercent, gcMemoryLimit
+	gcLock.Unlock()
+	return
+}
@@ -0,0 +1,168 @@
+//go:build gc.sizeclass
+
+package runtime
+
+// Size classes for small objects in the block-based garbage collector
+// (-gc=sizeclass).
+//
+// With the other block-based collectors, small and large objects share the
+// same free ranges. Over time, long-lived small objects end up scattered over
+// the heap, splitting it into free ranges that are too short for larger
+// objects. On a small heap, allocations may then fail even though there is
+// plenty of free memory.
+//
+// To avoid this, small objects (up to sizeClassMaxBlocks blocks) are allocated
+// in spans: runs of blocks that only contain objects of a single size class.
+// Each block count is a size class. A span starts with a descriptor, which is a
+// heap object itself, followed by slots of the size of its class. The objects
+// in a span are normal heap objects, so they are marked and swept like any
+// other object. The difference is in how the free blocks are reused: free
+// slots are put on a free list for their size class instead of in the free
+// ranges, so they can only be used for objects of the same size. Spans that
+// don't contain any objects after a GC cycle are released, and their blocks
+// become part of the free ranges again.
+//
+// Spans are kept in a list sorted by address (highest first), which allows
+// buildFreeRanges to skip over them while walking the heap.
+
+import "unsafe"
+
+const gcSizeClasses = true
+
+const (
+	// Objects of up to this many blocks (including the object header) are
+	// allocated in spans.
+	sizeClassMaxBlocks = 4
+
+	// Maximum number of blocks in a span, including the descriptor.
+	sizeClassSpanBlocks = 32
+)
+
+// sizeClassSpan is the descriptor of a span, stored in the first block of the
+// span after the object header.
+type sizeClassSpan struct {
+	next  *sizeClassSpan // next span, at a lower address
+	class uintptr        // size of the slots in blocks
+}
+
+// sizeClassSlot is a free slot in a span.
+type sizeClassSlot struct {
+	next *sizeClassSlot
+}
+
+var (
+	sizeClassSpans      *sizeClassSpan                     // spans, sorted by address from high to low
+	sizeClassFree       [sizeClassMaxBlocks]*sizeClassSlot // free slots for each size class
+	sizeClassFreeBlocks uintptr                            // number of blocks in free slots
+)
+
+// start returns the first block of the span, which contains the descriptor.
+func (span *sizeClassSpan) start() gcBlock {
+	return blockFromAddr(uintptr(unsafe.Pointer(span)))
+}
+
+// end returns the block just past the end of the span.
+func (span *sizeClassSpan) end() gcBlock {
+	slots := (sizeClassSpanBlocks - 1) / span.class
+	return span.start() + 1 + gcBlock(slots*span.class)
+}
+
+// isEmpty returns whether the span doesn't contain any objects.
+func (span *sizeClassSpan) isEmpty() bool {
+	for slot := span.start() + 1; slot < span.end(); slot += gcBlock(span.class) {
+		if slot.state() != blockStateFree {
+			return false
+		}
+	}
+	return true
+}
+
+// addFreeSlots puts the free slots of the span on the free list of its size
+// class, so that the lowest address is used first.
+func (span *sizeClassSpan) addFreeSlots() {
+	list := &sizeClassFree[span.class-1]
+	first := span.start() + 1
+	for slot := span.end(); slot != first; {
+		slot -= gcBlock(span.class)
+		if slot.state() != blockStateFree {
+			continue
+		}
+		free := (*sizeClassSlot)(slot.pointer())
+		free.next = *list
+		*list = free
+		sizeClassFreeBlocks += span.class
+	}
+}
+
+// sizeClassAlloc allocates a slot for an object of the given number of blocks.
+// It returns nil if the object is too big for a size class, or if there is no
+// free slot and no space for a new span.
+func sizeClassAlloc(blocks uintptr) unsafe.Pointer {
+	if blocks > sizeClassMaxBlocks {
+		return nil
+	}
+	list := &sizeClassFree[blocks-1]
+	if *list == nil && !sizeClassNewSpan(blocks) {
+		return nil
+	}
+	slot := *list
+	*list = slot.next
+	sizeClassFreeBlocks -= blocks
+	return unsafe.Pointer(slot)
+}
+
+// sizeClassNewSpan creates a new span for the given size class from the free
+// ranges. It returns false if there is no free range that is big enough.
+func sizeClassNewSpan(class uintptr) bool {
+	slots := (sizeClassSpanBlocks - 1) / class
+	pointer := popFreeRange(1 + slots*class)
+	if pointer == nil {
+		return false
+	}
+	if gcPacing {
+		gcHeapAlloc += bytesPerBlock
+	}
+
+	// The descriptor is a heap object. It stays alive as long as it is in the
+	// list of spans.
+	blockFromAddr(uintptr(pointer)).setState(blockStateHead)
+	header := (*objHeader)(pointer)
+	header.layout = parseGCLayout(nil)
+	span := (*sizeClassSpan)(unsafe.Add(pointer, align(unsafe.Sizeof(objHeader{}))))
+	span.class = class
+
+	// Insert the span, keeping the list sorted.
+	prev := &sizeClassSpans
+	for *prev != nil && uintptr(unsafe.Pointer(*prev)) > uintptr(unsafe.Pointer(span)) {
+		prev = &(*prev).next
+	}
+	span.next = *prev
+	*prev = span
+
+	span.addFreeSlots()
+	return true
+}
+
+// sizeClassRebuild rebuilds the free lists of the size classes. Spans that
+// don't contain any objects are released. It is called from buildFreeRanges,
+// after a GC sweep or heap resize, and returns the number of blocks in free
+// slots.
+func sizeClassRebuild() uintptr {
+	sizeClassFree = [sizeClassMaxBlocks]*sizeClassSlot{}
+	sizeClassFreeBlocks = 0
+	prev := &sizeClassSpans
+	for span := sizeClassSpans; span != nil; span = *prev {
+		if span.isEmpty() {
+			// Release the span by freeing the descriptor. The whole span is
+			// then free, and becomes part of a free range.
+			*prev = span.next
+			block := span.start()
+			stateBytePtr := (*uint8)(unsafe.Add(metadataStart, block/blocksPerStateByte))
+			*stateBytePtr &^= uint8(blockStateMask << (block % blocksPerStateByte))
+			continue
+		}
+		span.addFreeSlots()
+		prev = &span.next
+	}
+	return sizeClassFreeBlocks
+}
@@ -0,0 +1,34 @@
+//go:build gc.conservative || gc.precise || gc.incremental
+
+package runtime
+
+import "unsafe"
+
+// Size classes are only used with -gc=sizeclass. These declarations are used
+// by the other block-based GCs, and do nothing.
+
+const gcSizeClasses = false
+
+const sizeClassFreeBlocks = 0
+
+type sizeClassSpan struct {
+	next *sizeClassSpan
+}
+
+var sizeClassSpans *sizeClassSpan
+
+func (span *sizeClassSpan) start() gcBlock {
+	return 0
+}
+
+func (span *sizeClassSpan) end() gcBlock {
+	return 0
+}
+
+func sizeClassAlloc(blocks uintptr) unsafe.Pointer {
+	return nil
+}
+
+func sizeClassRebuild() uintptr {
+	return 0
+}
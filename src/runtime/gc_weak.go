@@ -0,0 +1,87 @@
+//go:build gc.conservative || gc.precise || gc.incremental || gc.sizeclass
+
+package runtime
+
+// Weak pointers for the block-based garbage collectors.
+//
+// A weak pointer is a pointer to a weak handle, which holds the (inverted)
+// address of the object. There is one handle per address, so that weak
+// pointers to the same address compare equal. The handles are stored in a
+// hash table that doesn't keep the objects alive. After the mark phase, the
+// handles of objects that weren't marked are cleared and removed from the
+// table.
+
+import "unsafe"
+
+// The weak handle of an address.
+type weakHandle struct {
+	next *weakHandle // next handle in the same hash table bucket
+	addr uintptr     // inverted, so the GC doesn't see it as a pointer; 0 once cleared
+}
+
+var (
+	weakHandles     [64]*weakHandle // hash table of all handles that are not cleared
+	hasWeakPointers bool            // a weak pointer has been created
+)
+
+func weakHandleBucket(addr uintptr) **weakHandle {
+	return &weakHandles[(addr/unsafe.Alignof(addr))%uintptr(len(weakHandles))]
+}
+
+//go:linkname registerWeakPointer weak.runtime_registerWeakPointer
+func registerWeakPointer(ptr unsafe.Pointer) unsafe.Pointer {
+	// Allocate the handle before taking the lock, as alloc may run the GC.
+	handle := &weakHandle{
+		addr: ^uintptr(ptr),
+	}
+
+	gcLock.Lock()
+	hasWeakPointers = true
+	bucket := weakHandleBucket(uintptr(ptr))
+	for h := *bucket; h != nil; h = h.next {
+		if h.addr == handle.addr {
+			gcLock.Unlock()
+			return unsafe.Pointer(h)
+		}
+	}
+	handle.next = *bucket
+	*bucket = handle
+	gcLock.Unlock()
+	return unsafe.Pointer(handle)
+}
+
+//go:linkname makeStrongFromWeak weak.runtime_makeStrongFromWeak
+func makeStrongFromWeak(u unsafe.Pointer) unsafe.Pointer {
+	// The lock makes sure the GC doesn't clear the handle while the pointer
+	// is being read. Once read, the pointer keeps the object alive.
+	gcLock.Lock()
+	addr := (*weakHandle)(u).addr
+	gcLock.Unlock()
+	if addr == 0 {
+		return nil
+	}
+	return unsafe.Pointer(^addr)
+}
+
+// clearWeakPointers clears the weak handles of all heap objects that are not
+// marked. It is called with gcLock held at the end of the mark phase, before
+// finalizers are queued.
+func clearWeakPointers() {
+	if !hasWeakPointers {
+		// Programs that don't use weak pointers never set this flag, so that
+		// the compiler can remove the hash table.
+		return
+	}
+	for i := range weakHandles {
+		prev := &weakHandles[i]
+		for h := *prev; h != nil; h = *prev {
+			addr := ^h.addr
+			if !isOnHeap(addr) || blockFromAddr(addr).findHead().state() == blockStateMark {
+				prev = &h.next
+				continue
+			}
+			h.addr = 0
+			*prev = h.next
+		}
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !(gc.conservative || gc.precise || gc.incremental || gc.sizeclass)
+
+package runtime
+
+import "unsafe"
+
+// Weak pointers are not supported with this garbage collector, so they are
+// strong pointers that are never cleared.
+
+//go:linkname registerWeakPointer weak.runtime_registerWeakPointer
+func registerWeakPointer(ptr unsafe.Pointer) unsafe.Pointer {
+	return ptr
+}
+
+//go:linkname makeStrongFromWeak weak.runtime_makeStrongFromWeak
+func makeStrongFromWeak(u unsafe.Pointer) unsafe.Pointer {
+	return u
+}
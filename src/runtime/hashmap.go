@@ -237,6 +237,7 @@ func hashmapSet(m *hashmap, key unsafe.Pointer, value unsafe.Pointer, hash uint3
 				if m.keyEqual(key, slotKey, m.keySize) {
 					// found same key, replace it
 					memcpy(slotValue, value, m.valueSize)
+					gcWriteBarrierRange(slotValue, m.valueSize)
 					return
 				}
 			}
@@ -252,7 +253,9 @@ func hashmapSet(m *hashmap, key unsafe.Pointer, value unsafe.Pointer, hash uint3
 	}
 	m.count++
 	memcpy(emptySlotKey, key, m.keySize)
+	gcWriteBarrierRange(emptySlotKey, m.keySize)
 	memcpy(emptySlotValue, value, m.valueSize)
+	gcWriteBarrierRange(emptySlotValue, m.valueSize)
 	*emptySlotTophash = tophash
 }
 
@@ -268,7 +271,9 @@ func hashmapInsertIntoNewBucket(m *hashmap, key, value unsafe.Pointer, tophash u
 	slotValue := hashmapSlotValue(m, bucket, 0)
 	m.count++
 	memcpy(slotKey, key, m.keySize)
+	gcWriteBarrierRange(slotKey, m.keySize)
 	memcpy(slotValue, value, m.valueSize)
+	gcWriteBarrierRange(slotValue, m.valueSize)
 	bucket.tophash[0] = tophash
 	return bucket
 }
@@ -337,6 +342,7 @@ func hashmapGet(m *hashmap, key, value unsafe.Pointer, valueSize uintptr, hash u
 				if m.keyEqual(key, slotKey, m.keySize) {
 					// Found the key, copy it.
 					memcpy(value, slotValue, m.valueSize)
+					gcWriteBarrierRange(value, m.valueSize)
 					return true
 				}
 			}
@@ -439,12 +445,14 @@ func hashmapNext(m *hashmap, it *hashmapIterator, key, value unsafe.Pointer) boo
 		// Found a key.
 		slotKey := hashmapSlotKey(m, it.bucket, it.bucketIndex)
 		memcpy(key, slotKey, m.keySize)
+		gcWriteBarrierRange(key, m.keySize)
 
 		if it.buckets == m.buckets {
 			// Our view of the buckets is the same as the parent map.
 			// Just copy the value we have
 			slotValue := hashmapSlotValue(m, it.bucket, it.bucketIndex)
 			memcpy(value, slotValue, m.valueSize)
+			gcWriteBarrierRange(value, m.valueSize)
 			it.bucketIndex++
 		} else {
 			it.bucketIndex++
@@ -1,50 +1,428 @@
-// Package metrics is a dummy package that is not yet implemented.
-
+// Package metrics provides a stable interface to access implementation-defined
+// metrics exported by the runtime.
+//
+// TinyGo supports a subset of the metrics of the gc toolchain, derived from
+// the statistics that the garbage collector and the scheduler keep track of.
+// Some of them (like the number of allocated objects) are always zero with
+// some garbage collectors. Reading a metric that is not supported results in
+// a value with KindBad, just like reading an unknown metric.
+//
+// The metrics starting with /tinygo/ are specific to TinyGo. They describe the
+// fragmentation of the heap, and are only set by the block-based garbage
+// collectors (-gc=conservative, -gc=precise, -gc=incremental and
+// -gc=sizeclass).
 package metrics
 
+import (
+	"math"
+	"runtime"
+	"unsafe"
+)
+
+// Description describes a runtime metric.
 type Description struct {
-	Name        string
+	// Name is the full name of the metric which includes the unit.
+	//
+	// The format of the metric may be described by the following regular expression.
+	//
+	// 	^(?P<name>/[^:]+):(?P<unit>[^:*/]+(?:[*/][^:*/]+)*)$
+	Name string
+
+	// Description is an English language sentence describing the metric.
 	Description string
-	Kind        ValueKind
-	Cumulative  bool
+
+	// Kind is the kind of value for this metric.
+	Kind ValueKind
+
+	// Cumulative is whether or not the metric is cumulative. If a cumulative
+	// metric is just a single number, then it increases monotonically.
+	Cumulative bool
+}
+
+// metricData is the data of the runtime needed to compute all the metrics.
+type metricData struct {
+	memStats    runtime.MemStats
+	goroutines  int
+	gomaxprocs  int
+	gcPercent   int32
+	memoryLimit int64
+
+	freeRanges    uint64
+	freeLargest   uint64
+	sizeClassIdle uint64
 }
 
+// metric is a single supported metric.
+type metric struct {
+	Description
+	compute func(d *metricData) uint64
+}
+
+// metrics is the list of all supported metrics, sorted by name.
+var metrics = []metric{
+	{
+		Description: Description{
+			Name:        "/gc/cycles/automatic:gc-cycles",
+			Description: "Count of completed GC cycles generated by the Go runtime.",
+			Kind:        KindUint64,
+			Cumulative:  true,
+		},
+		compute: func(d *metricData) uint64 {
+			return uint64(d.memStats.NumGC - d.memStats.NumForcedGC)
+		},
+	},
+	{
+		Description: Description{
+			Name:        "/gc/cycles/forced:gc-cycles",
+			Description: "Count of completed GC cycles forced by the application.",
+			Kind:        KindUint64,
+			Cumulative:  true,
+		},
+		compute: func(d *metricData) uint64 {
+			return uint64(d.memStats.NumForcedGC)
+		},
+	},
+	{
+		Description: Description{
+			Name:        "/gc/cycles/total:gc-cycles",
+			Description: "Count of all completed GC cycles.",
+			Kind:        KindUint64,
+			Cumulative:  true,
+		},
+		compute: func(d *metricData) uint64 {
+			return uint64(d.memStats.NumGC)
+		},
+	},
+	{
+		Description: Description{
+			Name:        "/gc/gogc:percent",
+			Description: "Heap size target percentage configured by the user, otherwise 100. This value is set by the GOGC environment variable, and the runtime/debug.SetGCPercent function.",
+			Kind:        KindUint64,
+		},
+		compute: func(d *metricData) uint64 {
+			return uint64(d.gcPercent)
+		},
+	},
+	{
+		Description: Description{
+			Name:        "/gc/gomemlimit:bytes",
+			Description: "Go runtime memory limit configured by the user, otherwise math.MaxInt64. This value is set by the GOMEMLIMIT environment variable, and the runtime/debug.SetMemoryLimit function.",
+			Kind:        KindUint64,
+		},
+		compute: func(d *metricData) uint64 {
+			return uint64(d.memoryLimit)
+		},
+	},
+	{
+		Description: Description{
+			Name:        "/gc/heap/allocs:bytes",
+			Description: "Cumulative sum of memory allocated to the heap by the application.",
+			Kind:        KindUint64,
+			Cumulative:  true,
+		},
+		compute: func(d *metricData) uint64 {
+			return d.memStats.TotalAlloc
+		},
+	},
+	{
+		Description: Description{
+			Name:        "/gc/heap/allocs:objects",
+			Description: "Cumulative count of heap allocations triggered by the application.",
+			Kind:        KindUint64,
+			Cumulative:  true,
+		},
+		compute: func(d *metricData) uint64 {
+			return d.memStats.Mallocs
+		},
+	},
+	{
+		Description: Description{
+			Name:        "/gc/heap/frees:objects",
+			Description: "Cumulative count of heap allocations whose storage was freed by the garbage collector.",
+			Kind:        KindUint64,
+			Cumulative:  true,
+		},
+		compute: func(d *metricData) uint64 {
+			return d.memStats.Frees
+		},
+	},
+	{
+		Description: Description{
+			Name:        "/gc/heap/goal:bytes",
+			Description: "Heap size target for the end of the GC cycle.",
+			Kind:        KindUint64,
+		},
+		compute: func(d *metricData) uint64 {
+			return d.memStats.NextGC
+		},
+	},
+	{
+		Description: Description{
+			Name:        "/gc/heap/objects:objects",
+			Description: "Number of objects, live or unswept, occupying heap memory.",
+			Kind:        KindUint64,
+		},
+		compute: func(d *metricData) uint64 {
+			return d.memStats.Mallocs - d.memStats.Frees
+		},
+	},
+	{
+		Description: Description{
+			Name:        "/memory/classes/heap/free:bytes",
+			Description: "Memory that is completely free and eligible to be returned to the underlying system, but has not been.",
+			Kind:        KindUint64,
+		},
+		compute: func(d *metricData) uint64 {
+			return heapFree(&d.memStats)
+		},
+	},
+	{
+		Description: Description{
+			Name:        "/memory/classes/heap/objects:bytes",
+			Description: "Memory occupied by live objects and dead objects that have not yet been marked free by the garbage collector.",
+			Kind:        KindUint64,
+		},
+		compute: func(d *metricData) uint64 {
+			return d.memStats.HeapAlloc
+		},
+	},
+	{
+		Description: Description{
+			Name:        "/memory/classes/heap/released:bytes",
+			Description: "Memory that is completely free and has been returned to the underlying system.",
+			Kind:        KindUint64,
+		},
+		compute: func(d *metricData) uint64 {
+			return d.memStats.HeapReleased
+		},
+	},
+	{
+		Description: Description{
+			Name:        "/memory/classes/heap/unused:bytes",
+			Description: "Memory that is reserved for heap objects but is not currently used to hold heap objects.",
+			Kind:        KindUint64,
+		},
+		compute: func(d *metricData) uint64 {
+			return heapUnused(&d.memStats)
+		},
+	},
+	{
+		Description: Description{
+			Name:        "/memory/classes/metadata/other:bytes",
+			Description: "Memory that is reserved for or used to hold runtime metadata.",
+			Kind:        KindUint64,
+		},
+		compute: func(d *metricData) uint64 {
+			return d.memStats.GCSys
+		},
+	},
+	{
+		Description: Description{
+			Name:        "/memory/classes/total:bytes",
+			Description: "All memory mapped by the Go runtime into the current process as read-write. Equals the sum of all /memory/classes metrics.",
+			Kind:        KindUint64,
+		},
+		compute: func(d *metricData) uint64 {
+			m := &d.memStats
+			return heapFree(m) + m.HeapAlloc + m.HeapReleased + heapUnused(m) + m.GCSys
+		},
+	},
+	{
+		Description: Description{
+			Name:        "/sched/gomaxprocs:threads",
+			Description: "The current runtime.GOMAXPROCS setting, or the number of operating system threads that can execute user-level Go code simultaneously.",
+			Kind:        KindUint64,
+		},
+		compute: func(d *metricData) uint64 {
+			return uint64(d.gomaxprocs)
+		},
+	},
+	{
+		Description: Description{
+			Name:        "/sched/goroutines:goroutines",
+			Description: "Count of live goroutines.",
+			Kind:        KindUint64,
+		},
+		compute: func(d *metricData) uint64 {
+			return uint64(d.goroutines)
+		},
+	},
+	{
+		Description: Description{
+			Name:        "/tinygo/gc/heap/free-ranges:ranges",
+			Description: "Number of ranges of contiguous free heap memory that are available for allocation.",
+			Kind:        KindUint64,
+		},
+		compute: func(d *metricData) uint64 {
+			return d.freeRanges
+		},
+	},
+	{
+		Description: Description{
+			Name:        "/tinygo/gc/heap/largest-free:bytes",
+			Description: "Size of the largest range of contiguous free heap memory. Larger allocations need a GC cycle or a bigger heap, even if there is more free heap memory in total.",
+			Kind:        KindUint64,
+		},
+		compute: func(d *metricData) uint64 {
+			return d.freeLargest
+		},
+	},
+	{
+		Description: Description{
+			Name:        "/tinygo/gc/heap/sizeclass-idle:bytes",
+			Description: "Free heap memory in the slots for small objects of -gc=sizeclass. It is part of /memory/classes/heap/free:bytes, but can only be used for objects of the same size class.",
+			Kind:        KindUint64,
+		},
+		compute: func(d *metricData) uint64 {
+			return d.sizeClassIdle
+		},
+	},
+}
+
+// heapFree returns the number of idle heap bytes that were not released to
+// the operating system.
+func heapFree(m *runtime.MemStats) uint64 {
+	if m.HeapIdle < m.HeapReleased {
+		return 0
+	}
+	return m.HeapIdle - m.HeapReleased
+}
+
+// heapUnused returns the number of bytes in in-use heap blocks that are not
+// part of an allocated object.
+func heapUnused(m *runtime.MemStats) uint64 {
+	if m.HeapInuse < m.HeapAlloc {
+		return 0
+	}
+	return m.HeapInuse - m.HeapAlloc
+}
+
+// runtime_gcSettings returns the GC percentage and the memory limit.
+// Implemented in the runtime.
+func runtime_gcSettings() (int32, int64)
+
+// runtime_heapFragmentation returns the number of free ranges in the heap, the
+// size of the largest one, and the size of the free size class slots.
+// Implemented in the runtime.
+func runtime_heapFragmentation() (ranges, largest, sizeClassIdle uint64)
+
+// All returns a slice containing metric descriptions for all supported
+// metrics.
 func All() []Description {
-	return nil
+	descriptions := make([]Description, len(metrics))
+	for i := range metrics {
+		descriptions[i] = metrics[i].Description
+	}
+	return descriptions
 }
 
+// Float64Histogram represents a distribution of float64 values.
 type Float64Histogram struct {
-	Counts  []uint64
+	// Counts contains the weights for each histogram bucket.
+	Counts []uint64
+
+	// Buckets contains the boundaries of the histogram buckets, in increasing
+	// order. Counts[n] is the weight of the bucket [Buckets[n], Buckets[n+1]).
 	Buckets []float64
 }
 
+// Sample captures a single metric sample.
 type Sample struct {
-	Name  string
+	// Name is the name of the metric sampled.
+	//
+	// It must correspond to a name in one of the metric descriptions
+	// returned by All.
+	Name string
+
+	// Value is the value of the metric sample.
 	Value Value
 }
 
-func Read(m []Sample) {}
+// Read populates each Value field in the given slice of metric samples.
+//
+// Desired metrics should be present in the slice with the appropriate name.
+// Sample values with names not appearing in All will have their Value
+// populated as KindBad to indicate that the name is unknown.
+//
+// The statistics are read once for all samples, so the values in a single
+// call to Read are consistent with each other.
+func Read(m []Sample) {
+	var d metricData
+	runtime.ReadMemStats(&d.memStats)
+	d.goroutines = runtime.NumGoroutine()
+	d.gomaxprocs = runtime.GOMAXPROCS(0)
+	d.gcPercent, d.memoryLimit = runtime_gcSettings()
+	d.freeRanges, d.freeLargest, d.sizeClassIdle = runtime_heapFragmentation()
 
-type Value struct{}
-
-func (v Value) Float64() float64 {
-	return 0
+	for i := range m {
+		m[i].Value = Value{}
+		for j := range metrics {
+			if metrics[j].Name == m[i].Name {
+				m[i].Value = Value{
+					kind:   metrics[j].Kind,
+					scalar: metrics[j].compute(&d),
+				}
+				break
+			}
+		}
+	}
 }
-func (v Value) Float64Histogram() *Float64Histogram {
-	return nil
+
+// Value represents a metric value returned by the runtime.
+type Value struct {
+	kind    ValueKind
+	scalar  uint64         // contains scalar values for scalar Kinds.
+	pointer unsafe.Pointer // contains non-scalar values.
 }
+
+// Kind returns the tag representing the kind of value this is.
 func (v Value) Kind() ValueKind {
-	return KindBad
+	return v.kind
 }
+
+// Uint64 returns the internal uint64 value for the metric.
+//
+// If v.Kind() != KindUint64, this method panics.
 func (v Value) Uint64() uint64 {
-	return 0
+	if v.kind != KindUint64 {
+		panic("called Uint64 on non-uint64 metric value")
+	}
+	return v.scalar
 }
 
+// Float64 returns the internal float64 value for the metric.
+//
+// If v.Kind() != KindFloat64, this method panics.
+func (v Value) Float64() float64 {
+	if v.kind != KindFloat64 {
+		panic("called Float64 on non-float64 metric value")
+	}
+	return math.Float64frombits(v.scalar)
+}
+
+// Float64Histogram returns the internal *Float64Histogram value for the metric.
+//
+// If v.Kind() != KindFloat64Histogram, this method panics.
+func (v Value) Float64Histogram() *Float64Histogram {
+	if v.kind != KindFloat64Histogram {
+		panic("called Float64Histogram on non-Float64Histogram metric value")
+	}
+	return (*Float64Histogram)(v.pointer)
+}
+
+// ValueKind is a tag for a metric Value which indicates its type.
 type ValueKind int
 
 const (
+	// KindBad indicates that the Value has no type and should not be used.
 	KindBad ValueKind = iota
+
+	// KindUint64 indicates that the type of the Value is a uint64.
 	KindUint64
+
+	// KindFloat64 indicates that the type of the Value is a float64.
 	KindFloat64
+
+	// KindFloat64Histogram indicates that the type of the Value is a *Float64Histogram.
 	KindFloat64Histogram
 )
@@ -0,0 +1,233 @@
+//go:build (gc.conservative || gc.precise || gc.incremental || gc.sizeclass) && symtab.full
+
+package runtime
+
+// Heap profile for the block-based garbage collectors.
+//
+// On average one allocation per MemProfileRate bytes is sampled. For each
+// sampled allocation, the stack is recorded in a bucket (one per unique stack)
+// and the object is added to a list of sampled objects. At the end of the mark
+// phase of every GC cycle, this list is checked for objects that weren't marked
+// and will be freed.
+//
+// The profile itself is stored on the heap. To avoid recursion, allocations
+// made while a sample is being recorded are never sampled.
+
+import "unsafe"
+
+const hasMemProfile = true
+
+const memProfileMaxStack = len(MemProfileRecord{}.Stack0)
+
+// All sampled allocations with the same stack.
+type memProfileBucket struct {
+	next                  *memProfileBucket // next bucket with the same hash
+	hash                  uintptr
+	allocs, frees         int64
+	allocBytes, freeBytes int64
+	depth                 uintptr
+	stack                 [memProfileMaxStack]uintptr
+}
+
+// A sampled object that hasn't been freed yet.
+type memProfileObject struct {
+	next   *memProfileObject
+	addr   uintptr // inverted, so the GC doesn't see it as a pointer
+	size   uintptr
+	bucket *memProfileBucket
+}
+
+var (
+	memProfileBuckets [256]*memProfileBucket // hash table of all buckets
+	memProfileObjects *memProfileObject      // sampled objects that are still alive
+	memProfileRate    int                    // MemProfileRate, as seen by the last allocation
+	memProfileNext    uintptr                // bytes left to allocate before the next sample
+	memProfileBusy    bool                   // a sample is being recorded
+	memProfilePending unsafe.Pointer         // object being recorded, kept alive until then
+)
+
+// memProfileSample is called for every allocation with gcLock held, and
+// returns whether it should be sampled. If so, memProfileAlloc must be called
+// once the object has been initialized and gcLock has been released.
+func memProfileSample(ptr unsafe.Pointer, size uintptr) bool {
+	rate := MemProfileRate
+	if rate <= 0 {
+		return false
+	}
+	if rate != memProfileRate {
+		memProfileRate = rate
+		memProfileNext = memProfileNextSample(rate)
+	}
+	if size < memProfileNext {
+		memProfileNext -= size
+		return false
+	}
+	memProfileNext = memProfileNextSample(rate)
+	if memProfileBusy {
+		// Another sample is being recorded. This is either an allocation
+		// made while recording the sample, or an allocation on another
+		// thread.
+		return false
+	}
+	memProfileBusy = true
+	memProfilePending = ptr
+	return true
+}
+
+// memProfileAlloc records a sampled allocation of the given size.
+func memProfileAlloc(ptr unsafe.Pointer, size uintptr) {
+	// Get the stack of the allocation, without the allocator itself and other
+	// runtime functions (like runtime.sliceAppend) that called it.
+	var buf [memProfileMaxStack + 8]uintptr
+	stack := buf[:callers(0, buf[:])]
+	for len(stack) != 0 && memProfileIsRuntimeFrame(stack[0]) {
+		stack = stack[1:]
+	}
+	if len(stack) > memProfileMaxStack {
+		stack = stack[:memProfileMaxStack]
+	}
+
+	// Find the bucket for this stack, or create a new one. The hash table is
+	// only modified while memProfileBusy is set, so can be read without
+	// holding gcLock.
+	hash := uintptr(2166136261)
+	for _, pc := range stack {
+		hash ^= pc
+		hash *= 16777619
+	}
+	index := hash % uintptr(len(memProfileBuckets))
+	bucket := memProfileBuckets[index]
+	for bucket != nil {
+		if bucket.hash == hash && bucket.depth == uintptr(len(stack)) && memProfileStackEqual(bucket.stack[:bucket.depth], stack) {
+			break
+		}
+		bucket = bucket.next
+	}
+	newBucket := bucket == nil
+	if newBucket {
+		bucket = &memProfileBucket{
+			hash:  hash,
+			depth: uintptr(len(stack)),
+		}
+		copy(bucket.stack[:], stack)
+	}
+	object := &memProfileObject{
+		addr:   ^uintptr(ptr),
+		size:   size,
+		bucket: bucket,
+	}
+
+	gcLock.Lock()
+	if newBucket {
+		bucket.next = memProfileBuckets[index]
+		memProfileBuckets[index] = bucket
+	}
+	bucket.allocs++
+	bucket.allocBytes += int64(size)
+	object.next = memProfileObjects
+	memProfileObjects = object
+	memProfilePending = nil
+	memProfileBusy = false
+	gcLock.Unlock()
+}
+
+// memProfileSweep is called at the end of the mark phase of every GC cycle
+// (with gcLock held) to update the profile with the sampled objects that will
+// be freed.
+func memProfileSweep() {
+	prev := &memProfileObjects
+	for object := memProfileObjects; object != nil; object = object.next {
+		if blockFromAddr(^object.addr).state() != blockStateMark {
+			object.bucket.frees++
+			object.bucket.freeBytes += int64(object.size)
+			*prev = object.next
+			continue
+		}
+		prev = &object.next
+	}
+}
+
+func memProfileRead(p []MemProfileRecord, inuseZombies bool) (n int, ok bool) {
+	gcLock.Lock()
+	for _, bucket := range memProfileBuckets {
+		for ; bucket != nil; bucket = bucket.next {
+			if inuseZombies || bucket.allocBytes != bucket.freeBytes {
+				n++
+			}
+		}
+	}
+	if n <= len(p) {
+		ok = true
+		i := 0
+		for _, bucket := range memProfileBuckets {
+			for ; bucket != nil; bucket = bucket.next {
+				if inuseZombies || bucket.allocBytes != bucket.freeBytes {
+					r := &p[i]
+					r.AllocBytes = bucket.allocBytes
+					r.FreeBytes = bucket.freeBytes
+					r.AllocObjects = bucket.allocs
+					r.FreeObjects = bucket.frees
+					r.Stack0 = bucket.stack
+					i++
+				}
+			}
+		}
+	}
+	gcLock.Unlock()
+	return
+}
+
+// memProfileIsRuntimeFrame returns whether all (inlined) functions at the
+// given return address are part of the runtime.
+func memProfileIsRuntimeFrame(ra uintptr) bool {
+	var iter symtabIterator
+	if !iter.init(callerPC(ra)) {
+		return false
+	}
+	var frame Frame
+	for iter.next(&frame) {
+		if len(frame.Function) < len("runtime.") || frame.Function[:len("runtime.")] != "runtime." {
+			return false
+		}
+	}
+	return true
+}
+
+func memProfileStackEqual(a, b []uintptr) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// memProfileNextSample returns the number of bytes to allocate before taking
+// the next sample. Like in the gc toolchain, this is a random number from an
+// exponential distribution with the given mean, so that the sampled values can
+// be scaled back to estimate the actual allocations.
+func memProfileNextSample(mean int) uintptr {
+	if mean == 1 {
+		// Sample every allocation.
+		return 0
+	}
+	// Compute -ln(q) * mean, with q uniformly distributed in (0, 1].
+	const randomBitCount = 26
+	q := fastrand()%(1<<randomBitCount) + 1
+	qlog := memProfileLog2(float64(q)) - randomBitCount
+	if qlog > 0 {
+		qlog = 0
+	}
+	const minusLog2 = -0.6931471805599453 // -ln(2)
+	return uintptr(qlog*(minusLog2*float64(mean))) + 1
+}
+
+// memProfileLog2 returns a fast approximation of log2(x), for x >= 1.
+func memProfileLog2(x float64) float64 {
+	bits := float64bits(x)
+	exp := float64(int(bits>>52&0x7ff) - 1023)
+	// The mantissa is in the range [1, 2), approximate log2 with a quadratic
+	// function in this range.
+	m := float64frombits(bits&^(0x7ff<<52)|1023<<52) - 1
+	return exp + m*(1.3465553-0.3465553*m)
+}
@@ -0,0 +1,23 @@
+//go:build !(gc.conservative || gc.precise || gc.incremental || gc.sizeclass) || !symtab.full
+
+package runtime
+
+import "unsafe"
+
+// The memory profile is not supported with this garbage collector, or the
+// symbol table is not available.
+const hasMemProfile = false
+
+func memProfileSample(ptr unsafe.Pointer, size uintptr) bool {
+	return false
+}
+
+func memProfileAlloc(ptr unsafe.Pointer, size uintptr) {
+}
+
+func memProfileSweep() {
+}
+
+func memProfileRead(p []MemProfileRecord, inuseZombies bool) (n int, ok bool) {
+	return 0, true
+}
@@ -53,4 +53,24 @@ func init() {
 		}
 		env = append(env, s[start:])
 	}
+
+	// The GC settings can only be read once the environment is parsed.
+	readGCSettings()
+}
+
+// gotracebackEnv returns the value of the GOTRACEBACK environment variable, if
+// it was passed to the program.
+func gotracebackEnv() (string, bool) {
+	return lookupEnv("GOTRACEBACK")
+}
+
+// lookupEnv returns the value of an environment variable, if it was passed to
+// the program.
+func lookupEnv(key string) (string, bool) {
+	for _, kv := range env {
+		if len(kv) > len(key) && kv[:len(key)] == key && kv[len(key)] == '=' {
+			return kv[len(key)+1:], true
+		}
+	}
+	return "", false
 }
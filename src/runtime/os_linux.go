@@ -17,6 +17,7 @@ const (
 	flag_PROT_WRITE    = 0x2
 	flag_MAP_PRIVATE   = 0x2
 	flag_MAP_ANONYMOUS = linux_MAP_ANONYMOUS // different on alpha, hppa, mips, xtensa
+	flag_MADV_DONTNEED = 0x4
 )
 
 // Source: https://github.com/torvalds/linux/blob/master/include/uapi/linux/time.h
@@ -78,6 +78,7 @@ func panicOrGoexit(message interface{}, panicking panicState) {
 	printstring("panic: ")
 	printitf(message)
 	printnl()
+	printPanicTraceback()
 	abort()
 }
 
@@ -104,6 +105,7 @@ func runtimePanicAt(addr unsafe.Pointer, msg string) {
 	}
 	printstring(msg)
 	printnl()
+	printPanicTraceback()
 	abort()
 }
 
@@ -1,44 +1,271 @@
+// Package pprof writes runtime profiling data in the format expected by the
+// pprof visualization tool.
+//
+// TinyGo supports CPU profiles on Linux, and heap profiles with the
+// block-based garbage collectors: -gc=conservative, -gc=precise (the default
+// on WebAssembly, use it on Linux), -gc=incremental and -gc=sizeclass. Both
+// need the symbol table in the binary (-symtab=full). Other profiles
+// (goroutine, block, mutex, etc) are not supported.
 package pprof
 
-// TinyGo does not implement pprof. However, a dummy shell is needed for the
-// testing package (and testing/internal/pprof).
-
 import (
+	"bufio"
 	"errors"
+	"fmt"
 	"io"
+	"math"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
 )
 
 var ErrUnimplemented = errors.New("runtime/pprof: unimplemented")
 
-type Profile struct{}
+var (
+	errCPUProfileUnsupported  = errors.New("runtime/pprof: CPU profiling is not supported on this target")
+	errHeapProfileUnsupported = errors.New("runtime/pprof: heap profiling needs a block-based GC (-gc=conservative, -gc=precise, -gc=incremental or -gc=sizeclass), and -symtab=full")
+)
 
-func StartCPUProfile(w io.Writer) error {
-	return nil
-}
+// Implemented in the runtime.
+func cpuProfileSupported() bool
+func startCPUProfile(hz int) (errno uintptr)
+func readCPUProfile(fn func(count int, stack []uintptr)) (lost int)
+func memProfileSupported() bool
 
-func StopCPUProfile() {
+// A Profile is a collection of stack traces showing the call sequences that
+// led to instances of a particular event, such as allocation.
+type Profile struct {
+	name              string
+	defaultSampleType string
 }
 
-func WriteHeapProfile(w io.Writer) error {
-	return nil
-}
+var (
+	allocsProfile = &Profile{name: "allocs", defaultSampleType: "alloc_space"}
+	heapProfile   = &Profile{name: "heap"}
+)
 
+// Lookup returns the profile with the given name, or nil if no such profile
+// exists. Only the "allocs" and "heap" profiles are currently supported.
 func Lookup(name string) *Profile {
+	switch name {
+	case "allocs":
+		return allocsProfile
+	case "heap":
+		return heapProfile
+	}
 	return nil
 }
 
+// Profiles returns a slice of all the known profiles, sorted by name.
+func Profiles() []*Profile {
+	return []*Profile{allocsProfile, heapProfile}
+}
+
+// Name returns this profile's name, which can be passed to Lookup to reobtain
+// the profile.
 func (p *Profile) Name() string {
-	return ""
+	if p == nil {
+		return ""
+	}
+	return p.name
 }
 
+// Count returns the number of execution stacks currently in the profile.
 func (p *Profile) Count() int {
-	return 0
+	if p == nil {
+		return 0
+	}
+	n, _ := runtime.MemProfile(nil, true)
+	return n
 }
 
+// WriteTo writes a pprof-formatted snapshot of the profile to w.
+// If a write to w returns an error, WriteTo returns that error.
+// Otherwise, WriteTo returns nil.
+//
+// The debug parameter enables additional output. Passing debug=0 writes the
+// gzip-compressed protocol buffer described in
+// https://github.com/google/pprof/tree/main/proto#overview. Passing debug=1
+// writes the legacy text format with comments translating addresses to
+// function names and line numbers, so that a programmer can read the profile
+// without tools.
 func (p *Profile) WriteTo(w io.Writer, debug int) error {
-	return ErrUnimplemented
+	if p == nil {
+		return ErrUnimplemented
+	}
+	return writeHeap(w, debug, p.defaultSampleType)
 }
 
-func Profiles() []*Profile {
+// WriteHeapProfile is shorthand for Lookup("heap").WriteTo(w, 0).
+// It is preserved for backwards compatibility.
+func WriteHeapProfile(w io.Writer) error {
+	return writeHeap(w, 0, heapProfile.defaultSampleType)
+}
+
+// writeHeap writes the current heap profile to w.
+func writeHeap(w io.Writer, debug int, defaultSampleType string) error {
+	if !memProfileSupported() {
+		return errHeapProfileUnsupported
+	}
+
+	// Read the memory profile. The number of records may change between
+	// calls, so try until it fits.
+	var records []runtime.MemProfileRecord
+	n, _ := runtime.MemProfile(nil, true)
+	for {
+		records = make([]runtime.MemProfileRecord, n+50)
+		var ok bool
+		n, ok = runtime.MemProfile(records, true)
+		if ok {
+			records = records[:n]
+			break
+		}
+	}
+
+	if debug != 0 {
+		return writeHeapText(w, records)
+	}
+	return writeHeapProto(w, records, int64(runtime.MemProfileRate), defaultSampleType)
+}
+
+// writeHeapProto writes the heap profile in the protocol buffer format.
+func writeHeapProto(w io.Writer, records []runtime.MemProfileRecord, rate int64, defaultSampleType string) error {
+	now := time.Now()
+	b := newProfileBuilder([][2]string{
+		{"alloc_objects", "count"},
+		{"alloc_space", "bytes"},
+		{"inuse_objects", "count"},
+		{"inuse_space", "bytes"},
+	}, [2]string{"space", "bytes"}, rate)
+	if defaultSampleType != "" {
+		b.setDefaultSampleType(defaultSampleType)
+	}
+	for i := range records {
+		r := &records[i]
+		allocObjects, allocBytes := scaleHeapSample(r.AllocObjects, r.AllocBytes, rate)
+		inuseObjects, inuseBytes := scaleHeapSample(r.InUseObjects(), r.InUseBytes(), rate)
+		b.addSample(r.Stack(), []int64{allocObjects, allocBytes, inuseObjects, inuseBytes})
+	}
+	return b.write(w, now, now)
+}
+
+// scaleHeapSample adjusts the data from a heap sample to account for its
+// probability of appearing in the collected data. Heap profiles are a sampling
+// of the memory allocations, with a probability that depends on the size of
+// the allocation, so this estimates the actual number of allocations.
+func scaleHeapSample(count, size, rate int64) (int64, int64) {
+	if count == 0 || size == 0 {
+		return 0, 0
+	}
+	if rate <= 1 {
+		// if rate==1 all samples were collected so no adjustment is needed.
+		// if rate<1 treat as unknown and skip scaling.
+		return count, size
+	}
+	avgSize := float64(size) / float64(count)
+	scale := 1 / (1 - math.Exp(-avgSize/float64(rate)))
+	return int64(float64(count) * scale), int64(float64(size) * scale)
+}
+
+// writeHeapText writes the heap profile in the legacy text format.
+func writeHeapText(w io.Writer, records []runtime.MemProfileRecord) error {
+	bw := bufio.NewWriter(w)
+	var total runtime.MemProfileRecord
+	for i := range records {
+		r := &records[i]
+		total.AllocBytes += r.AllocBytes
+		total.AllocObjects += r.AllocObjects
+		total.FreeBytes += r.FreeBytes
+		total.FreeObjects += r.FreeObjects
+	}
+	fmt.Fprintf(bw, "heap profile: %d: %d [%d: %d] @ heap/%d\n",
+		total.InUseObjects(), total.InUseBytes(),
+		total.AllocObjects, total.AllocBytes,
+		2*runtime.MemProfileRate)
+	for i := range records {
+		r := &records[i]
+		fmt.Fprintf(bw, "%d: %d [%d: %d] @",
+			r.InUseObjects(), r.InUseBytes(),
+			r.AllocObjects, r.AllocBytes)
+		for _, pc := range r.Stack() {
+			fmt.Fprintf(bw, " %#x", pc)
+		}
+		fmt.Fprintf(bw, "\n")
+		frames := runtime.CallersFrames(r.Stack())
+		for {
+			frame, more := frames.Next()
+			if frame.Function != "" {
+				fmt.Fprintf(bw, "#\t%#x\t%s+%#x\t%s:%d\n", frame.PC, frame.Function, frame.PC-frame.Entry, frame.File, frame.Line)
+			}
+			if !more {
+				break
+			}
+		}
+		fmt.Fprintf(bw, "\n")
+	}
+	return bw.Flush()
+}
+
+// The CPU profile that is currently running.
+var cpu struct {
+	sync.Mutex
+	profiling bool
+	w         io.Writer
+	start     time.Time
+}
+
+// The sampling rate of the CPU profiler, in Hz.
+const cpuProfileRate = 100
+
+// StartCPUProfile enables CPU profiling for the current process.
+// While profiling, the profile will be buffered and written to w.
+// StartCPUProfile returns an error if profiling is already enabled, or if CPU
+// profiling is not supported on this target.
+//
+// The profile is written to w when StopCPUProfile is called.
+func StartCPUProfile(w io.Writer) error {
+	if !cpuProfileSupported() {
+		return errCPUProfileUnsupported
+	}
+
+	cpu.Lock()
+	defer cpu.Unlock()
+	if cpu.profiling {
+		return fmt.Errorf("cpu profiling already in use")
+	}
+	if errno := startCPUProfile(cpuProfileRate); errno != 0 {
+		return fmt.Errorf("runtime/pprof: could not start CPU profiling: %w", syscall.Errno(errno))
+	}
+	cpu.profiling = true
+	cpu.w = w
+	cpu.start = time.Now()
 	return nil
 }
+
+// StopCPUProfile stops the current CPU profile, if any, and writes it to the
+// writer that was passed to StartCPUProfile.
+func StopCPUProfile() {
+	cpu.Lock()
+	defer cpu.Unlock()
+	if !cpu.profiling {
+		return
+	}
+	cpu.profiling = false
+	runtime.SetCPUProfileRate(0)
+	end := time.Now()
+
+	period := int64(time.Second / cpuProfileRate)
+	b := newProfileBuilder([][2]string{
+		{"samples", "count"},
+		{"cpu", "nanoseconds"},
+	}, [2]string{"cpu", "nanoseconds"}, period)
+	lost := readCPUProfile(func(count int, stack []uintptr) {
+		b.addSample(stack, []int64{int64(count), int64(count) * period})
+	})
+	if lost != 0 {
+		b.addLostSample([]int64{int64(lost), int64(lost) * period})
+	}
+	b.write(cpu.w, cpu.start, end)
+	cpu.w = nil
+}
@@ -0,0 +1,296 @@
+package pprof
+
+// This file writes profiles in the protocol buffer format that is read by
+// go tool pprof. See the description of the format here:
+// https://github.com/google/pprof/blob/main/proto/profile.proto
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"runtime"
+	"time"
+)
+
+// Field numbers of the messages in profile.proto.
+const (
+	// Profile
+	tagProfile_SampleType    = 1
+	tagProfile_Sample        = 2
+	tagProfile_Mapping       = 3
+	tagProfile_Location      = 4
+	tagProfile_Function      = 5
+	tagProfile_StringTable   = 6
+	tagProfile_TimeNanos     = 9
+	tagProfile_DurationNanos = 10
+	tagProfile_PeriodType    = 11
+	tagProfile_Period        = 12
+
+	tagProfile_DefaultSampleType = 14
+
+	// ValueType
+	tagValueType_Type = 1
+	tagValueType_Unit = 2
+
+	// Sample
+	tagSample_Location = 1
+	tagSample_Value    = 2
+
+	// Mapping
+	tagMapping_ID              = 1
+	tagMapping_Start           = 2
+	tagMapping_Limit           = 3
+	tagMapping_Filename        = 5
+	tagMapping_HasFunctions    = 7
+	tagMapping_HasFilenames    = 8
+	tagMapping_HasLineNumbers  = 9
+	tagMapping_HasInlineFrames = 10
+
+	// Location
+	tagLocation_ID        = 1
+	tagLocation_MappingID = 2
+	tagLocation_Address   = 3
+	tagLocation_Line      = 4
+
+	// Line
+	tagLine_FunctionID = 1
+	tagLine_Line       = 2
+
+	// Function
+	tagFunction_ID         = 1
+	tagFunction_Name       = 2
+	tagFunction_SystemName = 3
+	tagFunction_Filename   = 4
+)
+
+// protobuf is a minimal protocol buffer encoder.
+type protobuf struct {
+	data []byte
+}
+
+func (b *protobuf) varint(x uint64) {
+	for x >= 128 {
+		b.data = append(b.data, byte(x)|0x80)
+		x >>= 7
+	}
+	b.data = append(b.data, byte(x))
+}
+
+func (b *protobuf) key(tag int, wireType int) {
+	b.varint(uint64(tag)<<3 | uint64(wireType))
+}
+
+func (b *protobuf) uint64(tag int, x uint64) {
+	// Zero is the default value, so doesn't need to be encoded.
+	if x != 0 {
+		b.key(tag, 0)
+		b.varint(x)
+	}
+}
+
+func (b *protobuf) int64(tag int, x int64) {
+	b.uint64(tag, uint64(x))
+}
+
+func (b *protobuf) bool(tag int, x bool) {
+	if x {
+		b.uint64(tag, 1)
+	}
+}
+
+func (b *protobuf) bytes(tag int, x []byte) {
+	b.key(tag, 2)
+	b.varint(uint64(len(x)))
+	b.data = append(b.data, x...)
+}
+
+func (b *protobuf) string(tag int, x string) {
+	b.key(tag, 2)
+	b.varint(uint64(len(x)))
+	b.data = append(b.data, x...)
+}
+
+// uint64s writes a packed repeated field.
+func (b *protobuf) uint64s(tag int, x []uint64) {
+	var packed protobuf
+	for _, v := range x {
+		packed.varint(v)
+	}
+	b.bytes(tag, packed.data)
+}
+
+func (b *protobuf) int64s(tag int, x []int64) {
+	var packed protobuf
+	for _, v := range x {
+		packed.varint(uint64(v))
+	}
+	b.bytes(tag, packed.data)
+}
+
+// message writes a nested message.
+func (b *protobuf) message(tag int, m *protobuf) {
+	b.bytes(tag, m.data)
+}
+
+// A profileBuilder creates a single profile. Samples are added with addSample,
+// and the profile is written (gzip compressed) using write.
+type profileBuilder struct {
+	pb        protobuf
+	strings   []string
+	stringMap map[string]int64
+	locations map[uintptr]uint64 // program counter to location ID
+	functions map[string]uint64  // function name and file to function ID
+}
+
+// newProfileBuilder starts a new profile with the given sample types, as pairs
+// of (type, unit).
+func newProfileBuilder(sampleTypes [][2]string, periodType [2]string, period int64) *profileBuilder {
+	b := &profileBuilder{
+		strings:   []string{""},
+		stringMap: map[string]int64{"": 0},
+		locations: make(map[uintptr]uint64),
+		functions: make(map[string]uint64),
+	}
+	for _, sampleType := range sampleTypes {
+		b.pb.message(tagProfile_SampleType, b.valueType(sampleType))
+	}
+	b.pb.message(tagProfile_PeriodType, b.valueType(periodType))
+	b.pb.int64(tagProfile_Period, period)
+
+	// Add a single mapping for the executable. All locations are already
+	// symbolized, so pprof doesn't need to look up anything in the binary.
+	var mapping protobuf
+	mapping.uint64(tagMapping_ID, 1)
+	mapping.uint64(tagMapping_Start, 0)
+	mapping.uint64(tagMapping_Limit, ^uint64(0))
+	executable, _ := os.Executable()
+	if executable == "" && len(os.Args) != 0 {
+		executable = os.Args[0]
+	}
+	mapping.int64(tagMapping_Filename, b.stringIndex(executable))
+	mapping.bool(tagMapping_HasFunctions, true)
+	mapping.bool(tagMapping_HasFilenames, true)
+	mapping.bool(tagMapping_HasLineNumbers, true)
+	mapping.bool(tagMapping_HasInlineFrames, true)
+	b.pb.message(tagProfile_Mapping, &mapping)
+	return b
+}
+
+func (b *profileBuilder) valueType(valueType [2]string) *protobuf {
+	var m protobuf
+	m.int64(tagValueType_Type, b.stringIndex(valueType[0]))
+	m.int64(tagValueType_Unit, b.stringIndex(valueType[1]))
+	return &m
+}
+
+// stringIndex returns the index of the string in the string table, adding it
+// if needed.
+func (b *profileBuilder) stringIndex(s string) int64 {
+	index, ok := b.stringMap[s]
+	if !ok {
+		index = int64(len(b.strings))
+		b.strings = append(b.strings, s)
+		b.stringMap[s] = index
+	}
+	return index
+}
+
+// addSample adds a single sample with the given stack (as returned by
+// runtime.Callers) and values, one for each sample type.
+func (b *profileBuilder) addSample(stack []uintptr, values []int64) {
+	locations := make([]uint64, 0, len(stack))
+	for _, pc := range stack {
+		locations = append(locations, b.location(pc))
+	}
+	var sample protobuf
+	sample.uint64s(tagSample_Location, locations)
+	sample.int64s(tagSample_Value, values)
+	b.pb.message(tagProfile_Sample, &sample)
+}
+
+// addLostSample adds a sample for events that could not be recorded. It uses
+// the same function name as the gc toolchain.
+func (b *profileBuilder) addLostSample(values []int64) {
+	id := uint64(len(b.locations) + 1)
+	b.locations[^uintptr(0)] = id
+	var line protobuf
+	line.uint64(tagLine_FunctionID, b.function("runtime/pprof.lostProfileEvent", ""))
+	var location protobuf
+	location.uint64(tagLocation_ID, id)
+	location.message(tagLocation_Line, &line)
+	b.pb.message(tagProfile_Location, &location)
+
+	var sample protobuf
+	sample.uint64s(tagSample_Location, []uint64{id})
+	sample.int64s(tagSample_Value, values)
+	b.pb.message(tagProfile_Sample, &sample)
+}
+
+// location returns the location ID for the given return address, adding the
+// location (including inlined functions) to the profile if needed.
+func (b *profileBuilder) location(pc uintptr) uint64 {
+	if id, ok := b.locations[pc]; ok {
+		return id
+	}
+	id := uint64(len(b.locations) + 1)
+	b.locations[pc] = id
+
+	var location protobuf
+	location.uint64(tagLocation_ID, id)
+	location.uint64(tagLocation_MappingID, 1)
+	location.uint64(tagLocation_Address, uint64(pc))
+	frames := runtime.CallersFrames([]uintptr{pc})
+	for {
+		frame, more := frames.Next()
+		if frame.Function != "" {
+			var line protobuf
+			line.uint64(tagLine_FunctionID, b.function(frame.Function, frame.File))
+			line.int64(tagLine_Line, int64(frame.Line))
+			location.message(tagLocation_Line, &line)
+		}
+		if !more {
+			break
+		}
+	}
+	b.pb.message(tagProfile_Location, &location)
+	return id
+}
+
+// function returns the function ID for the given function, adding it to the
+// profile if needed.
+func (b *profileBuilder) function(name, file string) uint64 {
+	key := name + "\x00" + file
+	if id, ok := b.functions[key]; ok {
+		return id
+	}
+	id := uint64(len(b.functions) + 1)
+	b.functions[key] = id
+
+	var function protobuf
+	function.uint64(tagFunction_ID, id)
+	function.int64(tagFunction_Name, b.stringIndex(name))
+	function.int64(tagFunction_SystemName, b.stringIndex(name))
+	function.int64(tagFunction_Filename, b.stringIndex(file))
+	b.pb.message(tagProfile_Function, &function)
+	return id
+}
+
+// setDefaultSampleType sets the sample type that pprof shows by default.
+func (b *profileBuilder) setDefaultSampleType(sampleType string) {
+	b.pb.int64(tagProfile_DefaultSampleType, b.stringIndex(sampleType))
+}
+
+// write finishes the profile and writes it to w.
+func (b *profileBuilder) write(w io.Writer, start, end time.Time) error {
+	b.pb.int64(tagProfile_TimeNanos, start.UnixNano())
+	b.pb.int64(tagProfile_DurationNanos, end.Sub(start).Nanoseconds())
+	for _, s := range b.strings {
+		b.pb.string(tagProfile_StringTable, s)
+	}
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(b.pb.data); err != nil {
+		return err
+	}
+	return zw.Close()
+}
@@ -1,6 +1,7 @@
 package runtime
 
 import (
+	"internal/reflectlite"
 	"unsafe"
 )
 
@@ -47,6 +48,14 @@ func memcpy(dst, src unsafe.Pointer, size uintptr)
 // like llvm.memmove.p0.p0.i32(dst, src, size, false).
 func memmove(dst, src unsafe.Pointer, size uintptr)
 
+// Copy size bytes from src to dst, like memcpy, for memory that may contain
+// pointers. Packages outside the runtime (like internal/reflectlite) use it to
+// include the write barrier of the incremental GC.
+func typedmemcpy(dst, src unsafe.Pointer, size uintptr) {
+	memcpy(dst, src, size)
+	gcWriteBarrierRange(dst, size)
+}
+
 // Set the given number of bytes to zero.
 // This function is implemented by the compiler as a call to a LLVM intrinsic
 // like llvm.memset.p0.i32(ptr, 0, size, false).
@@ -112,31 +121,41 @@ func UnlockOSThread() {
 // point of the call.
 func KeepAlive(x interface{})
 
-// AddCleanup is a dummy cleanup implementation. It doesn't do any cleaning up.
+// AddCleanup attaches a cleanup function to ptr. Some time after ptr is no
+// longer reachable, the runtime will call cleanup(arg) in a separate goroutine.
+// See the documentation of runtime.AddCleanup in the standard library.
 //
-// We base this on the following loophole in the official runtime.AddCleanup
+// Cleanups are only supported by the block-based garbage collectors
+// (-gc=conservative, -gc=precise, -gc=incremental and -gc=sizeclass). With
+// other garbage collectors they never run, which is allowed by the
 // documentation:
 //
 // > The cleanup(arg) call is not always guaranteed to run; in particular it is
 // > not guaranteed to run before program exit.
-//
-// So it's technically correct (the best kind of correct) to not run any
-// cleanups. But of course, this can lead to resource leaks so cleanups may need
-// to be implemented eventually.
 func AddCleanup[T, S any](ptr *T, cleanup func(S), arg S) Cleanup {
-	return Cleanup{}
+	if ptr == nil {
+		runtimePanic("runtime.AddCleanup: ptr is nil")
+	}
+	if v := reflectlite.ValueOf(arg); v.Kind() == reflectlite.Ptr || v.Kind() == reflectlite.UnsafePointer {
+		if v.UnsafePointer() == unsafe.Pointer(ptr) {
+			runtimePanic("runtime.AddCleanup: ptr is equal to arg, cleanup will never run")
+		}
+	}
+	id := addCleanup(unsafe.Pointer(ptr), func() {
+		cleanup(arg)
+	})
+	return Cleanup{id}
 }
 
-type Cleanup struct{}
-
-func (c Cleanup) Stop() {}
+// Cleanup is a handle to a cleanup call for a specific object.
+type Cleanup struct {
+	id uint32 // 0 if the cleanup will never run
+}
 
-//go:linkname registerWeakPointer weak.runtime_registerWeakPointer
-func registerWeakPointer(ptr unsafe.Pointer) unsafe.Pointer {
-	// TODO: unimplemented.
-	// I hope not implementing this won't break anything, like packages that
-	// expect weak pointers to be GC'd before they actually are.
-	return ptr
+// Stop cancels the cleanup call. Stop will have no effect if the cleanup has
+// already been queued for execution.
+func (c Cleanup) Stop() {
+	removeCleanup(c.id)
 }
 
 var godebugUpdate func(string, string)
@@ -240,6 +240,12 @@ func growHeap() bool {
 	return false
 }
 
+// shrinkHeap tries to shrink the heap to the given end address. Shrinking the
+// heap is unimplemented.
+func shrinkHeap(newHeapEnd uintptr) bool {
+	return false
+}
+
 // getHeapBase returns the start address of the heap
 // this is externally linked by gonx
 func getHeapBase() uintptr {
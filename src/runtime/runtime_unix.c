@@ -10,16 +10,23 @@
 #include <ucontext.h>
 #include <string.h>
 
-void tinygo_handle_fatal_signal(int sig, uintptr_t addr);
+void tinygo_handle_fatal_signal(int sig, uintptr_t addr, uintptr_t sp, uintptr_t fp);
 
 static void signal_handler(int sig, siginfo_t *info, void *context) {
 	ucontext_t* uctx = context;
-	uintptr_t addr = 0;
+	// The program counter, stack pointer and frame pointer of the faulting
+	// code. The frame pointer is left at zero if it is not known, in which case
+	// the runtime only prints the faulting instruction.
+	uintptr_t addr = 0, sp = 0, fp = 0;
 	#if __APPLE__
 		#if __arm64__
 			addr = uctx->uc_mcontext->__ss.__pc;
+			sp = uctx->uc_mcontext->__ss.__sp;
+			fp = uctx->uc_mcontext->__ss.__fp;
 		#elif __x86_64__
 			addr = uctx->uc_mcontext->__ss.__rip;
+			sp = uctx->uc_mcontext->__ss.__rsp;
+			fp = uctx->uc_mcontext->__ss.__rbp;
 		#else
 			#error unknown architecture
 		#endif
@@ -28,17 +35,31 @@ static void signal_handler(int sig, siginfo_t *info, void *context) {
 		// but this works for now.
 		#if __arm__
 			addr = uctx->uc_mcontext.arm_pc;
+			sp = uctx->uc_mcontext.arm_sp;
+			#if __thumb__
+				fp = uctx->uc_mcontext.arm_r7;
+			#else
+				fp = uctx->uc_mcontext.arm_fp;
+			#endif
 		#elif __i386__
 			addr = uctx->uc_mcontext.gregs[REG_EIP];
+			sp = uctx->uc_mcontext.gregs[REG_ESP];
+			fp = uctx->uc_mcontext.gregs[REG_EBP];
 		#elif __x86_64__
 			addr = uctx->uc_mcontext.gregs[REG_RIP];
-		#else // aarch64, mips, maybe others
+			sp = uctx->uc_mcontext.gregs[REG_RSP];
+			fp = uctx->uc_mcontext.gregs[REG_RBP];
+		#elif __aarch64__
+			addr = uctx->uc_mcontext.pc;
+			sp = uctx->uc_mcontext.sp;
+			fp = uctx->uc_mcontext.regs[29];
+		#else // mips, maybe others
 			addr = uctx->uc_mcontext.pc;
 		#endif
 	#else
 		#error unknown platform
 	#endif
-	tinygo_handle_fatal_signal(sig, addr);
+	tinygo_handle_fatal_signal(sig, addr, sp, fp);
 }
 
 void tinygo_register_fatal_signals(void) {
@@ -28,6 +28,11 @@ func pause() int32
 //export mmap
 func mmap(addr unsafe.Pointer, length uintptr, prot, flags, fd int, offset int64) unsafe.Pointer
 
+// int madvise(void *addr, size_t length, int advice);
+//
+//export madvise
+func madvise(addr unsafe.Pointer, length uintptr, advice int) int
+
 //export abort
 func abort()
 
@@ -145,7 +150,7 @@ func tinygo_register_fatal_signals()
 // information.
 //
 //export tinygo_handle_fatal_signal
-func tinygo_handle_fatal_signal(sig int32, addr uintptr) {
+func tinygo_handle_fatal_signal(sig int32, addr, sp, fp uintptr) {
 	if panicStrategy() == tinygo.PanicStrategyTrap {
 		trap()
 	}
@@ -172,6 +177,8 @@ func tinygo_handle_fatal_signal(sig int32, addr uintptr) {
 	// TODO: it might be interesting to also print the invalid address for
 	// SIGSEGV and SIGBUS.
 
+	printSignalTraceback(addr, sp, fp)
+
 	// Do *not* abort here, instead raise the same signal again. The signal is
 	// registered with SA_RESETHAND which means it executes only once. So when
 	// we raise the signal again below, the signal isn't handled specially but
@@ -180,6 +187,29 @@ func tinygo_handle_fatal_signal(sig int32, addr uintptr) {
 	raise(sig)
 }
 
+// printSignalTraceback prints the stack trace starting at the given program
+// counter, stack pointer and frame pointer, after a fatal signal. The frame
+// pointer is zero if it is not known.
+func printSignalTraceback(pc, sp, fp uintptr) {
+	level := tracebackLevel()
+	if level == tracebackNone {
+		return
+	}
+	printnl()
+	w := stackWriter{
+		print:  true,
+		system: level >= tracebackSystem,
+	}
+	w.header()
+	var pcs [64]uintptr
+	count := 1
+	pcs[0] = pc + 1 // like callersFrom, so that callerPC returns pc
+	if fp != 0 {
+		count = callersFrom(pc, sp, fp, pcs[:])
+	}
+	w.frames(pcs[:count])
+}
+
 //go:extern environ
 var environ *unsafe.Pointer
 
@@ -341,6 +371,24 @@ func growHeap() bool {
 	return true
 }
 
+// shrinkHeap tries to shrink the heap to the given end address, and returns
+// the memory after it to the OS. It returns true if it succeeds.
+func shrinkHeap(newHeapEnd uintptr) bool {
+	newHeapSize := (newHeapEnd - heapStart + 4095) &^ 4095
+	if newHeapSize >= heapSize {
+		return false
+	}
+	oldHeapEnd := heapStart + heapSize
+	heapSize = newHeapSize
+	setHeapEnd(heapStart + heapSize)
+	// The memory stays mapped, so that the heap can grow again, but the OS may
+	// reuse the physical pages. They are zero when used again on Linux, but
+	// not necessarily on macOS: setHeapEnd clears new metadata, and alloc
+	// clears all new objects.
+	madvise(unsafe.Pointer(heapStart+heapSize), oldHeapEnd-(heapStart+heapSize), flag_MADV_DONTNEED)
+	return true
+}
+
 // Indicate whether signals have been registered.
 var hasSignals bool
 
@@ -442,6 +490,14 @@ func tinygo_signal_handler(s int32) {
 	}
 }
 
+// void tinygo_signal_profile(uintptr_t pc, uintptr_t sp, uintptr_t fp);
+//
+//export tinygo_signal_profile
+func tinygo_signal_profile(pc, sp, fp uintptr) {
+	// SIGPROF arrived, which is only enabled while the CPU profiler is running.
+	cpuProfileSignal(pc, sp, fp)
+}
+
 // Task waiting for a signal to arrive, or nil if it is running or there are no
 // signals.
 var signalRecvWaiter atomic.Pointer[task.Task]
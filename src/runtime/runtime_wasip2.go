@@ -17,6 +17,7 @@ func init() {
 		callMain()
 		return false
 	}
+	readGCSettings()
 }
 
 var args []string
@@ -50,3 +51,19 @@ func sleepTicks(d timeUnit) {
 func ticks() timeUnit {
 	return timeUnit(monotonicclock.Now())
 }
+
+// gotracebackEnv returns the value of the GOTRACEBACK environment variable, if
+// it is set.
+func gotracebackEnv() (string, bool) {
+	return lookupEnv("GOTRACEBACK")
+}
+
+// lookupEnv returns the value of an environment variable, if it is set.
+func lookupEnv(key string) (string, bool) {
+	for _, kv := range environment.GetEnvironment().Slice() {
+		if kv[0] == key {
+			return kv[1], true
+		}
+	}
+	return "", false
+}
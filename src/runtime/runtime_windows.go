@@ -246,6 +246,12 @@ func growHeap() bool {
 	return true
 }
 
+// shrinkHeap tries to shrink the heap to the given end address. Returning
+// memory to the OS is not implemented on Windows.
+func shrinkHeap(newHeapEnd uintptr) bool {
+	return false
+}
+
 //go:linkname syscall_loadsystemlibrary syscall.loadsystemlibrary
 func syscall_loadsystemlibrary(filename *uint16, absoluteFilepath *uint16) (handle, err uintptr) {
 	handle = _LoadLibraryExW(filename, 0, _LOAD_LIBRARY_SEARCH_SYSTEM32)
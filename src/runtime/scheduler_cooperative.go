@@ -50,17 +50,20 @@ var (
 //go:noinline
 func deadlock() {
 	// call yield without requesting a wakeup
+	traceGoBlock(traceBlockForever)
 	task.Pause()
 	panic("unreachable")
 }
 
 // Add this task to the end of the run queue.
 func scheduleTask(t *task.Task) {
+	traceGoUnblock(t)
 	runqueue.Push(t)
 }
 
 func Gosched() {
 	runqueue.Push(task.Current())
+	traceGoBlock(traceBlockYield)
 	task.Pause()
 }
 
@@ -153,6 +156,7 @@ func scheduler(returnAtDeadlock bool) {
 			sleepQueueBaseTime += timeUnit(t.Data)
 			sleepQueue = t.Next
 			t.Next = nil
+			traceGoUnblock(t)
 			runqueue.Push(t)
 		}
 
@@ -217,7 +221,9 @@ func scheduler(returnAtDeadlock bool) {
 
 		// Run the given task.
 		scheduleLogTask("  run:", t)
+		traceGoStart(t)
 		t.Resume()
+		traceGoStop(t)
 
 		// The last call to Resume() was a signal to stop the scheduler since a
 		// //go:wasmexport function returned.
@@ -237,6 +243,7 @@ func sleep(duration int64) {
 	}
 
 	addSleepTask(task.Current(), nanosecondsToTicks(duration))
+	traceGoBlock(traceBlockSleep)
 	task.Pause()
 }
 
@@ -3,14 +3,23 @@
 // Ignore the //go:build above. This file is manually included on Linux and
 // MacOS to provide os/signal support.
 
+#define _GNU_SOURCE // for REG_RIP etc
+#include <errno.h>
 #include <stdint.h>
 #include <signal.h>
+#include <sys/time.h>
 #include <time.h>
 #include <unistd.h>
+#if defined(__linux__)
+#include <ucontext.h>
+#endif
 
 // Signal handler in the runtime.
 void tinygo_signal_handler(int sig);
 
+// SIGPROF handler in the runtime, for the CPU profiler.
+void tinygo_signal_profile(uintptr_t pc, uintptr_t sp, uintptr_t fp);
+
 // Enable a signal from the runtime.
 void tinygo_signal_enable(uint32_t sig) {
     struct sigaction act = { 0 };
@@ -30,3 +39,59 @@ void tinygo_signal_disable(uint32_t sig) {
     act.sa_handler = SIG_DFL;
     sigaction(sig, &act, NULL);
 }
+
+#if defined(__linux__) && (defined(__x86_64__) || defined(__i386__) || defined(__aarch64__) || defined(__arm__))
+static void profile_handler(int sig, siginfo_t *info, void *context) {
+    // Pass the program counter, stack pointer and frame pointer of the
+    // interrupted code to the runtime, so it can walk the stack.
+    mcontext_t *mc = &((ucontext_t *)context)->uc_mcontext;
+#if defined(__x86_64__)
+    tinygo_signal_profile(mc->gregs[REG_RIP], mc->gregs[REG_RSP], mc->gregs[REG_RBP]);
+#elif defined(__i386__)
+    tinygo_signal_profile(mc->gregs[REG_EIP], mc->gregs[REG_ESP], mc->gregs[REG_EBP]);
+#elif defined(__aarch64__)
+    tinygo_signal_profile(mc->pc, mc->sp, mc->regs[29]);
+#elif defined(__thumb__)
+    tinygo_signal_profile(mc->arm_pc, mc->arm_sp, mc->arm_r7);
+#else
+    tinygo_signal_profile(mc->arm_pc, mc->arm_sp, mc->arm_fp);
+#endif
+}
+
+// Start sending SIGPROF at the given rate (in Hz), measured in consumed CPU
+// time. Returns 0 on success, or the error number on failure.
+int32_t tinygo_profile_start(uint32_t hz) {
+    struct sigaction act = { 0 };
+    act.sa_sigaction = &profile_handler;
+    act.sa_flags = SA_SIGINFO | SA_RESTART;
+    if (sigaction(SIGPROF, &act, NULL) != 0) {
+        return errno;
+    }
+
+    // tv_usec must be below one second, so a rate of 1Hz is a whole second.
+    struct itimerval timer = { 0 };
+    timer.it_interval.tv_sec = 1 / hz;
+    timer.it_interval.tv_usec = (1000000 / hz) % 1000000;
+    timer.it_value = timer.it_interval;
+    if (setitimer(ITIMER_PROF, &timer, NULL) != 0) {
+        return errno;
+    }
+    return 0;
+}
+
+// Stop sending SIGPROF. The signal handler stays installed, as the default
+// action for SIGPROF is to terminate the process and a signal might still be
+// pending.
+void tinygo_profile_stop(void) {
+    struct itimerval timer = { 0 };
+    setitimer(ITIMER_PROF, &timer, NULL);
+}
+#else
+int32_t tinygo_profile_start(uint32_t hz) {
+    // CPU profiling is not supported on this system.
+    return ENOSYS;
+}
+
+void tinygo_profile_stop(void) {
+}
+#endif
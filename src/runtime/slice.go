@@ -19,6 +19,7 @@ func sliceAppend(srcBuf, elemsBuf unsafe.Pointer, srcLen, srcCap, elemsLen, elem
 
 		// Append the new elements in-place.
 		memmove(unsafe.Add(srcBuf, srcLen*elemSize), elemsBuf, elemsLen*elemSize)
+		gcWriteBarrierRange(unsafe.Add(srcBuf, srcLen*elemSize), elemsLen*elemSize)
 	}
 
 	return srcBuf, newLen, srcCap
@@ -32,6 +33,7 @@ func sliceCopy(dst, src unsafe.Pointer, dstLen, srcLen uintptr, elemSize uintptr
 		n = dstLen
 	}
 	memmove(dst, src, n*elemSize)
+	gcWriteBarrierRange(dst, n*elemSize)
 	return int(n)
 }
 
@@ -1,24 +1,87 @@
 package runtime
 
+import "internal/task"
+
+// A Func represents a Go function in the running binary.
 type Func struct {
+	name  string
+	entry uintptr
 }
 
+// FuncForPC returns a *Func describing the function that contains the given
+// program counter address, or else nil.
+//
+// If pc represents multiple functions because of inlining, it returns the
+// *Func describing the innermost function, but with an entry of the outermost
+// function.
 func FuncForPC(pc uintptr) *Func {
-	return nil
+	var iter symtabIterator
+	if !iter.init(pc) {
+		return nil
+	}
+	var frame Frame
+	iter.next(&frame)
+	return &Func{name: frame.Function, entry: iter.entry()}
 }
 
+// Name returns the name of the function.
 func (f *Func) Name() string {
-	return ""
+	if f == nil {
+		return ""
+	}
+	return f.name
 }
 
-func (f *Func) FileLine(pc uintptr) (file string, line int) {
-	return "", 0
+// Entry returns the entry address of the function.
+func (f *Func) Entry() uintptr {
+	if f == nil {
+		return 0
+	}
+	return f.entry
 }
 
-func Caller(skip int) (pc uintptr, file string, line int, ok bool) {
-	return 0, "", 0, false
+// FileLine returns the file name and line number of the source code
+// corresponding to the program counter pc. The result will not be accurate if
+// pc is not a program counter within f.
+func (f *Func) FileLine(pc uintptr) (file string, line int) {
+	var iter symtabIterator
+	if !iter.init(pc) {
+		return "?", 0
+	}
+	var frame Frame
+	iter.next(&frame)
+	return frame.File, frame.Line
 }
 
+// Stack formats a stack trace of the calling goroutine into buf and returns
+// the number of bytes written to buf. If all is true, Stack formats stack
+// traces of all other goroutines into buf after the trace for the current
+// goroutine.
+//
+// TinyGo can only unwind the stack of the running goroutine, so only the
+// current goroutine is included even if all is true. Function names and source
+// locations need the symbol table in the binary (-symtab=full), without it only
+// the goroutine header is printed. Arguments are not known and are always
+// printed as "(...)".
+//
+//go:noinline
 func Stack(buf []byte, all bool) int {
-	return 0
+	w := stackWriter{
+		buf:    buf,
+		system: tracebackLevel() >= tracebackSystem,
+	}
+	w.traceback()
+	return w.n
+}
+
+// Set the maximum stack size of new goroutines and return the previous limit.
+//
+//go:linkname debug_setMaxStack runtime/debug.setMaxStack
+func debug_setMaxStack(n int) int {
+	prev := task.MaxStackSize
+	task.MaxStackSize = uint64(n)
+	if maxInt := uint64(^uint(0) >> 1); prev > maxInt {
+		return int(maxInt)
+	}
+	return int(prev)
 }
@@ -1,26 +1,148 @@
 package runtime
 
+// Frames may be used to get function/file/line information for a slice of PC
+// values returned by Callers.
 type Frames struct {
-	//
+	callers []uintptr
+	iter    symtabIterator
+	inFrame bool // iter contains frames that haven't been returned yet
 }
 
+// Frame is the information returned by Frames for each call frame.
 type Frame struct {
+	// PC is the program counter for the location in this frame.
 	PC uintptr
 
+	// Func is the Func value of this call frame. It is always nil in TinyGo.
 	Func *Func
 
+	// Function is the package path-qualified function name of this call
+	// frame.
 	Function string
 
+	// File and Line are the file name and line number of the location in
+	// this frame.
 	File string
 	Line int
 
+	// Entry point program counter for the function; may be zero if not known.
 	Entry uintptr
 }
 
+// CallersFrames takes a slice of PCs returned by Callers and prepares to
+// return function/file/line information. Do not change the slice until you
+// are done with the Frames.
 func CallersFrames(callers []uintptr) *Frames {
-	return nil
+	return &Frames{callers: callers}
 }
 
+// Next returns a Frame representing the next call frame in the slice of PC
+// values, and reports whether there are more call frames. Inlined functions
+// are returned as separate frames.
 func (ci *Frames) Next() (frame Frame, more bool) {
-	return Frame{}, false
+	for {
+		if ci.inFrame {
+			if ci.iter.next(&frame) {
+				return frame, ci.iter.more() || len(ci.callers) != 0
+			}
+			ci.inFrame = false
+		}
+		if len(ci.callers) == 0 {
+			return Frame{}, false
+		}
+		pc := callerPC(ci.callers[0])
+		ci.callers = ci.callers[1:]
+		// Skip PCs that are not in the symbol table, like the gc toolchain
+		// does for non-Go code.
+		ci.inFrame = ci.iter.init(pc)
+	}
+}
+
+// Callers fills the slice pc with the return program counters of function
+// invocations on the calling goroutine's stack. The argument skip is the
+// number of stack frames to skip before recording in pc, with 0 identifying
+// the frame for Callers itself and 1 identifying the caller of Callers. It
+// returns the number of entries written to pc.
+//
+// When skip ends in the middle of a group of inlined frames, the PC of the
+// entire group is returned so CallersFrames will also return the skipped
+// inlined frames.
+//
+// Callers needs the symbol table in the binary (-symtab=full), which is not
+// included by default on baremetal targets. Without it, Callers returns 0.
+//
+//go:noinline
+func Callers(skip int, pc []uintptr) int {
+	var buf [16]uintptr
+	n := 0
+	frame := 0
+	for n < len(pc) {
+		count := callers(frame, buf[:])
+		for _, ra := range buf[:count] {
+			if skip > 0 {
+				frames := symtabFrameCount(callerPC(ra))
+				if skip >= frames {
+					skip -= frames
+					continue
+				}
+				skip = 0
+			}
+			pc[n] = ra
+			n++
+			if n == len(pc) {
+				break
+			}
+		}
+		if count < len(buf) {
+			break
+		}
+		frame += count
+	}
+	return n
+}
+
+// Caller reports file and line number information about function invocations
+// on the calling goroutine's stack. The argument skip is the number of stack
+// frames to ascend, with 0 identifying the caller of Caller.
+//
+//go:noinline
+func Caller(skip int) (pc uintptr, file string, line int, ok bool) {
+	skip++ // skip Caller itself
+	var buf [16]uintptr
+	frame := 0
+	for {
+		count := callers(frame, buf[:])
+		for _, ra := range buf[:count] {
+			var iter symtabIterator
+			if !iter.init(callerPC(ra)) {
+				continue
+			}
+			var f Frame
+			for iter.next(&f) {
+				if skip == 0 {
+					return f.PC, f.File, f.Line, true
+				}
+				skip--
+			}
+		}
+		if count < len(buf) {
+			return 0, "", 0, false
+		}
+		frame += count
+	}
+}
+
+// symtabFrameCount returns the number of logical frames (including inlined
+// functions) at the given PC. Unknown PCs count as a single frame.
+func symtabFrameCount(pc uintptr) int {
+	var iter symtabIterator
+	if !iter.init(pc) {
+		return 1
+	}
+	count := 0
+	var frame Frame
+	for iter.next(&frame) {
+		count++
+	}
+	return count
 }
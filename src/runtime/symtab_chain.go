@@ -0,0 +1,130 @@
+//go:build symtab.full && tinygo.wasm
+
+package runtime
+
+// WebAssembly doesn't allow walking the stack. Instead, the compiler maintains
+// a chain of call frames: every function that calls other functions pushes a
+// callFrame on entry and pops it again before returning. Before each call, it
+// stores a pointer to a constant callSite in its frame. These call site
+// pointers are used as "program counters" on WebAssembly.
+//
+// Functions in the runtime don't maintain a call frame, so they are invisible
+// in stack traces.
+
+import "unsafe"
+
+const hasSymtab = true
+
+// callSiteMagic is stored in every call site, to be able to check whether a
+// given "program counter" really is a call site. It must match the constant of
+// the same name in the compiler.
+const callSiteMagic = 0x74676373
+
+// A call frame, allocated on the stack by the compiler.
+type callFrame struct {
+	parent *callFrame
+	site   *callSite
+}
+
+// A call site, emitted as a constant global by the compiler.
+type callSite struct {
+	function string
+	file     string
+	line     uint32
+	magic    uint32
+}
+
+// callChain is the most recently pushed call frame of the current goroutine.
+// It is updated by the compiler in every function prologue and epilogue.
+var callChain *callFrame
+
+// callersSite is the call site returned for the runtime function that called
+// callers (usually runtime.Callers), since runtime functions are not part of
+// the call chain.
+var callersSite = callSite{
+	function: "runtime.Callers",
+	magic:    callSiteMagic,
+}
+
+// swapCallChain swaps the call chain, on a goroutine switch.
+func swapCallChain(dst *unsafe.Pointer) {
+	*dst, callChain = unsafe.Pointer(callChain), (*callFrame)(*dst)
+}
+
+// callers stores the call sites of the calling goroutine in pcs, with skip 0
+// identifying the function that called callers. It returns the number of
+// entries written to pcs.
+func callers(skip int, pcs []uintptr) int {
+	n := 0
+	if skip > 0 {
+		skip--
+	} else if len(pcs) != 0 {
+		pcs[0] = uintptr(unsafe.Pointer(&callersSite))
+		n++
+	}
+	for frame := callChain; frame != nil && n < len(pcs); frame = frame.parent {
+		if frame.site == nil {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		pcs[n] = uintptr(unsafe.Pointer(frame.site))
+		n++
+	}
+	return n
+}
+
+// callerPC converts a "return address" as returned by callers to the PC of the
+// call. Call sites already describe the call itself.
+func callerPC(pc uintptr) uintptr {
+	return pc
+}
+
+// symtabIterator iterates over the logical frames at a given call site. There
+// is exactly one, since inlined functions maintain their own call frame.
+type symtabIterator struct {
+	site *callSite
+}
+
+// init looks up the given call site and reports whether it is valid.
+func (iter *symtabIterator) init(pc uintptr) bool {
+	// Call sites are constant globals, so they must be stored between
+	// globalsStart and globalsEnd.
+	if pc%4 != 0 || pc < globalsStart || pc+unsafe.Sizeof(callSite{}) > globalsEnd {
+		return false
+	}
+	site := (*callSite)(unsafe.Pointer(pc))
+	if site.magic != callSiteMagic {
+		return false
+	}
+	iter.site = site
+	return true
+}
+
+// next stores the frame of the call site in frame, or returns false if it was
+// already returned.
+func (iter *symtabIterator) next(frame *Frame) bool {
+	if iter.site == nil {
+		return false
+	}
+	*frame = Frame{
+		PC:       uintptr(unsafe.Pointer(iter.site)),
+		Function: iter.site.function,
+		File:     iter.site.file,
+		Line:     int(iter.site.line),
+	}
+	iter.site = nil
+	return true
+}
+
+// more returns whether there are frames left.
+func (iter *symtabIterator) more() bool {
+	return iter.site != nil
+}
+
+// entry returns the entry address of the function, which is not known.
+func (iter *symtabIterator) entry() uintptr {
+	return 0
+}
@@ -0,0 +1,14 @@
+//go:build symtab.full && arm && !tinygo.riscv && !avr && !xtensa && !tinygo.wasm
+
+package runtime
+
+import "unsafe"
+
+// On ARM, the frame pointer (r7 in Thumb mode, r11 in ARM mode) points to the
+// saved frame pointer of the caller, with the return address right above it.
+// Return addresses have the lowest bit set in Thumb mode.
+const (
+	framePointerOffset = 0
+	frameReturnOffset  = int(unsafe.Sizeof(uintptr(0)))
+	returnAddressFlags = 1
+)
@@ -0,0 +1,13 @@
+//go:build symtab.full && !arm && !tinygo.riscv && !riscv64 && !tinygo.wasm
+
+package runtime
+
+import "unsafe"
+
+// On x86 and AArch64, the frame pointer points to the saved frame pointer of
+// the caller, with the return address right above it.
+const (
+	framePointerOffset = 0
+	frameReturnOffset  = int(unsafe.Sizeof(uintptr(0)))
+	returnAddressFlags = 0
+)
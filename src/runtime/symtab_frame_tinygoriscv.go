@@ -0,0 +1,13 @@
+//go:build symtab.full && (tinygo.riscv || riscv64)
+
+package runtime
+
+import "unsafe"
+
+// On RISC-V, the frame pointer points to the top of the frame, with the return
+// address and the frame pointer of the caller stored right below it.
+const (
+	framePointerOffset = -2 * int(unsafe.Sizeof(uintptr(0)))
+	frameReturnOffset  = -1 * int(unsafe.Sizeof(uintptr(0)))
+	returnAddressFlags = 0
+)
@@ -0,0 +1,52 @@
+//go:build !symtab.full
+
+package runtime
+
+// No symbol table is included in the binary (-symtab=none), so the stack can't
+// be walked and PCs can't be symbolized.
+
+const hasSymtab = false
+
+// callers stores the return addresses of the calling goroutine in pcs, with
+// skip 0 identifying the function that called callers. It returns the number
+// of entries written to pcs.
+func callers(skip int, pcs []uintptr) int {
+	return 0
+}
+
+// callersFrom is like callers, but starts at the given program counter, stack
+// pointer and frame pointer instead of at the current frame.
+func callersFrom(pc, sp, fp uintptr, pcs []uintptr) int {
+	return 0
+}
+
+// callerPC converts a return address (as returned by callers) to the PC of the
+// call instruction.
+func callerPC(pc uintptr) uintptr {
+	return pc
+}
+
+// symtabIterator iterates over the logical frames (including inlined
+// functions) at a given PC, innermost first.
+type symtabIterator struct{}
+
+// init looks up the given PC and reports whether it was found.
+func (iter *symtabIterator) init(pc uintptr) bool {
+	return false
+}
+
+// next stores the next logical frame in frame, or returns false if there are
+// no frames left.
+func (iter *symtabIterator) next(frame *Frame) bool {
+	return false
+}
+
+// more returns whether there are frames left.
+func (iter *symtabIterator) more() bool {
+	return false
+}
+
+// entry returns the entry address of the (outermost) function.
+func (iter *symtabIterator) entry() uintptr {
+	return 0
+}
@@ -0,0 +1,17 @@
+//go:build symtab.full && !tinygo.wasm && !scheduler.threads
+
+package runtime
+
+// callersStackTop returns the highest address of the stack that contains the
+// given frame pointer, as far as it is known. Frame pointers at or above this
+// address are not followed.
+func callersStackTop(fp uintptr) uintptr {
+	if fp < stackTop {
+		// Running on the system stack (or a goroutine stack allocated below
+		// it).
+		return stackTop
+	}
+	// Running on a goroutine stack. The frame pointer chain ends with a zero
+	// frame pointer at the start of the goroutine.
+	return ^uintptr(0)
+}
@@ -0,0 +1,11 @@
+//go:build symtab.full && !tinygo.wasm && scheduler.threads
+
+package runtime
+
+import "internal/task"
+
+// callersStackTop returns the highest address of the stack of the current
+// thread. Frame pointers at or above this address are not followed.
+func callersStackTop(fp uintptr) uintptr {
+	return task.StackTop()
+}
@@ -0,0 +1,271 @@
+//go:build symtab.full && !tinygo.wasm
+
+package runtime
+
+// The stack is walked using frame pointers (which the compiler keeps when the
+// symbol table is enabled), and return addresses are looked up in a symbol
+// table that is created by the builder from DWARF debug information after
+// linking. See builder/symtab.go for how this table is created.
+
+import "unsafe"
+
+const hasSymtab = true
+
+// symbolTable is the header of the symbol table. All code addresses in the
+// table are stored as 32-bit offsets from base, all strings as 32-bit offsets
+// into a table of NUL-terminated strings.
+type symbolTable struct {
+	base    uintptr
+	funcs   *symtabFunc // sorted by entry
+	nfuncs  uintptr
+	lines   *symtabLine
+	nlines  uintptr
+	inlines *symtabInline
+	strings *byte
+}
+
+// A single function (or a single address range of it).
+type symtabFunc struct {
+	entry    uint32
+	end      uint32
+	name     uint32
+	lines    uint32 // index of the first line, they continue until the next function
+	inlines  uint32 // index of the first inline
+	ninlines uint32
+}
+
+// A row in the line table: code starting at pc belongs to the given file and
+// line, until the next row.
+type symtabLine struct {
+	pc   uint32
+	file uint32
+	line uint32
+}
+
+// An address range where a function is inlined into another function. They
+// are stored in preorder so the innermost inline for a given address is the
+// last one that contains it.
+type symtabInline struct {
+	start    uint32
+	end      uint32
+	parent   int32 // index relative to the first inline of the function, or -1
+	name     uint32
+	callFile uint32
+	callLine uint32
+}
+
+//go:extern tinygo_symtab
+var symtab symbolTable
+
+//export llvm.frameaddress.p0
+func frameAddress(level uint32) unsafe.Pointer
+
+// callers stores the return addresses of the calling goroutine in pcs, with
+// skip 0 identifying the function that called callers. It returns the number
+// of entries written to pcs.
+//
+//go:noinline
+func callers(skip int, pcs []uintptr) int {
+	fp := uintptr(frameAddress(0))
+	limit := callersStackTop(fp)
+	n := 0
+	for n < len(pcs) {
+		if fp == 0 || fp%unsafe.Alignof(fp) != 0 || fp >= limit {
+			break
+		}
+		ra := *(*uintptr)(unsafe.Add(unsafe.Pointer(fp), frameReturnOffset))
+		next := *(*uintptr)(unsafe.Add(unsafe.Pointer(fp), framePointerOffset))
+		if ra == 0 {
+			break
+		}
+		if skip > 0 {
+			skip--
+		} else {
+			pcs[n] = ra &^ returnAddressFlags
+			n++
+		}
+		if next <= fp {
+			// The stack grows down, so the frame of a caller must be at a
+			// higher address.
+			break
+		}
+		fp = next
+	}
+	return n
+}
+
+// callersFrom is like callers, but starts at the given program counter, stack
+// pointer and frame pointer (for example, from a signal context) instead of at
+// the current frame. The first entry stored in pcs is pc+1 so that callerPC
+// returns pc itself. Only the frame pointers of functions in the symbol table
+// are followed, as other code might use the frame pointer register for other
+// purposes. It returns the number of entries written to pcs.
+func callersFrom(pc, sp, fp uintptr, pcs []uintptr) int {
+	if len(pcs) == 0 {
+		return 0
+	}
+	pcs[0] = pc + 1
+	n := 1
+	var iter symtabIterator
+	if !iter.init(pc) {
+		return n
+	}
+	limit := callersStackTop(fp)
+	for n < len(pcs) {
+		if fp < sp || fp%unsafe.Alignof(fp) != 0 || fp >= limit {
+			break
+		}
+		ra := *(*uintptr)(unsafe.Add(unsafe.Pointer(fp), frameReturnOffset)) &^ returnAddressFlags
+		next := *(*uintptr)(unsafe.Add(unsafe.Pointer(fp), framePointerOffset))
+		if ra == 0 {
+			break
+		}
+		pcs[n] = ra
+		n++
+		if next <= fp || !iter.init(callerPC(ra)) {
+			break
+		}
+		sp = fp
+		fp = next
+	}
+	return n
+}
+
+// callerPC converts a return address (as returned by callers) to the PC of the
+// call instruction. Pointing into the call instruction is enough to find the
+// right line and inlined function.
+func callerPC(pc uintptr) uintptr {
+	return pc - 1
+}
+
+// symtabIterator iterates over the logical frames (including inlined
+// functions) at a given PC, innermost first.
+type symtabIterator struct {
+	pc     uintptr
+	fn     *symtabFunc
+	inline int32 // index of the next inline to return, or -1 for fn itself
+	file   uint32
+	line   uint32
+	done   bool
+}
+
+// init looks up the given PC and reports whether it was found.
+func (iter *symtabIterator) init(pc uintptr) bool {
+	*iter = symtabIterator{pc: pc, done: true}
+	if symtab.nfuncs == 0 || pc < symtab.base || uint64(pc-symtab.base) >= 1<<32 {
+		return false
+	}
+	offset := uint32(pc - symtab.base)
+
+	// Find the function with the highest entry that is <= offset.
+	low, high := uintptr(0), symtab.nfuncs
+	for low < high {
+		mid := low + (high-low)/2
+		if symtabFuncAt(mid).entry <= offset {
+			low = mid + 1
+		} else {
+			high = mid
+		}
+	}
+	if low == 0 {
+		return false
+	}
+	index := low - 1
+	fn := symtabFuncAt(index)
+	if offset >= fn.end {
+		return false
+	}
+	iter.fn = fn
+	iter.done = false
+
+	// Find the line table row that covers this PC.
+	lineEnd := symtab.nlines
+	if index+1 < symtab.nfuncs {
+		lineEnd = uintptr(symtabFuncAt(index + 1).lines)
+	}
+	for i := uintptr(fn.lines); i < lineEnd; i++ {
+		row := symtabLineAt(i)
+		if row.pc > offset {
+			break
+		}
+		iter.file = row.file
+		iter.line = row.line
+	}
+
+	// Find the innermost inlined function.
+	iter.inline = -1
+	for i := uint32(0); i < fn.ninlines; i++ {
+		inline := symtabInlineAt(uintptr(fn.inlines + i))
+		if inline.start <= offset && offset < inline.end {
+			iter.inline = int32(i)
+		}
+	}
+	return true
+}
+
+// next stores the next logical frame in frame, or returns false if there are
+// no frames left.
+func (iter *symtabIterator) next(frame *Frame) bool {
+	if iter.done {
+		return false
+	}
+	*frame = Frame{
+		PC:   iter.pc,
+		File: symtabString(iter.file),
+		Line: int(iter.line),
+	}
+	if iter.inline >= 0 {
+		// Inlined function. Continue with the function it was inlined into,
+		// at the location where it was called.
+		inline := symtabInlineAt(uintptr(iter.fn.inlines) + uintptr(iter.inline))
+		frame.Function = symtabString(inline.name)
+		iter.file = inline.callFile
+		iter.line = inline.callLine
+		iter.inline = inline.parent
+	} else {
+		frame.Function = symtabString(iter.fn.name)
+		frame.Entry = iter.entry()
+		iter.done = true
+	}
+	return true
+}
+
+// more returns whether there are frames left.
+func (iter *symtabIterator) more() bool {
+	return !iter.done
+}
+
+// entry returns the entry address of the (outermost) function.
+func (iter *symtabIterator) entry() uintptr {
+	if iter.fn == nil {
+		return 0
+	}
+	return symtab.base + uintptr(iter.fn.entry)
+}
+
+func symtabFuncAt(index uintptr) *symtabFunc {
+	return (*symtabFunc)(unsafe.Add(unsafe.Pointer(symtab.funcs), index*unsafe.Sizeof(symtabFunc{})))
+}
+
+func symtabLineAt(index uintptr) *symtabLine {
+	return (*symtabLine)(unsafe.Add(unsafe.Pointer(symtab.lines), index*unsafe.Sizeof(symtabLine{})))
+}
+
+func symtabInlineAt(index uintptr) *symtabInline {
+	return (*symtabInline)(unsafe.Add(unsafe.Pointer(symtab.inlines), index*unsafe.Sizeof(symtabInline{})))
+}
+
+// symtabString returns the NUL-terminated string at the given offset in the
+// string table, without copying it.
+func symtabString(offset uint32) string {
+	ptr := unsafe.Add(unsafe.Pointer(symtab.strings), offset)
+	length := uintptr(0)
+	for *(*byte)(unsafe.Add(ptr, length)) != 0 {
+		length++
+	}
+	s := _string{
+		ptr:    (*byte)(ptr),
+		length: length,
+	}
+	return *(*string)(unsafe.Pointer(&s))
+}
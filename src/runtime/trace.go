@@ -0,0 +1,121 @@
+package runtime
+
+// Execution tracer, used by the runtime/trace package. While tracing is
+// enabled, scheduler, channel and GC events are recorded in a fixed size ring
+// buffer. The runtime/trace package reads the buffer after tracing has been
+// stopped and converts it to a trace file.
+
+import (
+	"internal/task"
+	"unsafe"
+)
+
+// Kinds of trace events. These must be kept in sync with runtime/trace.
+const (
+	traceEvGoCreate  = 1 + iota // ptr: the new goroutine
+	traceEvGoStart              // ptr: the goroutine that is resumed by the scheduler
+	traceEvGoStop               // ptr: the goroutine that returned to the scheduler
+	traceEvGoEnd                // ptr: the goroutine that exited
+	traceEvGoBlock              // arg: the reason the current goroutine is about to block
+	traceEvGoUnblock            // ptr: the goroutine that is made runnable
+	traceEvChanSend             // ptr: the channel
+	traceEvChanRecv             // ptr: the channel
+	traceEvChanClose            // ptr: the channel
+	traceEvGCStart
+	traceEvGCDone
+	traceEvUser // ptr: annotation created by runtime/trace
+)
+
+// Reasons for a goroutine to block, stored in the arg field of
+// traceEvGoBlock. These must be kept in sync with runtime/trace.
+const (
+	traceBlockChanSend = 1 + iota
+	traceBlockChanRecv
+	traceBlockSelect
+	traceBlockSleep
+	traceBlockYield
+	traceBlockForever
+)
+
+//go:linkname trace_supported runtime/trace.supported
+func trace_supported() bool {
+	return hasTrace
+}
+
+//go:linkname trace_enabled runtime/trace.enabled
+func trace_enabled() bool {
+	return traceEnabled()
+}
+
+//go:linkname trace_start runtime/trace.start
+func trace_start() {
+	traceStart()
+}
+
+// Stop tracing and read (and discard) all the events in the trace buffer. The
+// fn callback is called for each event, from oldest to newest.
+//
+//go:linkname trace_stop runtime/trace.stop
+func trace_stop(fn func(time int64, kind uint8, g, ptr unsafe.Pointer, arg uint32)) {
+	traceStop(fn)
+}
+
+//go:linkname trace_userEvent runtime/trace.userEvent
+func trace_userEvent(data unsafe.Pointer) {
+	traceRecord(traceEvUser, data, 0)
+}
+
+// Called by internal/task when a new goroutine is started.
+func traceGoCreate(t *task.Task) {
+	traceRecord(traceEvGoCreate, unsafe.Pointer(t), 0)
+}
+
+// Called by internal/task when the current goroutine exits. The current
+// goroutine is only looked up while tracing, as that isn't free on all
+// systems.
+func traceGoEnd() {
+	if traceEnabled() {
+		traceRecord(traceEvGoEnd, unsafe.Pointer(task.Current()), 0)
+	}
+}
+
+func traceGoStart(t *task.Task) {
+	traceRecord(traceEvGoStart, unsafe.Pointer(t), 0)
+}
+
+func traceGoStop(t *task.Task) {
+	traceRecord(traceEvGoStop, unsafe.Pointer(t), 0)
+}
+
+func traceGoBlock(reason uint32) {
+	traceRecord(traceEvGoBlock, nil, reason)
+}
+
+func traceGoUnblock(t *task.Task) {
+	traceRecord(traceEvGoUnblock, unsafe.Pointer(t), 0)
+}
+
+func traceChan(kind uint8, ch *channel) {
+	traceRecord(kind, unsafe.Pointer(ch), 0)
+}
+
+// traceChanSelect records the channel operation that proceeded in a select
+// statement.
+func traceChanSelect(state *chanSelectState) {
+	if !hasTrace {
+		return
+	}
+	if state.value == nil {
+		traceChan(traceEvChanRecv, state.ch)
+	} else {
+		traceChan(traceEvChanSend, state.ch)
+	}
+}
+
+func traceGCStart() {
+	traceRecord(traceEvGCStart, nil, 0)
+}
+
+func traceGCDone() {
+	traceRecord(traceEvGCDone, nil, 0)
+}
@@ -0,0 +1,157 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Kinds of user annotations.
+const (
+	userTaskBegin = iota
+	userTaskEnd
+	userRegionBegin
+	userRegionEnd
+	userLog
+)
+
+// userAnnotation is a single user annotation, recorded in the runtime trace
+// buffer.
+type userAnnotation struct {
+	kind    uint8
+	id      uint64 // task ID, or region ID for regions
+	taskID  uint64 // parent task ID for tasks, or the task of a region or log message
+	name    string // task type, region type, or log category
+	message string // log message
+}
+
+func record(a *userAnnotation) {
+	userEvent(unsafe.Pointer(a))
+}
+
+type traceContextKey struct{}
+
+// NewTask creates a task instance with the type taskType and returns
+// it along with a Context that carries the task.
+// If the input context contains a task, the new task is its subtask.
+//
+// The taskType is used to classify task instances. Analysis tools
+// can show tasks of the same type next to each other.
+//
+// The returned Task's End method is used to mark the task's end.
+// The trace tool measures task latency as the time between task creation
+// and when the End method is called, and provides the latency
+// distribution per task type.
+// If the End method is called multiple times, only the first
+// call is used in the latency measurement.
+//
+//	ctx, task := trace.NewTask(ctx, "awesomeTask")
+//	trace.WithRegion(ctx, "preparation", prepWork)
+//	// preparation of the task
+//	go func() {  // continue processing the task in a separate goroutine.
+//	    defer task.End()
+//	    trace.WithRegion(ctx, "remainingWork", remainingWork)
+//	}()
+func NewTask(pctx context.Context, taskType string) (ctx context.Context, task *Task) {
+	pid := fromContext(pctx).id
+	id := newID()
+	if IsEnabled() {
+		record(&userAnnotation{kind: userTaskBegin, id: id, taskID: pid, name: taskType})
+	}
+	s := &Task{id: id, taskType: taskType}
+	return context.WithValue(pctx, traceContextKey{}, s), s
+}
+
+func fromContext(ctx context.Context) *Task {
+	if s, ok := ctx.Value(traceContextKey{}).(*Task); ok {
+		return s
+	}
+	return &bgTask
+}
+
+// Task is a data type for tracing a user-defined, logical operation.
+type Task struct {
+	id       uint64
+	taskType string
+}
+
+// End marks the end of the operation represented by the Task.
+func (t *Task) End() {
+	if IsEnabled() {
+		record(&userAnnotation{kind: userTaskEnd, id: t.id, name: t.taskType})
+	}
+}
+
+var lastID uint64
+
+func newID() uint64 {
+	return atomic.AddUint64(&lastID, 1)
+}
+
+var bgTask = Task{id: uint64(0)}
+
+// Log emits a one-off event with the given category and message.
+// Category can be empty and the API assumes there are only a handful of
+// unique categories in the system.
+func Log(ctx context.Context, category, message string) {
+	if IsEnabled() {
+		id := fromContext(ctx).id
+		record(&userAnnotation{kind: userLog, taskID: id, name: category, message: message})
+	}
+}
+
+// Logf is like Log, but the value is formatted using the specified format spec.
+func Logf(ctx context.Context, category, format string, args ...any) {
+	if IsEnabled() {
+		id := fromContext(ctx).id
+		record(&userAnnotation{kind: userLog, taskID: id, name: category, message: fmt.Sprintf(format, args...)})
+	}
+}
+
+// WithRegion starts a region associated with its calling goroutine, runs fn,
+// and then ends the region. If the context carries a task, the region is
+// associated with the task. Otherwise, the region is attached to the background
+// task.
+//
+// The regionType is used to classify regions, so there should be only a
+// handful of unique region types.
+func WithRegion(ctx context.Context, regionType string, fn func()) {
+	r := StartRegion(ctx, regionType)
+	defer r.End()
+	fn()
+}
+
+// StartRegion starts a region and returns it.
+// The returned Region's End method must be called
+// from the same goroutine where the region was started.
+// Within each goroutine, regions must nest. That is, regions started
+// after this region must be ended before this region can be ended.
+// Recommended usage is
+//
+//	defer trace.StartRegion(ctx, "myTracedRegion").End()
+func StartRegion(ctx context.Context, regionType string) *Region {
+	if !IsEnabled() {
+		return noopRegion
+	}
+	r := &Region{id: newID(), taskID: fromContext(ctx).id, regionType: regionType}
+	record(&userAnnotation{kind: userRegionBegin, id: r.id, taskID: r.taskID, name: regionType})
+	return r
+}
+
+// Region is a region of code whose execution time interval is traced.
+type Region struct {
+	id         uint64
+	taskID     uint64
+	regionType string
+}
+
+var noopRegion = &Region{}
+
+// End marks the end of the traced code region.
+func (r *Region) End() {
+	if r == noopRegion {
+		return
+	}
+	record(&userAnnotation{kind: userRegionEnd, id: r.id, taskID: r.taskID, name: r.regionType})
+}
@@ -1,42 +1,85 @@
-// Stubs for the runtime/trace package
+// Package trace contains facilities for programs to generate traces of their
+// execution.
+//
+// The tracer records goroutine creation, blocking, unblocking and execution,
+// channel operations, garbage collection cycles, and user annotations (tasks,
+// regions and log messages). The events are kept in a fixed size ring buffer
+// in the runtime, so only the most recent events are kept in long traces.
+//
+// Unlike the gc toolchain, the trace is written in the Chrome trace event
+// format (JSON) when tracing stops. It can be viewed in Perfetto
+// (https://ui.perfetto.dev) or in chrome://tracing, not with go tool trace.
+// Each goroutine is shown as a separate thread, with slices for the time it
+// was running, runnable, or blocked.
+//
+// Tracing is only supported with the cooperative schedulers (-scheduler=tasks
+// and -scheduler=asyncify).
 package trace
 
 import (
-	"context"
 	"errors"
 	"io"
+	"sync"
+	"unsafe"
 )
 
-func Start(w io.Writer) error {
-	return errors.New("not implemented")
-}
+var errUnsupported = errors.New("runtime/trace: tracing needs -scheduler=tasks or -scheduler=asyncify")
 
-func Stop() {}
+// Implemented in the runtime.
+func supported() bool
+func enabled() bool
+func start()
+func stop(fn func(time int64, kind uint8, g, ptr unsafe.Pointer, arg uint32))
+func userEvent(data unsafe.Pointer)
 
-func NewTask(pctx context.Context, taskType string) (ctx context.Context, task *Task) {
-	return context.TODO(), nil
+// The trace that is currently running.
+var tracing struct {
+	sync.Mutex
+	enabled bool
+	w       io.Writer
 }
 
-type Task struct{}
-
-func (t *Task) End() {}
-
-func Log(ctx context.Context, category, message string) {}
-
-func Logf(ctx context.Context, category, format string, args ...any) {}
-
-func WithRegion(ctx context.Context, regionType string, fn func()) {
-	fn()
-}
+// Start enables tracing for the current program.
+// While tracing, the trace will be buffered and written to w when Stop is
+// called.
+// Start returns an error if tracing is already enabled, or if tracing is not
+// supported with the scheduler in use.
+func Start(w io.Writer) error {
+	if !supported() {
+		return errUnsupported
+	}
 
-func StartRegion(ctx context.Context, regionType string) *Region {
+	tracing.Lock()
+	defer tracing.Unlock()
+	if tracing.enabled {
+		return errors.New("tracing is already enabled")
+	}
+	tracing.enabled = true
+	tracing.w = w
+	start()
 	return nil
 }
 
-type Region struct{}
+// Stop stops the current tracing, if any, and writes the trace to the writer
+// that was passed to Start.
+func Stop() {
+	tracing.Lock()
+	defer tracing.Unlock()
+	if !tracing.enabled {
+		return
+	}
+	tracing.enabled = false
 
-func (r *Region) End() {}
+	tw := newTraceWriter()
+	stop(tw.event)
+	tw.finish()
+	tracing.w.Write(tw.buf)
+	tracing.w = nil
+}
 
+// IsEnabled reports whether tracing is enabled.
+// The information is advisory only. The tracing status
+// may have changed by the time this function returns.
 func IsEnabled() bool {
-	return false
+	return enabled()
 }
@@ -0,0 +1,374 @@
+package trace
+
+// This file converts the events recorded by the runtime to the Chrome trace
+// event format, as described here:
+// https://docs.google.com/document/d/1CvAClvFfyA5R-PhYUmn5OOQtYMH4h6I0nSsKchNAySU
+
+import (
+	"strconv"
+	"unsafe"
+)
+
+// Kinds of runtime events. These must be kept in sync with the runtime.
+const (
+	evGoCreate = 1 + iota
+	evGoStart
+	evGoStop
+	evGoEnd
+	evGoBlock
+	evGoUnblock
+	evChanSend
+	evChanRecv
+	evChanClose
+	evGCStart
+	evGCDone
+	evUser
+)
+
+// Reasons for a goroutine to block, stored in the argument of evGoBlock.
+// These must be kept in sync with the runtime.
+const (
+	blockChanSend = 1 + iota
+	blockChanRecv
+	blockSelect
+	blockSleep
+	blockYield
+	blockForever
+)
+
+var blockReasons = [...]string{
+	blockChanSend: "chan send",
+	blockChanRecv: "chan receive",
+	blockSelect:   "select",
+	blockSleep:    "sleep",
+	blockYield:    "yield",
+	blockForever:  "forever",
+}
+
+// States of a goroutine in the trace.
+const (
+	gUnknown = iota // no scheduler events seen yet
+	gRunning
+	gRunnable
+	gBlocked
+	gExited
+)
+
+// Thread ID of events that do not happen in a goroutine, like GC cycles and
+// events recorded by the scheduler.
+const runtimeTID = 0
+
+type goroutine struct {
+	id     int
+	state  uint8
+	since  int64  // start time of the current state
+	reason uint32 // reason for the next (or current) blocked state
+}
+
+// A traceWriter converts runtime events to a trace, which is built in memory.
+type traceWriter struct {
+	buf        []byte
+	events     int   // number of trace events written to buf
+	base       int64 // time of the first event
+	now        int64 // time of the last event
+	goroutines map[unsafe.Pointer]*goroutine
+	list       []*goroutine // all goroutines, in order of their IDs
+	gcStart    int64
+	inGC       bool
+}
+
+func newTraceWriter() *traceWriter {
+	w := &traceWriter{
+		goroutines: make(map[unsafe.Pointer]*goroutine),
+		base:       -1,
+	}
+	w.buf = append(w.buf, `{"displayTimeUnit":"ns","traceEvents":[`...)
+	w.metadata("process_name", runtimeTID, "name", "TinyGo")
+	w.metadata("thread_name", runtimeTID, "name", "runtime")
+	return w
+}
+
+// event processes a single event recorded by the runtime.
+func (w *traceWriter) event(time int64, kind uint8, g, ptr unsafe.Pointer, arg uint32) {
+	if w.base < 0 {
+		w.base = time
+	}
+	w.now = time
+
+	// An event recorded by a goroutine means it is running right now.
+	cur := w.current(g)
+	tid := runtimeTID
+	if cur != nil {
+		tid = cur.id
+	}
+
+	switch kind {
+	case evGoCreate:
+		newg := w.newGoroutine(ptr)
+		newg.state = gRunnable
+		newg.since = time
+		w.begin("i", "go", tid)
+		w.ts(time)
+		w.buf = append(w.buf, `,"s":"t","args":{"goroutine":`...)
+		w.buf = strconv.AppendInt(w.buf, int64(newg.id), 10)
+		w.buf = append(w.buf, '}')
+		w.end()
+	case evGoStart:
+		w.setState(w.goroutine(ptr), gRunning, time, nil)
+	case evGoStop:
+		s := w.goroutine(ptr)
+		if s.state == gRunning {
+			if s.reason == blockYield {
+				// Gosched, so the goroutine is still runnable.
+				w.setState(s, gRunnable, time, nil)
+			} else {
+				w.setState(s, gBlocked, time, nil)
+			}
+		}
+	case evGoEnd:
+		w.setState(w.goroutine(ptr), gExited, time, nil)
+	case evGoBlock:
+		if cur != nil && int(arg) < len(blockReasons) {
+			cur.reason = arg
+		}
+	case evGoUnblock:
+		s := w.goroutine(ptr)
+		if s.state == gBlocked {
+			w.setState(s, gRunnable, time, cur)
+		}
+	case evChanSend, evChanRecv, evChanClose:
+		name := "chan send"
+		if kind == evChanRecv {
+			name = "chan receive"
+		} else if kind == evChanClose {
+			name = "chan close"
+		}
+		w.begin("i", name, tid)
+		w.ts(time)
+		w.buf = append(w.buf, `,"s":"t","args":{"chan":"0x`...)
+		w.buf = strconv.AppendUint(w.buf, uint64(uintptr(ptr)), 16)
+		w.buf = append(w.buf, `"}`...)
+		w.end()
+	case evGCStart:
+		w.gcStart = time
+		w.inGC = true
+	case evGCDone:
+		if !w.inGC {
+			// The start of this cycle was overwritten in the ring buffer.
+			w.gcStart = w.base
+		}
+		w.slice("GC", runtimeTID, w.gcStart, time)
+		w.inGC = false
+	case evUser:
+		w.user((*userAnnotation)(ptr), tid, time)
+	}
+}
+
+// current returns the goroutine that recorded an event, or nil if the event
+// was not recorded in a goroutine.
+func (w *traceWriter) current(g unsafe.Pointer) *goroutine {
+	if g == nil {
+		return nil
+	}
+	s := w.goroutine(g)
+	if s.state == gUnknown {
+		// The goroutine started running before the oldest event in the trace.
+		s.state = gRunning
+		s.since = w.base
+	}
+	return s
+}
+
+// goroutine returns the goroutine for the given task pointer.
+func (w *traceWriter) goroutine(ptr unsafe.Pointer) *goroutine {
+	if s := w.goroutines[ptr]; s != nil {
+		return s
+	}
+	return w.newGoroutine(ptr)
+}
+
+// newGoroutine adds a new goroutine to the trace. The task pointer might have
+// been used by a goroutine that exited before, so this always creates a new
+// goroutine ID.
+func (w *traceWriter) newGoroutine(ptr unsafe.Pointer) *goroutine {
+	s := &goroutine{id: len(w.list) + 1}
+	w.goroutines[ptr] = s
+	w.list = append(w.list, s)
+	w.metadata("thread_name", s.id, "name", "goroutine "+strconv.Itoa(s.id))
+	w.begin("M", "thread_sort_index", s.id)
+	w.buf = append(w.buf, `,"args":{"sort_index":`...)
+	w.buf = strconv.AppendInt(w.buf, int64(s.id), 10)
+	w.buf = append(w.buf, '}')
+	w.end()
+	return s
+}
+
+// setState ends the slice for the current state of the goroutine, and moves
+// it to the new state. The waker is the goroutine that unblocked it, if known.
+func (w *traceWriter) setState(s *goroutine, state uint8, time int64, waker *goroutine) {
+	w.endState(s, time, waker)
+	if state != gBlocked {
+		// The reason is kept while the goroutine is blocked.
+		s.reason = 0
+	}
+	s.state = state
+	s.since = time
+}
+
+// endState writes the slice for the current state of the goroutine.
+func (w *traceWriter) endState(s *goroutine, time int64, waker *goroutine) {
+	var name string
+	switch s.state {
+	case gRunning:
+		name = "running"
+	case gRunnable:
+		name = "runnable"
+	case gBlocked:
+		name = "blocked"
+		if s.reason != 0 {
+			name += ": " + blockReasons[s.reason]
+		}
+	default:
+		return
+	}
+	w.begin("X", name, s.id)
+	w.ts(s.since)
+	w.buf = append(w.buf, `,"dur":`...)
+	w.buf = appendMicros(w.buf, time-s.since)
+	if waker != nil {
+		w.buf = append(w.buf, `,"args":{"unblocked by":`...)
+		w.buf = appendString(w.buf, "goroutine "+strconv.Itoa(waker.id))
+		w.buf = append(w.buf, '}')
+	}
+	w.end()
+}
+
+// user writes a user annotation created by NewTask, StartRegion, or Log.
+func (w *traceWriter) user(a *userAnnotation, tid int, time int64) {
+	switch a.kind {
+	case userTaskBegin, userTaskEnd, userRegionBegin, userRegionEnd:
+		ph, cat := "b", "task"
+		if a.kind == userTaskEnd || a.kind == userRegionEnd {
+			ph = "e"
+		}
+		if a.kind == userRegionBegin || a.kind == userRegionEnd {
+			cat = "region"
+		}
+		w.begin(ph, a.name, tid)
+		w.ts(time)
+		w.buf = append(w.buf, `,"cat":"`...)
+		w.buf = append(w.buf, cat...)
+		w.buf = append(w.buf, `","id":`...)
+		w.buf = strconv.AppendUint(w.buf, a.id, 10)
+		if a.kind == userTaskBegin {
+			w.buf = append(w.buf, `,"args":{"parent":`...)
+			w.buf = strconv.AppendUint(w.buf, a.taskID, 10)
+			w.buf = append(w.buf, '}')
+		} else if a.kind == userRegionBegin {
+			w.buf = append(w.buf, `,"args":{"task":`...)
+			w.buf = strconv.AppendUint(w.buf, a.taskID, 10)
+			w.buf = append(w.buf, '}')
+		}
+		w.end()
+	case userLog:
+		name := a.name
+		if name == "" {
+			name = "log"
+		}
+		w.begin("i", name, tid)
+		w.ts(time)
+		w.buf = append(w.buf, `,"s":"t","args":{"message":`...)
+		w.buf = appendString(w.buf, a.message)
+		w.buf = append(w.buf, `,"task":`...)
+		w.buf = strconv.AppendUint(w.buf, a.taskID, 10)
+		w.buf = append(w.buf, '}')
+		w.end()
+	}
+}
+
+// finish ends all slices that are still open at the end of the trace, and
+// finishes the JSON document.
+func (w *traceWriter) finish() {
+	for _, s := range w.list {
+		w.endState(s, w.now, nil)
+	}
+	if w.inGC {
+		w.slice("GC", runtimeTID, w.gcStart, w.now)
+	}
+	w.buf = append(w.buf, "]}\n"...)
+}
+
+// slice writes a complete event with the given start and end time.
+func (w *traceWriter) slice(name string, tid int, start, end int64) {
+	w.begin("X", name, tid)
+	w.ts(start)
+	w.buf = append(w.buf, `,"dur":`...)
+	w.buf = appendMicros(w.buf, end-start)
+	w.end()
+}
+
+// metadata writes a metadata event with a single string argument.
+func (w *traceWriter) metadata(name string, tid int, key, value string) {
+	w.begin("M", name, tid)
+	w.buf = append(w.buf, `,"args":{`...)
+	w.buf = appendString(w.buf, key)
+	w.buf = append(w.buf, ':')
+	w.buf = appendString(w.buf, value)
+	w.buf = append(w.buf, '}')
+	w.end()
+}
+
+// begin starts a new event with the fields that all events have in common.
+// The event must be finished by calling end.
+func (w *traceWriter) begin(ph, name string, tid int) {
+	if w.events != 0 {
+		w.buf = append(w.buf, ',')
+	}
+	w.events++
+	w.buf = append(w.buf, "\n"+`{"ph":"`...)
+	w.buf = append(w.buf, ph...)
+	w.buf = append(w.buf, `","name":`...)
+	w.buf = appendString(w.buf, name)
+	w.buf = append(w.buf, `,"pid":1,"tid":`...)
+	w.buf = strconv.AppendInt(w.buf, int64(tid), 10)
+}
+
+// ts writes the timestamp of the event, relative to the start of the trace.
+func (w *traceWriter) ts(time int64) {
+	w.buf = append(w.buf, `,"ts":`...)
+	w.buf = appendMicros(w.buf, time-w.base)
+}
+
+func (w *traceWriter) end() {
+	w.buf = append(w.buf, '}')
+}
+
+// appendMicros appends the duration in nanoseconds as a number of
+// microseconds, which is the unit of time used in the trace format.
+func appendMicros(buf []byte, ns int64) []byte {
+	if ns < 0 {
+		buf = append(buf, '-')
+		ns = -ns
+	}
+	buf = strconv.AppendInt(buf, ns/1000, 10)
+	frac := ns % 1000
+	return append(buf, '.', byte('0'+frac/100), byte('0'+frac/10%10), byte('0'+frac%10))
+}
+
+// appendString appends s as a quoted JSON string.
+func appendString(buf []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	buf = append(buf, '"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			buf = append(buf, '\\', c)
+		case c < 0x20:
+			buf = append(buf, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return append(buf, '"')
+}
@@ -0,0 +1,93 @@
+//go:build scheduler.tasks || scheduler.asyncify
+
+package runtime
+
+// Ring buffer of trace events for the cooperative scheduler. Events may be
+// recorded from interrupts, so interrupts are disabled while an event is
+// written.
+
+import (
+	"internal/task"
+	"runtime/interrupt"
+	"unsafe"
+)
+
+const hasTrace = true
+
+// Number of events in the ring buffer: 128 on 16-bit systems, 1024 on 32-bit
+// systems and 8192 on 64-bit systems. Older events are overwritten when the
+// buffer is full.
+const traceBufferEvents = 16 * unsafe.Sizeof(uintptr(0)) * unsafe.Sizeof(uintptr(0)) * unsafe.Sizeof(uintptr(0))
+
+// A single event in the trace buffer.
+type traceEvent struct {
+	time int64          // time of the event as returned by nanotime
+	g    *task.Task     // goroutine that recorded the event, or nil in the scheduler
+	ptr  unsafe.Pointer // goroutine, channel, or user annotation (depending on the kind)
+	arg  uint32
+	kind uint8
+}
+
+var traceBuffer struct {
+	enabled bool
+	wrapped bool // whether older events have been overwritten
+	next    uintptr
+	events  []traceEvent
+}
+
+func traceEnabled() bool {
+	return traceBuffer.enabled
+}
+
+func traceStart() {
+	// Allocate the buffer before enabling tracing, as the allocation may
+	// trigger a GC cycle.
+	events := make([]traceEvent, traceBufferEvents)
+	mask := interrupt.Disable()
+	traceBuffer.events = events
+	traceBuffer.next = 0
+	traceBuffer.wrapped = false
+	traceBuffer.enabled = true
+	interrupt.Restore(mask)
+}
+
+func traceStop(fn func(time int64, kind uint8, g, ptr unsafe.Pointer, arg uint32)) {
+	mask := interrupt.Disable()
+	traceBuffer.enabled = false
+	events := traceBuffer.events
+	start := uintptr(0)
+	count := traceBuffer.next
+	if traceBuffer.wrapped {
+		start = traceBuffer.next
+		count = uintptr(len(events))
+	}
+	traceBuffer.events = nil
+	interrupt.Restore(mask)
+
+	for i := uintptr(0); i < count; i++ {
+		e := &events[(start+i)%uintptr(len(events))]
+		fn(e.time, e.kind, unsafe.Pointer(e.g), e.ptr, e.arg)
+	}
+}
+
+// traceRecord adds a single event to the trace buffer, if tracing is enabled.
+func traceRecord(kind uint8, ptr unsafe.Pointer, arg uint32) {
+	if !traceBuffer.enabled {
+		return
+	}
+	mask := interrupt.Disable()
+	if traceBuffer.enabled {
+		e := &traceBuffer.events[traceBuffer.next]
+		e.time = nanotime()
+		e.g = task.Current()
+		e.ptr = ptr
+		e.arg = arg
+		e.kind = kind
+		traceBuffer.next++
+		if traceBuffer.next == uintptr(len(traceBuffer.events)) {
+			traceBuffer.next = 0
+			traceBuffer.wrapped = true
+		}
+	}
+	interrupt.Restore(mask)
+}
@@ -0,0 +1,21 @@
+//go:build !scheduler.tasks && !scheduler.asyncify
+
+package runtime
+
+import "unsafe"
+
+// Execution tracing is only supported with the cooperative schedulers.
+const hasTrace = false
+
+func traceEnabled() bool {
+	return false
+}
+
+func traceStart() {
+}
+
+func traceStop(fn func(time int64, kind uint8, g, ptr unsafe.Pointer, arg uint32)) {
+}
+
+func traceRecord(kind uint8, ptr unsafe.Pointer, arg uint32) {
+}
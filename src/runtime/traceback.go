@@ -0,0 +1,229 @@
+package runtime
+
+import "internal/task"
+
+// Traceback levels, as set by the GOTRACEBACK environment variable or by
+// runtime/debug.SetTraceback.
+const (
+	tracebackNone   = iota // only print the panic message
+	tracebackSingle        // print the current goroutine (the default)
+	tracebackAll           // print all goroutines (the same as single in TinyGo)
+	tracebackSystem        // like all, but include runtime functions
+	tracebackCrash         // like system, but crash instead of exiting
+)
+
+// tracebackSetting is the level set by debug.SetTraceback, or -1 if it was
+// never called.
+var tracebackSetting int8 = -1
+
+// tracebackLevel returns the current traceback level. Like with the gc
+// toolchain, debug.SetTraceback cannot reduce the level below the one set in
+// the GOTRACEBACK environment variable.
+func tracebackLevel() uint8 {
+	level := uint8(tracebackSingle)
+	value, hasEnv := gotracebackEnv()
+	if hasEnv {
+		level = parseTraceback(value)
+	}
+	if tracebackSetting >= 0 && (!hasEnv || uint8(tracebackSetting) > level) {
+		level = uint8(tracebackSetting)
+	}
+	return level
+}
+
+// parseTraceback parses the value of GOTRACEBACK. Unknown values result in the
+// default level.
+func parseTraceback(value string) uint8 {
+	switch value {
+	case "none", "0":
+		return tracebackNone
+	case "all", "1":
+		return tracebackAll
+	case "system", "2":
+		return tracebackSystem
+	case "crash", "wer":
+		return tracebackCrash
+	default:
+		return tracebackSingle
+	}
+}
+
+//go:linkname debug_setTraceback runtime/debug.SetTraceback
+func debug_setTraceback(level string) {
+	tracebackSetting = int8(parseTraceback(level))
+}
+
+// printPanicTraceback prints the stack trace of the current goroutine after a
+// panic message, unless disabled with GOTRACEBACK=none. Nothing is printed if
+// no frame can be symbolized (for example, with -symtab=none), so that the
+// panic message isn't followed by an empty trace.
+//
+//go:noinline
+func printPanicTraceback() {
+	if !hasSymtab {
+		return
+	}
+	level := tracebackLevel()
+	if level == tracebackNone {
+		return
+	}
+	w := stackWriter{
+		print:       true,
+		system:      level >= tracebackSystem,
+		delayHeader: true,
+	}
+	w.traceback()
+}
+
+// goroutineID returns the ID of the current goroutine, or 0 when not running
+// in a goroutine (for example, in an interrupt).
+func goroutineID() uint64 {
+	t := task.Current()
+	if t == nil {
+		return 0
+	}
+	return t.ID()
+}
+
+// stackWriter writes a stack trace in the same format as the gc toolchain. It
+// either writes to a fixed size buffer (truncating the trace if it doesn't
+// fit), or prints it directly.
+type stackWriter struct {
+	buf    []byte
+	n      int
+	print  bool // print instead of writing to buf
+	system bool // include runtime functions
+
+	// Write the header (after an empty line) just before the first frame,
+	// and not at all if there are no frames.
+	delayHeader bool
+}
+
+// traceback writes the stack trace of the current goroutine, starting at the
+// caller of the function that calls traceback. It must be inlined, so that
+// there is exactly one frame to skip on all platforms.
+//
+//go:inline
+func (w *stackWriter) traceback() {
+	if !w.delayHeader {
+		w.header()
+	}
+	var pcs [16]uintptr
+	skip := 1
+	for {
+		count := callers(skip, pcs[:])
+		w.frames(pcs[:count])
+		if count < len(pcs) || w.full() {
+			break
+		}
+		skip += count
+	}
+}
+
+func (w *stackWriter) header() {
+	w.string("goroutine ")
+	w.uint(goroutineID())
+	w.string(" [running]:\n")
+}
+
+// frames writes all frames (including inlined functions) of the given return
+// addresses, as returned by callers.
+func (w *stackWriter) frames(pcs []uintptr) {
+	for _, ra := range pcs {
+		// Like CallersFrames, but without allocating.
+		var iter symtabIterator
+		if !iter.init(callerPC(ra)) {
+			continue
+		}
+		var frame Frame
+		for iter.next(&frame) {
+			if w.system || showFrame(frame.Function) {
+				w.frame(&frame)
+			}
+		}
+	}
+}
+
+// frame writes a single frame. The offset from the start of the function is
+// only known for frames that were not inlined.
+func (w *stackWriter) frame(frame *Frame) {
+	if w.delayHeader {
+		w.delayHeader = false
+		w.string("\n")
+		w.header()
+	}
+	w.string(frame.Function)
+	w.string("(...)\n\t")
+	w.string(frame.File)
+	w.string(":")
+	w.uint(uint64(frame.Line))
+	if frame.Entry != 0 {
+		w.string(" +")
+		w.hex(frame.PC - frame.Entry)
+	}
+	w.string("\n")
+}
+
+// showFrame returns whether a frame of the given function is shown when not
+// including runtime functions. Like with the gc toolchain, these are all
+// functions except for unexported functions in the runtime.
+func showFrame(name string) bool {
+	const prefix = "runtime."
+	if len(name) <= len(prefix) || name[:len(prefix)] != prefix {
+		return true
+	}
+	c := name[len(prefix)]
+	return 'A' <= c && c <= 'Z'
+}
+
+func (w *stackWriter) full() bool {
+	return !w.print && w.n == len(w.buf)
+}
+
+func (w *stackWriter) string(s string) {
+	if w.print {
+		printstring(s)
+		return
+	}
+	w.n += copy(w.buf[w.n:], s)
+}
+
+func (w *stackWriter) bytes(b []byte) {
+	if w.print {
+		for _, c := range b {
+			putchar(c)
+		}
+		return
+	}
+	w.n += copy(w.buf[w.n:], b)
+}
+
+func (w *stackWriter) uint(x uint64) {
+	var digits [20]byte
+	i := len(digits)
+	for {
+		i--
+		digits[i] = byte('0' + x%10)
+		x /= 10
+		if x == 0 {
+			break
+		}
+	}
+	w.bytes(digits[i:])
+}
+
+func (w *stackWriter) hex(x uintptr) {
+	const hexDigits = "0123456789abcdef"
+	var digits [16]byte
+	i := len(digits)
+	for {
+		i--
+		digits[i] = hexDigits[x%16]
+		x /= 16
+		if x == 0 {
+			break
+		}
+	}
+	w.string("0x")
+	w.bytes(digits[i:])
+}
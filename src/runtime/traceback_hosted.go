@@ -0,0 +1,20 @@
+//go:build !baremetal && !js && !wasm_unknown && !nintendoswitch && !wasip2
+
+package runtime
+
+import "unsafe"
+
+// gotracebackEnv returns the value of the GOTRACEBACK environment variable, if
+// it is set. It doesn't allocate, so that it can be used while panicking.
+func gotracebackEnv() (string, bool) {
+	name := "GOTRACEBACK\x00"
+	value := libc_getenv((*_string)(unsafe.Pointer(&name)).ptr)
+	if value == nil {
+		return "", false
+	}
+	s := _string{
+		ptr:    value,
+		length: strlen(unsafe.Pointer(value)),
+	}
+	return *(*string)(unsafe.Pointer(&s)), true
+}
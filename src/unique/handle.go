@@ -1,7 +1,10 @@
 // Package unique implements the upstream Go unique package for TinyGo.
 //
-// It is not a full implementation: while it should behave the same way, it
-// doesn't free unreferenced uniqued objects.
+// Uniqued objects are referenced by weak pointers, so that they are freed once
+// no Handle refers to them anymore. This requires one of the block-based
+// garbage collectors (-gc=conservative, -gc=precise, -gc=incremental or
+// -gc=sizeclass): with other garbage collectors, uniqued objects are never
+// freed.
 package unique
 
 import (
@@ -12,7 +15,7 @@ import (
 var (
 	// We use a two-level map because that way it's easier to store and retrieve
 	// values.
-	globalMap map[unsafe.Pointer]any // map value type is always map[T]Handle[T]
+	globalMap map[unsafe.Pointer]any // map value type is always map[T]unsafe.Pointer (a weak pointer to a T)
 
 	globalMapMutex sync.Mutex
 )
@@ -47,22 +50,28 @@ func Make[T comparable](value T) Handle[T] {
 
 	// Retrieve the type-specific map, creating it if not yet present.
 	typeptr, _ := decomposeInterface(value)
-	var typeSpecificMap map[T]Handle[T]
+	var typeSpecificMap map[T]unsafe.Pointer
 	if typeSpecificMapValue, ok := globalMap[typeptr]; !ok {
-		typeSpecificMap = make(map[T]Handle[T])
+		typeSpecificMap = make(map[T]unsafe.Pointer)
 		globalMap[typeptr] = typeSpecificMap
 	} else {
-		typeSpecificMap = typeSpecificMapValue.(map[T]Handle[T])
+		typeSpecificMap = typeSpecificMapValue.(map[T]unsafe.Pointer)
 	}
 
-	// Retrieve the handle for the value, creating it if it isn't created yet.
+	// Retrieve the handle for the value, creating it if it isn't created yet
+	// or if it was freed already.
 	var handle Handle[T]
-	if h, ok := typeSpecificMap[value]; !ok {
+	if weak, ok := typeSpecificMap[value]; ok {
+		handle.value = (*T)(makeStrongFromWeak(weak))
+	}
+	if handle.value == nil {
 		var clone T = value
 		handle.value = &clone
-		typeSpecificMap[value] = handle
-	} else {
-		handle = h
+		weak := registerWeakPointer(unsafe.Pointer(handle.value))
+		typeSpecificMap[value] = weak
+		addCleanup(unsafe.Pointer(handle.value), func() {
+			removeValue(typeptr, value, weak)
+		})
 	}
 
 	globalMapMutex.Unlock()
@@ -70,5 +79,24 @@ func Make[T comparable](value T) Handle[T] {
 	return handle
 }
 
+// removeValue removes the map entry of a value that was freed, unless the
+// value was added to the map again in the meantime.
+func removeValue[T comparable](typeptr unsafe.Pointer, value T, weak unsafe.Pointer) {
+	globalMapMutex.Lock()
+	if typeSpecificMap, ok := globalMap[typeptr].(map[T]unsafe.Pointer); ok && typeSpecificMap[value] == weak {
+		delete(typeSpecificMap, value)
+	}
+	globalMapMutex.Unlock()
+}
+
 //go:linkname decomposeInterface runtime.decomposeInterface
 func decomposeInterface(i interface{}) (unsafe.Pointer, unsafe.Pointer)
+
+//go:linkname registerWeakPointer weak.runtime_registerWeakPointer
+func registerWeakPointer(ptr unsafe.Pointer) unsafe.Pointer
+
+//go:linkname makeStrongFromWeak weak.runtime_makeStrongFromWeak
+func makeStrongFromWeak(u unsafe.Pointer) unsafe.Pointer
+
+//go:linkname addCleanup runtime.addCleanup
+func addCleanup(ptr unsafe.Pointer, cleanup func()) uint32
@@ -10,7 +10,10 @@ package unique
 import (
 	"fmt"
 	"reflect"
+	"runtime"
 	"testing"
+	"time"
+	"unsafe"
 )
 
 // Set up special types. Because the internal maps are sharded by type,
@@ -66,6 +69,76 @@ func testHandle[T comparable](t *testing.T, value T) {
 	})
 }
 
+// Handles that are made again after the previous handles were freed must still
+// be unique.
+func TestMakeAfterFree(t *testing.T) {
+	makeTestStrings()
+	for i := 0; i < 5; i++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+
+	// The values aren't referenced anymore, so they must have been freed and
+	// removed from the map. Other GCs never free them.
+	if gcClearsWeakPointers() {
+		if n := countTestStrings(); n != 0 {
+			t.Errorf("%d of 10 unreferenced values were not freed", n)
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		value := testString(fmt.Sprint("free", i))
+		v0 := Make(value)
+		v1 := Make(value)
+		if v0 != v1 {
+			t.Errorf("%s: v0 != v1", value)
+		}
+		if v0.Value() != value {
+			t.Errorf("%s: v0.Value not %#v", value, value)
+		}
+	}
+
+	drainMaps(t)
+}
+
+//go:noinline
+func makeTestStrings() {
+	for i := 0; i < 10; i++ {
+		Make(testString(fmt.Sprint("free", i)))
+	}
+}
+
+// countTestStrings returns the number of values made by makeTestStrings that
+// are still in the map.
+func countTestStrings() int {
+	globalMapMutex.Lock()
+	defer globalMapMutex.Unlock()
+	typeptr, _ := decomposeInterface(testString(""))
+	typeSpecificMap, _ := globalMap[typeptr].(map[testString]unsafe.Pointer)
+	n := 0
+	for i := 0; i < 10; i++ {
+		if _, ok := typeSpecificMap[testString(fmt.Sprint("free", i))]; ok {
+			n++
+		}
+	}
+	return n
+}
+
+// gcClearsWeakPointers returns whether the garbage collector frees unreachable
+// objects and clears weak pointers to them, which only the block-based GCs do.
+func gcClearsWeakPointers() bool {
+	weak := makeWeakPointer()
+	for i := 0; i < 5; i++ {
+		runtime.GC()
+	}
+	return makeStrongFromWeak(weak) == nil
+}
+
+//go:noinline
+func makeWeakPointer() unsafe.Pointer {
+	return registerWeakPointer(unsafe.Pointer(new([4]int)))
+}
+
 // drainMaps ensures that the internal maps are drained.
 func drainMaps(t *testing.T) {
 	t.Helper()
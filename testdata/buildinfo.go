@@ -0,0 +1,30 @@
+package main
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+func main() {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		println("no build info")
+		return
+	}
+	println("path:", info.Path)
+	settings := map[string]string{}
+	for _, setting := range info.Settings {
+		settings[setting.Key] = setting.Value
+	}
+	println("compiler:", settings["-compiler"])
+	println("GOOS:", settings["GOOS"] == runtime.GOOS)
+	println("GOARCH:", settings["GOARCH"] == runtime.GOARCH)
+
+	// The build info must survive a round trip through String.
+	parsed, err := debug.ParseBuildInfo(info.String())
+	if err != nil {
+		println("could not parse:", err.Error())
+		return
+	}
+	println("parsed:", parsed.Path == info.Path, len(parsed.Settings) == len(info.Settings))
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+type T struct{}
+
+func main() {
+	printCaller()
+	printFrames()
+	var t T
+	t.method()
+	func() {
+		printCaller()
+	}()
+	printFunc()
+	printStack()
+}
+
+func printCaller() {
+	pc, file, line, ok := runtime.Caller(1)
+	if !ok {
+		println("Caller failed")
+		return
+	}
+	println("caller:", baseName(file), line, runtime.FuncForPC(pc).Name())
+}
+
+func printFrames() {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		println("frame:", baseName(frame.File), frame.Line, frame.Function)
+		if frame.Function == "main.main" || !more {
+			break
+		}
+	}
+}
+
+func (t *T) method() {
+	printCaller()
+	printFrames()
+}
+
+func printFunc() {
+	pc, _, _, _ := runtime.Caller(0)
+	f := runtime.FuncForPC(pc)
+	file, line := f.FileLine(pc)
+	println("func:", f.Name(), baseName(file), line)
+}
+
+func printStack() {
+	// Strip the directories and offsets, which depend on the build.
+	lines := strings.Split(string(debug.Stack()), "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "\t") {
+			line, _, _ = strings.Cut(line, " +")
+			line = "\t" + baseName(line)
+		}
+		println("stack:", line)
+		if i > 0 && lines[i-1] == "main.main(...)" {
+			break
+		}
+	}
+}
+
+func baseName(path string) string {
+	return path[strings.LastIndexByte(path, '/')+1:]
+}
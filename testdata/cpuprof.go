@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"runtime/pprof"
+	"time"
+)
+
+func main() {
+	testCPUProfile()
+}
+
+//go:noinline
+func spin(d time.Duration) int {
+	n := 0
+	for start := time.Now(); time.Since(start) < d; {
+		n++
+	}
+	return n
+}
+
+func testCPUProfile() {
+	buf := &bytes.Buffer{}
+	if err := pprof.StartCPUProfile(buf); err != nil {
+		println("StartCPUProfile:", err.Error())
+		return
+	}
+	if err := pprof.StartCPUProfile(buf); err == nil {
+		println("StartCPUProfile: expected an error")
+	}
+	spin(500 * time.Millisecond)
+	pprof.StopCPUProfile()
+	checkProfile("cpu", buf, "main.spin")
+}
+
+// checkProfile checks that the profile is valid gzip data and that it contains
+// the given function name in its string table.
+func checkProfile(name string, buf *bytes.Buffer, function string) {
+	r, err := gzip.NewReader(buf)
+	if err != nil {
+		println(name, "profile:", err.Error())
+		return
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		println(name, "profile:", err.Error())
+		return
+	}
+	println(name, "profile contains", function+":", bytes.Contains(data, []byte(function)))
+}
@@ -2,7 +2,10 @@ package main
 
 import (
 	"embed"
+	"errors"
+	"os"
 	"strings"
+	"syscall"
 )
 
 //go:embed a hello.txt
@@ -28,6 +31,7 @@ func main() {
 	println("[]byte(string):", strings.TrimSpace(string(helloStringBytes)))
 	println("files:")
 	readFiles(".")
+	testMount()
 }
 
 func readFiles(dir string) {
@@ -47,3 +51,34 @@ func readFiles(dir string) {
 		}
 	}
 }
+
+// testMount reads the files through the os package.
+func testMount() {
+	os.MountFS("/embed/", files)
+	data, err := os.ReadFile("/embed/a/b/foo.txt")
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	println("mounted foo.txt:", strings.TrimSpace(string(data)))
+	entries, err := os.ReadDir("/embed/a/b")
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	print("mounted a/b:")
+	for _, entry := range entries {
+		print(" ", entry.Name())
+	}
+	println()
+	info, err := os.Stat("/embed/hello.txt")
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	println("mounted hello.txt:", info.Size(), info.IsDir())
+	_, err = os.Stat("/embed/a/b/.hidden")
+	println("hidden:", errors.Is(err, os.ErrNotExist))
+	err = os.WriteFile("/embed/new.txt", nil, 0666)
+	println("read-only:", errors.Is(err, syscall.EROFS))
+}
@@ -0,0 +1,167 @@
+package main
+
+// Test the FAT filesystem, mounted in the os package.
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"machine/fatfs"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// ramDevice is a block device in memory.
+type ramDevice []byte
+
+func (d ramDevice) ReadAt(b []byte, off int64) (int, error) {
+	return copy(b, d[off:]), nil
+}
+
+func (d ramDevice) WriteAt(b []byte, off int64) (int, error) {
+	return copy(d[off:], b), nil
+}
+
+func (d ramDevice) Size() int64 {
+	return int64(len(d))
+}
+
+func main() {
+	testFilesystem("/fat12/", 2<<20, 12)
+	testFilesystem("/fat16/", 8<<20, 16)
+	testFilesystem("/fat32/", 40<<20, 32)
+}
+
+func testFilesystem(prefix string, size int, fatType int) {
+	println("# FAT" + strconv.Itoa(fatType))
+	dev := make(ramDevice, size)
+	err := fatfs.Format(dev, fatfs.FormatConfig{Type: fatType, Label: "TINYGO"})
+	check(err)
+	volume, err := fatfs.Mount(dev)
+	check(err)
+	println("type:", volume.Type())
+	free, err := volume.FreeSpace()
+	check(err)
+	os.Mount(prefix, volume)
+
+	// Short and long names, in several directories.
+	check(os.WriteFile(prefix+"hello.txt", []byte("hello world\n"), 0666))
+	big := make([]byte, 50000)
+	for i := range big {
+		big[i] = byte(i * 7)
+	}
+	check(os.WriteFile(prefix+"A file with a long name.data", big, 0666))
+	check(os.Mkdir(prefix+"logs", 0777))
+	check(os.Mkdir(prefix+"logs/Second Level", 0777))
+	for i := 0; i < 20; i++ {
+		name := prefix + "logs/Second Level/log file " + strconv.Itoa(i) + ".txt"
+		check(os.WriteFile(name, []byte(strconv.Itoa(i*i)), 0666))
+	}
+
+	// Appending.
+	f, err := os.OpenFile(prefix+"hello.txt", os.O_WRONLY|os.O_APPEND, 0)
+	check(err)
+	_, err = f.Write([]byte("more\n"))
+	check(err)
+	check(f.Close())
+
+	// Seeking, and writing after the end of the file.
+	f, err = os.OpenFile(prefix+"sparse", os.O_RDWR|os.O_CREATE, 0666)
+	check(err)
+	_, err = f.WriteAt([]byte("end"), 3000)
+	check(err)
+	_, err = f.Seek(2999, io.SeekStart)
+	check(err)
+	buf := make([]byte, 10)
+	n, _ := f.Read(buf)
+	println("sparse:", n, buf[0], string(buf[1:n]))
+	check(f.Close())
+
+	// Errors.
+	_, err = os.Open(prefix + "missing.txt")
+	println("missing:", errors.Is(err, fs.ErrNotExist))
+	_, err = os.OpenFile(prefix+"hello.txt", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	println("exclusive:", errors.Is(err, fs.ErrExist))
+	println("not empty:", errors.Is(os.Remove(prefix+"logs"), syscall.ENOTEMPTY))
+	check(os.WriteFile(prefix+"readonly", nil, 0444))
+	_, err = os.OpenFile(prefix+"readonly", os.O_WRONLY, 0)
+	println("read-only:", errors.Is(err, fs.ErrPermission))
+
+	// Renaming, truncating and changing permissions.
+	check(os.Rename(prefix+"sparse", prefix+"logs/moved"))
+	check(os.Truncate(prefix+"logs/moved", 10))
+	check(os.Chmod(prefix+"readonly", 0666))
+	err = os.Rename(prefix+"logs", prefix+"logs/Second Level/logs")
+	println("rename into itself:", err != nil)
+
+	// Mount the volume again, to check that everything was written.
+	volume, err = fatfs.Mount(dev)
+	check(err)
+	os.Mount(prefix, volume)
+	data, err := os.ReadFile(prefix + "HELLO.TXT")
+	check(err)
+	print("hello.txt: ", string(data))
+	data, err = os.ReadFile(prefix + "a file with a long name.DATA")
+	check(err)
+	println("long name:", bytes.Equal(data, big))
+	data, err = os.ReadFile(prefix + "AFILEW~1.DAT")
+	check(err)
+	println("short name:", bytes.Equal(data, big))
+	data, err = os.ReadFile(prefix + "logs/second level/log file 19.txt")
+	check(err)
+	println("log file 19:", string(data))
+
+	// Directories and file information.
+	entries, err := os.ReadDir(prefix + "logs")
+	check(err)
+	print("logs:")
+	for _, entry := range entries {
+		print(" ", entry.Name(), " ", entry.IsDir())
+	}
+	println()
+	info, err := os.Stat(prefix + "logs/moved")
+	check(err)
+	println("moved:", info.Name(), info.Size(), info.Mode().String())
+	info, err = os.Stat(prefix + "readonly")
+	check(err)
+	println("not read-only:", info.Mode().String())
+	_, err = os.Stat(prefix + "sparse")
+	println("old name:", errors.Is(err, fs.ErrNotExist))
+	f, err = os.Open(prefix + "logs/Second Level")
+	check(err)
+	count := 0
+	for {
+		entries, err := f.ReadDir(8)
+		count += len(entries)
+		if err == io.EOF {
+			break
+		}
+		check(err)
+	}
+	check(f.Close())
+	println("log files:", count)
+
+	// Remove everything, which should free all clusters.
+	for i := 0; i < 20; i++ {
+		check(os.Remove(prefix + "logs/Second Level/log file " + strconv.Itoa(i) + ".txt"))
+	}
+	check(os.Remove(prefix + "logs/Second Level"))
+	check(os.Remove(prefix + "logs/moved"))
+	check(os.Remove(prefix + "logs"))
+	check(os.Remove(prefix + "hello.txt"))
+	check(os.Remove(prefix + "A file with a long name.data"))
+	check(os.Remove(prefix + "readonly"))
+	volume, err = fatfs.Mount(dev)
+	check(err)
+	freeAfter, err := volume.FreeSpace()
+	check(err)
+	println("all space freed:", free == freeAfter)
+}
+
+func check(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+type resource struct {
+	magic uint32
+	data  [16]byte
+}
+
+const magic = 0x5eed
+
+var (
+	finalized   atomic.Int32
+	corrupted   atomic.Int32
+	removed     atomic.Int32
+	cleaned     atomic.Int32
+	badArgument atomic.Int32
+	stopped     atomic.Int32
+)
+
+func main() {
+	// Finalizers run after the object becomes unreachable, and the object is
+	// still intact when they run.
+	allocFinalized()
+	waitFor(&finalized)
+	println("finalizer ran:", finalized.Load() > 0)
+	println("object corrupted:", corrupted.Load() > 0)
+
+	// Cleanups run with their argument.
+	allocCleanups()
+	waitFor(&cleaned)
+	println("cleanup ran:", cleaned.Load() > 0)
+	println("wrong cleanup argument:", badArgument.Load() > 0)
+
+	// Removed finalizers and stopped cleanups never run.
+	println("removed finalizer ran:", removed.Load() > 0)
+	println("stopped cleanup ran:", stopped.Load() > 0)
+}
+
+//go:noinline
+func allocFinalized() {
+	for i := 0; i < 10; i++ {
+		r := &resource{magic: magic}
+		runtime.SetFinalizer(r, func(r *resource) {
+			if r.magic != magic {
+				corrupted.Add(1)
+			}
+			finalized.Add(1)
+		})
+
+		r = &resource{magic: magic}
+		runtime.SetFinalizer(r, func(r *resource) {
+			removed.Add(1)
+		})
+		runtime.SetFinalizer(r, nil)
+	}
+}
+
+//go:noinline
+func allocCleanups() {
+	for i := 0; i < 10; i++ {
+		r := &resource{magic: magic}
+		runtime.AddCleanup(r, func(m uint32) {
+			if m != magic {
+				badArgument.Add(1)
+			}
+			cleaned.Add(1)
+		}, r.magic)
+
+		r = &resource{magic: magic}
+		c := runtime.AddCleanup(r, func(int) {
+			stopped.Add(1)
+		}, 0)
+		c.Stop()
+	}
+}
+
+// waitFor runs the GC until the counter is non-zero. Not all objects may be
+// freed as the stack is scanned conservatively, but some of them will be.
+func waitFor(counter *atomic.Int32) {
+	for i := 0; i < 20 && counter.Load() == 0; i++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,390 @@
+package main
+
+// Test the flash filesystem, mounted in the os package, including power loss
+// at every point of a sequence of changes.
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"machine/flashfs"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+var errPowerLoss = errors.New("power loss")
+
+// norFlash is NOR flash memory in RAM: writes can only clear bits, and erasing
+// sets all bits of an erase block. It can simulate power loss in the middle of
+// a write or erase.
+type norFlash struct {
+	data   []byte
+	erases []int
+
+	ops    int // number of writes and erases
+	failAt int // the write or erase that is interrupted, if not 0
+	dead   bool
+}
+
+const (
+	eraseBlockSize = 4096
+	writeBlockSize = 4
+)
+
+func newNORFlash(size int) *norFlash {
+	d := &norFlash{
+		data:   make([]byte, size),
+		erases: make([]int, size/eraseBlockSize),
+	}
+	for i := range d.data {
+		d.data[i] = 0xff
+	}
+	return d
+}
+
+// powerLoss returns whether power is lost during the current operation.
+func (d *norFlash) powerLoss() bool {
+	d.ops++
+	if d.ops == d.failAt {
+		d.dead = true
+	}
+	return d.dead
+}
+
+// restore turns the power back on.
+func (d *norFlash) restore() {
+	d.dead = false
+	d.failAt = 0
+}
+
+func (d *norFlash) ReadAt(b []byte, off int64) (int, error) {
+	if d.dead {
+		return 0, errPowerLoss
+	}
+	return copy(b, d.data[off:]), nil
+}
+
+func (d *norFlash) WriteAt(b []byte, off int64) (int, error) {
+	if d.dead {
+		return 0, errPowerLoss
+	}
+	if off%writeBlockSize != 0 {
+		panic("unaligned write")
+	}
+	n := len(b)
+	lost := d.powerLoss()
+	if lost {
+		// Only part of the data is written, and the last byte is
+		// garbage.
+		n = d.ops % (len(b) + 1)
+		if n < len(b) {
+			d.data[off+int64(n)] &= byte(d.ops)
+		}
+	}
+	for i, c := range b[:n] {
+		if !lost && d.data[off+int64(i)]&c != c {
+			panic("write to flash that was not erased")
+		}
+		d.data[off+int64(i)] &= c
+	}
+	if lost {
+		return n, errPowerLoss
+	}
+	return n, nil
+}
+
+func (d *norFlash) Size() int64 {
+	return int64(len(d.data))
+}
+
+func (d *norFlash) WriteBlockSize() int64 {
+	return writeBlockSize
+}
+
+func (d *norFlash) EraseBlockSize() int64 {
+	return eraseBlockSize
+}
+
+func (d *norFlash) EraseBlocks(start, length int64) error {
+	if d.dead {
+		return errPowerLoss
+	}
+	for blk := start; blk < start+length; blk++ {
+		block := d.data[blk*eraseBlockSize : (blk+1)*eraseBlockSize]
+		if d.powerLoss() {
+			// Only some bytes are erased.
+			for i := range block {
+				if (i*7+d.ops)%3 == 0 {
+					block[i] = 0xff
+				}
+			}
+			return errPowerLoss
+		}
+		for i := range block {
+			block[i] = 0xff
+		}
+		d.erases[blk]++
+	}
+	return nil
+}
+
+func main() {
+	testFiles()
+	testPowerLoss()
+	testWear()
+}
+
+// testFiles tests the filesystem through the os package.
+func testFiles() {
+	dev := newNORFlash(128 << 10)
+	_, err := flashfs.Mount(dev)
+	println("not formatted:", err == flashfs.ErrNotFormatted)
+	check(flashfs.Format(dev, flashfs.Config{}))
+	volume, err := flashfs.Mount(dev)
+	check(err)
+	os.Mount("/flash/", volume)
+
+	check(os.WriteFile("/flash/hello.txt", []byte("hello world\n"), 0666))
+	big := pattern(20000, 1)
+	check(os.WriteFile("/flash/big.data", big, 0666))
+	check(os.Mkdir("/flash/logs", 0777))
+	for i := 0; i < 10; i++ {
+		name := "/flash/logs/log" + strconv.Itoa(i) + ".txt"
+		check(os.WriteFile(name, []byte(strconv.Itoa(i*i)), 0666))
+	}
+
+	// Appending.
+	f, err := os.OpenFile("/flash/hello.txt", os.O_WRONLY|os.O_APPEND, 0)
+	check(err)
+	_, err = f.Write([]byte("more\n"))
+	check(err)
+	check(f.Close())
+
+	// Writing after the end of the file.
+	f, err = os.OpenFile("/flash/sparse", os.O_RDWR|os.O_CREATE, 0666)
+	check(err)
+	_, err = f.WriteAt([]byte("end"), 3000)
+	check(err)
+	buf := make([]byte, 10)
+	n, _ := f.ReadAt(buf, 2999)
+	println("sparse:", n, buf[0], string(buf[1:n]))
+	check(f.Close())
+
+	// Errors.
+	_, err = os.Open("/flash/missing.txt")
+	println("missing:", errors.Is(err, fs.ErrNotExist))
+	_, err = os.OpenFile("/flash/hello.txt", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	println("exclusive:", errors.Is(err, fs.ErrExist))
+	println("not empty:", errors.Is(os.Remove("/flash/logs"), syscall.ENOTEMPTY))
+
+	// Renaming, truncating and changing permissions.
+	check(os.Rename("/flash/logs/log0.txt", "/flash/first.txt"))
+	check(os.Rename("/flash/sparse", "/flash/logs/sparse"))
+	check(os.Truncate("/flash/logs/sparse", 10))
+	check(os.Chmod("/flash/first.txt", 0444))
+	err = os.Rename("/flash/logs", "/flash/logs/logs")
+	println("rename into itself:", err != nil)
+
+	// Mount the filesystem again, to check that everything was written.
+	volume, err = flashfs.Mount(dev)
+	check(err)
+	os.Mount("/flash/", volume)
+	data, err := os.ReadFile("/flash/hello.txt")
+	check(err)
+	print("hello.txt: ", string(data))
+	data, err = os.ReadFile("/flash/big.data")
+	check(err)
+	println("big.data:", bytes.Equal(data, big))
+	data, err = os.ReadFile("/flash/logs/log9.txt")
+	check(err)
+	println("log9.txt:", string(data))
+
+	// Directories and file information.
+	entries, err := os.ReadDir("/flash/logs")
+	check(err)
+	print("logs:")
+	for _, entry := range entries {
+		print(" ", entry.Name())
+	}
+	println()
+	info, err := os.Stat("/flash/logs/sparse")
+	check(err)
+	println("sparse:", info.Name(), info.Size(), info.Mode().String())
+	info, err = os.Stat("/flash/logs")
+	check(err)
+	println("logs:", info.IsDir(), info.Mode().String())
+	data, err = os.ReadFile("/flash/first.txt")
+	check(err)
+	println("first.txt:", string(data))
+	_, err = os.OpenFile("/flash/first.txt", os.O_WRONLY, 0)
+	println("read-only:", errors.Is(err, fs.ErrPermission))
+	check(os.Chmod("/flash/first.txt", 0666))
+	check(os.Remove("/flash/first.txt"))
+
+	// Fill the filesystem. Removing files must still work.
+	full := false
+	for i := 0; i < 10 && !full; i++ {
+		err = os.WriteFile("/flash/fill"+strconv.Itoa(i), big, 0666)
+		full = errors.Is(err, syscall.ENOSPC)
+	}
+	println("full:", full)
+	for i := 0; i < 10; i++ {
+		os.Remove("/flash/fill" + strconv.Itoa(i))
+	}
+	check(os.WriteFile("/flash/after", big, 0666))
+	data, err = os.ReadFile("/flash/big.data")
+	check(err)
+	println("big.data after filling:", bytes.Equal(data, big))
+}
+
+// testPowerLoss loses power at every write and erase of a sequence of file
+// changes, and checks that every file has either its old or its new contents
+// after mounting the filesystem again.
+func testPowerLoss() {
+	type change struct {
+		name string
+		data []byte
+	}
+	changes := []change{
+		{"a", pattern(300, 2)},
+		{"b", pattern(5000, 3)},
+		{"a", pattern(700, 4)},
+		{"c", pattern(10, 5)},
+		{"b", pattern(6000, 6)},
+		{"c", nil}, // remove
+		{"a", pattern(9000, 7)},
+	}
+	dev := newNORFlash(48 << 10) // small, so that blocks are collected
+	mounts, consistent := 0, true
+	for failAt := 1; ; failAt++ {
+		// Start from the same state every time.
+		check(flashfs.Format(dev, flashfs.Config{}))
+		volume, err := flashfs.Mount(dev)
+		check(err)
+		files := map[string][]byte{}
+		dev.ops = 0
+		dev.failAt = failAt
+		var current change
+		for _, c := range changes {
+			current = c
+			if err = writeChange(volume, c.name, c.data); err != nil {
+				break
+			}
+			if c.data == nil {
+				delete(files, c.name)
+			} else {
+				files[c.name] = c.data
+			}
+		}
+		if err == nil {
+			break // no power loss: all writes and erases were tested
+		}
+		if err != errPowerLoss {
+			panic(err)
+		}
+		dev.restore()
+		volume, err = flashfs.Mount(dev)
+		check(err)
+		mounts++
+		for _, name := range []string{"a", "b", "c"} {
+			data, ok := readFile(volume, name)
+			old, oldOK := files[name]
+			if ok == oldOK && bytes.Equal(data, old) {
+				continue
+			}
+			if name == current.name && ok == (current.data != nil) && bytes.Equal(data, current.data) {
+				continue
+			}
+			if name == current.name && ok && !oldOK && len(data) == 0 {
+				continue // created, but nothing written yet
+			}
+			println("inconsistent after power loss at", failAt, ":", name)
+			consistent = false
+		}
+	}
+	println("power loss mounts:", mounts > 100)
+	println("consistent:", consistent)
+}
+
+// testWear rewrites one file many times, next to a file that never changes,
+// and checks that all blocks are erased about as often.
+func testWear() {
+	dev := newNORFlash(64 << 10)
+	check(flashfs.Format(dev, flashfs.Config{}))
+	volume, err := flashfs.Mount(dev)
+	check(err)
+	static := pattern(10000, 8)
+	check(writeChange(volume, "static", static))
+	for i := 0; i < 300; i++ {
+		check(writeChange(volume, "counter", []byte(strconv.Itoa(i))))
+	}
+	minErases, maxErases := dev.erases[0], dev.erases[0]
+	for _, n := range dev.erases {
+		if n < minErases {
+			minErases = n
+		}
+		if n > maxErases {
+			maxErases = n
+		}
+	}
+	println("erased:", minErases > 0, "spread:", maxErases-minErases <= 2)
+	stats := volume.Stats()
+	println("stats:", stats.Blocks, stats.MaxErases-stats.MinErases <= 2)
+	data, _ := readFile(volume, "static")
+	println("static:", bytes.Equal(data, static))
+}
+
+// writeChange replaces the contents of a file, or removes it if data is nil.
+func writeChange(volume *flashfs.FS, name string, data []byte) error {
+	if data == nil {
+		return volume.Remove(name)
+	}
+	f, err := volume.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	for len(data) > 0 {
+		chunk := data
+		if len(chunk) > 1000 {
+			chunk = chunk[:1000]
+		}
+		if _, err := f.Write(chunk); err != nil {
+			f.Close()
+			return err
+		}
+		data = data[len(chunk):]
+	}
+	return f.Close()
+}
+
+func readFile(volume *flashfs.FS, name string) ([]byte, bool) {
+	f, err := volume.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+	var data []byte
+	buf := make([]byte, 512)
+	for {
+		n, err := f.Read(buf)
+		data = append(data, buf[:n]...)
+		if err != nil {
+			return data, true
+		}
+	}
+}
+
+func pattern(size int, seed byte) []byte {
+	b := make([]byte, size)
+	for i := range b {
+		b[i] = byte(i*7) + seed
+	}
+	return b
+}
+
+func check(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+// Test for -gc=incremental. Pointers are moved around between heap objects,
+// stacks, maps, channels and goroutines while a lot of garbage is allocated,
+// so that this happens in the middle of GC cycles. Objects that are still
+// reachable must not be freed, which is checked by looking at their contents.
+// The live data is kept small, so that it fits in the heap of the emulated
+// microcontrollers.
+
+import "runtime"
+
+type node struct {
+	value       int
+	left, right *node
+	data        []byte
+}
+
+var sink []byte
+
+func newNode(value int) *node {
+	n := &node{value: value, data: make([]byte, 64)}
+	for i := range n.data {
+		n.data[i] = byte(value)
+	}
+	return n
+}
+
+func makeTree(depth, value int) *node {
+	n := newNode(value)
+	if depth > 0 {
+		n.left = makeTree(depth-1, value*2)
+		n.right = makeTree(depth-1, value*2+1)
+	}
+	return n
+}
+
+// check returns the sum of the values in the tree, or -1 if a node was
+// overwritten.
+func (n *node) check() int {
+	if n == nil {
+		return 0
+	}
+	if !n.valid() {
+		return -1
+	}
+	left, right := n.left.check(), n.right.check()
+	if left < 0 || right < 0 {
+		return -1
+	}
+	return n.value + left + right
+}
+
+func (n *node) valid() bool {
+	if len(n.data) != 64 {
+		return false
+	}
+	for _, b := range n.data {
+		if b != byte(n.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// garbage allocates memory that is not used anymore, to make the GC run.
+func garbage() {
+	for i := 0; i < 16; i++ {
+		sink = make([]byte, 1024)
+	}
+}
+
+func main() {
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	// Swap subtrees, while a subtree is only referenced from the stack.
+	tree := makeTree(6, 1)
+	want := tree.check()
+	for i := 0; i < 2000; i++ {
+		left := tree.left
+		tree.left = nil
+		garbage()
+		tree.left = tree.right
+		tree.right = left
+		garbage()
+	}
+	println("tree:", tree.check() == want)
+
+	// Move nodes between slices and maps.
+	m := make(map[int]*node)
+	var list []*node
+	for i := 0; i < 200; i++ {
+		list = append(list, newNode(i))
+		garbage()
+		if i%2 == 0 {
+			m[i] = list[0]
+			copy(list, list[1:])
+			list = list[:len(list)-1]
+		}
+	}
+	valid := len(m)+len(list) == 200
+	for _, n := range m {
+		valid = valid && n.valid()
+	}
+	for _, n := range list {
+		valid = valid && n.valid()
+	}
+	println("map:", valid)
+
+	// Send nodes from a goroutine, that only has them on its stack while it
+	// is paused.
+	ch := make(chan *node)
+	go func() {
+		for i := 0; i < 1000; i++ {
+			n := newNode(i)
+			runtime.Gosched()
+			ch <- n
+		}
+		close(ch)
+	}()
+	valid = true
+	count := 0
+	for n := range ch {
+		garbage()
+		valid = valid && n.valid() && n.value == count
+		count++
+	}
+	println("channel:", valid && count == 1000)
+
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+	println("ran GC:", after.NumGC > before.NumGC)
+}
@@ -0,0 +1,132 @@
+package main
+
+// Test for -gc=sizeclass. A long-running mix of small and large allocations
+// with a small memory limit runs many GC cycles, in which spans for small
+// objects are created and released. Objects that are still reachable must not
+// be freed or overwritten, which is checked by looking at their contents.
+// After that, long-lived small objects are allocated in between large buffers,
+// which must not fragment the heap once the buffers are freed. The live data is
+// kept small, so that it fits in the heap of the emulated microcontrollers.
+
+import (
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+)
+
+const (
+	numEntries = 64
+	numBuffers = 32
+	bufferSize = 512
+)
+
+type small struct {
+	value int
+	next  *small
+}
+
+type entry struct {
+	seed  int
+	small *small
+	data  []byte
+}
+
+var seed uint32 = 1
+
+// random returns a pseudo-random number, so that the test is deterministic.
+func random() int {
+	seed = seed*1664525 + 1013904223
+	return int(seed >> 8)
+}
+
+func newEntry(n int) entry {
+	e := entry{seed: n}
+	switch n % 16 {
+	case 0:
+		// A large object that isn't allocated in a span.
+		e.data = make([]byte, 512+n%1536)
+	case 1, 2, 3, 4, 5, 6, 7:
+		e.data = make([]byte, 1+n%100)
+	default:
+		e.small = &small{value: n, next: &small{value: n + 1}}
+	}
+	for i := range e.data {
+		e.data[i] = byte(n)
+	}
+	return e
+}
+
+func (e entry) valid() bool {
+	if e.small != nil {
+		return e.small.value == e.seed && e.small.next != nil && e.small.next.value == e.seed+1
+	}
+	for _, b := range e.data {
+		if b != byte(e.seed) {
+			return false
+		}
+	}
+	return len(e.data) != 0
+}
+
+// heapFragmentation returns the number of free ranges in the heap, the size of
+// the largest one, and the size of the free size class slots.
+func heapFragmentation() (ranges, largest, sizeClassIdle uint64) {
+	samples := []metrics.Sample{
+		{Name: "/tinygo/gc/heap/free-ranges:ranges"},
+		{Name: "/tinygo/gc/heap/largest-free:bytes"},
+		{Name: "/tinygo/gc/heap/sizeclass-idle:bytes"},
+	}
+	metrics.Read(samples)
+	return samples[0].Value.Uint64(), samples[1].Value.Uint64(), samples[2].Value.Uint64()
+}
+
+func main() {
+	debug.SetMemoryLimit(64 << 10)
+
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	// Replace random objects, checking the replaced ones.
+	entries := make([]entry, numEntries)
+	for i := range entries {
+		entries[i] = newEntry(random())
+	}
+	valid := true
+	for i := 0; i < 100000; i++ {
+		index := random() % len(entries)
+		valid = valid && entries[index].valid()
+		entries[index] = newEntry(random())
+	}
+	for _, e := range entries {
+		valid = valid && e.valid()
+	}
+	println("stress:", valid)
+
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+	println("ran GC:", after.NumGC > before.NumGC)
+
+	// Allocate small objects in between large buffers. The small objects are
+	// put together in spans, so freeing the buffers leaves only a few free
+	// ranges behind.
+	entries = nil
+	runtime.GC()
+	rangesBefore, _, _ := heapFragmentation()
+	keep := make([]*small, numBuffers)
+	buffers := make([][]byte, numBuffers)
+	for i := range keep {
+		buffers[i] = make([]byte, bufferSize)
+		keep[i] = &small{value: i}
+	}
+	buffers = nil
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	ranges, largest, sizeClassIdle := heapFragmentation()
+	valid = true
+	for i, s := range keep {
+		valid = valid && s.value == i
+	}
+	println("small objects:", valid)
+	println("fragmentation:", int(ranges)-int(rangesBefore) < numBuffers/4)
+	println("stats:", largest > 0 && sizeClassIdle > 0 && largest+sizeClassIdle <= after.HeapIdle)
+}
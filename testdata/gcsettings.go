@@ -0,0 +1,10 @@
+package main
+
+import "runtime/debug"
+
+func main() {
+	// The GC settings are read from GOGC and GOMEMLIMIT (set by the test
+	// runner) at startup.
+	println("GOGC:", debug.SetGCPercent(100))
+	println("GOMEMLIMIT:", debug.SetMemoryLimit(-1))
+}
@@ -0,0 +1,171 @@
+package main
+
+// Test the simulated board that the machine package uses on the host.
+
+import (
+	"errors"
+	"machine"
+	"machine/sim"
+)
+
+func main() {
+	testGPIO()
+	testPWM()
+	testUART()
+	testI2C()
+	testSPI()
+	testADC()
+}
+
+func testGPIO() {
+	sim.Reset()
+	println("# GPIO")
+
+	led := machine.GPIO25
+	led.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	var changes int
+	sim.WatchPin(uint8(led), func(level bool) {
+		changes++
+	})
+	led.High()
+	println("led high:", sim.Pin(uint8(led)))
+	led.Low()
+	println("led low:", sim.Pin(uint8(led)))
+	println("changes:", changes)
+	mode, configured := sim.PinMode(uint8(led))
+	println("mode:", mode == sim.PinOutput, configured)
+
+	button := machine.GPIO2
+	button.Configure(machine.PinConfig{Mode: machine.PinInputPullup})
+	println("button released:", button.Get())
+	sim.SetPin(uint8(button), false)
+	println("button pressed:", button.Get())
+	sim.ReleasePin(uint8(button))
+	println("button released:", button.Get())
+}
+
+func testPWM() {
+	sim.Reset()
+	println("# PWM")
+
+	pwm := machine.PWM0
+	err := pwm.Configure(machine.PWMConfig{Period: 1e6})
+	if err != nil {
+		println("could not configure PWM:", err.Error())
+		return
+	}
+	ch, err := pwm.Channel(machine.GPIO16)
+	if err != nil {
+		println("could not get channel:", err.Error())
+		return
+	}
+	pwm.Set(ch, pwm.Top()/4)
+	value, top, frequency, ok := sim.PWM(uint8(machine.GPIO16))
+	println("channel:", ch, ok)
+	println("duty:", value*100/top, "percent")
+	println("period:", uint64(float64(top)/frequency*1e9), "ns")
+	_, _, _, ok = sim.PWM(uint8(machine.GPIO17))
+	println("other pin:", ok)
+}
+
+func testUART() {
+	sim.Reset()
+	println("# UART")
+
+	uart := machine.UART0
+	uart.Configure(machine.UARTConfig{})
+	uart.Write([]byte("hello"))
+	uart.WriteByte('!')
+	println("output:", string(sim.UARTOutput(0)))
+	println("output after read:", len(sim.UARTOutput(0)))
+
+	sim.FeedUART(0, []byte("abc"))
+	println("buffered:", uart.Buffered())
+	b, _ := uart.ReadByte()
+	println("read byte:", string(b))
+	buf := make([]byte, 10)
+	n, _ := uart.Read(buf)
+	println("read:", string(buf[:n]))
+	println("buffered:", uart.Buffered())
+}
+
+// A device that always fails.
+type brokenDevice struct{}
+
+func (brokenDevice) Tx(w, r []byte) error {
+	return errors.New("broken")
+}
+
+func testI2C() {
+	sim.Reset()
+	println("# I2C")
+
+	i2c := machine.I2C0
+	i2c.Configure(machine.I2CConfig{SCL: machine.GPIO5, SDA: machine.GPIO4})
+	err := i2c.Tx(0x40, []byte{0}, nil)
+	println("no devices:", err != nil)
+
+	sensor := sim.NewRegisters(8)
+	sensor.Data[3] = 0x12
+	sensor.Data[4] = 0x34
+	sim.AddI2CDevice(0, 0x40, sensor)
+	sim.AddI2CDevice(0, 0x41, brokenDevice{})
+
+	r := make([]byte, 2)
+	err = i2c.Tx(0x40, []byte{3}, r)
+	println("read register:", err == nil, r[0], r[1])
+	err = i2c.WriteRegister(0x40, 6, []byte{0xab})
+	println("write register:", err == nil, sensor.Data[6])
+	err = i2c.Tx(0x50, []byte{0}, nil)
+	println("wrong address:", err != nil)
+	err = i2c.Tx(0x41, []byte{0}, nil)
+	println("broken device:", err != nil)
+
+	for _, tx := range sim.I2CTransactions(0) {
+		println("transaction:", tx.Addr, len(tx.W), len(tx.R))
+	}
+}
+
+// A device that returns each byte plus one.
+type incrementDevice struct{}
+
+func (incrementDevice) Tx(w, r []byte) {
+	for i := range w {
+		r[i] = w[i] + 1
+	}
+}
+
+func testSPI() {
+	sim.Reset()
+	println("# SPI")
+
+	spi := machine.SPI0
+	spi.Configure(machine.SPIConfig{})
+	b, _ := spi.Transfer(5)
+	println("no device:", b)
+
+	sim.SetSPIDevice(0, incrementDevice{})
+	b, _ = spi.Transfer(5)
+	println("transfer:", b)
+	r := make([]byte, 3)
+	spi.Tx([]byte{1, 2, 3}, r)
+	println("tx:", r[0], r[1], r[2])
+	spi.Tx(nil, r)
+	println("read only:", r[0], r[1], r[2])
+
+	for _, tx := range sim.SPITransactions(0) {
+		println("transaction:", len(tx.W), tx.W[0], tx.R[0])
+	}
+}
+
+func testADC() {
+	sim.Reset()
+	println("# ADC")
+
+	machine.InitADC()
+	adc := machine.ADC{Pin: machine.GPIO26}
+	adc.Configure(machine.ADCConfig{})
+	println("default:", adc.Get())
+	sim.SetADC(uint8(machine.GPIO26), 0x8000)
+	println("value:", adc.Get())
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"math"
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+)
+
+var sink []byte
+
+func main() {
+	// Check that the descriptions are consistent with the values.
+	descs := metrics.All()
+	samples := make([]metrics.Sample, len(descs))
+	for i, desc := range descs {
+		samples[i].Name = desc.Name
+	}
+	metrics.Read(samples)
+	for i, sample := range samples {
+		if sample.Value.Kind() != descs[i].Kind {
+			println("unexpected kind for", sample.Name)
+		}
+	}
+
+	// Unknown metrics are reported as such.
+	unknown := []metrics.Sample{{Name: "/unknown/metric:bytes"}}
+	metrics.Read(unknown)
+	println("unknown metric is bad:", unknown[0].Value.Kind() == metrics.KindBad)
+
+	// Allocated bytes must increase after an allocation.
+	before := readUint64("/gc/heap/allocs:bytes")
+	sink = make([]byte, 1000)
+	after := readUint64("/gc/heap/allocs:bytes")
+	println("allocated bytes increased:", after >= before+1000)
+
+	// Forced GC cycles are counted.
+	forced := readUint64("/gc/cycles/forced:gc-cycles")
+	runtime.GC()
+	println("forced GC cycles:", readUint64("/gc/cycles/forced:gc-cycles")-forced)
+	println("total includes forced:", readUint64("/gc/cycles/total:gc-cycles") >= readUint64("/gc/cycles/forced:gc-cycles"))
+
+	// The GC settings can be changed, and are reported.
+	println("gogc:", readUint64("/gc/gogc:percent"))
+	println("previous GC percent:", debug.SetGCPercent(50))
+	println("gogc:", readUint64("/gc/gogc:percent"))
+	debug.SetGCPercent(100)
+	println("memory limit is off:", debug.SetMemoryLimit(64<<20) == math.MaxInt64)
+	println("gomemlimit:", readUint64("/gc/gomemlimit:bytes"))
+	println("query memory limit:", debug.SetMemoryLimit(-1))
+	debug.SetMemoryLimit(math.MaxInt64)
+
+	// Goroutines are counted, including the main goroutine.
+	println("goroutines:", readUint64("/sched/goroutines:goroutines"))
+	started := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		started <- struct{}{}
+		<-done
+	}()
+	<-started
+	println("goroutines:", readUint64("/sched/goroutines:goroutines"))
+	close(done)
+}
+
+func readUint64(name string) uint64 {
+	samples := []metrics.Sample{{Name: name}}
+	metrics.Read(samples)
+	return samples[0].Value.Uint64()
+}
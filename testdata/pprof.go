@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"runtime"
+	"runtime/pprof"
+)
+
+var sink []byte
+
+func main() {
+	runtime.MemProfileRate = 1
+	testHeapProfile()
+}
+
+func allocate() {
+	sink = make([]byte, 100)
+}
+
+func testHeapProfile() {
+	for i := 0; i < 10; i++ {
+		allocate()
+	}
+	sink = nil
+	runtime.GC()
+
+	// Find the allocations in the profile.
+	records := make([]runtime.MemProfileRecord, 1000)
+	n, ok := runtime.MemProfile(records, true)
+	if !ok {
+		println("MemProfile: too many records:", n)
+		return
+	}
+	for _, r := range records[:n] {
+		frame, _ := runtime.CallersFrames(r.Stack()).Next()
+		if frame.Function == "main.allocate" {
+			println("allocations:", r.AllocObjects, r.AllocBytes)
+			println("freed:", r.FreeObjects <= r.AllocObjects)
+		}
+	}
+
+	// Check the protobuf version of the profile.
+	buf := &bytes.Buffer{}
+	if err := pprof.WriteHeapProfile(buf); err != nil {
+		println("WriteHeapProfile:", err.Error())
+		return
+	}
+	checkProfile("heap", buf, "main.allocate")
+}
+
+// checkProfile checks that the profile is valid gzip data and that it contains
+// the given function name in its string table.
+func checkProfile(name string, buf *bytes.Buffer, function string) {
+	r, err := gzip.NewReader(buf)
+	if err != nil {
+		println(name, "profile:", err.Error())
+		return
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		println(name, "profile:", err.Error())
+		return
+	}
+	println(name, "profile contains", function+":", bytes.Contains(data, []byte(function)))
+}
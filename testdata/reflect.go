@@ -364,6 +364,15 @@ func main() {
 	println("\nv.Interface() method")
 	testInterfaceMethod()
 
+	println("\nfunction calls")
+	testCall()
+
+	println("\nMakeFunc")
+	testMakeFunc()
+
+	println("\nchannels")
+	testChan()
+
 	// Test reflect.DeepEqual.
 	var selfref1, selfref2 selfref
 	selfref1.x = &selfref1
@@ -573,6 +582,116 @@ func testInterfaceMethod() {
 	}
 }
 
+type counter struct {
+	n int
+}
+
+func (c counter) Get() int {
+	return c.n
+}
+
+func (c *counter) Add(delta int) {
+	c.n += delta
+}
+
+// Test calling functions and methods through reflection.
+func testCall() {
+	sum := reflect.ValueOf(func(base int, values ...int) (int, string) {
+		for _, value := range values {
+			base += value
+		}
+		return base, strconv.Itoa(len(values)) + " values"
+	})
+	typ := sum.Type()
+	println(typ.String(), typ.NumIn(), typ.NumOut(), typ.IsVariadic())
+	println("in:", typ.In(0).String(), typ.In(1).String(), "out:", typ.Out(0).String(), typ.Out(1).String())
+	out := sum.Call([]reflect.Value{reflect.ValueOf(1), reflect.ValueOf(2), reflect.ValueOf(3)})
+	println("Call:", out[0].Int(), out[1].String())
+	out = sum.CallSlice([]reflect.Value{reflect.ValueOf(10), reflect.ValueOf([]int{20, 30})})
+	println("CallSlice:", out[0].Int(), out[1].String())
+
+	c := &counter{n: 5}
+	v := reflect.ValueOf(c)
+	println("methods:", v.Type().NumMethod(), v.Elem().Type().NumMethod())
+	for i := 0; i < v.Type().NumMethod(); i++ {
+		m := v.Type().Method(i)
+		println("method:", m.Name, m.Type.String(), m.Index)
+	}
+	v.MethodByName("Add").Call([]reflect.Value{reflect.ValueOf(3)})
+	println("Get:", v.Method(1).Call(nil)[0].Int())
+	m, ok := reflect.TypeOf(counter{}).MethodByName("Get")
+	println("Func:", ok, m.Func.Call([]reflect.Value{reflect.ValueOf(counter{n: 42})})[0].Int())
+	println("missing:", v.MethodByName("Missing").IsValid())
+}
+
+// Test creating functions with reflect.MakeFunc, and converting method values
+// to functions.
+func testMakeFunc() {
+	var swap func(int, string, [3]int16) ([3]int16, string, int)
+	fn := reflect.MakeFunc(reflect.TypeOf(swap), func(in []reflect.Value) []reflect.Value {
+		return []reflect.Value{in[2], in[1], in[0]}
+	})
+	reflect.ValueOf(&swap).Elem().Set(fn)
+	x, y, z := swap(3, "two", [3]int16{1, -1, 2})
+	println("swap:", x[0], x[1], x[2], y, z)
+
+	var join func(string, ...string) string
+	fn = reflect.MakeFunc(reflect.TypeOf(join), func(in []reflect.Value) []reflect.Value {
+		s := ""
+		for i := 0; i < in[1].Len(); i++ {
+			s += in[1].Index(i).String() + in[0].String()
+		}
+		return []reflect.Value{reflect.ValueOf(s)}
+	})
+	join = fn.Interface().(func(string, ...string) string)
+	println("join:", join(",", "a", "b", "c"))
+	println("Call:", fn.Call([]reflect.Value{reflect.ValueOf("-"), reflect.ValueOf("x"), reflect.ValueOf("y")})[0].String())
+
+	c := &counter{n: 1}
+	add := reflect.ValueOf(c).MethodByName("Add").Interface().(func(int))
+	add(10)
+	var get func() int
+	reflect.ValueOf(&get).Elem().Set(reflect.ValueOf(c).MethodByName("Get"))
+	println("method value:", get(), c.n)
+}
+
+// Test channel operations through reflection.
+func testChan() {
+	ch := reflect.MakeChan(reflect.TypeOf((chan string)(nil)), 2)
+	println("MakeChan:", ch.Type().String(), ch.Len(), ch.Cap())
+	ch.Send(reflect.ValueOf("first"))
+	println("TrySend:", ch.TrySend(reflect.ValueOf("second")), ch.TrySend(reflect.ValueOf("third")))
+	x, ok := ch.Recv()
+	println("Recv:", x.String(), ok)
+	x, ok = ch.TryRecv()
+	println("TryRecv:", x.String(), ok)
+	x, ok = ch.TryRecv()
+	println("TryRecv empty:", x.IsValid(), ok)
+	ch.Close()
+	x, ok = ch.Recv()
+	println("Recv closed:", x.String() == "", ok)
+
+	ints := make(chan int)
+	done := make(chan struct{}, 1)
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectSend, Chan: reflect.ValueOf(done), Send: reflect.ValueOf(struct{}{})},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ints)},
+	}
+	chosen, recv, recvOK := reflect.Select(cases)
+	println("Select:", chosen, recv.IsValid(), recvOK)
+	go func() {
+		ints <- 42
+	}()
+	chosen, recv, recvOK = reflect.Select(cases[1:])
+	println("Select recv:", chosen, recv.Int(), recvOK)
+	cases[0].Chan = reflect.Value{}
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectDefault})
+	chosen, recv, recvOK = reflect.Select(cases)
+	println("Select default:", chosen, recv.IsValid(), recvOK)
+
+	println("ChanOf:", reflect.ChanOf(reflect.RecvDir, reflect.TypeOf(0)) == reflect.TypeOf((<-chan int)(nil)))
+}
+
 var xorshift32State uint32 = 1
 
 func xorshift32(x uint32) uint32 {
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"runtime"
+	"runtime/trace"
+	"strings"
+)
+
+func main() {
+	println("enabled before start:", trace.IsEnabled())
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		println("could not start trace:", err.Error())
+		return
+	}
+	println("enabled:", trace.IsEnabled())
+	println("start again fails:", trace.Start(&buf) != nil)
+
+	ctx, task := trace.NewTask(context.Background(), "main task")
+	ch := make(chan int)
+	done := make(chan struct{})
+	go func() {
+		trace.WithRegion(ctx, "worker region", func() {
+			for v := range ch {
+				trace.Logf(ctx, "worker", "received %d", v)
+			}
+		})
+		close(done)
+	}()
+	for i := 0; i < 3; i++ {
+		ch <- i
+	}
+	close(ch)
+	<-done
+	runtime.GC()
+	task.End()
+	trace.Stop()
+	println("enabled after stop:", trace.IsEnabled())
+
+	println("valid JSON:", json.Valid(buf.Bytes()))
+	out := buf.String()
+	for _, s := range []string{
+		`"name":"goroutine 2"`,
+		`"name":"running"`,
+		`"name":"blocked: chan send"`,
+		`"name":"chan close"`,
+		`"name":"GC"`,
+		`"name":"main task"`,
+		`"name":"worker region"`,
+		`"message":"received 2"`,
+	} {
+		println("contains", s+":", strings.Contains(out, s))
+	}
+}
@@ -263,6 +263,20 @@ func (p *lowerInterfacesPass) run() error {
 		})
 	}
 
+	// Check whether the reflect package needs the method sets or the function
+	// call wrappers at runtime. This must be done before defining the invoke
+	// thunks, as those reference all methods in the method sets.
+	methodSetFunctions := make(map[llvm.Value]struct{})
+	for _, t := range p.types {
+		for _, method := range t.methods {
+			methodSetFunctions[method.function] = struct{}{}
+		}
+	}
+	keepMethodSets := p.isReflectUsed(reflectMethodFunctions, reflectMethodSignatures, interfaceInvokeFunctions, methodSetFunctions)
+	keepMethodSignatures := keepMethodSets || p.isReflectUsed(reflectImplementsFunctions, nil, interfaceInvokeFunctions, methodSetFunctions)
+	keepCallWrappers := p.isReflectUsed(reflectCallFunctions, reflectCallSignatures, interfaceInvokeFunctions, methodSetFunctions)
+	keepMakeFuncTrampolines := keepMethodSets || p.isReflectUsed(reflectMakeFuncFunctions, nil, interfaceInvokeFunctions, methodSetFunctions)
+
 	// Define all interface invoke thunks.
 	for _, fn := range interfaceInvokeFunctions {
 		methodsAttr := fn.GetStringAttributeAtIndex(-1, "tinygo-methods")
@@ -325,12 +339,76 @@ func (p *lowerInterfacesPass) run() error {
 	}
 	sort.Strings(typeNames)
 
+	// Remove the function call wrappers from all function types if
+	// reflect.Value.Call is never used, and the MakeFunc trampolines if
+	// reflect.MakeFunc is never used (method values also need them), so that
+	// they can be removed by the optimizer.
+	var removeFuncTypeFields []int
+	if !keepCallWrappers {
+		removeFuncTypeFields = append(removeFuncTypeFields, funcTypeCallIndex)
+	}
+	if !keepMakeFuncTrampolines {
+		removeFuncTypeFields = append(removeFuncTypeFields, funcTypeMakeFuncIndex)
+	}
+	if len(removeFuncTypeFields) != 0 {
+		for _, name := range typeNames {
+			t := p.types[name]
+			if !strings.HasPrefix(name, "func:") {
+				continue
+			}
+			initializer := t.typecode.Initializer()
+			if initializer.Type().StructElementTypesCount() <= funcTypeMakeFuncIndex {
+				continue
+			}
+			var fields []llvm.Value
+			for i := 0; i < initializer.Type().StructElementTypesCount(); i++ {
+				field := p.builder.CreateExtractValue(initializer, i, "")
+				for _, index := range removeFuncTypeFields {
+					if i == index {
+						field = llvm.ConstNull(field.Type())
+					}
+				}
+				fields = append(fields, field)
+			}
+			t.typecode.SetInitializer(p.ctx.ConstStruct(fields, false))
+		}
+	}
+
+	// Define the list of channel types used by reflect.ChanOf.
+	p.defineChanTypes(typeNames)
+
 	// Remove all method sets, which are now unnecessary and inhibit later
-	// optimizations if they are left in place.
+	// optimizations if they are left in place. They are only kept when the
+	// reflect package needs them to look up methods at runtime. When it only
+	// needs to check whether a type implements an interface, only the
+	// signatures are kept, so that the methods themselves can still be removed.
 	zero := llvm.ConstInt(p.ctx.Int32Type(), 0, false)
+	signatureMethodSets := make(map[llvm.Value]llvm.Value)
 	for _, name := range typeNames {
 		t := p.types[name]
-		if !t.methodSet.IsNil() {
+		if newGlobal, ok := signatureMethodSets[t.methodSet]; ok {
+			// Method set shared with a type that was already handled.
+			t.methodSet = newGlobal
+		} else if !t.methodSet.IsNil() && !keepMethodSets && keepMethodSignatures {
+			initializer := t.methodSet.Initializer()
+			newInitializer := p.ctx.ConstStruct([]llvm.Value{
+				p.builder.CreateExtractValue(initializer, 0, ""), // length
+				p.builder.CreateExtractValue(initializer, 1, ""), // signatures
+			}, false)
+			methodSetName := t.methodSet.Name()
+			newGlobal := llvm.AddGlobal(p.mod, newInitializer.Type(), methodSetName+".tmp")
+			newGlobal.SetInitializer(newInitializer)
+			newGlobal.SetLinkage(t.methodSet.Linkage())
+			newGlobal.SetGlobalConstant(true)
+			newGlobal.SetUnnamedAddr(true)
+			newGlobal.SetAlignment(t.methodSet.Alignment())
+			t.methodSet.ReplaceAllUsesWith(newGlobal)
+			t.methodSet.EraseFromParentAsGlobal()
+			newGlobal.SetName(methodSetName)
+			signatureMethodSets[t.methodSet] = newGlobal
+			t.methodSet = newGlobal
+		}
+		if !t.methodSet.IsNil() && !keepMethodSignatures {
 			initializer := t.typecode.Initializer()
 			var newInitializerFields []llvm.Value
 			for i := 1; i < initializer.Type().StructElementTypesCount(); i++ {
@@ -369,6 +447,146 @@ func (p *lowerInterfacesPass) run() error {
 	return nil
 }
 
+// Functions and interface method signatures in the reflect package that need
+// the method sets at runtime.
+var (
+	reflectMethodFunctions = []string{
+		"(reflect.Value).Method",
+		"(reflect.Value).MethodByName",
+		"(*reflect.rawType).Method",
+		"(*reflect.rawType).MethodByName",
+	}
+	reflectMethodSignatures = []string{
+		"reflect/methods.Method(int) reflect.Value",
+		"reflect/methods.MethodByName(string) reflect.Value",
+		"reflect/methods.Method(int) reflect.Method",
+		"reflect/methods.MethodByName(string) (reflect.Method, bool)",
+	}
+)
+
+// Functions in the reflect package that need the method signatures in the
+// method sets at runtime, to check whether a type implements an interface.
+var reflectImplementsFunctions = []string{
+	"(*internal/reflectlite.RawType).implements",
+}
+
+// Functions and interface method signatures in the reflect package that need
+// the function call wrappers at runtime.
+var (
+	reflectCallFunctions = []string{
+		"(reflect.Value).Call",
+		"(reflect.Value).CallSlice",
+		"(internal/reflectlite.Value).Call", // used by the runtime to run finalizers
+		"(internal/reflectlite.Value).CallSlice",
+	}
+	reflectCallSignatures = []string{
+		"reflect/methods.Call([]reflect.Value) []reflect.Value",
+		"reflect/methods.CallSlice([]reflect.Value) []reflect.Value",
+	}
+)
+
+// Functions in the reflect package that need the MakeFunc trampolines at
+// runtime.
+var reflectMakeFuncFunctions = []string{
+	"reflect.MakeFunc",
+}
+
+// Indices of the call wrapper and the MakeFunc trampoline in a function type
+// struct. These must match the funcType struct in
+// src/internal/reflectlite/type.go.
+const (
+	funcTypeCallIndex     = 5
+	funcTypeMakeFuncIndex = 6
+)
+
+// isReflectUsed returns whether any of the given reflect functions is used
+// (other than through a method set), or whether any of the given interface
+// method signatures is invoked.
+func (p *lowerInterfacesPass) isReflectUsed(functions, signatures []string, invokeFunctions []llvm.Value, methodSetFunctions map[llvm.Value]struct{}) bool {
+	for _, fn := range invokeFunctions {
+		invokeAttr := fn.GetStringAttributeAtIndex(-1, "tinygo-invoke")
+		for _, signature := range signatures {
+			if invokeAttr.GetStringValue() == signature {
+				return true
+			}
+		}
+	}
+	checked := make(map[llvm.Value]struct{})
+	for _, name := range functions {
+		fn := p.mod.NamedFunction(name)
+		if !fn.IsNil() && usedOutsideMethodSets(fn, methodSetFunctions, checked) {
+			return true
+		}
+	}
+	return false
+}
+
+// usedOutsideMethodSets returns whether the given value is used anywhere other
+// than in a method set. Methods are also referenced from method sets (directly
+// or through a wrapper), so these uses don't count.
+func usedOutsideMethodSets(value llvm.Value, methodSetFunctions map[llvm.Value]struct{}, checked map[llvm.Value]struct{}) bool {
+	if _, ok := checked[value]; ok {
+		return false
+	}
+	checked[value] = struct{}{}
+	for _, use := range getUses(value) {
+		switch {
+		case !use.IsAGlobalVariable().IsNil():
+			if !strings.HasSuffix(use.Name(), "$methodset") {
+				return true
+			}
+		case !use.IsAInstruction().IsNil():
+			parent := use.InstructionParent().Parent()
+			if _, ok := methodSetFunctions[parent]; !ok {
+				return true
+			}
+			// This is a wrapper in a method set, check where it is used.
+			if usedOutsideMethodSets(parent, methodSetFunctions, checked) {
+				return true
+			}
+		default:
+			// Constant expression, check where it is used.
+			if usedOutsideMethodSets(use, methodSetFunctions, checked) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// defineChanTypes defines the list of all channel types in the program, which
+// is used by reflect.ChanOf to find an existing channel type (it is not
+// possible to create new types at runtime). This list is only created when it
+// is referenced, that is, when ChanOf is used.
+func (p *lowerInterfacesPass) defineChanTypes(typeNames []string) {
+	chanTypes := p.mod.NamedGlobal("internal/reflectlite.chanTypes")
+	if chanTypes.IsNil() || !chanTypes.IsDeclaration() {
+		return
+	}
+	var typecodes []llvm.Value
+	for _, name := range typeNames {
+		if strings.HasPrefix(name, "chan:") {
+			typecodes = append(typecodes, p.types[name].typecodeGEP)
+		}
+	}
+
+	// The list is a struct with the number of types followed by the types
+	// themselves, see chanTypes in src/internal/reflectlite/type.go.
+	initializer := p.ctx.ConstStruct([]llvm.Value{
+		llvm.ConstInt(p.uintptrType, uint64(len(typecodes)), false),
+		llvm.ConstArray(p.ptrType, typecodes),
+	}, false)
+	global := llvm.AddGlobal(p.mod, initializer.Type(), "")
+	global.SetInitializer(initializer)
+	global.SetGlobalConstant(true)
+	global.SetLinkage(llvm.InternalLinkage)
+	global.SetAlignment(p.targetData.ABITypeAlignment(p.uintptrType))
+	chanTypes.ReplaceAllUsesWith(global)
+	name := chanTypes.Name()
+	chanTypes.EraseFromParentAsGlobal()
+	global.SetName(name)
+}
+
 // addTypeMethods reads the method set of the given type info struct. It
 // retrieves the signatures and the references to the method functions
 // themselves for later type<->interface matching.
@@ -23,3 +23,41 @@ func TestInterfaceLowering(t *testing.T) {
 		}
 	})
 }
+
+// Test that the function call wrappers are kept when only the runtime calls
+// functions through reflection, to run finalizers.
+func TestInterfaceLoweringFinalizer(t *testing.T) {
+	t.Parallel()
+	testTransform(t, "testdata/interface-finalizer", func(mod llvm.Module) {
+		err := transform.LowerInterfaces(mod, defaultTestConfig)
+		if err != nil {
+			t.Error(err)
+		}
+
+		po := llvm.NewPassBuilderOptions()
+		defer po.Dispose()
+		err = mod.RunPasses("globaldce", llvm.TargetMachine{}, po)
+		if err != nil {
+			t.Error("failed to run passes:", err)
+		}
+	})
+}
+
+// Test that only the method signatures are kept in the method sets when the
+// reflect package only needs to check whether a type implements an interface.
+func TestInterfaceLoweringImplements(t *testing.T) {
+	t.Parallel()
+	testTransform(t, "testdata/interface-implements", func(mod llvm.Module) {
+		err := transform.LowerInterfaces(mod, defaultTestConfig)
+		if err != nil {
+			t.Error(err)
+		}
+
+		po := llvm.NewPassBuilderOptions()
+		defer po.Dispose()
+		err = mod.RunPasses("globaldce", llvm.TargetMachine{}, po)
+		if err != nil {
+			t.Error("failed to run passes:", err)
+		}
+	})
+}
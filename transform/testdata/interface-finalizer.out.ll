@@ -0,0 +1,25 @@
+target datalayout = "e-m:e-p:32:32-i64:64-v128:64:128-a:0:32-n32-S64"
+target triple = "armv7m-none-eabi"
+
+@"reflect/types.type:basic:int" = linkonce_odr constant { i8, ptr } { i8 2, ptr @"reflect/types.type:pointer:basic:int" }, align 4
+@"reflect/types.type:pointer:basic:int" = linkonce_odr constant { i8, ptr } { i8 21, ptr @"reflect/types.type:basic:int" }, align 4
+@"reflect/types.type:func:{pointer:basic:int}{}" = linkonce_odr constant { i8, i16, ptr, i16, i16, ptr, ptr, [1 x ptr] } { i8 19, i16 0, ptr @"reflect/types.type:pointer:func:{pointer:basic:int}{}", i16 1, i16 0, ptr @"reflect/types.type:func:{pointer:basic:int}{}.$call", ptr null, [1 x ptr] [ptr @"reflect/types.type:pointer:basic:int"] }, align 4
+@"reflect/types.type:pointer:func:{pointer:basic:int}{}" = linkonce_odr constant { i8, ptr } { i8 21, ptr @"reflect/types.type:func:{pointer:basic:int}{}" }, align 4
+
+declare void @runtime.SetFinalizer(ptr, ptr, ptr, ptr, ptr)
+
+declare { ptr, i32, i32 } @"(internal/reflectlite.Value).Call"(ptr, ptr, i8, ptr, i32, i32, ptr)
+
+define void @main.main(ptr %obj, ptr %finalizer) {
+  call void @runtime.SetFinalizer(ptr @"reflect/types.type:pointer:basic:int", ptr %obj, ptr @"reflect/types.type:func:{pointer:basic:int}{}", ptr %finalizer, ptr undef)
+  ret void
+}
+
+define void @runtime.runFinalizers(ptr %typecode, ptr %value, ptr %args) {
+  %result = call { ptr, i32, i32 } @"(internal/reflectlite.Value).Call"(ptr %typecode, ptr %value, i8 0, ptr %args, i32 1, i32 1, ptr undef)
+  ret void
+}
+
+define internal void @"reflect/types.type:func:{pointer:basic:int}{}.$call"(ptr %fn, ptr %args, ptr %results) {
+  ret void
+}
@@ -0,0 +1,32 @@
+target datalayout = "e-m:e-p:32:32-i64:64-v128:64:128-a:0:32-n32-S64"
+target triple = "armv7m-none-eabi"
+
+; A program that checks whether a type implements an interface using reflect,
+; but doesn't look up methods. Only the signatures in the method set are kept,
+; so that the method itself can be removed.
+
+@"reflect/types.type:basic:int" = linkonce_odr constant { i8, ptr } { i8 2, ptr @"reflect/types.type:pointer:basic:int" }, align 4
+@"reflect/types.type:pointer:basic:int" = linkonce_odr constant { i8, ptr } { i8 21, ptr @"reflect/types.type:basic:int" }, align 4
+@"reflect/methods.Double() int" = linkonce_odr constant i8 0
+@"Number$methodset" = linkonce_odr unnamed_addr constant { i32, [1 x ptr], { ptr }, [1 x ptr], [1 x ptr] } { i32 1, [1 x ptr] [ptr @"reflect/methods.Double() int"], { ptr } { ptr @"(Number).Double$invoke" }, [1 x ptr] [ptr @"reflect/types.type:basic:int"], [1 x ptr] [ptr @"(Number).Double"] }
+@"reflect/types.type:named:Number" = linkonce_odr constant { ptr, i8, i16, ptr, ptr } { ptr @"Number$methodset", i8 34, i16 1, ptr @"reflect/types.type:pointer:named:Number", ptr @"reflect/types.type:basic:int" }, align 4
+@"reflect/types.type:pointer:named:Number" = linkonce_odr constant { i8, ptr } { i8 21, ptr getelementptr inbounds ({ ptr, i8, i16, ptr, ptr }, ptr @"reflect/types.type:named:Number", i32 0, i32 1) }, align 4
+@"reflect/types.type:interface:{Double:func:{}{basic:int}}" = linkonce_odr constant { i8, i16, ptr, [1 x ptr] } { i8 20, i16 1, ptr null, [1 x ptr] [ptr @"reflect/methods.Double() int"] }, align 4
+
+declare i1 @"(*internal/reflectlite.RawType).implements"(ptr, ptr, ptr)
+
+define i1 @main.implements() {
+  %result = call i1 @"(*internal/reflectlite.RawType).implements"(ptr getelementptr inbounds ({ ptr, i8, i16, ptr, ptr }, ptr @"reflect/types.type:named:Number", i32 0, i32 1), ptr @"reflect/types.type:interface:{Double:func:{}{basic:int}}", ptr undef)
+  ret i1 %result
+}
+
+define internal i32 @"(Number).Double"(i32 %receiver, ptr %context) {
+  %ret = mul i32 %receiver, 2
+  ret i32 %ret
+}
+
+define internal i32 @"(Number).Double$invoke"(ptr %receiverPtr, ptr %context) {
+  %receiver = ptrtoint ptr %receiverPtr to i32
+  %ret = call i32 @"(Number).Double"(i32 %receiver, ptr undef)
+  ret i32 %ret
+}
@@ -0,0 +1,17 @@
+target datalayout = "e-m:e-p:32:32-i64:64-v128:64:128-a:0:32-n32-S64"
+target triple = "armv7m-none-eabi"
+
+@"reflect/types.type:basic:int" = linkonce_odr constant { i8, ptr } { i8 2, ptr @"reflect/types.type:pointer:basic:int" }, align 4
+@"reflect/types.type:pointer:basic:int" = linkonce_odr constant { i8, ptr } { i8 21, ptr @"reflect/types.type:basic:int" }, align 4
+@"reflect/methods.Double() int" = linkonce_odr constant i8 0
+@"reflect/types.type:named:Number" = linkonce_odr constant { ptr, i8, i16, ptr, ptr } { ptr @"Number$methodset", i8 34, i16 1, ptr @"reflect/types.type:pointer:named:Number", ptr @"reflect/types.type:basic:int" }, align 4
+@"reflect/types.type:pointer:named:Number" = linkonce_odr constant { i8, ptr } { i8 21, ptr getelementptr inbounds ({ ptr, i8, i16, ptr, ptr }, ptr @"reflect/types.type:named:Number", i32 0, i32 1) }, align 4
+@"reflect/types.type:interface:{Double:func:{}{basic:int}}" = linkonce_odr constant { i8, i16, ptr, [1 x ptr] } { i8 20, i16 1, ptr null, [1 x ptr] [ptr @"reflect/methods.Double() int"] }, align 4
+@"Number$methodset" = linkonce_odr unnamed_addr constant { i32, [1 x ptr] } { i32 1, [1 x ptr] [ptr @"reflect/methods.Double() int"] }
+
+declare i1 @"(*internal/reflectlite.RawType).implements"(ptr, ptr, ptr)
+
+define i1 @main.implements() {
+  %result = call i1 @"(*internal/reflectlite.RawType).implements"(ptr getelementptr inbounds ({ ptr, i8, i16, ptr, ptr }, ptr @"reflect/types.type:named:Number", i32 0, i32 1), ptr @"reflect/types.type:interface:{Double:func:{}{basic:int}}", ptr undef)
+  ret i1 %result
+}